@@ -0,0 +1,143 @@
+package log
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alrusov/misc"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// AccessFormat -- format of the access log line
+type AccessFormat int
+
+const (
+	// CommonLog -- host - - "METHOD path?query" status bytes duration
+	CommonLog AccessFormat = iota
+	// CombinedLog -- CommonLog with "referer" "user-agent" before the duration and the request id at the end
+	CombinedLog
+	// KV -- key=value pairs
+	KV
+)
+
+// AccessRecord -- served request
+type AccessRecord struct {
+	Method     string
+	Path       string
+	Query      string
+	Status     int
+	Bytes      int64
+	Duration   time.Duration
+	RemoteAddr string
+	UserAgent  string
+	RequestID  string
+}
+
+// AccessLog -- access log writer for HTTP middlewares. The date is not repeated, the log line already has it.
+type AccessLog struct {
+	f       *Facility
+	level   Level
+	format  AccessFormat
+	replace *misc.Replace
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// NewAccessLog -- create the access log writing to the facility with the level
+func NewAccessLog(f *Facility, level Level, format AccessFormat) *AccessLog {
+	if f == nil {
+		f = stdFacility
+	}
+
+	return &AccessLog{
+		f:      f,
+		level:  level,
+		format: format,
+	}
+}
+
+// SetRedaction -- replace rules applied to query strings
+func (a *AccessLog) SetRedaction(replace *misc.Replace) {
+	a.replace = replace
+}
+
+// Log -- write the record. Nothing is formatted if the level is disabled for the facility.
+func (a *AccessLog) Log(rec AccessRecord) {
+	if a.f.disabled.Load() || !a.level.passes(a.f.token.Load()) {
+		return
+	}
+
+	a.f.MessageEx(1, a.level, nil, "%s", a.Format(rec))
+}
+
+// Format -- the record as it is written to the log
+func (a *AccessLog) Format(rec AccessRecord) string {
+	query := rec.Query
+	if query != "" && a.replace != nil {
+		query = Redact(a.replace, query)
+	}
+
+	duration := strconv.FormatFloat(float64(rec.Duration)/float64(time.Millisecond), 'f', 3, 64)
+
+	if a.format == KV {
+		var b strings.Builder
+		kv := func(name string, v string) {
+			writeKV(&b, name, v)
+		}
+
+		kv("remote", rec.RemoteAddr)
+		kv("method", rec.Method)
+		kv("path", rec.Path)
+		kv("query", query)
+		kv("status", strconv.Itoa(rec.Status))
+		kv("bytes", strconv.FormatInt(rec.Bytes, 10))
+		kv("duration_ms", duration)
+		kv("ua", rec.UserAgent)
+		kv("rid", rec.RequestID)
+		return b.String()
+	}
+
+	uri := rec.Path
+	if query != "" {
+		uri += "?" + query
+	}
+
+	bytes := "-"
+	if rec.Bytes > 0 {
+		bytes = strconv.FormatInt(rec.Bytes, 10)
+	}
+
+	line := fmt.Sprintf(`%s - - "%s %s" %d %s`, accessHost(rec.RemoteAddr), rec.Method, accessEscape(uri), rec.Status, bytes)
+
+	if a.format == CombinedLog {
+		return fmt.Sprintf(`%s "-" "%s" %sms %s`, line, accessEscape(rec.UserAgent), duration, accessDash(rec.RequestID))
+	}
+
+	return fmt.Sprintf("%s %sms", line, duration)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func accessHost(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	return accessDash(addr)
+}
+
+func accessDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func accessEscape(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, `\`, `\\`), `"`, `\"`)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
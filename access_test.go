@@ -0,0 +1,92 @@
+package log
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alrusov/misc"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestAccessLogFormats(t *testing.T) {
+	resetLog(t)
+
+	replace := misc.NewReplace()
+	replace.Add(`(token=)[^&]*(&|$)`, "${1}***$2")
+
+	recs := []AccessRecord{
+		{
+			Method:     "GET",
+			Path:       "/api/items",
+			Query:      "id=5&token=secret",
+			Status:     200,
+			Bytes:      512,
+			Duration:   12345678 * time.Nanosecond,
+			RemoteAddr: "[2001:db8::1]:54321",
+			UserAgent:  `curl/8.0 "test"`,
+			RequestID:  "r-1",
+		},
+		{
+			Method:     "HEAD",
+			Path:       "/",
+			Status:     304,
+			Duration:   500 * time.Microsecond,
+			RemoteAddr: "10.0.0.1:80",
+		},
+	}
+
+	expected := map[AccessFormat][]string{
+		CommonLog: {
+			`2001:db8::1 - - "GET /api/items?id=5&token=***" 200 512 12.346ms`,
+			`10.0.0.1 - - "HEAD /" 304 - 0.500ms`,
+		},
+		CombinedLog: {
+			`2001:db8::1 - - "GET /api/items?id=5&token=***" 200 512 "-" "curl/8.0 \"test\"" 12.346ms r-1`,
+			`10.0.0.1 - - "HEAD /" 304 - "-" "" 0.500ms -`,
+		},
+		KV: {
+			`remote=[2001:db8::1]:54321 method=GET path=/api/items query="id=5&token=***" status=200 bytes=512 duration_ms=12.346 ua="curl/8.0 \"test\"" rid=r-1`,
+			`remote=10.0.0.1:80 method=HEAD path=/ query="" status=304 bytes=0 duration_ms=0.500 ua="" rid=""`,
+		},
+	}
+
+	for format, lines := range expected {
+		a := NewAccessLog(nil, INFO, format)
+		a.SetRedaction(replace)
+		for i, rec := range recs {
+			if s := a.Format(rec); s != lines[i] {
+				t.Errorf("[%d.%d] got\n%s\nexpected\n%s", format, i, s, lines[i])
+			}
+		}
+	}
+}
+
+func TestAccessLogDisabled(t *testing.T) {
+	w := resetLog(t)
+
+	f := GetFacility("http")
+	a := NewAccessLog(f, DEBUG, CommonLog)
+	rec := AccessRecord{Method: "GET", Path: "/", Status: 200}
+
+	f.SetLogLevel("INFO", FuncNameModeNone)
+	w.buf.Reset()
+	a.Log(rec)
+	if s := w.String(); s != "" {
+		t.Fatalf("unexpected output %q", s)
+	}
+
+	if n := testing.AllocsPerRun(100, func() { a.Log(rec) }); n != 0 {
+		t.Errorf("disabled access log allocates %v times", n)
+	}
+
+	f.SetLogLevel("DEBUG", FuncNameModeNone)
+	w.buf.Reset()
+	a.Log(rec)
+	if s := strings.TrimSpace(w.String()); !strings.HasSuffix(s, `<http> - - - "GET /" 200 - 0.000ms`) {
+		t.Errorf("unexpected output %q", s)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,176 @@
+package log
+
+import (
+	"fmt"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Adapters log the messages of other libraries: the hijacked stdlib logger, the service manager and the cron
+// scheduler. Each of them writes to the facility with the levels chosen by the deployment, so the sources can be told
+// apart by the facility tag. Without the configuration they write to the standard facility with the classic levels:
+// NOTICE for the stdlib logger, ERR, WARNING and INFO for the service, TRACE2 and ERR for the cron.
+
+// ServiceLevelMap -- levels of the ServiceLogger methods
+type ServiceLevelMap struct {
+	Error   Level
+	Warning Level
+	Info    Level
+}
+
+// ServiceLogger -- the logger of the service manager. The zero value writes to the standard facility with
+// DefaultServiceLevels.
+type ServiceLogger struct {
+	facility *Facility
+	levels   *ServiceLevelMap
+}
+
+// CronLog -- the logger of the cron scheduler
+type CronLog struct {
+	facility   *Facility
+	infoLevel  Level
+	errLevel   Level
+	configured bool
+}
+
+var (
+	// DefaultServiceLevels -- levels of the ServiceLogger without the mapping
+	DefaultServiceLevels = ServiceLevelMap{Error: ERR, Warning: WARNING, Info: INFO}
+
+	stdlogFacility *Facility // nil for the standard facility
+	stdlogLevel    = NOTICE
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetStdlogRouting -- the facility and the level of the hijacked stdlib logger output, "" is the standard facility
+func SetStdlogRouting(facility string, level Level) {
+	f := GetFacility(facility)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	stdlogFacility = f
+	stdlogLevel = level
+}
+
+// stdlogRoute -- the facility and the level of the stdlib logger output
+func stdlogRoute() (*Facility, Level) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if stdlogFacility == nil {
+		return stdFacility, stdlogLevel
+	}
+	return stdlogFacility, stdlogLevel
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// NewServiceLogger -- the service logger writing to the facility (nil is the standard one) with the levels
+func NewServiceLogger(f *Facility, mapping ServiceLevelMap) *ServiceLogger {
+	return &ServiceLogger{
+		facility: f,
+		levels:   &mapping,
+	}
+}
+
+// Error --
+func (l *ServiceLogger) Error(v ...any) error {
+	l.target().MessageEx(1, l.mapping().Error, nil, "%s", fmt.Sprint(v...))
+	return nil
+}
+
+// Warning --
+func (l *ServiceLogger) Warning(v ...any) error {
+	l.target().MessageEx(1, l.mapping().Warning, nil, "%s", fmt.Sprint(v...))
+	return nil
+}
+
+// Info --
+func (l *ServiceLogger) Info(v ...any) error {
+	l.target().MessageEx(1, l.mapping().Info, nil, "%s", fmt.Sprint(v...))
+	return nil
+}
+
+// Errorf --
+func (l *ServiceLogger) Errorf(message string, a ...any) error {
+	l.target().MessageEx(1, l.mapping().Error, nil, message, a...)
+	return nil
+}
+
+// Warningf --
+func (l *ServiceLogger) Warningf(message string, a ...any) error {
+	l.target().MessageEx(1, l.mapping().Warning, nil, message, a...)
+	return nil
+}
+
+// Infof --
+func (l *ServiceLogger) Infof(message string, a ...any) error {
+	l.target().MessageEx(1, l.mapping().Info, nil, message, a...)
+	return nil
+}
+
+func (l *ServiceLogger) target() *Facility {
+	if l.facility == nil {
+		return stdFacility
+	}
+	return l.facility
+}
+
+func (l *ServiceLogger) mapping() *ServiceLevelMap {
+	if l.levels == nil {
+		return &DefaultServiceLevels
+	}
+	return l.levels
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// NewCronLog -- the cron logger writing to the facility (nil is the standard one). The zero value writes to
+// the standard facility with TRACE2 and ERR.
+func NewCronLog(f *Facility, infoLevel Level, errLevel Level) *CronLog {
+	return &CronLog{
+		facility:   f,
+		infoLevel:  infoLevel,
+		errLevel:   errLevel,
+		configured: true,
+	}
+}
+
+// Info -- the message with the key-value pairs
+func (l *CronLog) Info(msg string, keysAndValues ...any) {
+	f, info, _ := l.route()
+	f.MessageEx(1, info, nil, "%s", cronMessage(msg, nil, keysAndValues))
+}
+
+// Error -- the message with the error and the key-value pairs
+func (l *CronLog) Error(err error, msg string, keysAndValues ...any) {
+	f, _, errLevel := l.route()
+	f.MessageEx(1, errLevel, nil, "%s", cronMessage(msg, err, keysAndValues))
+}
+
+func (l *CronLog) route() (f *Facility, info Level, err Level) {
+	f, info, err = l.facility, l.infoLevel, l.errLevel
+	if !l.configured {
+		info, err = TRACE2, ERR
+	}
+	if f == nil {
+		f = stdFacility
+	}
+	return
+}
+
+func cronMessage(msg string, err error, keysAndValues []any) string {
+	if err != nil {
+		keysAndValues = append([]any{"error", err.Error()}, keysAndValues...)
+	}
+
+	kv := RenderKV(NormalizeKV(keysAndValues))
+	if kv == "" {
+		return msg
+	}
+	return msg + " " + kv
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
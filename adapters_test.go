@@ -0,0 +1,111 @@
+package log
+
+import (
+	"errors"
+	stdlog "log"
+	"strings"
+	"testing"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func checkAdapterLines(t *testing.T, console *captureWriter, expected []string) {
+	t.Helper()
+
+	lines := console.Lines()
+	if len(lines) != len(expected) {
+		t.Fatalf("got %d lines, expected %d:\n%s", len(lines), len(expected), console)
+	}
+	for i, e := range expected {
+		if !strings.Contains(lines[i], e) {
+			t.Errorf("[%d] got %q, expected %q", i, lines[i], e)
+		}
+	}
+}
+
+func TestStdlogRouting(t *testing.T) {
+	console := resetLog(t)
+	SetLogLevel("TRACE4", FuncNameModeNone)
+	console.buf.Reset()
+
+	std := stdlog.New(Writer(), "", 0)
+
+	std.Print("default")
+	SetStdlogRouting("stdlib", WARNING)
+	std.Print("routed")
+	std.Print("first\nsecond")
+
+	checkAdapterLines(t, console, []string{
+		" NO ",
+		" WA ",
+		" WA ",
+		" WA ",
+	})
+	lines := console.Lines()
+	if !strings.HasSuffix(lines[0], " default") || strings.Contains(lines[0], "<") || !strings.HasSuffix(lines[1], " <stdlib> routed") ||
+		!strings.Contains(lines[2], " <stdlib> first (blk=") || !strings.Contains(lines[3], " <stdlib> second (blk=") {
+		t.Errorf("unexpected lines:\n%s", console)
+	}
+}
+
+func TestServiceLoggerRouting(t *testing.T) {
+	console := resetLog(t)
+	SetLogLevel("TRACE4", FuncNameModeNone)
+	console.buf.Reset()
+
+	def := &ServiceLogger{}
+	def.Error("a", 1)
+	def.Warningf("b %d", 2)
+	def.Info("c")
+
+	l := NewServiceLogger(GetFacility("service"), ServiceLevelMap{Error: CRIT, Warning: NOTICE, Info: DEBUG})
+	l.Errorf("d %s", "x")
+	l.Warning("e")
+	l.Infof("f %d%%", 100)
+
+	checkAdapterLines(t, console, []string{
+		" ER ",
+		" WA ",
+		" IN ",
+		" CR ",
+		" NO ",
+		" DE ",
+	})
+
+	expected := []string{" a1", " b 2", " c", " <service> d x", " <service> e", " <service> f 100%"}
+	for i, line := range console.Lines() {
+		if !strings.HasSuffix(line, expected[i]) || (i < 3 && strings.Contains(line, "<")) {
+			t.Errorf("[%d] got %q, expected suffix %q", i, line, expected[i])
+		}
+	}
+}
+
+func TestCronLogRouting(t *testing.T) {
+	console := resetLog(t)
+	SetLogLevel("TRACE4", FuncNameModeNone)
+	console.buf.Reset()
+
+	def := &CronLog{}
+	def.Info("run", "entry", 1)
+	def.Error(errors.New("boom"), "failed", "entry", 1)
+
+	l := NewCronLog(GetFacility("cron"), INFO, ALERT)
+	l.Info("schedule", "next", "12:00 UTC")
+	l.Error(errors.New("job failed"), "failed")
+
+	checkAdapterLines(t, console, []string{
+		" T2 ",
+		" ER ",
+		" IN ",
+		" AL ",
+	})
+
+	expected := []string{" run entry=1", ` failed error=boom entry=1`, ` <cron> schedule next="12:00 UTC"`, ` <cron> failed error="job failed"`}
+	for i, line := range console.Lines() {
+		if !strings.HasSuffix(line, expected[i]) || (i < 2 && strings.Contains(line, "<")) {
+			t.Errorf("[%d] got %q, expected suffix %q", i, line, expected[i])
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
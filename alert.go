@@ -0,0 +1,57 @@
+package log
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Level change subscribers are called after the mutex is released, so they may log and change levels themselves.
+// The facility has already had the new level when they are called.
+
+type alertNotifications []func()
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// AddAlertFunc -- subscribe to the level changes of this facility only
+func (f *Facility) AddAlertFunc(alert ChangeLevelAlertFunc) int64 {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if f.alertSubscribers == nil {
+		f.alertSubscribers = map[int64]ChangeLevelAlertFunc{}
+	}
+
+	alertSubscriberID++
+	f.alertSubscribers[alertSubscriberID] = alert
+	return alertSubscriberID
+}
+
+// DelAlertFunc --
+func (f *Facility) DelAlertFunc(id int64) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	delete(f.alertSubscribers, id)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// add -- snapshot subscribers of the change. Must be called under the mutex.
+func (n *alertNotifications) add(f *Facility, change LevelChange) {
+	for _, alert := range alertSubscribers {
+		*n = append(*n, func() { alert(change.Facility, change.Old, change.New) })
+	}
+	for _, alert := range f.alertSubscribers {
+		*n = append(*n, func() { alert(change.Facility, change.Old, change.New) })
+	}
+	for _, alert := range levelChangeSubscribers {
+		*n = append(*n, func() { alert(change) })
+	}
+}
+
+// call -- must be called without the mutex
+func (n *alertNotifications) call() {
+	for _, f := range *n {
+		f()
+	}
+	*n = nil
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,67 @@
+package log
+
+import (
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestFacilityAlertFunc(t *testing.T) {
+	resetLog(t)
+
+	cache := GetFacility("cache")
+	other := GetFacility("other")
+
+	var seen []Level
+	id := cache.AddAlertFunc(func(facility string, old Level, new Level) {
+		if facility != "cache" {
+			t.Errorf("unexpected facility %q", facility)
+		}
+		seen = append(seen, cache.CurrentLogLevel())
+
+		// Must not deadlock
+		cache.Message(INFO, "level changed from %d to %d", old, new)
+		if new == TRACE1 {
+			other.SetLogLevel("ERR", FuncNameModeNone)
+		}
+	})
+
+	globalCalls := 0
+	gid := AddAlertFunc(func(facility string, old Level, new Level) {
+		globalCalls++
+		GetFacility(facility).Message(INFO, "global alert")
+	})
+	defer DelAlertFunc(gid)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		other.SetLogLevel("INFO", FuncNameModeNone)
+		cache.SetLogLevel("TRACE1", FuncNameModeNone)
+		SetLogLevels("DEBUG", nil, FuncNameModeNone)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("deadlock")
+	}
+
+	if len(seen) != 2 || seen[0] != TRACE1 || seen[1] != DEBUG {
+		t.Errorf("facility callback saw levels %v, expected [TRACE1 DEBUG]", seen)
+	}
+
+	// other: INFO, ERR, DEBUG; cache: TRACE1, DEBUG
+	if globalCalls != 5 {
+		t.Errorf("global callback called %d times, expected 5", globalCalls)
+	}
+
+	cache.DelAlertFunc(id)
+	cache.SetLogLevel("INFO", FuncNameModeNone)
+	if len(seen) != 2 {
+		t.Error("deleted callback called")
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
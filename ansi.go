@@ -0,0 +1,92 @@
+package log
+
+import (
+	"strings"
+	"sync/atomic"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Escape sequences of colored lines written by libraries can be removed from the file, the last lines and other copies,
+// the console gets the line as is. CSI sequences (including SGR colors) and two-byte sequences are removed,
+// the incomplete sequence cut by maxLen is removed too. Off by default.
+
+const (
+	ansiText = iota
+	ansiEscape
+	ansiCSI
+
+	ansiESC = 0x1b
+)
+
+var (
+	stripANSI atomic.Bool
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetStripANSIForFile -- remove escape sequences from lines except the console copy
+func SetStripANSIForFile(enabled bool) {
+	stripANSI.Store(enabled)
+}
+
+// stripForFile -- the text without escape sequences and the console text keeping them
+func stripForFile(text string, console string) (string, string) {
+	if !stripANSI.Load() || strings.IndexByte(text, ansiESC) < 0 {
+		return text, console
+	}
+
+	if console == "" {
+		console = text
+	}
+	return stripEscapes(text), console
+}
+
+// stripEscapes -- remove escape sequences, the broken one is removed up to the first byte which can't belong to it
+func stripEscapes(s string) string {
+	b := make([]byte, 0, len(s))
+	state := ansiText
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		switch state {
+		case ansiText:
+			if c == ansiESC {
+				state = ansiEscape
+				continue
+			}
+			b = append(b, c)
+
+		case ansiEscape:
+			switch {
+			case c == '[':
+				state = ansiCSI
+			case c >= 0x40 && c <= 0x5f:
+				// Two-byte sequence
+				state = ansiText
+			default:
+				// Not a sequence, the byte is the text
+				state = ansiText
+				i--
+			}
+
+		case ansiCSI:
+			switch {
+			case c >= 0x20 && c <= 0x3f:
+				// Parameter and intermediate bytes
+			case c >= 0x40 && c <= 0x7e:
+				// Final byte
+				state = ansiText
+			default:
+				// Broken sequence, the byte is the text
+				state = ansiText
+				i--
+			}
+		}
+	}
+
+	return string(b)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,84 @@
+package log
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestStripEscapes(t *testing.T) {
+	cases := []struct {
+		src    string
+		expect string
+	}{
+		{"plain", "plain"},
+		{"\x1b[31mred\x1b[0m", "red"},
+		{"\x1b[1;38;5;208mbold orange\x1b[m and \x1b[32mgreen\x1b[39m", "bold orange and green"},
+		{"cursor\x1b[2K\x1b[1A up", "cursor up"},
+		{"two-byte \x1bMreverse", "two-byte reverse"},
+		{"cut \x1b[3", "cut "},
+		{"cut \x1b", "cut "},
+		{"cut \x1b[38;5\n", "cut \n"},
+		{"double \x1b\x1b[31mred", "double red"},
+		{"not \x1b(sequence", "not (sequence"},
+		{"utf-8 \x1b[33mжёлтый\x1b[0m", "utf-8 жёлтый"},
+	}
+
+	for i, c := range cases {
+		if s := stripEscapes(c.src); s != c.expect {
+			t.Errorf("[%d] got %q, expected %q", i, s, c.expect)
+		}
+	}
+}
+
+func TestStripANSIForFile(t *testing.T) {
+	console := resetLog(t)
+
+	SetFile(t.TempDir(), "", false, 0, 0)
+	SetStripANSIForFile(true)
+	Message(INFO, "opened")
+
+	colored := "\x1b[32mgreen\x1b[0m and \x1b[1;31mbold red\x1b[0m"
+	start := len(console.Lines())
+	Message(INFO, "%s", colored)
+	NewLevelWriter(GetFacility("lib"), WARNING, "").Write([]byte(colored + "\n"))
+
+	// The cut goes inside of the escape sequence
+	line := console.Lines()[start]
+	cut := strings.Index(line, "\x1b[1;31m") + 4
+	MaxLen(cut)
+	Message(INFO, "%s", colored)
+	MaxLen(0)
+
+	data, err := os.ReadFile(FileName())
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := string(data)
+
+	if strings.Contains(file, "\x1b") || strings.Contains(file, "[1;") || strings.Contains(file, "[0m") {
+		t.Errorf("the file isn't clean:\n%q", file)
+	}
+	if n := strings.Count(file, "green and bold red\n"); n != 2 {
+		t.Errorf("got %d clean lines:\n%s", n, file)
+	}
+	if !strings.Contains(file, "green and \n") {
+		t.Errorf("the truncated line isn't found:\n%q", file)
+	}
+
+	for _, s := range GetLastLog() {
+		if strings.Contains(s, "\x1b") {
+			t.Errorf("the last line isn't clean: %q", s)
+		}
+	}
+
+	lines := console.Lines()[start:]
+	if len(lines) != 3 || !strings.HasSuffix(lines[0], colored) || !strings.HasSuffix(lines[1], colored) ||
+		!strings.HasSuffix(lines[2], "\x1b[1;") {
+		t.Errorf("the console isn't intact:\n%q", lines)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,209 @@
+package log
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// With the asynchronous opening the caller never opens the file. The line that needs the new file and all following lines
+// are queued, the opener goroutine opens the file without the mutex, installs it and writes the queued lines in order.
+// Off by default.
+
+type pendingLine struct {
+	level Level
+	dt    string
+	text  string
+}
+
+var (
+	asyncOpen   = false
+	opening     = false
+	openGen     = 0
+	openPending = []pendingLine{}
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetAsyncFileOpen -- open and rotate the file by the background goroutine
+func SetAsyncFileOpen(enabled bool) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if !enabled {
+		flushOpenPending()
+	}
+	asyncOpen = enabled
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// queueForOpen -- true if the line is queued until the file is opened. Must be called under the mutex.
+func queueForOpen(level Level, dt string, text string) bool {
+	if !asyncOpen || outputWriter != nil || fileNamePattern == "-" {
+		return false
+	}
+
+	if !opening && ((dst != nil && lastWriteDate == dt && !writeBroken) || openThrottled(dt)) {
+		return false
+	}
+
+	if len(openPending) >= fallbackBufSize {
+		statDrop()
+		return true
+	}
+
+	openPending = append(openPending, pendingLine{level: level, dt: dt, text: text})
+
+	if !opening {
+		opening = true
+		go asyncOpenFile()
+	}
+
+	return true
+}
+
+// flushOpenPending -- write the queued lines synchronously, the opener result is discarded. Must be called under the mutex.
+func flushOpenPending() {
+	openGen++
+
+	lines := openPending
+	openPending = []pendingLine{}
+
+	for _, l := range lines {
+		outputFile(l.level, l.dt, l.text)
+	}
+}
+
+func asyncOpenFile() {
+	for {
+		mutex.Lock()
+
+		if len(openPending) == 0 {
+			opening = false
+			mutex.Unlock()
+			return
+		}
+
+		dt := openPending[0].dt
+		if !asyncOpen || openThrottled(dt) {
+			flushOpenPending()
+			opening = false
+			mutex.Unlock()
+			return
+		}
+
+		// The lock is released by Shutdown
+		pattern, err := exclusivePattern(fileNamePattern)
+		if err != nil {
+			// The synchronous opening reports the error
+			flushOpenPending()
+			opening = false
+			mutex.Unlock()
+			return
+		}
+		fileNamePattern = pattern
+
+		gen := openGen
+		name := fmt.Sprintf(fileNamePattern, dt)
+		directory := fileDirectory
+		fbDirectory := fallbackDirectory
+		key := encryptionKey
+
+		mutex.Unlock()
+
+		o := tryOpenFile(directory, name, fbDirectory, key)
+
+		mutex.Lock()
+
+		if gen != openGen {
+			// The settings are changed meanwhile
+			mutex.Unlock()
+			closeFiles(o.file)
+			continue
+		}
+
+		oldWriter, oldDst := installLogFile(dt, o)
+
+		n := 0
+		for n < len(openPending) && openPending[n].dt == dt {
+			l := openPending[n]
+			if dst != nil {
+				write(l.text)
+				flushIfSevere(l.level)
+			} else {
+				fallbackAppend(l.text)
+			}
+			n++
+		}
+		openPending = openPending[n:]
+
+		mutex.Unlock()
+
+		if oldWriter != nil {
+			oldWriter.Flush()
+		}
+		if oldDst != nil {
+			oldDst.Close()
+		}
+	}
+}
+
+// installLogFile -- replace the current file by the opened one, the old file is returned to be closed without the mutex.
+// Must be called under the mutex.
+func installLogFile(dt string, o openedFile) (oldWriter *bufio.Writer, oldDst io.WriteCloser) {
+	lastOpenDate = dt
+	lastOpenAttempt = lastStamp
+
+	writeTrailer(o.name)
+
+	fileWriterMutex.Lock()
+	oldWriter, oldDst = fileWriter, dst
+	fileWriter = nil
+	dst = nil
+	file = nil
+	fileWriteErr = nil
+	fileWriterMutex.Unlock()
+	writeBroken = false
+	resetCompact()
+
+	if stderrIntercepted {
+		updateCrashOutput()
+	}
+
+	setFileTier(o.tier, o.name, o.err)
+	fileName, file = o.name, o.file
+
+	if file != nil {
+		setDestination(injectFailures(compression.wrap(o.cipher.wrap(file))))
+		redirectStderr()
+	}
+
+	startLogFile()
+
+	if file != nil {
+		updateSymlinks(fileName)
+		applyRetention()
+	}
+
+	if dst != nil {
+		lastWriteDate = dt
+	} else {
+		lastWriteDate = ""
+	}
+
+	return
+}
+
+func closeFiles(files ...*os.File) {
+	for _, f := range files {
+		if f != nil {
+			f.Close()
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
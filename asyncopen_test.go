@@ -0,0 +1,161 @@
+package log
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// slowOpen -- every file opening waits for the release
+func slowOpen(t testing.TB) (release chan struct{}) {
+	release = make(chan struct{})
+
+	mutex.Lock()
+	openFile = func(dir string, name string) (*os.File, error) {
+		<-release
+		return openFileInDir(dir, name)
+	}
+	mutex.Unlock()
+
+	t.Cleanup(func() {
+		mutex.Lock()
+		openFile = openFileInDir
+		mutex.Unlock()
+	})
+	return
+}
+
+func waitFile(t *testing.T, name string, lines int) []string {
+	t.Helper()
+
+	for i := 0; i < 200; i++ {
+		writerFlush()
+		data, _ := os.ReadFile(name)
+		list := strings.Split(strings.TrimSpace(string(data)), "\n")
+		if len(list) >= lines {
+			return list
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("%s has less than %d lines", name, lines)
+	return nil
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestAsyncFileOpen(t *testing.T) {
+	resetLog(t)
+	clock := setFakeClock(time.Date(2024, 5, 1, 23, 59, 59, 0, time.UTC))
+
+	dir := t.TempDir()
+	SetFile(dir, "", false, 4096, 0)
+	SetAsyncFileOpen(true)
+
+	for _, day := range []string{"2024-05-01", "2024-05-02"} {
+		release := slowOpen(t)
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			Message(INFO, "%s first", day)
+			Message(INFO, "%s second", day)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Message is blocked by the file opening")
+		}
+
+		close(release)
+
+		lines := waitFile(t, filepath.Join(dir, day+".log"), 3)
+		if len(lines) != 3 || !strings.Contains(lines[0], " was launched at ") || !strings.HasSuffix(lines[1], day+" first") || !strings.HasSuffix(lines[2], day+" second") {
+			t.Errorf("unexpected %s file %q", day, lines)
+		}
+
+		clock.Add(time.Second)
+	}
+}
+
+func TestAsyncFileOpenDisable(t *testing.T) {
+	resetLog(t)
+
+	SetFile(t.TempDir(), "", false, 0, 0)
+	SetAsyncFileOpen(true)
+
+	release := slowOpen(t)
+	Message(INFO, "queued")
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+	}()
+
+	// The queued line is written synchronously
+	SetAsyncFileOpen(false)
+
+	data, _ := os.ReadFile(FileName())
+	if !strings.HasSuffix(string(data), " queued\n") {
+		t.Errorf("unexpected file:\n%s", data)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func BenchmarkRotationLatency(b *testing.B) {
+	for _, async := range []bool{false, true} {
+		name := "Sync"
+		if async {
+			name = "Async"
+		}
+
+		b.Run(name, func(b *testing.B) {
+			clock := setFakeClock(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC))
+			defer func() {
+				SetConsoleWriter(nil)
+				mutex.Lock()
+				timeNow = time.Now
+				openFile = openFileInDir
+				mutex.Unlock()
+				SetAsyncFileOpen(false)
+			}()
+
+			SetConsoleWriter(io.Discard)
+			SetFile(b.TempDir(), "", false, 64*1024, 0)
+			SetAsyncFileOpen(async)
+
+			mutex.Lock()
+			openFile = func(dir string, name string) (*os.File, error) {
+				time.Sleep(20 * time.Millisecond)
+				return openFileInDir(dir, name)
+			}
+			mutex.Unlock()
+
+			latencies := make([]time.Duration, 0, b.N)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if i%50 == 49 {
+					clock.Add(24 * time.Hour)
+				}
+				t0 := time.Now()
+				Message(INFO, "message %d", i)
+				latencies = append(latencies, time.Since(t0))
+			}
+			b.StopTimer()
+
+			slices.Sort(latencies)
+			b.ReportMetric(float64(latencies[len(latencies)*99/100].Nanoseconds()), "p99-ns")
+			b.ReportMetric(float64(latencies[len(latencies)-1].Nanoseconds()), "max-ns")
+		})
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,82 @@
+package log
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+var (
+	autoFacility      atomic.Bool
+	autoFacilityCache sync.Map // program counter -> *Facility, nil for frames to skip
+	ownPackage        string
+)
+
+func init() {
+	pc, _, _, _ := runtime.Caller(0)
+	ownPackage = funcPackage(runtime.FuncForPC(pc).Name())
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetAutoFacilityFromCaller -- route messages of the std facility through the facility named by the last two segments
+// of the caller package path. Levels of such facilities are set as usual, SetLogLevelsEx with createMissing
+// allows to set them before the first message.
+func SetAutoFacilityFromCaller(enabled bool) {
+	autoFacility.Store(enabled)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// callerFacility -- the facility derived from the first caller outside this package
+func callerFacility() *Facility {
+	var pcs [16]uintptr
+	n := runtime.Callers(3, pcs[:])
+
+	for _, pc := range pcs[:n] {
+		v, exists := autoFacilityCache.Load(pc)
+		if !exists {
+			v = derivedFacility(pc)
+			autoFacilityCache.Store(pc, v)
+		}
+
+		if f := v.(*Facility); f != nil {
+			return f
+		}
+	}
+
+	return stdFacility
+}
+
+func derivedFacility(pc uintptr) *Facility {
+	fn := runtime.FuncForPC(pc - 1)
+	if fn == nil {
+		return nil
+	}
+
+	pkg := funcPackage(fn.Name())
+	if pkg == ownPackage || pkg == "log" {
+		return nil
+	}
+
+	parts := strings.Split(pkg, "/")
+	if len(parts) > 2 {
+		parts = parts[len(parts)-2:]
+	}
+
+	return GetFacility(strings.Join(parts, "/"))
+}
+
+// funcPackage -- package path of the full function name like "github.com/a/b.(*T).Method"
+func funcPackage(name string) string {
+	slash := strings.LastIndex(name, "/")
+	if dot := strings.Index(name[slash+1:], "."); dot >= 0 {
+		return name[:slash+1+dot]
+	}
+	return name
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
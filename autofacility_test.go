@@ -0,0 +1,85 @@
+package log_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/alrusov/log"
+	"github.com/alrusov/log/internal/autotest/alpha"
+	"github.com/alrusov/log/internal/autotest/beta"
+	"github.com/alrusov/misc"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+type lockedBuffer struct {
+	mutex sync.Mutex
+	buf   bytes.Buffer
+}
+
+func (w *lockedBuffer) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *lockedBuffer) String() string {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.buf.String()
+}
+
+func autoFacilitySetup(t testing.TB, w io.Writer) {
+	log.SetConsoleWriter(w)
+	log.SetAutoFacilityFromCaller(true)
+	t.Cleanup(func() {
+		log.SetAutoFacilityFromCaller(false)
+		log.GetFacility("autotest/alpha").SetLogLevel("DEBUG", log.FuncNameModeNone)
+		log.GetFacility("autotest/beta").SetLogLevel("DEBUG", log.FuncNameModeNone)
+		log.SetConsoleWriter(nil)
+	})
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestAutoFacilityFromCaller(t *testing.T) {
+	w := &lockedBuffer{}
+	autoFacilitySetup(t, w)
+
+	err := log.SetLogLevelsEx("DEBUG", misc.StringMap{"autotest/beta": "ERR"}, log.FuncNameModeNone, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alpha.Log(log.INFO, "from alpha")
+	beta.Log(log.INFO, "filtered beta")
+	beta.Log(log.ERR, "from beta")
+	beta.Log(log.EMERG, "emerg beta")
+
+	s := w.String()
+	for _, expected := range []string{"<autotest/alpha> from alpha", "<autotest/beta> from beta", "<autotest/beta> runtime.goexit"} {
+		if !strings.Contains(s, expected) {
+			t.Errorf("%q not found in\n%s", expected, s)
+		}
+	}
+	if strings.Contains(s, "filtered beta") {
+		t.Errorf("beta level is not applied\n%s", s)
+	}
+}
+
+func BenchmarkAutoFacilityFromCaller(b *testing.B) {
+	autoFacilitySetup(b, io.Discard)
+	log.GetFacility("autotest/alpha").SetLogLevel("ERR", log.FuncNameModeNone)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		alpha.Log(log.DEBUG, "filtered")
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
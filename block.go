@@ -0,0 +1,93 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// MessageBlock writes the logically single record of several lines (a table, a diff). The header and the lines are
+// written back-to-back, nothing is interleaved with them in the file and on the console. Every line has the standard
+// prefix and the "(blk=XXXX n/N)" suffix, the header is 0/N. maxLen and the redaction are applied to every line
+// before the suffix is added, so the suffix is never truncated.
+// The last lines buffer keeps only the header, the storm protection counts the block as one message.
+
+var (
+	blockID uint32
+
+	blockBody bool // the lines after the header are written, guarded by mutex
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// MessageBlock -- add the block of lines to the log as one record
+func (f *Facility) MessageBlock(level Level, header string, lines []string) {
+	f.messageBlock(1, level, header, lines)
+}
+
+// MessageBlock -- add the block of lines to the log as one record
+func MessageBlock(level Level, header string, lines []string) {
+	stdFacility.messageBlock(1, level, header, lines)
+}
+
+func (f *Facility) messageBlock(shift int, level Level, header string, lines []string) {
+	if f == stdFacility && autoFacility.Load() {
+		f = callerFacility()
+	}
+
+	if f.disabled.Load() {
+		return
+	}
+
+	level = checkLevel(shift+1, f, level)
+	if !level.passes(f.token.Load()) || stormDrop(f, level, "", header, nil) {
+		return
+	}
+
+	id := uint16(atomic.AddUint32(&blockID, 1))
+	n := len(lines)
+
+	defer commitBarrier()()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	logger(false, shift+1, f.name, level, &messageOptions{suffix: blockSuffix(id, 0, n)}, "%s", header)
+
+	blockBody = true
+	defer func() { blockBody = false }()
+
+	for i, line := range lines {
+		logger(false, shift+1, f.name, level, &messageOptions{suffix: blockSuffix(id, i+1, n)}, "%s", strings.TrimRight(line, "\r"))
+	}
+}
+
+// writeBlock -- log lines back-to-back under one mutex acquisition, every line gets the "(blk=XXXX n/N)" suffix
+// after maxLen is applied
+func writeBlock(f *Facility, level Level, lines []string) {
+	if f.disabled.Load() || !level.passes(f.token.Load()) {
+		return
+	}
+
+	id := uint16(atomic.AddUint32(&blockID, 1))
+
+	// The lines enqueued by the group commit go first
+	defer commitBarrier()()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	for i, line := range lines {
+		mo := &messageOptions{suffix: blockSuffix(id, i+1, len(lines))}
+		logger(false, 1, f.name, level, mo, "%s", strings.TrimRight(line, "\r"))
+	}
+}
+
+// blockSuffix -- " (blk=XXXX n/N)"
+func blockSuffix(id uint16, n int, total int) string {
+	return fmt.Sprintf(" (blk=%04x %d/%d)", id, n, total)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
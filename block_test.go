@@ -0,0 +1,255 @@
+package log
+
+import (
+	"fmt"
+	stdlog "log"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestSysWriterBlock(t *testing.T) {
+	console := resetLog(t)
+
+	const (
+		writers = 2
+		blocks  = 50
+		size    = 7
+	)
+
+	wg := new(sync.WaitGroup)
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for b := 0; b < blocks; b++ {
+				lines := make([]string, size)
+				for i := range lines {
+					lines[i] = fmt.Sprintf("w%d b%d l%d", w, b, i+1)
+				}
+				Writer().Write([]byte(strings.Join(lines, "\n") + "\n"))
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	Writer().Write([]byte("single line\n"))
+
+	re := regexp.MustCompile(` NO [^ ]+ [^ ]+ (w\d+ b\d+) l(\d) \(blk=([0-9a-f]{4}) (\d)/7\)$`)
+
+	lines := console.Lines()
+	if len(lines) != writers*blocks*size+1 {
+		t.Fatalf("got %d lines, expected %d", len(lines), writers*blocks*size+1)
+	}
+
+	for i := 0; i < writers*blocks*size; i += size {
+		var block, id string
+		for j := 0; j < size; j++ {
+			m := re.FindStringSubmatch(lines[i+j])
+			if m == nil {
+				t.Fatalf("bad line %q", lines[i+j])
+			}
+			if j == 0 {
+				block, id = m[1], m[3]
+			}
+			n := fmt.Sprint(j + 1)
+			if m[1] != block || m[3] != id || m[2] != n || m[4] != n {
+				t.Fatalf("block is broken at %q", lines[i+j])
+			}
+		}
+	}
+
+	if last := lines[len(lines)-1]; !strings.HasSuffix(last, " single line") {
+		t.Errorf("single line is changed: %q", last)
+	}
+}
+
+func TestSysWriterBlockMaxLen(t *testing.T) {
+	console := resetLog(t)
+
+	MaxLen(60)
+	defer MaxLen(0)
+
+	Writer().Write([]byte(strings.Repeat("x", 100) + "\nshort\n"))
+
+	lines := console.Lines()
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines:\n%s", len(lines), console)
+	}
+
+	// The body is truncated, the suffix is kept
+	re := regexp.MustCompile(`^(.*) \(blk=[0-9a-f]{4} 1/2\)$`)
+	if m := re.FindStringSubmatch(lines[0]); m == nil || len(m[1]) != 60 || !strings.HasSuffix(m[1], "xxx") {
+		t.Errorf("unexpected line %q", lines[0])
+	}
+	if !regexp.MustCompile(` short \(blk=[0-9a-f]{4} 2/2\)$`).MatchString(lines[1]) {
+		t.Errorf("unexpected line %q", lines[1])
+	}
+}
+
+func TestMessageBlock(t *testing.T) {
+	const (
+		writers = 4
+		blocks  = 30
+		size    = 14
+	)
+
+	for _, mode := range []GroupCommitMode{GroupCommitOff, GroupCommitAsync} {
+		t.Run(fmt.Sprintf("mode%d", mode), func(t *testing.T) {
+			console := resetLog(t)
+			SetGroupCommit(mode)
+
+			wg := new(sync.WaitGroup)
+			for w := 0; w < writers; w++ {
+				wg.Add(2)
+				go func(w int) {
+					defer wg.Done()
+					f := GetFacility(fmt.Sprintf("w%d", w))
+					for b := 0; b < blocks; b++ {
+						lines := make([]string, size)
+						for i := range lines {
+							lines[i] = fmt.Sprintf("w%d b%d l%d", w, b, i+1)
+						}
+						f.MessageBlock(INFO, fmt.Sprintf("w%d b%d table", w, b), lines)
+					}
+				}(w)
+				go func(w int) {
+					defer wg.Done()
+					for b := 0; b < blocks; b++ {
+						Message(INFO, "single w%d %d", w, b)
+					}
+				}(w)
+			}
+			wg.Wait()
+
+			SetGroupCommit(GroupCommitOff)
+
+			re := regexp.MustCompile(` IN [^ ]+ [^ ]+ <(w\d+)> (w\d+ b\d+) (table|l\d+) \(blk=([0-9a-f]{4}) (\d+)/14\)$`)
+
+			lines := console.Lines()
+			if len(lines) != writers*blocks*(size+2) {
+				t.Fatalf("got %d lines, expected %d", len(lines), writers*blocks*(size+2))
+			}
+
+			headers := 0
+			for i := 0; i < len(lines); i++ {
+				if strings.Contains(lines[i], " single ") {
+					continue
+				}
+
+				headers++
+				for j := 0; j <= size; j++ {
+					m := re.FindStringSubmatch(lines[i+j])
+					if m == nil {
+						t.Fatalf("block is interleaved at %q", lines[i+j])
+					}
+
+					first := re.FindStringSubmatch(lines[i])
+					n := fmt.Sprint(j)
+					if j == 0 && m[3] != "table" || j != 0 && m[3] != "l"+n || m[5] != n || m[2] != first[2] || m[4] != first[4] || m[1] != first[1] {
+						t.Fatalf("block is broken at %q", lines[i+j])
+					}
+				}
+				i += size
+			}
+
+			if headers != writers*blocks {
+				t.Errorf("got %d blocks, expected %d", headers, writers*blocks)
+			}
+		})
+	}
+}
+
+func TestMessageBlockLine(t *testing.T) {
+	console := resetLog(t)
+
+	path := filepath.Join(t.TempDir(), "rules.toml")
+	writeRules(t, path, "[[redact]]\nname = \"password\"\nregexp = '(password=)\\w+'\nreplacement = \"${1}***\"\n", time.Now())
+	if err := LoadRulesFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	MaxLen(80)
+	defer MaxLen(0)
+
+	MessageBlock(INFO, "config", []string{"user=admin password=secret", strings.Repeat("x", 100)})
+	Message(INFO, "after")
+
+	lines := console.Lines()
+	if len(lines) != 4 {
+		t.Fatalf("got %d lines:\n%s", len(lines), console)
+	}
+	if !regexp.MustCompile(` config \(blk=[0-9a-f]{4} 0/2\)$`).MatchString(lines[0]) {
+		t.Errorf("unexpected header %q", lines[0])
+	}
+	if strings.Contains(lines[1], "secret") || !strings.Contains(lines[1], "password=*** (blk=") {
+		t.Errorf("the line isn't redacted: %q", lines[1])
+	}
+	if m := regexp.MustCompile(`^(.*) \(blk=[0-9a-f]{4} 2/2\)$`).FindStringSubmatch(lines[2]); m == nil || len(m[1]) != 80 {
+		t.Errorf("the line isn't truncated before the suffix: %q", lines[2])
+	}
+
+	last := GetLastLog()
+	if len(last) != 2 || !strings.HasSuffix(last[0], " 0/2)") || !strings.HasSuffix(last[1], " after") {
+		t.Errorf("unexpected last lines %q", last)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// TestStdLogOrdering -- the stdlib logger output and direct messages of one goroutine keep the program order
+func TestStdLogOrdering(t *testing.T) {
+	count := 10000
+	if testing.Short() {
+		count /= 10
+	}
+
+	for _, mode := range []GroupCommitMode{GroupCommitOff, GroupCommitSync, GroupCommitAsync} {
+		t.Run(fmt.Sprintf("mode%d", mode), func(t *testing.T) {
+			console := resetLog(t)
+			SetGroupCommit(mode)
+
+			// The same writer the hijacked stdlib logger gets
+			std := stdlog.New(Writer(), "", 0)
+
+			for i := 0; i < count; i++ {
+				if i%100 == 0 {
+					std.Printf("seq %d\nsecond line", 2*i)
+				} else {
+					std.Printf("seq %d", 2*i)
+				}
+				Message(INFO, "seq %d", 2*i+1)
+			}
+
+			SetGroupCommit(GroupCommitOff)
+
+			n := 0
+			for _, line := range console.Lines() {
+				_, s, ok := strings.Cut(line, " seq ")
+				if !ok {
+					continue
+				}
+
+				var seq int
+				if _, err := fmt.Sscanf(s, "%d", &seq); err != nil {
+					t.Fatalf("bad line %q", line)
+				}
+				if seq != n {
+					t.Fatalf("got %d, expected %d: %q", seq, n, line)
+				}
+				n++
+			}
+
+			if n != 2*count {
+				t.Errorf("got %d lines, expected %d", n, 2*count)
+			}
+		})
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,156 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// The boost raises the level of the facilities for a while when the external signal (an alert webhook for example)
+// calls TriggerBoost with the rule key. The facilities existing at the trigger moment and matching the rule get
+// the boost level if it is more verbose than the current one, the level is restored when the boost expires.
+// The repeated or overlapping trigger extends the boost and never stacks: the level to restore is the one before
+// the first boost. The manual level change of the boosted facility cancels its boost. Transitions are logged
+// with NOTICE. Boosts are checked by the flusher.
+
+// BoostRule -- the temporary level of the facilities switched on by the trigger key
+type BoostRule struct {
+	Key        string        // trigger key
+	Facilities []string      // facility names, "prefix*" patterns and StdFacilityAlias for the std facility
+	Level      Level         // boost level
+	Duration   time.Duration // how long the boost lasts after the last trigger
+}
+
+type boostState struct {
+	key     string
+	restore Level
+	until   time.Time
+}
+
+var (
+	boostRules = map[string]BoostRule{}
+	boosts     = map[*Facility]*boostState{}
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// ConfigureAutoBoost -- replace the boost rules. Active boosts last until they expire.
+func ConfigureAutoBoost(rules []BoostRule) error {
+	list := make(map[string]BoostRule, len(rules))
+
+	for _, r := range rules {
+		switch {
+		case r.Key == "":
+			return errors.New("boost rule without key")
+		case len(r.Facilities) == 0:
+			return fmt.Errorf(`boost rule "%s" without facilities`, r.Key)
+		case r.Level < EMERG || r.Level == UNKNOWN || int(r.Level) >= len(levels):
+			return fmt.Errorf(`boost rule "%s" has invalid level %d`, r.Key, r.Level)
+		case r.Duration <= 0:
+			return fmt.Errorf(`boost rule "%s" has invalid duration %s`, r.Key, r.Duration)
+		}
+
+		if _, exists := list[r.Key]; exists {
+			return fmt.Errorf(`duplicate boost rule "%s"`, r.Key)
+		}
+		r.Facilities = append([]string(nil), r.Facilities...)
+		list[r.Key] = r
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	boostRules = list
+	return nil
+}
+
+// TriggerBoost -- boost or extend the boost of the facilities of the rule, the boosted facilities are returned
+func TriggerBoost(key string) (applied []string, err error) {
+	var notify alertNotifications
+	defer notify.call()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	r, exists := boostRules[key]
+	if !exists {
+		return nil, fmt.Errorf(`unknown boost trigger "%s"`, key)
+	}
+
+	until := now().Add(r.Duration)
+
+	for name, f := range facilities {
+		if !matchFacility(r.Facilities, name) {
+			continue
+		}
+
+		b, boosted := boosts[f]
+		if !boosted {
+			if r.Level.passes(f.level) {
+				continue
+			}
+			b = &boostState{restore: f.level}
+		}
+
+		level := f.level
+		if !r.Level.passes(level) {
+			level = r.Level
+		}
+
+		delete(boosts, f)
+		if level != f.level {
+			_, _ = f.setLogLevel(levels[level].name, FuncNameModeKeep, "boost", fmt.Sprintf(`trigger "%s"`, key), &notify)
+		}
+
+		b.key = key
+		if b.until.Before(until) {
+			b.until = until
+		}
+		boosts[f] = b
+
+		logger(false, 0, name, NOTICE, nil, `Log level is boosted to "%s" until %s by trigger "%s"`,
+			levels[level].name, b.until.Format(time.RFC3339), key)
+		applied = append(applied, name)
+	}
+
+	sort.Strings(applied)
+	return applied, nil
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// boostTick -- restore the levels of the expired boosts
+func boostTick() {
+	var notify alertNotifications
+	defer notify.call()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	t := now()
+	for f, b := range boosts {
+		if t.Before(b.until) {
+			continue
+		}
+
+		delete(boosts, f)
+		logger(false, 0, f.name, NOTICE, nil, `Log level boost by trigger "%s" is expired, "%s" is restored`, b.key, levels[b.restore].name)
+		_, _ = f.setLogLevel(levels[b.restore].name, FuncNameModeKeep, "boost", fmt.Sprintf(`trigger "%s" expired`, b.key), &notify)
+	}
+}
+
+// cancelBoost -- the level of the facility is changed manually. Must be called under the mutex.
+func cancelBoost(f *Facility) {
+	b, boosted := boosts[f]
+	if !boosted {
+		return
+	}
+
+	delete(boosts, f)
+	logger(false, 0, f.name, NOTICE, nil, `Log level boost by trigger "%s" is cancelled by the manual change`, b.key)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,109 @@
+package log
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestAutoBoost(t *testing.T) {
+	console := resetLog(t)
+	clock := setFakeClock(time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC))
+
+	if err := ConfigureAutoBoost([]BoostRule{{Key: "x", Facilities: []string{"db"}, Level: DEBUG}}); err == nil {
+		t.Error("the rule without duration is accepted")
+	}
+
+	err := ConfigureAutoBoost(
+		[]BoostRule{
+			{Key: "errors", Facilities: []string{"db*", StdFacilityAlias}, Level: DEBUG, Duration: 10 * time.Minute},
+			{Key: "latency", Facilities: []string{"db.pool"}, Level: TRACE1, Duration: 5 * time.Minute},
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db := GetFacility("db")
+	pool := GetFacility("db.pool")
+	http := GetFacility("http")
+	if err := SetLogLevelsEx("INFO", nil, FuncNameModeKeep, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.SetLogLevel("TRACE2", FuncNameModeKeep); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := TriggerBoost("unknown"); err == nil {
+		t.Error("the unknown trigger is accepted")
+	}
+
+	// db is already more verbose
+	applied, err := TriggerBoost("errors")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(applied, []string{StdFacilityName, "db.pool"}) {
+		t.Errorf("got applied %q", applied)
+	}
+	if pool.CurrentLogLevel() != DEBUG || CurrentLogLevel() != DEBUG || db.CurrentLogLevel() != TRACE2 || http.CurrentLogLevel() != INFO {
+		t.Error("unexpected levels")
+	}
+
+	// Overlapping triggers extend and raise, never stack
+	clock.Add(8 * time.Minute)
+	if _, err := TriggerBoost("latency"); err != nil {
+		t.Fatal(err)
+	}
+	if pool.CurrentLogLevel() != TRACE1 {
+		t.Errorf("got %d", pool.CurrentLogLevel())
+	}
+
+	clock.Add(3 * time.Minute)
+	boostTick()
+	if CurrentLogLevel() != INFO || pool.CurrentLogLevel() != TRACE1 {
+		t.Error("std isn't restored or db.pool is restored too early")
+	}
+
+	// The less verbose trigger extends the boost without lowering the level
+	if _, err := TriggerBoost("errors"); err != nil {
+		t.Fatal(err)
+	}
+	clock.Add(9 * time.Minute)
+	boostTick()
+	if pool.CurrentLogLevel() != TRACE1 || CurrentLogLevel() != DEBUG {
+		t.Error("the boost is lowered or not extended")
+	}
+
+	// The manual change cancels the boost of the facility only
+	if _, err := SetLogLevel("WARNING", FuncNameModeKeep); err != nil {
+		t.Fatal(err)
+	}
+
+	clock.Add(2 * time.Minute)
+	boostTick()
+	if pool.CurrentLogLevel() != INFO || CurrentLogLevel() != WARNING {
+		t.Errorf("got levels %d, %d", pool.CurrentLogLevel(), CurrentLogLevel())
+	}
+
+	s := console.String()
+	for _, expected := range []string{
+		`<db.pool> Log level is boosted to "DEBUG" until 2024-05-03T12:10:00Z by trigger "errors"`,
+		`<db.pool> Log level is boosted to "TRACE1" until 2024-05-03T12:13:00Z by trigger "latency"`,
+		` Log level boost by trigger "errors" is expired, "INFO" is restored`,
+		`<db.pool> Log level boost by trigger "errors" is expired, "INFO" is restored`,
+		` Log level boost by trigger "errors" is cancelled by the manual change`,
+	} {
+		if !strings.Contains(s, " NO 2024-05-03 ") || !strings.Contains(s, expected+"\n") {
+			t.Errorf("%q isn't logged:\n%s", expected, s)
+		}
+	}
+	if n := len(boosts); n != 0 {
+		t.Errorf("%d boosts left", n)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,45 @@
+package log
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/alrusov/misc"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// The daily file can start at some time of the day instead of midnight: the file date is the date of the time shifted
+// back by the boundary, so with 06:00 the line at 05:59 still goes to the file of the previous day. Lines keep
+// the real date. The day change message, the retention ages, the file names of CaptureWindow, MoveTo and SelfTest use
+// the same boundary. Midnight by default.
+
+var (
+	rotationBoundary atomic.Int64 // time.Duration since midnight
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetRotationBoundary -- the time of the day when the next daily file starts
+func SetRotationBoundary(hour int, minute int) error {
+	if hour < 0 || hour > 23 || minute < 0 || minute > 59 {
+		return fmt.Errorf("bad rotation boundary %02d:%02d", hour, minute)
+	}
+
+	rotationBoundary.Store(int64(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute))
+	return nil
+}
+
+// fileDate -- date of the daily file for the time
+func fileDate(t time.Time) string {
+	b := time.Duration(rotationBoundary.Load())
+	if b == 0 {
+		date, _ := formatStamp(t)
+		return date
+	}
+
+	return t.Add(-b).Format(misc.DateFormatRev)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
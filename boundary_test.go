@@ -0,0 +1,77 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestRotationBoundary(t *testing.T) {
+	resetLog(t)
+	clock := setFakeClock(time.Date(2024, 5, 3, 5, 58, 0, 0, time.UTC))
+
+	if err := SetRotationBoundary(24, 0); err == nil {
+		t.Error("the bad boundary is accepted")
+	}
+	if err := SetRotationBoundary(6, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	SetFile(dir, "", false, 0, 0)
+
+	steps := []struct {
+		t    time.Time
+		file string
+	}{
+		{time.Date(2024, 5, 3, 5, 58, 0, 0, time.UTC), "2024-05-02.log"},
+		{time.Date(2024, 5, 3, 5, 59, 59, 0, time.UTC), "2024-05-02.log"},
+		{time.Date(2024, 5, 3, 6, 0, 0, 0, time.UTC), "2024-05-03.log"},
+		{time.Date(2024, 5, 3, 23, 59, 0, 0, time.UTC), "2024-05-03.log"},
+		{time.Date(2024, 5, 4, 0, 1, 0, 0, time.UTC), "2024-05-03.log"},
+		{time.Date(2024, 5, 4, 5, 59, 0, 0, time.UTC), "2024-05-03.log"},
+	}
+
+	for i, s := range steps {
+		clock.Set(s.t)
+		Message(INFO, "step %d", i)
+		if name := filepath.Base(FileName()); name != s.file {
+			t.Errorf("[%d] got %s, expected %s", i, name, s.file)
+		}
+	}
+
+	names, _ := filepath.Glob(filepath.Join(dir, "*.log"))
+	for i := range names {
+		names[i] = filepath.Base(names[i])
+	}
+	if !slices.Equal(names, []string{"2024-05-02.log", "2024-05-03.log"}) {
+		t.Fatalf("got files %q", names)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dir, "2024-05-03.log"))
+	file := string(data)
+	if n := strings.Count(file, " was launched at "); n != 1 {
+		t.Errorf("got %d banners", n)
+	}
+	if !strings.Contains(file, " 2024-05-04 00:01:00.000 step 4\n") {
+		t.Errorf("the line doesn't keep the real date:\n%s", file)
+	}
+
+	mutex.Lock()
+	st := currentRetention()
+	mutex.Unlock()
+	files, err := st.dailyFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files[0].Age != 23*time.Hour+59*time.Minute || !files[0].Current {
+		t.Errorf("unexpected age %s of %s", files[0].Age, files[0].Name)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,167 @@
+package log
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/alrusov/misc"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// CaptureWindow -- write to w the lines of the daily files logged since the time with the level minLevel or more severe.
+// Lines which can't be parsed (continuations, truncation markers) follow the decision for the preceding line.
+// Compact files are captured in the classic format.
+func CaptureWindow(since time.Time, minLevel Level, w io.Writer) error {
+	writerFlush()
+
+	mutex.Lock()
+	pattern := fileNamePattern
+	key := encryptionKey
+	t := now()
+	mutex.Unlock()
+
+	if pattern == "" || pattern == "-" || !strings.Contains(pattern, "%s") {
+		return errors.New("log files are not configured")
+	}
+
+	since = since.In(t.Location())
+
+	bw := bufio.NewWriter(w)
+	defer bw.Flush()
+
+	end := fileDate(t)
+	for day := since; ; day = day.AddDate(0, 0, 1) {
+		dt := fileDate(day)
+		if dt > end {
+			break
+		}
+
+		err := captureFile(fmt.Sprintf(pattern, dt), key, since, minLevel, bw)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
+
+func captureFile(name string, key []byte, since time.Time, minLevel Level, w io.Writer) error {
+	fd, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+
+	r, err := decryptingReader(fd, key)
+	if err != nil {
+		return err
+	}
+	if strings.HasSuffix(name, CompressionGzip.extension()) {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	// Lines of the compact file are written in the classic form, the headers are dropped
+	h := CompactHeader{}
+
+	include := false
+	for scanner.Scan() {
+		line, isLine := h.expand(scanner.Text())
+		if !isLine {
+			continue
+		}
+
+		if info, ok := ParseLine(line); ok {
+			include = !info.Time.Before(since) && info.Level.passes(minLevel)
+		}
+
+		if include {
+			if _, err := io.WriteString(w, line+misc.EOS); err != nil {
+				return err
+			}
+		}
+	}
+
+	err = scanner.Err()
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		// The active gzip stream is not closed yet or the last chunk is being written
+		err = nil
+	}
+	return err
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// CaptureWindowToZip -- zip with the lines logged since the time, the current status and the facility levels
+func CaptureWindowToZip(since time.Time, path string) (err error) {
+	var logData bytes.Buffer
+	err = CaptureWindow(since, UNKNOWN, &logData)
+	if err != nil {
+		return
+	}
+
+	status, err := json.MarshalIndent(Status(), "", "  ")
+	if err != nil {
+		return
+	}
+
+	levels, err := json.MarshalIndent(CurrentLogLevelNamesOfAll(), "", "  ")
+	if err != nil {
+		return
+	}
+
+	fd, err := os.Create(path)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if e := fd.Close(); err == nil {
+			err = e
+		}
+	}()
+
+	zw := zip.NewWriter(fd)
+
+	files := []struct {
+		name string
+		data []byte
+	}{
+		{"log.txt", logData.Bytes()},
+		{"status.json", status},
+		{"levels.json", levels},
+	}
+
+	for _, f := range files {
+		var fw io.Writer
+		fw, err = zw.Create(f.name)
+		if err != nil {
+			return
+		}
+		_, err = fw.Write(f.data)
+		if err != nil {
+			return
+		}
+	}
+
+	err = zw.Close()
+	return
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,162 @@
+package log
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestParseLine(t *testing.T) {
+	resetLog(t)
+
+	info, ok := ParseLine("[123] WA 2024-05-03 14:22:01.250 <http> something happened #crc=00000000")
+	if !ok {
+		t.Fatal("not parsed")
+	}
+
+	exp := LineInfo{
+		PID:      123,
+		Level:    WARNING,
+		Time:     time.Date(2024, 5, 3, 14, 22, 1, 250*int(time.Millisecond), time.UTC),
+		Facility: "http",
+		Text:     "something happened",
+	}
+	if info != exp {
+		t.Errorf("got %+v, expected %+v", info, exp)
+	}
+
+	for _, s := range []string{"", "...", "  at [3] main.main", "[x] IN 2024-05-03 14:22:01.250 text", "[1] XX 2024-05-03 14:22:01.250 text"} {
+		if _, ok := ParseLine(s); ok {
+			t.Errorf("%q is parsed", s)
+		}
+	}
+}
+
+func TestCaptureWindow(t *testing.T) {
+	resetLog(t)
+
+	clock := setFakeClock(time.Date(2024, 5, 3, 23, 50, 0, 0, time.UTC))
+
+	dir := t.TempDir()
+	SetFile(dir, "", false, 0, 0)
+
+	for i := 0; i < 40; i++ {
+		level := INFO
+		if i%4 == 0 {
+			level = ERR
+		}
+		Message(level, "message %02d", i)
+		if i == 30 {
+			WriteRaw("raw block line 1\n  raw block line 2")
+		}
+		clock.Add(time.Minute)
+	}
+
+	since := time.Date(2024, 5, 3, 23, 58, 0, 0, time.UTC)
+
+	var buf bytes.Buffer
+	if err := CaptureWindow(since, ERR, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	var got []string
+	for _, line := range lines {
+		got = append(got, line[strings.LastIndex(line, " ")+1:])
+	}
+	exp := []string{"08", "12", "16", "20", "24", "28", "32", "36"}
+	if strings.Join(got, ",") != strings.Join(exp, ",") {
+		t.Errorf("got %q, expected %q", got, exp)
+	}
+
+	buf.Reset()
+	if err := CaptureWindow(time.Date(2024, 5, 4, 0, 20, 0, 0, time.UTC), INFO, &buf); err != nil {
+		t.Fatal(err)
+	}
+	s := buf.String()
+	if !strings.Contains(s, "message 30\nraw block line 1\n  raw block line 2\n") || strings.Contains(s, "message 29") {
+		t.Errorf("unexpected capture:\n%s", s)
+	}
+
+	path := filepath.Join(t.TempDir(), "bundle.zip")
+	if err := CaptureWindowToZip(since, path); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+
+	names := []string{}
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+		if f.Name == "log.txt" {
+			r, _ := f.Open()
+			data, _ := io.ReadAll(r)
+			r.Close()
+			if !strings.Contains(string(data), "message 09") {
+				t.Errorf("unexpected log.txt:\n%s", data)
+			}
+		}
+	}
+	if strings.Join(names, ",") != "log.txt,status.json,levels.json" {
+		t.Errorf("unexpected zip content %q", names)
+	}
+}
+
+func TestCaptureWindowEncryptedGzip(t *testing.T) {
+	resetLog(t)
+
+	clock := setFakeClock(time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC))
+	SetFileEncryption(testKey, EncryptionAESGCM)
+	SetFileEx(FileOptions{Directory: t.TempDir(), BufSize: 4096, Compression: CompressionGzip})
+
+	for i := 0; i < 10; i++ {
+		Message(INFO, "message %02d", i)
+		clock.Add(time.Minute)
+	}
+
+	var buf bytes.Buffer
+	if err := CaptureWindow(time.Date(2024, 5, 3, 12, 5, 0, 0, time.UTC), INFO, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	s := buf.String()
+	if !strings.Contains(s, "message 05\n") || !strings.Contains(s, "message 09\n") || strings.Contains(s, "message 04") {
+		t.Errorf("unexpected capture:\n%s", s)
+	}
+}
+
+func TestCaptureWindowCompact(t *testing.T) {
+	resetLog(t)
+
+	clock := setFakeClock(time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC))
+	SetFile(t.TempDir(), "", false, 0, 0)
+	SetFileFormat(FormatCompact)
+
+	for i := 0; i < 10; i++ {
+		Message(INFO, "message %02d", i)
+		clock.Add(time.Minute)
+	}
+
+	var buf bytes.Buffer
+	if err := CaptureWindow(time.Date(2024, 5, 3, 12, 5, 0, 0, time.UTC), INFO, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	s := buf.String()
+	if !strings.Contains(s, " 2024-05-03 12:05:00.000 message 05\n") || !strings.Contains(s, "message 09\n") ||
+		strings.Contains(s, "message 04") || strings.Contains(s, compactHeaderPrefix) {
+		t.Errorf("unexpected capture:\n%s", s)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
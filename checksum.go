@@ -0,0 +1,88 @@
+package log
+
+import (
+	"bufio"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/alrusov/misc"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+const checksumPrefix = " #crc="
+
+var (
+	lineChecksums = false
+	crcTable      = crc32.MakeTable(crc32.Castagnoli)
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetLineChecksums -- append " #crc=XXXXXXXX" (CRC32C of the line) to every line written to the file
+func SetLineChecksums(enabled bool) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	lineChecksums = enabled
+}
+
+func addChecksum(s string) string {
+	s = strings.TrimSuffix(s, misc.EOS)
+	return fmt.Sprintf("%s%s%08x%s", s, checksumPrefix, crc32.Checksum([]byte(s), crcTable), misc.EOS)
+}
+
+// splitChecksum -- line without the trailer, the trailer presence and validity
+func splitChecksum(line string) (s string, found bool, valid bool) {
+	i := strings.LastIndex(line, checksumPrefix)
+	if i < 0 {
+		return line, false, false
+	}
+
+	s = line[:i]
+	crc, err := strconv.ParseUint(line[i+len(checksumPrefix):], 16, 32)
+	if err != nil {
+		return line, false, false
+	}
+
+	return s, true, uint32(crc) == crc32.Checksum([]byte(s), crcTable)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// VerifyFile -- count lines with the valid checksum and lines with the invalid or absent one. Compact files are
+// verified too.
+func VerifyFile(path string) (good int, bad int, err error) {
+	fd, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer fd.Close()
+
+	scanner := bufio.NewScanner(fd)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	// The checksum of the compact line covers its classic form, the headers have no checksum
+	h := CompactHeader{}
+
+	for scanner.Scan() {
+		line, isLine := h.expand(strings.TrimSuffix(scanner.Text(), "\r"))
+		if !isLine || line == "" {
+			continue
+		}
+
+		if _, _, valid := splitChecksum(line); valid {
+			good++
+		} else {
+			bad++
+		}
+	}
+
+	err = scanner.Err()
+	return
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,85 @@
+package log
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestLineChecksums(t *testing.T) {
+	console := resetLog(t)
+
+	SetFile(t.TempDir(), "", false, 0, 0)
+	SetLineChecksums(true)
+	MaxLen(60)
+
+	for i := 0; i < 10; i++ {
+		Message(INFO, "message %d with a tail long enough to be truncated", i)
+	}
+
+	if strings.Contains(console.String(), checksumPrefix) {
+		t.Errorf("checksum on the console:\n%s", console)
+	}
+
+	good, bad, err := VerifyFile(FileName())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if good != 11 || bad != 0 {
+		t.Fatalf("got good=%d bad=%d, expected 11 and 0", good, bad)
+	}
+
+	data, err := os.ReadFile(FileName())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.SplitAfter(string(data), "\n")
+	// Damage two lines and cut the tail of the last one
+	lines[3] = strings.Replace(lines[3], "message", "mEssage", 1)
+	lines[5] = lines[5][:10] + "X" + lines[5][11:]
+	lines[10] = lines[10][:20]
+	if err := os.WriteFile(FileName(), []byte(strings.Join(lines, "")), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	good, bad, err = VerifyFile(FileName())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if good != 8 || bad != 3 {
+		t.Errorf("got good=%d bad=%d, expected 8 and 3", good, bad)
+	}
+}
+
+func TestLineChecksumsCompact(t *testing.T) {
+	resetLog(t)
+
+	SetFile(t.TempDir(), "", false, 0, 0)
+	SetFileFormat(FormatCompact)
+	SetLineChecksums(true)
+
+	for i := 0; i < 5; i++ {
+		GetFacility("db").Message(INFO, "message %d", i)
+	}
+
+	data, err := os.ReadFile(FileName())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), compactHeaderPrefix) {
+		t.Fatalf("not compact:\n%s", data)
+	}
+
+	good, bad, err := VerifyFile(FileName())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if good != 6 || bad != 0 {
+		t.Errorf("got good=%d bad=%d, expected 6 and 0", good, bad)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
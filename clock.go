@@ -0,0 +1,71 @@
+package log
+
+import (
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// The wall clock can be stepped back (NTP on VMs). Timestamps of lines never go back, the step over the tolerance is
+// counted and reported once with WARNING until the clock catches up. The file is never rotated backwards: lines dated
+// before the current file go to it, the next file is opened only when the date moves ahead of the current one.
+
+const (
+	defaultClockTolerance = time.Second
+)
+
+var (
+	clockTolerance = defaultClockTolerance
+	clockBehind    = false
+	clockAnomalies = int64(0)
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetClockTolerance -- the backward clock step not reported, 1 second by default
+func SetClockTolerance(tolerance time.Duration) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if tolerance <= 0 {
+		tolerance = defaultClockTolerance
+	}
+	clockTolerance = tolerance
+}
+
+// ClockAnomalies -- number of the backward clock steps over the tolerance
+func ClockAnomalies() int64 {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	return clockAnomalies
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// observeStamp -- move lastStamp forward, false if the time is before it. Must be called under the mutex.
+func observeStamp(t time.Time) bool {
+	if t.Before(lastStamp) {
+		if d := lastStamp.Sub(t); d > clockTolerance && !clockBehind {
+			clockBehind = true
+			clockAnomalies++
+			logger(false, 0, StdFacilityName, WARNING, nil, "Clock jumped backwards by %s, the log time is held at %s",
+				d, lastStamp.Format(time.RFC3339Nano))
+		}
+		return false
+	}
+
+	clockBehind = false
+	lastStamp = t
+	return true
+}
+
+// forwardDate -- the date of the current file for the line dated before it. Must be called under the mutex.
+func forwardDate(dt string) string {
+	if dst != nil && dt < lastWriteDate {
+		return lastWriteDate
+	}
+	return dt
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,72 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestClockBackwards(t *testing.T) {
+	console := resetLog(t)
+	clock := setFakeClock(time.Date(2024, 5, 3, 23, 59, 58, 0, time.UTC))
+
+	dir := t.TempDir()
+	SetFile(dir, "", false, 4096, time.Minute)
+
+	Message(INFO, "before midnight")
+	clock.Set(time.Date(2024, 5, 4, 0, 0, 2, 0, time.UTC))
+	Message(INFO, "after midnight")
+	today := FileName()
+
+	// Small steps are within the tolerance
+	clock.Add(-500 * time.Millisecond)
+	Message(INFO, "jitter")
+
+	clock.Set(time.Date(2024, 5, 3, 23, 59, 57, 0, time.UTC))
+	Message(INFO, "stepped back")
+	Message(INFO, "still behind")
+
+	if FileName() != today {
+		t.Fatalf("the file is reopened: %s", FileName())
+	}
+	if n := ClockAnomalies(); n != 1 || Status().ClockAnomalies != 1 {
+		t.Errorf("got %d anomalies", n)
+	}
+
+	s := console.String()
+	if n := strings.Count(s, "Clock jumped backwards by 5s"); n != 1 {
+		t.Errorf("the warning is reported %d times:\n%s", n, s)
+	}
+
+	// The clock catches up, the next step is reported again
+	clock.Set(time.Date(2024, 5, 4, 0, 1, 0, 0, time.UTC))
+	Message(INFO, "caught up")
+	clock.Add(-time.Minute)
+	Message(INFO, "stepped back again")
+	if n := ClockAnomalies(); n != 2 {
+		t.Errorf("got %d anomalies", n)
+	}
+
+	writerFlush()
+
+	data, err := os.ReadFile(today)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"after midnight", "jitter", "stepped back", "still behind", "caught up", "stepped back again"} {
+		if !strings.Contains(string(data), " 2024-05-04 00:") || !strings.Contains(string(data), " "+line+"\n") {
+			t.Errorf("%q isn't found in today's file:\n%s", line, data)
+		}
+	}
+
+	data, _ = os.ReadFile(filepath.Join(dir, "2024-05-03.log"))
+	if strings.Contains(string(data), "stepped back") || strings.Count(string(data), " was launched at ") != 1 {
+		t.Errorf("yesterday's file is reopened:\n%s", data)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,103 @@
+package log
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// With the coarse clock the ticker publishes the time with its formatted parts every resolution and messages take it
+// instead of the current time. Timestamps and the file rotation lag by up to the resolution.
+// EMERG and ALERT messages always take the precise time. Off by default.
+
+type coarseStamp struct {
+	t    time.Time
+	date string
+	tm   string
+}
+
+var (
+	coarseClock atomic.Pointer[coarseStamp]
+
+	coarseMutex      sync.Mutex
+	coarseResolution time.Duration
+	coarseStop       chan struct{}
+	coarseDone       chan struct{}
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetCoarseClock -- take timestamps from the clock updated every resolution. Zero disables it.
+func SetCoarseClock(resolution time.Duration) {
+	coarseMutex.Lock()
+	defer coarseMutex.Unlock()
+
+	if coarseStop != nil {
+		close(coarseStop)
+		<-coarseDone
+		coarseStop = nil
+		coarseDone = nil
+	}
+
+	coarseClock.Store(nil)
+	coarseResolution = max(resolution, 0)
+
+	if resolution <= 0 {
+		return
+	}
+
+	coarseTick()
+
+	coarseStop = make(chan struct{})
+	coarseDone = make(chan struct{})
+	go coarseTicker(resolution, coarseStop, coarseDone)
+}
+
+// currentCoarseClock -- the resolution of the coarse clock, 0 if it is off
+func currentCoarseClock() time.Duration {
+	coarseMutex.Lock()
+	defer coarseMutex.Unlock()
+
+	return coarseResolution
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func coarseTicker(resolution time.Duration, stop chan struct{}, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(resolution)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			coarseTick()
+		}
+	}
+}
+
+// coarseTick -- publish the current time
+func coarseTick() {
+	mutex.Lock()
+	t := now()
+	mutex.Unlock()
+
+	c := &coarseStamp{t: t}
+	c.date, c.tm = formatStamp(t)
+	coarseClock.Store(c)
+}
+
+// messageTime -- the coarse time if it is enabled and allowed for the level, otherwise the current one
+func messageTime(level Level) time.Time {
+	if c := coarseClock.Load(); c != nil && level != EMERG && level != ALERT {
+		return c.t
+	}
+	return now()
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
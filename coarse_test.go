@@ -0,0 +1,108 @@
+package log
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestCoarseClock(t *testing.T) {
+	console := resetLog(t)
+	clock := setFakeClock(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+
+	// The ticker never fires, the clock is published by coarseTick
+	SetCoarseClock(time.Hour)
+	defer SetCoarseClock(0)
+
+	messages := []struct {
+		tick    bool
+		advance time.Duration
+		level   Level
+		stamp   string
+	}{
+		{false, 3 * time.Millisecond, INFO, "12:00:00.000"},
+		{false, 4 * time.Millisecond, ERR, "12:00:00.000"},
+		{false, 0, ALERT, "12:00:00.007"},
+		{false, 1 * time.Millisecond, INFO, "12:00:00.007"}, // never less than the previous one
+		{false, 2 * time.Millisecond, EMERG, "12:00:00.010"},
+		{true, 5 * time.Millisecond, DEBUG, "12:00:00.015"},
+		{false, 3 * time.Millisecond, INFO, "12:00:00.015"},
+	}
+
+	for i, m := range messages {
+		clock.Add(m.advance)
+		if m.tick {
+			coarseTick()
+		}
+
+		n := len(console.Lines())
+		ForceMessage(m.level, "message %d", i)
+
+		lines := console.Lines()
+		if len(lines) != n+1 || !strings.Contains(lines[n], " 2024-05-01 "+m.stamp+" ") {
+			t.Errorf("%d: got %q, expected %s", i, lines[n:], m.stamp)
+		}
+	}
+}
+
+func TestCoarseClockRotation(t *testing.T) {
+	resetLog(t)
+	clock := setFakeClock(time.Date(2024, 5, 1, 23, 59, 59, 995*int(time.Millisecond), time.UTC))
+
+	dir := t.TempDir()
+	SetFile(dir, "", false, 0, 0)
+
+	SetCoarseClock(time.Hour)
+	defer SetCoarseClock(0)
+
+	clock.Add(10 * time.Millisecond)
+	Message(INFO, "late")
+
+	coarseTick()
+	Message(INFO, "next day")
+
+	old, _ := os.ReadFile(filepath.Join(dir, "2024-05-01.log"))
+	if !strings.HasSuffix(string(old), " 2024-05-01 23:59:59.995 late\n") {
+		t.Errorf("unexpected old file:\n%s", old)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dir, "2024-05-02.log"))
+	if !strings.HasSuffix(string(data), " 2024-05-02 00:00:00.005 next day\n") {
+		t.Errorf("unexpected new file:\n%s", data)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func BenchmarkCoarseClock(b *testing.B) {
+	for _, resolution := range []time.Duration{0, 10 * time.Millisecond} {
+		name := "Precise"
+		if resolution != 0 {
+			name = "Coarse"
+		}
+
+		b.Run(name, func(b *testing.B) {
+			defer func() {
+				SetCoarseClock(0)
+				SetConsoleWriter(nil)
+			}()
+
+			SetConsoleWriter(io.Discard)
+			SetFile(b.TempDir(), "", false, 64*1024, 0)
+			SetCoarseClock(resolution)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				Message(INFO, "benchmark")
+			}
+		})
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
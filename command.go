@@ -0,0 +1,34 @@
+package log
+
+import (
+	"errors"
+	"os/exec"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// AttachCommand -- log the stdout and stderr of the command with the levels and the prefix. Must be called before cmd.Start.
+// flush must be called after cmd.Wait to log the incomplete last lines.
+func AttachCommand(cmd *exec.Cmd, f *Facility, stdoutLevel Level, stderrLevel Level, prefix string) (flush func(), err error) {
+	if cmd.Process != nil {
+		return nil, errors.New("command is already started")
+	}
+	if cmd.Stdout != nil || cmd.Stderr != nil {
+		return nil, errors.New("command output is already set")
+	}
+
+	stdout := NewLevelWriter(f, stdoutLevel, prefix)
+	stderr := NewLevelWriter(f, stderrLevel, prefix)
+
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	flush = func() {
+		stdout.Flush()
+		stderr.Flush()
+	}
+
+	return flush, nil
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,48 @@
+package log
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestAttachCommand(t *testing.T) {
+	console := resetLog(t)
+
+	cmd := exec.Command("sh", "-c", `echo out 1; echo err 1 >&2; echo out 2; printf 'no eol' >&2`)
+
+	flush, err := AttachCommand(cmd, GetFacility("helper"), INFO, ERR, "[sh] ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	flush()
+
+	lines := console.Lines()
+
+	var out, errs []string
+	for _, line := range lines {
+		_, text, _ := strings.Cut(line, "<helper> [sh] ")
+		switch {
+		case strings.Contains(line, " IN "):
+			out = append(out, text)
+		case strings.Contains(line, " ER "):
+			errs = append(errs, text)
+		}
+	}
+
+	if strings.Join(out, "|") != "out 1|out 2" || strings.Join(errs, "|") != "err 1|no eol" {
+		t.Errorf("unexpected output %q", lines)
+	}
+
+	if _, err := AttachCommand(cmd, nil, INFO, ERR, ""); err == nil {
+		t.Error("started command is accepted")
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
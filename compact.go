@@ -0,0 +1,210 @@
+package log
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/alrusov/misc"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// The compact file format omits the pid and the date of every line:
+//
+//	#compact [12345] 2024-05-01
+//	12:00:00.000 IN <facility> text
+//
+// The header line declares them and it is repeated when they are changed. Lines in other formats are written as is.
+// The checksum trailer covers the classic form of the line.
+
+// FileFormat -- format of lines in the file
+type FileFormat int
+
+const (
+	// FormatClassic -- "[pid] LV date time text" (default)
+	FormatClassic FileFormat = iota
+	// FormatCompact -- "time LV text" after the header with the pid and the date
+	FormatCompact
+)
+
+// CompactHeader -- pid and date declared by the last header of the compact file
+type CompactHeader struct {
+	PID  int
+	Date string
+}
+
+const compactHeaderPrefix = "#compact ["
+
+var (
+	fileFormat = FormatClassic
+
+	compactPID  string
+	compactDate string
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetFileFormat -- format of lines in the file, the console isn't affected
+func SetFileFormat(format FileFormat) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	fileFormat = format
+	resetCompact()
+}
+
+// ExpandCompactFile -- convert the compact file to the classic format. Header lines are removed.
+func ExpandCompactFile(r io.Reader, w io.Writer) error {
+	br := bufio.NewReader(r)
+	bw := bufio.NewWriter(w)
+
+	h := CompactHeader{}
+
+	for {
+		line, err := br.ReadString('\n')
+		if line != "" {
+			if s, isLine := h.expand(line); isLine {
+				bw.WriteString(s)
+			}
+		}
+
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// resetCompact -- the next line starts with the header. Must be called under the mutex.
+func resetCompact() {
+	compactPID = ""
+	compactDate = ""
+}
+
+// compactLines -- compact form of the text. Must be called under the mutex.
+func compactLines(s string) string {
+	var sb strings.Builder
+	sb.Grow(len(s))
+
+	for s != "" {
+		line := s
+		if i := strings.IndexByte(s, '\n'); i >= 0 {
+			line, s = s[:i+1], s[i+1:]
+		} else {
+			s = ""
+		}
+
+		pid, level, dt, tm, rest, ok := splitClassicLine(line)
+		if !ok {
+			sb.WriteString(line)
+			continue
+		}
+
+		if pid != compactPID || dt != compactDate {
+			compactPID = pid
+			compactDate = dt
+			sb.WriteString(compactHeaderPrefix + pid + "] " + dt + misc.EOS)
+		}
+
+		sb.WriteString(tm + " " + level + rest)
+	}
+
+	return sb.String()
+}
+
+// splitClassicLine -- "[pid] level date time" and the rest of the line
+func splitClassicLine(line string) (pid string, level string, dt string, tm string, rest string, ok bool) {
+	if !strings.HasPrefix(line, "[") {
+		return
+	}
+
+	i := strings.Index(line, "] ")
+	if i < 2 {
+		return
+	}
+	pid = line[1:i]
+	if _, err := strconv.Atoi(pid); err != nil {
+		return
+	}
+
+	s := line[i+2:]
+
+	level, s, ok = strings.Cut(s, " ")
+	if !ok || level == "" {
+		return
+	}
+
+	dt, s, ok = strings.Cut(s, " ")
+	if !ok || len(dt) != len(misc.DateFormatRev) {
+		ok = false
+		return
+	}
+
+	tm = s
+	if j := strings.IndexAny(s, " \r\n"); j >= 0 {
+		tm, rest = s[:j], s[j:]
+	}
+	ok = isCompactTime(tm)
+	return
+}
+
+// isCompactTime -- "15:04:05.000"
+func isCompactTime(s string) bool {
+	if len(s) != len(misc.TimeFormatWithMS) {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		switch i {
+		case 2, 5:
+			if s[i] != ':' {
+				return false
+			}
+		case 8:
+			if s[i] != '.' {
+				return false
+			}
+		default:
+			if s[i] < '0' || s[i] > '9' {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// expand -- the classic form of the line, false for the header which is stored into h
+func (h *CompactHeader) expand(line string) (string, bool) {
+	if s, found := strings.CutPrefix(line, compactHeaderPrefix); found {
+		pid, dt, ok := strings.Cut(strings.TrimRight(s, "\r\n"), "] ")
+		if n, err := strconv.Atoi(pid); ok && err == nil {
+			h.PID = n
+			h.Date = dt
+			return "", false
+		}
+	}
+
+	n := len(misc.TimeFormatWithMS)
+	if h.Date == "" || len(line) <= n || line[n] != ' ' || !isCompactTime(line[:n]) {
+		return line, true
+	}
+
+	rest := line[n+1:]
+	j := strings.IndexAny(rest, " \r\n")
+	if j < 0 {
+		j = len(rest)
+	}
+
+	return "[" + strconv.Itoa(h.PID) + "] " + rest[:j] + " " + h.Date + " " + line[:n] + rest[j:], true
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,97 @@
+package log
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func writeSyntheticLog(t *testing.T, format FileFormat) []byte {
+	t.Helper()
+
+	resetLog(t)
+
+	// The flusher would log the date change of the fake clock
+	if BackgroundRunning() {
+		StopBackground()
+		t.Cleanup(StartBackground)
+	}
+
+	clock := setFakeClock(time.Date(2024, 5, 1, 23, 58, 0, 0, time.UTC))
+
+	SetFile(t.TempDir(), "", false, 64*1024, 0)
+	SetFileFormat(format)
+
+	facilities := []*Facility{stdFacility, GetFacility("http"), GetFacility("db")}
+
+	var names []string
+	for i := 0; i < 10000; i++ {
+		Message(INFO, "")
+		facilities[i%len(facilities)].Message(INFO, "request %d done in %dms", i, i%97)
+		clock.Add(17 * time.Millisecond)
+
+		if len(names) == 0 || names[len(names)-1] != FileName() {
+			names = append(names, FileName())
+		}
+	}
+	writerFlush()
+
+	if len(names) != 2 {
+		t.Fatalf("got %d files, expected 2", len(names))
+	}
+
+	var data []byte
+	for _, name := range names {
+		d, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data = append(data, d...)
+	}
+	return data
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestCompactFormat(t *testing.T) {
+	classic := writeSyntheticLog(t, FormatClassic)
+	compact := writeSyntheticLog(t, FormatCompact)
+
+	t.Logf("classic %d bytes, compact %d bytes, %.1f%% less", len(classic), len(compact), 100*(1-float64(len(compact))/float64(len(classic))))
+	if len(compact) >= len(classic)*3/4 {
+		t.Errorf("compact %d bytes is too large for classic %d bytes", len(compact), len(classic))
+	}
+	if n := strings.Count(string(compact), compactHeaderPrefix); n != 2 {
+		t.Errorf("got %d headers, expected 2", n)
+	}
+
+	var expanded bytes.Buffer
+	if err := ExpandCompactFile(bytes.NewReader(compact), &expanded); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(expanded.Bytes(), classic) {
+		t.Fatalf("expanded file differs from the classic one")
+	}
+
+	h := &CompactHeader{}
+	n := 0
+	for _, line := range strings.SplitAfter(string(compact), "\n") {
+		info, ok := ParseLineEx(line, h)
+		if !ok {
+			continue
+		}
+		if n == 4 && (info.PID != pid || info.Facility != "http" || info.Text != "request 1 done in 1ms" || info.Time.Format(time.DateOnly) != "2024-05-01") {
+			t.Errorf("unexpected parsed line %+v", info)
+		}
+		n++
+	}
+	if n != 20002 {
+		t.Errorf("got %d parsed lines, expected 20002", n)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
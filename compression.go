@@ -0,0 +1,88 @@
+package log
+
+import (
+	"compress/gzip"
+	"io"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Compression -- compression of the active log file
+type Compression string
+
+const (
+	// CompressionNone --
+	CompressionNone = Compression("")
+	// CompressionGzip -- every open of the file starts a new gzip member, the stream is flushed by the flusher
+	CompressionGzip = Compression("gzip")
+)
+
+// streamFlusher -- destination with its own internal buffer flushed together with the file buffer
+type streamFlusher interface {
+	Flush() error
+}
+
+type gzipFile struct {
+	w  io.WriteCloser
+	gz *gzip.Writer
+}
+
+var (
+	compression = CompressionNone
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func (c Compression) extension() string {
+	switch c {
+	case CompressionGzip:
+		return ".gz"
+	default:
+		return ""
+	}
+}
+
+func (c Compression) wrap(w io.WriteCloser) io.WriteCloser {
+	switch c {
+	case CompressionGzip:
+		return &gzipFile{
+			w:  w,
+			gz: gzip.NewWriter(w),
+		}
+	default:
+		return w
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func (f *gzipFile) Write(p []byte) (int, error) {
+	return f.gz.Write(p)
+}
+
+func (f *gzipFile) Flush() error {
+	err := f.gz.Flush()
+	if s, ok := f.w.(streamFlusher); ok {
+		if e := s.Flush(); err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+func (f *gzipFile) Sync() error {
+	if s, ok := f.w.(interface{ Sync() error }); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+func (f *gzipFile) Close() error {
+	err := f.gz.Close()
+	if e := f.w.Close(); err == nil {
+		err = e
+	}
+	return err
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,100 @@
+package log
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func readGzip(t *testing.T, path string, complete bool) string {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := io.ReadAll(r)
+	if err != nil && (complete || err != io.ErrUnexpectedEOF) {
+		t.Fatal(err)
+	}
+
+	return string(s)
+}
+
+func TestGzipFile(t *testing.T) {
+	resetLog(t)
+
+	clock := setFakeClock(time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC))
+
+	dir := t.TempDir()
+	SetFileEx(FileOptions{Directory: dir, BufSize: 4096, Compression: CompressionGzip})
+
+	Message(INFO, "first")
+	Message(INFO, "second")
+
+	if !strings.HasSuffix(FileName(), "/2024-05-03.log.gz") {
+		t.Fatalf("unexpected file name %s", FileName())
+	}
+
+	// Mid-day: the stream is flushed but not closed
+	writerFlush()
+	s := readGzip(t, FileName(), false)
+	if !strings.Contains(s, " *** ") || !strings.Contains(s, "first\n") || !strings.HasSuffix(s, "second\n") {
+		t.Errorf("unexpected partial content:\n%s", s)
+	}
+
+	// Rotation closes the stream, the reopened file gets a new member
+	first := FileName()
+	clock.Add(24 * time.Hour)
+	Message(INFO, "next day")
+
+	s = readGzip(t, first, true)
+	if !strings.HasSuffix(s, "second\n") {
+		t.Errorf("unexpected closed content:\n%s", s)
+	}
+
+	clock.Add(-24 * time.Hour)
+	lastStamp = time.Time{}
+	lastWriteDate = "" // the file is never rotated backwards by the clock
+	Message(INFO, "back again")
+	closeLogFile()
+
+	s = readGzip(t, first, true)
+	if !strings.Contains(s, "second\n") || !strings.HasSuffix(s, "back again\n") {
+		t.Errorf("unexpected reopened content:\n%s", s)
+	}
+}
+
+func TestGzipFileStderr(t *testing.T) {
+	resetLog(t)
+
+	setFakeClock(time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC))
+
+	SetFileEx(FileOptions{Directory: t.TempDir(), Compression: CompressionGzip})
+	Message(INFO, "before")
+
+	// The stderr isn't the compressed file, the stray write doesn't get into the stream
+	os.Stderr.WriteString("stray stderr write\n")
+
+	Message(INFO, "after")
+	closeLogFile()
+
+	s := readGzip(t, FileName(), true)
+	if strings.Contains(s, "stray") || !strings.HasSuffix(s, "after\n") {
+		t.Errorf("unexpected content:\n%s", s)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,518 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"slices"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// The snapshot keeps all mutable settings: facility levels, enabled flags, sampling, look-behind and quotas, formatting,
+// the console, the file settings and retention, the output writer and the memory mode, flushing, storm protection,
+// rules, targets, destinations, notifiers, the group commit, clocks, the stderr and stdlib logger handling, timings,
+// usage counting, the flight recorder, the crash dump and the level persistence. Every exported Set and Enable function
+// is either restored or listed as excluded in the test.
+// Restoring brings the settings back. The file is reopened only if its location, output writer, memory mode,
+// compression, encryption or failure injection is changed. The buffers resized by restoring lose their content.
+// Targets, subscriptions, alert, level and config change functions and rules watchers added after the snapshot are
+// removed, the removed ones can't be brought back. Facilities created after the snapshot get the default level.
+// Level and config alerts aren't called by restoring.
+
+// ConfigSnapshot -- the settings taken by SnapshotConfig. It's immutable and can be shared between goroutines.
+type ConfigSnapshot struct {
+	taken bool
+
+	enabled          bool
+	facilities       map[string]facilityConfig
+	autoFacility     bool
+	maxFacilities    int
+	strictFacilities bool
+	hierarchical     bool
+	funcName         int32
+	maxLen           int
+	legacy           bool
+	localTime        bool
+	moduleTagging    bool
+	humanUnits       bool
+	durationDigits   int32
+	tokenTTL         int64
+
+	consoleWriter io.Writer
+	consoleFilter []string
+	consoleDedup  time.Duration
+	strictConsole bool
+
+	fileDirectory string
+	filePattern   string
+	bufSize       int
+	flushPeriod   time.Duration
+	compression   Compression
+	fileFormat    FileFormat
+	checksums     bool
+	encryption    []byte
+	flushOnSevere Level
+	syncPeriod    time.Duration
+	retention     RetentionOptions
+	stripANSI     bool
+	fileTrailer   bool
+	boundary      int64
+	output        io.WriteCloser
+	memory        bool
+	memoryLines   int
+	asyncOpen     bool
+	exclusive     bool
+	exclusiveMode ExclusivePolicy
+	symlinks      bool
+	truncCheck    bool
+	minFreeSpace  uint64
+	fallbackDir   string
+	fallbackSize  int
+	failure       *injectorHolder
+	flushAlign    bool
+	idleShrink    int
+	degraded      time.Duration
+
+	groupCommit    GroupCommitMode
+	enqueueTimeout int64
+	coarseClock    time.Duration
+	clockTolerance time.Duration
+
+	stdlogFacility *Facility
+	stdlogLevel    Level
+	hijackStdLog   bool
+	stderr         bool
+	runtimeTrace   bool
+
+	timingsPattern string
+	timingsOnly    bool
+
+	usageResolution time.Duration
+	usageBuckets    int
+	usageDump       bool
+
+	lastLogSize    int
+	flightSize     int
+	flightInterval time.Duration
+	crashDump      string
+
+	levelPersistPath   string
+	levelPersistMaxAge time.Duration
+
+	stormThreshold int
+	stormWindow    time.Duration
+	stormAction    StormAction
+
+	rules        *ruleSet
+	targets      map[string]Target
+	fileDest     destination
+	destinations []destination
+	severe       *severeNotifier
+	cooldown     int64
+
+	alertID        int64
+	levelChangeID  int64
+	subscriberID   int64
+	rulesWatcherID int64
+	configAlertID  int64
+}
+
+type facilityConfig struct {
+	level    Level
+	disabled bool
+
+	samplingLevel Level
+	samplingN     int
+
+	lookBehindDepth   int
+	lookBehindTrigger Level
+
+	quota       int64
+	quotaAction QuotaAction
+}
+
+var (
+	// ErrNoSnapshot -- the snapshot isn't taken by SnapshotConfig
+	ErrNoSnapshot = errors.New("config snapshot is not taken")
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SnapshotConfig -- take the current settings
+func SnapshotConfig() ConfigSnapshot {
+	// They have their own locks
+	coarse := currentCoarseClock()
+	crash := currentCrashDump()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	s := ConfigSnapshot{
+		taken: true,
+
+		enabled:          enabled,
+		facilities:       make(map[string]facilityConfig, len(facilities)),
+		autoFacility:     autoFacility.Load(),
+		maxFacilities:    maxFacilities,
+		strictFacilities: strictFacilities,
+		hierarchical:     hierarchicalLevels.Load(),
+		funcName:         logFuncName.Load(),
+		maxLen:           int(maxLen.Load()),
+		legacy:           legacyFormatting.Load(),
+		localTime:        localTime,
+		moduleTagging:    moduleTagging.Load(),
+		humanUnits:       humanUnits.Load(),
+		durationDigits:   durationPrecision.Load(),
+		tokenTTL:         logTokenTTL.Load(),
+
+		consoleWriter: consoleWriter,
+		consoleFilter: slices.Clone(consoleFilter),
+		consoleDedup:  consoleDedupWindow,
+		strictConsole: strictConsole.Load(),
+
+		fileDirectory: fileDirectory,
+		filePattern:   fileNamePattern,
+		bufSize:       fileWriterBufSize,
+		flushPeriod:   fileWriterFlushPeriod,
+		compression:   compression,
+		fileFormat:    fileFormat,
+		checksums:     lineChecksums,
+		encryption:    bytes.Clone(encryptionKey),
+		flushOnSevere: flushOnSevere,
+		syncPeriod:    syncPeriod,
+		retention:     retention,
+		stripANSI:     stripANSI.Load(),
+		fileTrailer:   fileTrailer,
+		boundary:      rotationBoundary.Load(),
+		output:        outputWriter,
+		memory:        memoryMode,
+		memoryLines:   memoryMaxLines,
+		asyncOpen:     asyncOpen,
+		exclusive:     exclusiveFile,
+		exclusiveMode: exclusivePolicy,
+		symlinks:      maintainSymlinks,
+		truncCheck:    truncationCheck,
+		minFreeSpace:  minFreeSpace,
+		fallbackDir:   fallbackDirectory,
+		fallbackSize:  fallbackBufSize,
+		failure:       failureInjector.Load(),
+		flushAlign:    flushAlign,
+		idleShrink:    idleShrinkPeriods,
+		degraded:      degradedInterval,
+
+		groupCommit:    currentGroupCommit(),
+		enqueueTimeout: enqueueTimeout.Load(),
+		coarseClock:    coarse,
+		clockTolerance: clockTolerance,
+
+		stdlogFacility: stdlogFacility,
+		stdlogLevel:    stdlogLevel,
+		hijackStdLog:   hijackStdLog.Load(),
+		stderr:         stderrIntercepted,
+		runtimeTrace:   runtimeTraceParsing,
+
+		timingsPattern: timingsPattern,
+		timingsOnly:    timingsOnly,
+
+		usageDump: usageDump.Load(),
+
+		lastLogSize:    len(lastLog),
+		flightInterval: flightDumpInterval,
+		crashDump:      crash,
+
+		levelPersistPath:   levelPersistPath,
+		levelPersistMaxAge: levelPersistMaxAge,
+
+		stormThreshold: int(stormThreshold),
+		stormWindow:    stormWindow,
+		stormAction:    stormAction,
+
+		rules:        activeRules.Load(),
+		targets:      make(map[string]Target, len(targets)),
+		fileDest:     *fileDestination,
+		destinations: make([]destination, len(destinations)),
+		severe:       severe.Load(),
+		cooldown:     severeCooldown.Load(),
+
+		alertID:        alertSubscriberID,
+		levelChangeID:  levelChangeSubscriberID,
+		subscriberID:   subscriberID,
+		rulesWatcherID: rulesWatcherID,
+		configAlertID:  configAlertID,
+	}
+
+	for name, f := range facilities {
+		depth, trigger := f.lookBehindConfig()
+		s.facilities[name] = facilityConfig{
+			level:         f.level,
+			disabled:      f.disabled.Load(),
+			samplingLevel: Level(f.sampling.level.Load()),
+			samplingN:     int(f.sampling.n.Load()),
+
+			lookBehindDepth:   depth,
+			lookBehindTrigger: trigger,
+		}
+	}
+	for name, q := range quotas {
+		if c, exists := s.facilities[name]; exists {
+			c.quota, c.quotaAction = q.Limit, q.action
+			s.facilities[name] = c
+		}
+	}
+	if u := usage.Load(); u != nil {
+		s.usageResolution, s.usageBuckets = u.resolution, len(u.slots)
+	}
+	if flight != nil {
+		s.flightSize = len(flight.buf)
+	}
+	for name, t := range targets {
+		s.targets[name] = t
+	}
+	for i, d := range destinations {
+		s.destinations[i] = *d
+	}
+
+	return s
+}
+
+// RestoreConfig -- bring back the settings of the snapshot. The errors of the crash dump file and of the stderr interception
+// are returned, the other settings are restored anyway.
+func RestoreConfig(s ConfigSnapshot) error {
+	if !s.taken {
+		return ErrNoSnapshot
+	}
+
+	var closing []Target
+	var stops []func()
+
+	mutex.Lock()
+
+	flushOpenPending()
+
+	for name, f := range facilities {
+		c, exists := s.facilities[name]
+		if !exists {
+			c = facilityConfig{level: s.facilities[StdFacilityName].level}
+		}
+		f.setLevel(c.level)
+		f.disabled.Store(c.disabled)
+		f.SetSampling(c.samplingLevel, c.samplingN)
+		if depth, trigger := f.lookBehindConfig(); depth != c.lookBehindDepth || trigger != c.lookBehindTrigger {
+			f.setLookBehind(c.lookBehindDepth, c.lookBehindTrigger)
+		}
+		setDailyQuota(name, c.quota, c.quotaAction)
+		for id := range f.alertSubscribers {
+			if id > s.alertID {
+				delete(f.alertSubscribers, id)
+			}
+		}
+	}
+
+	enabled = s.enabled
+	autoFacility.Store(s.autoFacility)
+	if maxFacilities != s.maxFacilities {
+		maxFacilities = s.maxFacilities
+		rejectedNames = nil
+		rejectedReported = false
+	}
+	strictFacilities = s.strictFacilities
+	hierarchicalLevels.Store(s.hierarchical)
+	logFuncName.Store(s.funcName)
+	maxLen.Store(int64(s.maxLen))
+	legacyFormatting.Store(s.legacy)
+	if localTime != s.localTime {
+		localTime = s.localTime
+		resetTimeCache()
+	}
+	moduleTagging.Store(s.moduleTagging)
+	humanUnits.Store(s.humanUnits)
+	durationPrecision.Store(s.durationDigits)
+	logTokenTTL.Store(s.tokenTTL)
+
+	consoleWriter = s.consoleWriter
+	consoleFilter = slices.Clone(s.consoleFilter)
+	if consoleDedupWindow != s.consoleDedup {
+		flushConsoleDup()
+		consoleDedupWindow = s.consoleDedup
+	}
+	strictConsole.Store(s.strictConsole)
+
+	if fileDirectory != s.fileDirectory || fileNamePattern != s.filePattern || compression != s.compression ||
+		!bytes.Equal(encryptionKey, s.encryption) || outputWriter != s.output || memoryMode != s.memory ||
+		failureInjector.Load() != s.failure {
+		closeLogFile()
+		lastWriteDate = ""
+	}
+	if memoryMode != s.memory {
+		if s.memory {
+			memoryMode = true
+			memoryBuf = []memoryLine{}
+		} else {
+			memoryToFile()
+		}
+	}
+	memoryMaxLines = s.memoryLines
+	outputWriter = s.output
+	failureInjector.Store(s.failure)
+	asyncOpen = s.asyncOpen
+	exclusiveFile = s.exclusive
+	exclusivePolicy = s.exclusiveMode
+	if !exclusiveFile {
+		releaseExclusive()
+	}
+	maintainSymlinks = s.symlinks
+	truncationCheck = s.truncCheck
+	minFreeSpace = s.minFreeSpace
+	fallbackDirectory = s.fallbackDir
+	fallbackBufSize = s.fallbackSize
+	flushAlign = s.flushAlign
+	idleShrinkPeriods = s.idleShrink
+	degradedInterval = s.degraded
+	fileDirectory = s.fileDirectory
+	fileNamePattern = s.filePattern
+	fileWriterBufSize = s.bufSize
+	fileWriterFlushPeriod = s.flushPeriod
+	compression = s.compression
+	fileFormat = s.fileFormat
+	lineChecksums = s.checksums
+	encryptionKey = bytes.Clone(s.encryption)
+	flushOnSevere = s.flushOnSevere
+	syncPeriod = s.syncPeriod
+	retention = s.retention
+	stripANSI.Store(s.stripANSI)
+	fileTrailer = s.fileTrailer
+	rotationBoundary.Store(s.boundary)
+
+	enqueueTimeout.Store(s.enqueueTimeout)
+	clockTolerance = s.clockTolerance
+
+	stdlogFacility = s.stdlogFacility
+	stdlogLevel = s.stdlogLevel
+	stderrChanged := stderrIntercepted != s.stderr
+	runtimeTraceParsing = s.runtimeTrace
+
+	if timingsPattern != s.timingsPattern {
+		closeTimingsFile()
+		timingsPattern = s.timingsPattern
+	}
+	timingsOnly = s.timingsOnly
+
+	if len(lastLog) != s.lastLogSize {
+		lastLog = make([]LogEntry, s.lastLogSize)
+		lastLogStart = 0
+		lastLogLen = 0
+	}
+	flightSize := 0
+	if flight != nil {
+		flightSize = len(flight.buf)
+	}
+	if flightSize != s.flightSize {
+		flight = nil
+		if s.flightSize > 0 {
+			flight = &flightRing{buf: make([]byte, s.flightSize)}
+		}
+	}
+	flightDumpInterval = s.flightInterval
+
+	if levelPersistPath != s.levelPersistPath {
+		levelPersistPath = s.levelPersistPath
+		levelPersistPending = false
+	}
+	levelPersistMaxAge = s.levelPersistMaxAge
+
+	if int(stormThreshold) != s.stormThreshold || stormWindow != s.stormWindow || stormAction != s.stormAction {
+		setStormProtection(s.stormThreshold, s.stormWindow, s.stormAction)
+	}
+
+	activeRules.Store(s.rules)
+
+	for name, t := range targets {
+		if old, exists := s.targets[name]; !exists || old != t {
+			delete(targets, name)
+			closing = append(closing, t)
+		}
+	}
+
+	fd := s.fileDest
+	fileDestination = &fd
+	destinations = make([]*destination, len(s.destinations))
+	for i := range s.destinations {
+		d := s.destinations[i]
+		destinations[i] = &d
+	}
+
+	for id := range alertSubscribers {
+		if id > s.alertID {
+			delete(alertSubscribers, id)
+		}
+	}
+	for id := range levelChangeSubscribers {
+		if id > s.levelChangeID {
+			delete(levelChangeSubscribers, id)
+		}
+	}
+	for id := range configAlertSubscribers {
+		if id > s.configAlertID {
+			delete(configAlertSubscribers, id)
+		}
+	}
+	for id, sub := range subscribers {
+		if id > s.subscriberID {
+			delete(subscribers, id)
+			close(sub.ch)
+		}
+	}
+	for id, stop := range rulesWatchers {
+		if id > s.rulesWatcherID {
+			stops = append(stops, stop)
+		}
+	}
+
+	mutex.Unlock()
+
+	// Stopped watchers and closed targets may log
+	for _, stop := range stops {
+		stop()
+	}
+	for _, t := range closing {
+		t.Close()
+	}
+
+	if n := severe.Load(); n != s.severe {
+		if s.severe == nil {
+			SetSevereNotifier(0, nil)
+		} else {
+			SetSevereNotifier(s.severe.minLevel, s.severe.fn)
+		}
+	}
+	severeCooldown.Store(s.cooldown)
+
+	// The settings with their own goroutines and locks
+	if currentGroupCommit() != s.groupCommit {
+		SetGroupCommit(s.groupCommit)
+	}
+	if currentCoarseClock() != s.coarseClock {
+		SetCoarseClock(s.coarseClock)
+	}
+	if u := usage.Load(); (u == nil && s.usageResolution != 0) || (u != nil && (u.resolution != s.usageResolution || len(u.slots) != s.usageBuckets)) {
+		SetUsageBuckets(s.usageResolution, s.usageBuckets)
+	}
+	usageDump.Store(s.usageDump)
+	if hijackStdLog.Load() != s.hijackStdLog {
+		SetHijackStdLog(s.hijackStdLog)
+	}
+
+	var err error
+	if currentCrashDump() != s.crashDump {
+		err = EnableCrashDump(s.crashDump)
+	}
+	if stderrChanged {
+		err = errors.Join(err, SetStderrInterception(s.stderr))
+	}
+
+	return err
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
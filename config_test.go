@@ -0,0 +1,286 @@
+package log
+
+import (
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func configScenario() {
+	Message(DEBUG, "debug line")
+	Message(INFO, "info line long enough to be truncated")
+	GetFacility("cfg-a").Message(INFO, "a info")
+	GetFacility("cfg-a").Message(WARNING, "a warning")
+	GetFacility("cfg-new").Message(INFO, "new facility")
+	Message(INFO, "request token=abc health")
+}
+
+func TestConfigSnapshotRestore(t *testing.T) {
+	console := resetLog(t)
+	setFakeClock(time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC))
+
+	if err := RestoreConfig(ConfigSnapshot{}); !errors.Is(err, ErrNoSnapshot) {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	dir := t.TempDir()
+	SetFile(dir, "", false, 0, 0)
+	SetLogLevel("INFO", FuncNameModeNone)
+	GetFacility("cfg-a").SetLogLevel("WARNING", FuncNameModeNone)
+
+	start := len(console.Lines())
+	configScenario()
+	golden := console.Lines()[start:]
+	if len(golden) == 0 {
+		t.Fatal("nothing is written")
+	}
+	name := FileName()
+
+	snap := SnapshotConfig()
+
+	// Everything is changed
+	other := &captureWriter{}
+	sink := &captureWriter{}
+	target := &failingSink{}
+
+	SetLogLevel("TRACE4", FuncNameModeFull)
+	GetFacility("cfg-a").SetLogLevel("DEBUG", FuncNameModeFull)
+	GetFacility("cfg-new").Disable()
+	GetFacility("cfg-a").SetSampling(WARNING, 3)
+	GetFacility("cfg-a").EnableLookBehind(2, WARNING)
+	MaxLen(20)
+	SetConsoleWriter(other)
+	SetConsoleFacilityFilter("nothing")
+	SetFile(t.TempDir(), "moved", true, 4096, 0)
+	SetFlushOnSevere(ERR)
+	SetStormProtection(1, time.Hour, StormDropDuplicates)
+	AddTarget("cfg-target", target)
+	AddDestination("cfg-extra", FormatJSON, sink, UNKNOWN)
+	AddDestination(DestinationConsole, FormatJSON, nil, ERR)
+	ch, _ := SubscribeAll(10)
+	alerted := false
+	AddAlertFunc(func(string, Level, Level) { alerted = true })
+
+	rules := filepath.Join(t.TempDir(), "rules.toml")
+	writeRules(t, rules, testRules, time.Now())
+	stop, err := WatchRulesFile(rules, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+
+	configScenario()
+	sinkLines := len(sink.Lines())
+
+	if err := RestoreConfig(snap); err != nil {
+		t.Fatal(err)
+	}
+
+	start = len(console.Lines())
+	configScenario()
+
+	if restored := console.Lines()[start:]; !slices.Equal(restored, golden) {
+		t.Errorf("got\n%s\nexpected\n%s", strings.Join(restored, "\n"), strings.Join(golden, "\n"))
+	}
+
+	if FileName() != name {
+		t.Errorf("the file is %s, expected %s", FileName(), name)
+	}
+	data, _ := os.ReadFile(name)
+	if n := strings.Count(string(data), " a warning\n"); n != 2 {
+		t.Errorf("the restored file got %d lines", n)
+	}
+
+	if len(sink.Lines()) != sinkLines || len(TargetNames()) != 0 || activeRules.Load() != nil {
+		t.Errorf("hooks are left")
+	}
+	if _, open := <-ch; open {
+		for range ch {
+		}
+	}
+	if _, open := <-ch; open {
+		t.Errorf("the subscription isn't closed")
+	}
+
+	SetLogLevel("DEBUG", FuncNameModeNone)
+	if alerted {
+		t.Errorf("the alert function isn't removed")
+	}
+}
+
+// comparableSnapshot -- the snapshot without the destinations and the notifier, they have functions
+func comparableSnapshot(s ConfigSnapshot) ConfigSnapshot {
+	s.fileDest = destination{}
+	s.destinations = nil
+	s.severe = nil
+	return s
+}
+
+func TestConfigSnapshotSettings(t *testing.T) {
+	resetLog(t)
+	dir := t.TempDir()
+	SetFile(dir, "", false, 0, 0)
+	f := GetFacility("cfg-q")
+	Message(INFO, "opened")
+
+	for _, memory := range []bool{false, true} {
+		snap := SnapshotConfig()
+
+		// Every setting is changed
+		Disable()
+		SetAutoFacilityFromCaller(true)
+		SetMaxFacilities(100)
+		SetStrictFacilities(true)
+		SetModuleTagging(true)
+		SetHumanUnits(false)
+		SetDurationPrecision(1)
+		SetLogTokenTTL(time.Minute)
+		SetStrictConsoleOrdering(true)
+		if memory {
+			SetMemoryMode(10)
+		} else {
+			SetOutput(&testCloser{}, OutputOptions{})
+		}
+		SetAsyncFileOpen(true)
+		SetExclusiveFile(true, ExclusiveFail)
+		SetMaintainSymlinks(true)
+		SetTruncationCheck(false)
+		SetMinFreeSpace(1 << 20)
+		SetFallbackDirectory(t.TempDir())
+		SetFallbackBufferSize(5)
+		SetFailureInjection(NewScriptedInjector())
+		SetFlushAlignment(true)
+		SetIdleShrink(3)
+		SetDegradedReportInterval(time.Hour)
+		SetGroupCommit(GroupCommitAsync)
+		SetEnqueueTimeout(time.Second)
+		SetCoarseClock(time.Millisecond)
+		SetClockTolerance(time.Hour)
+		SetStdlogRouting("cfg-q", ERR)
+		SetHijackStdLog(!hijackStdLog.Load())
+		SetRuntimeTraceParsing(true)
+		SetTimingsFile(t.TempDir(), "cfg")
+		SetTimingsOnlyToTimingsFile(true)
+		SetUsageBuckets(time.Minute, 5)
+		SetUsageDump(true)
+		SetLastLogSize(7)
+		EnableFlightRecorder(4096)
+		SetFlightRecorderDumpInterval(time.Hour)
+		if err := EnableCrashDump(filepath.Join(t.TempDir(), "crash.jsonl")); err != nil {
+			t.Fatal(err)
+		}
+		EnableLevelPersistence(filepath.Join(t.TempDir(), "levels.json"))
+		SetLevelPersistenceMaxAge(time.Hour)
+		SetSevereNotifierCooldown(time.Hour)
+		f.SetDailyQuota(1000, QuotaDrop)
+		f.SetVerbosity(2)
+
+		if err := RestoreConfig(snap); err != nil {
+			t.Fatal(err)
+		}
+
+		if s := comparableSnapshot(SnapshotConfig()); !reflect.DeepEqual(s, comparableSnapshot(snap)) {
+			t.Errorf("[%v] not restored\n%+v\nexpected\n%+v", memory, s, comparableSnapshot(snap))
+		}
+	}
+
+	Message(INFO, "restored")
+	writerFlush()
+
+	if data, _ := os.ReadFile(FileName()); !strings.Contains(string(data), " opened\n") || !strings.Contains(string(data), " restored\n") {
+		t.Errorf("the file isn't restored:\n%s", data)
+	}
+}
+
+// TestConfigSnapshotCoverage -- every exported Set and Enable function is restored by RestoreConfig or excluded explicitly.
+// The new setter must be added to SnapshotConfig, RestoreConfig and TestConfigSnapshotSettings or to the excluded list.
+func TestConfigSnapshotCoverage(t *testing.T) {
+	covered := []string{
+		"Enable", "Facility.Enable", "Facility.EnableLookBehind", "Facility.SetDailyQuota", "Facility.SetLogLevel",
+		"Facility.SetLogLevelWithReason", "Facility.SetSampling", "Facility.SetVerbosity",
+		"EnableCrashDump", "EnableFlightRecorder", "EnableLevelPersistence",
+		"SetAsyncFileOpen", "SetAutoFacilityFromCaller", "SetClockTolerance", "SetCoarseClock", "SetConsoleDeduplication",
+		"SetConsoleFacilityFilter", "SetConsoleWriter", "SetDegradedReportInterval", "SetDurationPrecision", "SetEnqueueTimeout",
+		"SetExclusiveFile", "SetFailureInjection", "SetFallbackBufferSize", "SetFallbackDirectory", "SetFile", "SetFileChecked",
+		"SetFileEncryption", "SetFileEx", "SetFileFormat", "SetFileTrailer", "SetFlightRecorderDumpInterval", "SetFlushAlignment",
+		"SetFlushOnSevere", "SetFuncNameMode", "SetGroupCommit", "SetHierarchicalLevels", "SetHijackStdLog", "SetHumanUnits", "SetIdleShrink",
+		"SetLastLogSize", "SetLegacyFormatting", "SetLevelPersistenceMaxAge", "SetLineChecksums", "SetLogLevel", "SetLogLevels",
+		"SetLogLevelsEx", "SetLogLevelWithReason", "SetLogTokenTTL", "SetMaintainSymlinks", "SetMaxFacilities", "SetMemoryMode",
+		"SetMinFreeSpace", "SetModuleTagging", "SetOutput", "SetPeriodicSync", "SetRetention", "SetRotationBoundary",
+		"SetRuntimeTraceParsing", "SetSevereNotifier", "SetSevereNotifierCooldown", "SetStderrInterception", "SetStdlogRouting",
+		"SetStormProtection", "SetStrictConsoleOrdering", "SetStrictFacilities", "SetStripANSIForFile", "SetTestWriter",
+		"SetTimingsFile", "SetTimingsOnlyToTimingsFile", "SetTruncationCheck", "SetUsageBuckets", "SetUsageDump",
+	}
+
+	excluded := map[string]string{
+		"AccessLog.SetRedaction": "the setting of the AccessLog value",
+		"Facility.Enabled":       "the getter",
+	}
+
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fset := token.NewFileSet()
+	found := map[string]bool{}
+
+	for _, name := range files {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+
+		file, err := parser.ParseFile(fset, name, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || !fn.Name.IsExported() || !(strings.HasPrefix(fn.Name.Name, "Set") || strings.HasPrefix(fn.Name.Name, "Enable")) {
+				continue
+			}
+
+			id := fn.Name.Name
+			if fn.Recv != nil {
+				tp := fn.Recv.List[0].Type
+				if star, ok := tp.(*ast.StarExpr); ok {
+					tp = star.X
+				}
+				ident, ok := tp.(*ast.Ident)
+				if !ok || !ident.IsExported() {
+					continue
+				}
+				id = ident.Name + "." + id
+			}
+
+			found[id] = true
+			if _, exists := excluded[id]; !exists && !slices.Contains(covered, id) {
+				t.Errorf("%s (%s) isn't covered by the config snapshot", id, fset.Position(fn.Pos()))
+			}
+		}
+	}
+
+	for _, id := range covered {
+		if !found[id] {
+			t.Errorf("%s is covered but doesn't exist", id)
+		}
+	}
+	for id := range excluded {
+		if !found[id] {
+			t.Errorf("%s is excluded but doesn't exist", id)
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
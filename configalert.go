@@ -0,0 +1,174 @@
+package log
+
+import (
+	"strconv"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Config subscribers are told about the changes of the global settings: the function name mode, maxLen, the local time
+// and the file settings. As the level subscribers they are called after the mutex is released, one call per changed
+// setting. The values are rendered as strings. RestoreConfig doesn't call them.
+
+// ConfigChange -- change of the global setting
+type ConfigChange struct {
+	Time    time.Time `json:"time"`
+	Setting string    `json:"setting"`
+	Old     string    `json:"old"`
+	New     string    `json:"new"`
+}
+
+// ConfigAlertFunc -- config change subscriber
+type ConfigAlertFunc func(change ConfigChange)
+
+const (
+	// FuncNameModeKeep -- leave the function name mode unchanged, an empty mode means the same
+	FuncNameModeKeep = FuncNameMode("keep")
+)
+
+const (
+	// ConfigFuncNameMode -- the function name mode
+	ConfigFuncNameMode = "funcNameMode"
+	// ConfigMaxLen -- the maximal line length
+	ConfigMaxLen = "maxLen"
+	// ConfigLocalTime -- the local time instead of UTC
+	ConfigLocalTime = "localTime"
+	// ConfigFilePattern -- the file name pattern, empty if the log isn't written to the daily files
+	ConfigFilePattern = "file.pattern"
+	// ConfigFileBufSize -- the file buffer size
+	ConfigFileBufSize = "file.bufSize"
+	// ConfigFileFlushPeriod -- the file flush period
+	ConfigFileFlushPeriod = "file.flushPeriod"
+	// ConfigFileCompression -- the file compression
+	ConfigFileCompression = "file.compression"
+)
+
+// fileSettings -- the file settings reported to the config subscribers
+type fileSettings struct {
+	localTime   bool
+	pattern     string
+	bufSize     int
+	flushPeriod time.Duration
+	compression Compression
+}
+
+var (
+	configAlertID          = int64(0)
+	configAlertSubscribers = map[int64]ConfigAlertFunc{}
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// AddConfigAlertFunc -- subscribe to the changes of the global settings
+func AddConfigAlertFunc(f ConfigAlertFunc) int64 {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	configAlertID++
+	configAlertSubscribers[configAlertID] = f
+	return configAlertID
+}
+
+// DelConfigAlertFunc --
+func DelConfigAlertFunc(id int64) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	delete(configAlertSubscribers, id)
+}
+
+// SetFuncNameMode -- set the function name mode, FuncNameModeKeep and empty mode leave it unchanged
+func SetFuncNameMode(mode FuncNameMode) {
+	var notify alertNotifications
+	defer notify.call()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	setFuncNameMode(mode, &notify)
+}
+
+// CurrentFuncNameMode -- the function name mode in use
+func CurrentFuncNameMode() FuncNameMode {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	return currentFuncNameMode()
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// addConfig -- snapshot config subscribers of the change. Must be called under the mutex.
+func (n *alertNotifications) addConfig(setting string, old string, new string) {
+	if old == new {
+		return
+	}
+
+	change := ConfigChange{
+		Time:    now(),
+		Setting: setting,
+		Old:     old,
+		New:     new,
+	}
+
+	for _, alert := range configAlertSubscribers {
+		*n = append(*n, func() { alert(change) })
+	}
+}
+
+// setFuncNameMode -- must be called under the mutex
+func setFuncNameMode(mode FuncNameMode, notify *alertNotifications) {
+	old := currentFuncNameMode()
+
+	switch mode {
+	case "", FuncNameModeKeep:
+		return
+	case FuncNameModeShort:
+		logFuncName.Store(logFuncNameShort)
+	case FuncNameModeFull:
+		logFuncName.Store(logFuncNameFull)
+	case FuncNameModeNone:
+		fallthrough
+	default:
+		logFuncName.Store(logFuncNameNone)
+	}
+
+	notify.addConfig(ConfigFuncNameMode, string(old), string(currentFuncNameMode()))
+}
+
+// currentFuncNameMode -- the function name mode in use. Must be called under the mutex.
+func currentFuncNameMode() FuncNameMode {
+	switch logFuncName.Load() {
+	case logFuncNameShort:
+		return FuncNameModeShort
+	case logFuncNameFull:
+		return FuncNameModeFull
+	default:
+		return FuncNameModeNone
+	}
+}
+
+// Must be called under the mutex
+func currentFileSettings() fileSettings {
+	return fileSettings{
+		localTime:   localTime,
+		pattern:     fileNamePattern,
+		bufSize:     fileWriterBufSize,
+		flushPeriod: fileWriterFlushPeriod,
+		compression: compression,
+	}
+}
+
+// addFileChanges -- compare the current file settings with the old ones. Must be called under the mutex.
+func (n *alertNotifications) addFileChanges(old fileSettings) {
+	cur := currentFileSettings()
+
+	n.addConfig(ConfigLocalTime, strconv.FormatBool(old.localTime), strconv.FormatBool(cur.localTime))
+	n.addConfig(ConfigFilePattern, old.pattern, cur.pattern)
+	n.addConfig(ConfigFileBufSize, strconv.Itoa(old.bufSize), strconv.Itoa(cur.bufSize))
+	n.addConfig(ConfigFileFlushPeriod, old.flushPeriod.String(), cur.flushPeriod.String())
+	n.addConfig(ConfigFileCompression, compressionName(old.compression), compressionName(cur.compression))
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,83 @@
+package log
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestFuncNameModeDecoupled(t *testing.T) {
+	resetLog(t)
+
+	SetFuncNameMode(FuncNameModeFull)
+	if mode := CurrentFuncNameMode(); mode != FuncNameModeFull {
+		t.Fatalf("got %s", mode)
+	}
+
+	f := GetFacility("decoupled")
+	f.SetLogLevel("TRACE1", FuncNameModeKeep)
+	f.SetLogLevel("INFO", "")
+	f.SetVerbosity(3)
+	if mode := CurrentFuncNameMode(); mode != FuncNameModeFull {
+		t.Errorf("the level change set the mode %s", mode)
+	}
+
+	f.SetLogLevel("DEBUG", FuncNameModeShort)
+	if mode := CurrentFuncNameMode(); mode != FuncNameModeShort {
+		t.Errorf("got %s", mode)
+	}
+}
+
+func TestConfigAlerts(t *testing.T) {
+	resetLog(t)
+	setFakeClock(time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC))
+
+	var (
+		m       sync.Mutex
+		changes []string
+	)
+	id := AddConfigAlertFunc(func(c ConfigChange) {
+		// Must not deadlock
+		CurrentFuncNameMode()
+
+		m.Lock()
+		defer m.Unlock()
+		changes = append(changes, c.Setting+" "+c.Old+" -> "+c.New)
+	})
+
+	SetFuncNameMode(FuncNameModeShort)
+	SetFuncNameMode(FuncNameModeShort)
+	GetFacility("alerts").SetLogLevel("TRACE2", FuncNameModeFull)
+	GetFacility("alerts").SetLogLevel("INFO", FuncNameModeKeep)
+	MaxLen(100)
+	MaxLen(100)
+
+	dir := t.TempDir()
+	SetFile(dir, "", true, 4096, time.Minute)
+	SetFile(dir, "", true, 4096, time.Minute)
+	pattern := FileNamePattern()
+
+	DelConfigAlertFunc(id)
+	MaxLen(0)
+
+	expected := []string{
+		"funcNameMode none -> short",
+		"funcNameMode short -> full",
+		"maxLen 0 -> 100",
+		"localTime false -> true",
+		"file.pattern  -> " + pattern,
+		"file.bufSize 0 -> 4096",
+		"file.flushPeriod 0s -> 1m0s",
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	if !slices.Equal(changes, expected) {
+		t.Errorf("got\n%q\nexpected\n%q", changes, expected)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
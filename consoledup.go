@@ -0,0 +1,96 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+var (
+	consoleDedupWindow time.Duration
+
+	dupKey   string
+	dupLevel Level
+	dupFirst time.Time
+	dupLast  time.Time
+	dupCount int
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetConsoleDeduplication -- collapse identical consecutive console lines within the window into the first one
+// and the "(repeated N× in T)" line. Zero disables it. The file and other destinations get every line.
+func SetConsoleDeduplication(window time.Duration) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	flushConsoleDup()
+
+	if window < 0 {
+		window = 0
+	}
+	consoleDedupWindow = window
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// consoleOutput -- write the line to the console with the deduplication, lines with the same event ID are identical.
+// Must be called under the mutex.
+func consoleOutput(r *Record, text string) {
+	if consoleDedupWindow <= 0 {
+		writeToConsole(text)
+		return
+	}
+
+	key := dupLineKey(text)
+	if r.EventID != "" {
+		key = fmt.Sprintf("%d <%s> %s", r.Level, r.Facility, eventToken(r.EventID))
+	}
+	t := lastStamp
+
+	if key == dupKey && t.Sub(dupFirst) < consoleDedupWindow {
+		dupCount++
+		dupLast = t
+		return
+	}
+
+	flushConsoleDup()
+
+	dupKey = key
+	dupLevel = r.Level
+	dupFirst = t
+	writeToConsole(text)
+}
+
+// consoleDedupTick -- called by the flusher to report repeats when the window lapsed without new lines
+func consoleDedupTick() {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if dupKey != "" && now().Sub(dupFirst) >= consoleDedupWindow {
+		flushConsoleDup()
+	}
+}
+
+// Must be called under the mutex
+func flushConsoleDup() {
+	if dupCount > 0 {
+		writeToConsole(formatDirectLine(dupLevel, fmt.Sprintf("(repeated %d× in %s)", dupCount, dupLast.Sub(dupFirst).Round(time.Millisecond))))
+	}
+
+	dupKey = ""
+	dupCount = 0
+}
+
+// dupLineKey -- the line without pid, date and time: "[pid] LL date time text"
+func dupLineKey(text string) string {
+	parts := strings.SplitN(text, " ", 5)
+	if len(parts) < 5 {
+		return text
+	}
+	return parts[1] + " " + parts[4]
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
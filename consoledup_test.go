@@ -0,0 +1,78 @@
+package log
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestConsoleDeduplication(t *testing.T) {
+	w := resetLog(t)
+	clock := setFakeClock(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+
+	SetConsoleDeduplication(5 * time.Second)
+
+	for i := 0; i < 50; i++ {
+		Message(WARNING, "retry failed")
+		clock.Add(10 * time.Millisecond)
+	}
+
+	if lines := w.Lines(); len(lines) != 1 {
+		t.Fatalf("got %d console lines, expected 1:\n%s", len(lines), w.String())
+	}
+
+	clock.Add(5 * time.Second)
+	consoleDedupTick()
+
+	lines := w.Lines()
+	if len(lines) != 2 {
+		t.Fatalf("got %d console lines, expected 2:\n%s", len(lines), w.String())
+	}
+	if !strings.HasSuffix(lines[0], " retry failed") || !strings.HasSuffix(lines[1], "WA 2024-05-01 12:00:00.490 (repeated 49× in 490ms)") {
+		t.Errorf("unexpected console lines:\n%s", w.String())
+	}
+
+	n := 0
+	for _, s := range beforeFileBuf {
+		if strings.HasSuffix(strings.TrimSpace(s), " retry failed") {
+			n++
+		}
+	}
+	if n != 50 {
+		t.Errorf("got %d lines in the file buffer, expected 50", n)
+	}
+}
+
+func TestConsoleDeduplicationOtherMessage(t *testing.T) {
+	w := resetLog(t)
+	setFakeClock(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+
+	SetConsoleDeduplication(5 * time.Second)
+
+	Message(WARNING, "retry failed")
+	Message(WARNING, "retry failed")
+	Message(ERR, "retry failed")
+	Message(INFO, "done")
+	Message(INFO, "done")
+
+	expected := []string{
+		" retry failed",
+		" (repeated 1× in 0s)",
+		"ER 2024-05-01 12:00:00.000 retry failed",
+		" done",
+	}
+
+	lines := w.Lines()
+	if len(lines) != len(expected) {
+		t.Fatalf("got %d console lines, expected %d:\n%s", len(lines), len(expected), w.String())
+	}
+	for i, e := range expected {
+		if !strings.HasSuffix(lines[i], e) {
+			t.Errorf("[%d] got %q, expected suffix %q", i, lines[i], e)
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,76 @@
+package log
+
+import (
+	"strings"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// StdFacilityAlias -- name of the std facility in the facility filters
+const StdFacilityAlias = "<std>"
+
+var (
+	consoleFilter []string
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetConsoleFacilityFilter -- mirror to the console only the listed facilities. Empty list resets the filter.
+// A name with the trailing "*" matches all facilities with that prefix, StdFacilityAlias matches the std facility.
+// File output, last log and subscribers are not affected.
+func SetConsoleFacilityFilter(names ...string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if len(names) == 0 {
+		consoleFilter = nil
+		return
+	}
+
+	consoleFilter = make([]string, len(names))
+	copy(consoleFilter, names)
+}
+
+// ConsoleFacilityFilter -- current console facility filter, nil if not set
+func ConsoleFacilityFilter() []string {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if consoleFilter == nil {
+		return nil
+	}
+
+	list := make([]string, len(consoleFilter))
+	copy(list, consoleFilter)
+	return list
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Must be called under the mutex
+func consoleAllowed(facility string) bool {
+	if consoleFilter == nil {
+		return true
+	}
+
+	return matchFacility(consoleFilter, facility)
+}
+
+func matchFacility(patterns []string, facility string) bool {
+	if facility == StdFacilityName {
+		facility = StdFacilityAlias
+	}
+
+	for _, p := range patterns {
+		if p == facility {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(p, "*"); ok && strings.HasPrefix(facility, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,50 @@
+package log
+
+import (
+	"strings"
+	"testing"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestConsoleFacilityFilter(t *testing.T) {
+	console := resetLog(t)
+
+	SetConsoleFacilityFilter("replication")
+
+	GetFacility("replication").Message(INFO, "from replication")
+	GetFacility("http").Message(INFO, "from http")
+	Message(INFO, "from std")
+
+	lines := console.Lines()
+	if len(lines) != 1 || !strings.HasSuffix(lines[0], "<replication> from replication") {
+		t.Errorf("unexpected console lines %q", lines)
+	}
+
+	if len(beforeFileBuf) != 3 {
+		t.Errorf("got %d lines in the file buffer, expected 3", len(beforeFileBuf))
+	}
+	if len(GetLastLog()) != 3 {
+		t.Errorf("got %d lines in the last log, expected 3", len(GetLastLog()))
+	}
+
+	SetConsoleFacilityFilter("rep*", StdFacilityAlias)
+	if f := ConsoleFacilityFilter(); len(f) != 2 {
+		t.Errorf("unexpected filter %q", f)
+	}
+
+	GetFacility("replication").Message(INFO, "from replication")
+	GetFacility("http").Message(INFO, "from http")
+	Message(INFO, "from std")
+
+	if n := len(console.Lines()); n != 3 {
+		t.Errorf("got %d console lines, expected 3", n)
+	}
+
+	SetConsoleFacilityFilter()
+	if f := ConsoleFacilityFilter(); f != nil {
+		t.Errorf("filter is not reset: %q", f)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,152 @@
+package log
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// The console is written outside of the mutex: lines are queued under it and written after the release by the goroutine
+// that gets consoleMutex first, the others don't wait for the console and go on. If the console is slow and the turn
+// lasts longer than consoleTurn, the rest of the queue is handed over to the background writer. So a slow terminal
+// doesn't hold the file writes of the whole process. The console gets lines in the same order as the file, but it can
+// be behind it: the file may already have later lines of other goroutines and the line is not necessarily on
+// the console when the call that logged it returns. Lines above consoleQueueLimit are dropped and counted, the queue
+// is written out by Shutdown. SetStrictConsoleOrdering(true) writes the console under the mutex as before.
+
+// outputMutex -- the mutex writing the queued console lines after the release
+type outputMutex struct {
+	sync.Mutex
+}
+
+type consoleLine struct {
+	w    io.Writer
+	text string
+}
+
+const (
+	consoleQueueLimit = 10000
+	consoleTurn       = time.Millisecond
+)
+
+var (
+	strictConsole atomic.Bool
+
+	consoleMutex      sync.Mutex // the console writing
+	consoleQueueMutex sync.Mutex // consoleQueue only, never held during the writing
+	consoleQueue      []consoleLine
+	consolePending    atomic.Bool
+
+	consoleBgOnce   sync.Once
+	consoleBgSignal = make(chan struct{}, 1)
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetStrictConsoleOrdering -- write the console under the mutex, so it is never behind the file (off by default)
+func SetStrictConsoleOrdering(strict bool) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	strictConsole.Store(strict)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Unlock -- release the mutex and write the queued console lines
+func (m *outputMutex) Unlock() {
+	m.Mutex.Unlock()
+
+	if consolePending.Load() {
+		flushConsoleQueue()
+	}
+}
+
+// writeToConsole -- write or queue the line. Must be called under the mutex.
+func writeToConsole(msg string) {
+	w := consoleWriter
+	if w == nil || consoleDropped() {
+		return
+	}
+
+	if strictConsole.Load() {
+		w.Write([]byte(msg))
+		return
+	}
+
+	consoleQueueMutex.Lock()
+	defer consoleQueueMutex.Unlock()
+
+	if len(consoleQueue) >= consoleQueueLimit {
+		statDrop()
+		return
+	}
+
+	consoleQueue = append(consoleQueue, consoleLine{w: w, text: msg})
+	consolePending.Store(true)
+}
+
+// flushConsoleQueue -- write the queued lines unless another goroutine is writing them
+func flushConsoleQueue() {
+	// The line queued after the writer found the queue empty but before it released consoleMutex is taken on the next turn
+	for consolePending.Load() && consoleMutex.TryLock() {
+		done := writeConsoleQueue(time.Now().Add(consoleTurn))
+		consoleMutex.Unlock()
+
+		if !done {
+			consoleBgOnce.Do(func() { go consoleBackground() })
+			select {
+			case consoleBgSignal <- struct{}{}:
+			default:
+			}
+			return
+		}
+	}
+}
+
+// waitConsole -- write out the queue waiting for the current writer
+func waitConsole() {
+	consoleMutex.Lock()
+	defer consoleMutex.Unlock()
+
+	writeConsoleQueue(time.Time{})
+}
+
+// consoleBackground -- the writer of the slow console
+func consoleBackground() {
+	for range consoleBgSignal {
+		// The line queued while the queue was written out is not signaled
+		for consolePending.Load() {
+			waitConsole()
+		}
+	}
+}
+
+// writeConsoleQueue -- write the queue until it is empty or the deadline (if not zero) passes, false if the queue
+// isn't empty. Must be called under consoleMutex.
+func writeConsoleQueue(deadline time.Time) bool {
+	for {
+		consoleQueueMutex.Lock()
+		list := consoleQueue
+		consoleQueue = nil
+		consolePending.Store(false)
+		consoleQueueMutex.Unlock()
+
+		if len(list) == 0 {
+			return true
+		}
+
+		for _, line := range list {
+			line.w.Write([]byte(line.text))
+		}
+
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return !consolePending.Load()
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
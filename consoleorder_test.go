@@ -0,0 +1,172 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// slowConsole -- the console writer spending the delay on every line
+type slowConsole struct {
+	captureWriter
+	delay time.Duration
+}
+
+func (w *slowConsole) Write(p []byte) (int, error) {
+	for start := time.Now(); time.Since(start) < w.delay; {
+	}
+	return w.captureWriter.Write(p)
+}
+
+// blockedConsole -- the console writer waiting for the release
+type blockedConsole struct {
+	captureWriter
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (w *blockedConsole) Write(p []byte) (int, error) {
+	select {
+	case w.entered <- struct{}{}:
+	default:
+	}
+	<-w.release
+	return w.captureWriter.Write(p)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestConsoleOutsideMutex(t *testing.T) {
+	resetLog(t)
+
+	console := &blockedConsole{entered: make(chan struct{}, 1), release: make(chan struct{})}
+	SetConsoleWriter(console)
+
+	sink := &captureWriter{}
+	AddDestination("sink", FormatText, sink, UNKNOWN)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		Message(INFO, "first")
+	}()
+	<-console.entered
+
+	// The console is blocked by the first message, but the other ones are written
+	for i := 0; i < 10; i++ {
+		Message(INFO, "next %d", i)
+	}
+	if n := strings.Count(sink.String(), " next "); n != 10 {
+		t.Errorf("got %d lines in the sink, expected 10", n)
+	}
+
+	close(console.release)
+	<-done
+
+	lines := console.Lines()
+	if len(lines) != 11 || !strings.HasSuffix(lines[0], " first") || !strings.HasSuffix(lines[10], " next 9") {
+		t.Errorf("unexpected console lines:\n%s", console.String())
+	}
+}
+
+func TestStrictConsoleOrdering(t *testing.T) {
+	resetLog(t)
+
+	console := &blockedConsole{entered: make(chan struct{}, 1), release: make(chan struct{})}
+	SetConsoleWriter(console)
+	SetStrictConsoleOrdering(true)
+
+	go Message(INFO, "first")
+	<-console.entered
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		Message(INFO, "second")
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("the message is written while the console is blocked")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(console.release)
+	<-done
+
+	if lines := console.Lines(); len(lines) != 2 || !strings.HasSuffix(lines[1], " second") {
+		t.Errorf("unexpected console lines:\n%s", console.String())
+	}
+}
+
+func TestConsoleOrderRace(t *testing.T) {
+	resetLog(t)
+
+	const (
+		writers = 8
+		count   = 200
+	)
+
+	console := &slowConsole{delay: 10 * time.Microsecond}
+	SetConsoleWriter(console)
+
+	sink := &captureWriter{}
+	AddDestination("sink", FormatText, sink, UNKNOWN)
+
+	wg := new(sync.WaitGroup)
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < count; i++ {
+				Message(INFO, "w%d %d", w, i)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	// The background writer may still be writing the queue
+	waitConsole()
+
+	// The console has the lines of the file in the same order
+	if c, s := console.String(), sink.String(); c != s {
+		t.Fatalf("the console differs from the file: %d and %d lines", strings.Count(c, "\n"), strings.Count(s, "\n"))
+	}
+	if n := len(console.Lines()); n != writers*count {
+		t.Errorf("got %d lines, expected %d", n, writers*count)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// BenchmarkSlowConsole -- the file throughput with the console spending 50µs on every line. The lines above the queue
+// limit are dropped if the console isn't strict.
+func BenchmarkSlowConsole(b *testing.B) {
+	for _, strict := range []bool{false, true} {
+		b.Run(fmt.Sprintf("strict=%t", strict), func(b *testing.B) {
+			groupCommitFile(b, GroupCommitOff)
+			SetConsoleWriter(&slowConsole{delay: 50 * time.Microsecond})
+			SetStrictConsoleOrdering(strict)
+			defer SetStrictConsoleOrdering(false)
+			defer SetConsoleWriter(nil)
+
+			b.SetParallelism(8)
+			b.ResetTimer()
+
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					Message(INFO, "benchmark %d", 12345)
+				}
+			})
+
+			b.StopTimer()
+			waitConsole()
+		})
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
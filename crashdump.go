@@ -0,0 +1,221 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/alrusov/misc"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// On SIGTERM, SIGQUIT and SIGABRT one JSON line with the last lines, the bytes pending in the file buffer, the status
+// and the facility levels is appended to the pre-opened file. SIGTERM is left to the application stop, SIGQUIT and
+// SIGABRT are raised again with the default handling. The snapshot waits for the mutex a short time only and is taken
+// without it if the mutex is stuck. Lines aren't dumped if the file encryption is used. The signal list is platform
+// specific, it's empty where these signals don't exist (js/wasm) and nothing is dumped there. Off by default.
+
+type crashSnapshot struct {
+	Time      time.Time         `json:"time"`
+	Signal    string            `json:"signal"`
+	Locked    bool              `json:"locked"`
+	Encrypted bool              `json:"encrypted,omitempty"`
+	LastLines []string          `json:"lastLines"`
+	Pending   string            `json:"pending"`
+	Status    StatusInfo        `json:"status"`
+	Levels    map[string]string `json:"levels"`
+}
+
+const crashDumpLockWait = 100 * time.Millisecond
+
+var (
+	crashDumpMutex sync.Mutex
+	crashDumpFile  *os.File
+	crashDumpHead  []byte // pre-serialized `{"pid":...,"app":"...",`
+	crashDumpStop  chan struct{}
+	crashDumpPath  string // "" if off
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// EnableCrashDump -- append the state snapshot to the file on the fatal signals, empty path disables it
+func EnableCrashDump(path string) error {
+	crashDumpMutex.Lock()
+	defer crashDumpMutex.Unlock()
+
+	if crashDumpStop != nil {
+		close(crashDumpStop)
+		crashDumpStop = nil
+	}
+	if crashDumpFile != nil {
+		crashDumpFile.Close()
+		crashDumpFile = nil
+	}
+
+	crashDumpPath = ""
+
+	if path == "" {
+		return nil
+	}
+
+	fd, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	crashDumpPath = path
+
+	app, _ := json.Marshal(misc.AppName())
+	crashDumpHead = []byte(fmt.Sprintf(`{"pid":%d,"app":%s,`, pid, app))
+	crashDumpFile = fd
+
+	if len(crashSignals) == 0 {
+		// signal.Notify without signals relays all of them
+		return nil
+	}
+
+	ch := make(chan os.Signal, 1)
+	stop := make(chan struct{})
+	crashDumpStop = stop
+	signal.Notify(ch, crashSignals...)
+
+	go crashDumpHandler(ch, stop)
+
+	return nil
+}
+
+func crashDumpHandler(ch chan os.Signal, stop chan struct{}) {
+	defer signal.Stop(ch)
+
+	for {
+		select {
+		case <-stop:
+			return
+		case sig := <-ch:
+			writeCrashDump(sig)
+
+			if sig == crashStopSignal {
+				continue
+			}
+
+			signal.Reset(sig)
+			p, err := os.FindProcess(os.Getpid())
+			if err == nil {
+				err = p.Signal(sig)
+			}
+			if err != nil {
+				os.Exit(2)
+			}
+			return
+		}
+	}
+}
+
+// currentCrashDump -- the path of the crash dump file, "" if off
+func currentCrashDump() string {
+	crashDumpMutex.Lock()
+	defer crashDumpMutex.Unlock()
+
+	return crashDumpPath
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// writeCrashDump -- append the snapshot line to the crash dump file
+func writeCrashDump(sig os.Signal) error {
+	crashDumpMutex.Lock()
+	defer crashDumpMutex.Unlock()
+
+	if crashDumpFile == nil {
+		return nil
+	}
+
+	snap := crashSnapshot{
+		Signal: sig.String(),
+		Locked: tryLockFor(&mutex.Mutex, crashDumpLockWait),
+	}
+
+	snap.Time = now()
+	snap.Status = currentStatus()
+
+	snap.Levels = make(map[string]string, len(facilities))
+	for name, f := range facilities {
+		_, snap.Levels[name] = GetLogLevelName(f.token.Load())
+	}
+
+	snap.Encrypted = encryptionKey != nil
+	if !snap.Encrypted {
+		snap.LastLines = make([]string, len(lastBuf))
+		for i, s := range lastBuf {
+			snap.LastLines[i] = strings.TrimSuffix(s, misc.EOS)
+		}
+
+		if tryLockFor(fileWriterMutex, crashDumpLockWait) {
+			snap.Pending = string(pendingBytes())
+			fileWriterMutex.Unlock()
+		}
+	}
+
+	if snap.Locked {
+		mutex.Unlock()
+	}
+
+	body, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+
+	buf := bytes.NewBuffer(make([]byte, 0, len(crashDumpHead)+len(body)+1))
+	buf.Write(crashDumpHead)
+	buf.Write(body[1:])
+	buf.WriteByte('\n')
+
+	if _, err = crashDumpFile.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	return crashDumpFile.Sync()
+}
+
+// tryLockFor -- lock the mutex if it's possible during the wait
+func tryLockFor(m *sync.Mutex, wait time.Duration) bool {
+	deadline := time.Now().Add(wait)
+	for {
+		if m.TryLock() {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// pendingBytes -- bytes in the file buffer not written yet, peeked through the available part of the buffer.
+// Must be called under fileWriterMutex.
+func pendingBytes() []byte {
+	if fileWriter == nil {
+		return nil
+	}
+
+	n := fileWriter.Buffered()
+	if n == 0 {
+		return nil
+	}
+
+	// The available part starts right after the buffered bytes, it has no address if the buffer is full
+	free := fileWriter.AvailableBuffer()
+	if cap(free) == 0 {
+		return nil
+	}
+
+	p := unsafe.Add(unsafe.Pointer(unsafe.SliceData(free)), -n)
+	return bytes.Clone(unsafe.Slice((*byte)(p), n))
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
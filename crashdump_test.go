@@ -0,0 +1,94 @@
+package log
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestCrashDump(t *testing.T) {
+	resetLog(t)
+	setFakeClock(time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC))
+
+	SetFile(t.TempDir(), "", false, 64*1024, 0)
+	GetFacility("db").SetLogLevel("DEBUG", FuncNameModeNone)
+
+	path := filepath.Join(t.TempDir(), "crash.jsonl")
+	if err := EnableCrashDump(path); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { EnableCrashDump("") })
+
+	for i := 0; i < 3; i++ {
+		Message(INFO, "recent %d", i)
+	}
+
+	name := FileName()
+	if err := writeCrashDump(syscall.SIGQUIT); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SetFileEncryption([]byte("0123456789abcdef"), EncryptionAESGCM); err != nil {
+		t.Fatal(err)
+	}
+	Message(INFO, "hidden")
+	if err := writeCrashDump(syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+
+	fd, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+
+	var snaps []map[string]any
+	sc := bufio.NewScanner(fd)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		var v map[string]any
+		if err := json.Unmarshal(sc.Bytes(), &v); err != nil {
+			t.Fatalf("%s: %s", err, sc.Bytes())
+		}
+		snaps = append(snaps, v)
+	}
+	if len(snaps) != 2 {
+		t.Fatalf("got %d snapshots", len(snaps))
+	}
+
+	s := snaps[0]
+	if s["signal"] != syscall.SIGQUIT.String() || s["pid"] != float64(pid) || s["locked"] != true {
+		t.Errorf("unexpected snapshot %v", s)
+	}
+
+	var lines []string
+	for _, l := range s["lastLines"].([]any) {
+		lines = append(lines, l.(string))
+	}
+	if len(lines) < 3 || !strings.HasSuffix(lines[len(lines)-1], " recent 2") {
+		t.Errorf("unexpected last lines %q", lines)
+	}
+	if p, _ := s["pending"].(string); !strings.Contains(p, " recent 0\n") || !strings.HasSuffix(p, " recent 2\n") {
+		t.Errorf("unexpected pending bytes %q", p)
+	}
+	if st, _ := s["status"].(map[string]any); st["fileName"] != name {
+		t.Errorf("unexpected status %v", st)
+	}
+	if levels, _ := s["levels"].(map[string]any); levels["db"] != "DEBUG" {
+		t.Errorf("unexpected levels %v", levels)
+	}
+
+	s = snaps[1]
+	if s["encrypted"] != true || s["pending"] != "" || s["lastLines"] != nil {
+		t.Errorf("lines of the encrypted log are dumped %v", s)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,20 @@
+//go:build go1.23
+
+package log
+
+import (
+	"os"
+	"runtime/debug"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+const crashOutputSupported = true
+
+// setCrashOutput -- the runtime writes fatal tracebacks to the file in addition to fd 2, nil stops it.
+// The runtime keeps its own copy of the descriptor, the file can be closed.
+func setCrashOutput(f *os.File) {
+	debug.SetCrashOutput(f, debug.CrashOptions{})
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
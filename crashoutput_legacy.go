@@ -0,0 +1,17 @@
+//go:build !go1.23
+
+package log
+
+import (
+	"os"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+const crashOutputSupported = false
+
+// setCrashOutput -- there is no additional crash output before go1.23, the intercepted tracebacks are lost
+func setCrashOutput(f *os.File) {
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,17 @@
+//go:build unix || windows
+
+package log
+
+import (
+	"os"
+	"syscall"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+var (
+	crashSignals              = []os.Signal{syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGABRT}
+	crashStopSignal os.Signal = syscall.SIGTERM // left to the application stop
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
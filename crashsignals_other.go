@@ -0,0 +1,18 @@
+//go:build !(unix || windows)
+
+package log
+
+import (
+	"os"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// The fatal signals don't exist here, the crash dump file is opened but never written
+
+var (
+	crashSignals    []os.Signal
+	crashStopSignal os.Signal
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
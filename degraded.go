@@ -0,0 +1,176 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Errors of the file, the targets and the added destinations are collected and reported together by the flusher at most
+// once per interval: "output degraded: file=... syslog=... (last 30s: 1432 lines affected)". The destination which
+// writes successfully again is reported once with NOTICE. Nothing is allocated while everything is healthy.
+
+type destError struct {
+	err       error
+	lines     int64 // failed lines since the last report
+	recovered bool
+}
+
+var (
+	degradedInterval = 30 * time.Second
+
+	destErrors     map[string]*destError // nil while everything is healthy
+	degradedReport time.Time
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetDegradedReportInterval -- minimal interval between the reports of the failed destinations
+func SetDegradedReportInterval(interval time.Duration) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	degradedInterval = interval
+}
+
+// destinationFailed -- the lines aren't written to the destination, nil err keeps the previous error. Must be called under the mutex.
+func destinationFailed(name string, lines int, err error) {
+	if destErrors == nil {
+		destErrors = map[string]*destError{}
+	}
+
+	e, exists := destErrors[name]
+	if !exists {
+		e = &destError{}
+		destErrors[name] = e
+	}
+	e.lines += int64(lines)
+	if err != nil || e.err == nil {
+		if err == nil {
+			err = errors.New("failed")
+		}
+		e.err = err
+	}
+	e.recovered = false
+}
+
+// destinationWritten -- the line is written to the destination. Must be called under the mutex.
+func destinationWritten(name string) {
+	if destErrors == nil {
+		return
+	}
+
+	if e, exists := destErrors[name]; exists {
+		e.recovered = true
+	}
+}
+
+// destinationErrors -- the copy of the current errors. Must be called under the mutex.
+func destinationErrors() map[string]error {
+	if destErrors == nil {
+		return nil
+	}
+
+	m := make(map[string]error, len(destErrors))
+	for name, e := range destErrors {
+		if !e.recovered {
+			m[name] = e.err
+		}
+	}
+	if len(m) == 0 {
+		return nil
+	}
+	return m
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// degradedTick -- report the failed and the recovered destinations
+func degradedTick() {
+	mutex.Lock()
+
+	if destErrors == nil {
+		mutex.Unlock()
+		return
+	}
+
+	var recovered []string
+	var failed []string
+
+	for name, e := range destErrors {
+		if e.recovered {
+			recovered = append(recovered, name)
+			delete(destErrors, name)
+		} else {
+			failed = append(failed, name)
+		}
+	}
+
+	report := ""
+	t := now()
+
+	if len(failed) > 0 && (degradedReport.IsZero() || t.Sub(degradedReport) >= degradedInterval) {
+		sort.Strings(failed)
+
+		// Lines usually fail in all the destinations together
+		lines := int64(0)
+		list := make([]string, len(failed))
+		for i, name := range failed {
+			e := destErrors[name]
+			list[i] = name + "=" + shortError(e.err)
+			lines = max(lines, e.lines)
+		}
+		for _, e := range destErrors {
+			e.lines = 0
+		}
+
+		period := degradedInterval
+		if !degradedReport.IsZero() {
+			period = t.Sub(degradedReport)
+		}
+
+		report = fmt.Sprintf("output degraded: %s (last %s: %d lines affected)", strings.Join(list, " "), period.Round(time.Second), lines)
+		degradedReport = t
+	}
+
+	if len(destErrors) == 0 {
+		destErrors = nil
+		degradedReport = time.Time{}
+	}
+
+	mutex.Unlock()
+
+	sort.Strings(recovered)
+	for _, name := range recovered {
+		Message(NOTICE, "output restored: %s", name)
+	}
+
+	if report != "" {
+		Message(WARNING, "%s", report)
+	}
+}
+
+// shortError -- the error without the path or the syscall name
+func shortError(err error) string {
+	var pe *os.PathError
+	if errors.As(err, &pe) {
+		return pe.Err.Error()
+	}
+
+	var se *os.SyscallError
+	if errors.As(err, &se) {
+		return se.Err.Error()
+	}
+
+	return err.Error()
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
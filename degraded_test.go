@@ -0,0 +1,118 @@
+package log
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+type failingSink struct {
+	err atomic.Pointer[error]
+}
+
+func (s *failingSink) fail(err error) {
+	if err == nil {
+		s.err.Store(nil)
+		return
+	}
+	s.err.Store(&err)
+}
+
+func (s *failingSink) Write(p []byte) (int, error) {
+	if err := s.err.Load(); err != nil {
+		return 0, *err
+	}
+	return len(p), nil
+}
+
+func (s *failingSink) Close() error {
+	return nil
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestDegradedReport(t *testing.T) {
+	console := resetLog(t)
+	clock := setFakeClock(time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC))
+
+	target := &failingSink{}
+	sink := &failingSink{}
+	AddTarget("tcp-target", target)
+	AddDestination("syslog", FormatText, sink, UNKNOWN)
+
+	Message(INFO, "healthy")
+	degradedTick()
+	if destErrors != nil || Status().DestinationErrors != nil {
+		t.Fatalf("unexpected errors %v", destErrors)
+	}
+
+	target.fail(errors.New("timeout"))
+	sink.fail(syscall.ECONNREFUSED)
+
+	for i := 0; i < 5; i++ {
+		Message(INFO, "line %d", i)
+	}
+
+	st := Status().DestinationErrors
+	if len(st) != 2 || st["tcp-target"].Error() != "timeout" || !errors.Is(st["syslog"], syscall.ECONNREFUSED) {
+		t.Errorf("unexpected status %v", st)
+	}
+
+	degradedTick()
+	clock.Add(10 * time.Second)
+	Message(INFO, "more")
+	degradedTick()
+
+	reports := func() []string {
+		var list []string
+		for _, line := range console.Lines() {
+			if strings.Contains(line, "output degraded:") {
+				list = append(list, line)
+			}
+		}
+		return list
+	}
+
+	list := reports()
+	if len(list) != 1 || !strings.Contains(list[0], " WA ") ||
+		!strings.HasSuffix(list[0], "output degraded: syslog=connection refused tcp-target=timeout (last 30s: 5 lines affected)") {
+		t.Fatalf("unexpected reports %q", list)
+	}
+
+	clock.Add(30 * time.Second)
+	degradedTick()
+
+	if list = reports(); len(list) != 2 || !strings.HasSuffix(list[1], "(last 40s: 2 lines affected)") {
+		t.Errorf("unexpected reports %q", list)
+	}
+
+	target.fail(nil)
+	Message(INFO, "half")
+	degradedTick()
+	degradedTick()
+
+	if n := strings.Count(console.String(), "output restored: tcp-target\n"); n != 1 {
+		t.Errorf("recovery is reported %d times:\n%s", n, console)
+	}
+	if st := Status().DestinationErrors; len(st) != 1 || st["syslog"] == nil {
+		t.Errorf("unexpected status %v", st)
+	}
+
+	sink.fail(nil)
+	Message(INFO, "all")
+	degradedTick()
+
+	if destErrors != nil || Status().DestinationErrors != nil {
+		t.Errorf("errors are left %v", destErrors)
+	}
+	if n := len(reports()); n != 2 {
+		t.Errorf("got %d reports", n)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,233 @@
+package log
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Every record goes to the destinations: the file, the console and the added ones in the order of adding.
+// The record is rendered lazily at most once per format: destinations of the same format share the rendering,
+// the destination whose level doesn't pass doesn't render it, the raw records pass every level. Last lines, subscribers and targets get the classic line.
+
+// Format -- rendering of the record, formats with the same ID render the same
+type Format interface {
+	ID() string
+	Render(r Record) string
+}
+
+// Sink -- receives the rendered records. Write is called under the package mutex so it must not block and must not log itself.
+type Sink interface {
+	Write(p []byte) (int, error)
+}
+
+type renderCache []renderedText
+
+type renderedText struct {
+	id   string
+	text string
+}
+
+type destination struct {
+	name     string
+	format   Format
+	sink     Sink
+	minLevel Level
+	builtin  func(r *Record, text string)
+	dflt     Format // the format of the pre-registered destination
+}
+
+type textFormat struct{}
+
+type consoleFormat struct{}
+
+const (
+	// DestinationFile -- the pre-registered destination of the log file
+	DestinationFile = "file"
+	// DestinationConsole -- the pre-registered destination of the console
+	DestinationConsole = "console"
+)
+
+var (
+	// FormatText -- the classic line "[pid] LV date time <facility> text"
+	FormatText Format = textFormat{}
+	// FormatJSON -- JSON object with the schema version, time, level, facility, function and text per line
+	FormatJSON Format = mustJSONFormat(JSONOptions{})
+	// FormatConsole -- the classic line, events use the console rendering (default for the console)
+	FormatConsole Format = consoleFormat{}
+
+	fileDestination *destination
+	destinations    []*destination // the console and the added ones
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func init() {
+	resetDestinations()
+}
+
+// resetDestinations -- only the pre-registered destinations with the default formats
+func resetDestinations() {
+	fileDestination = &destination{
+		name:     DestinationFile,
+		format:   FormatText,
+		dflt:     FormatText,
+		minLevel: UNKNOWN,
+		builtin: func(r *Record, text string) {
+			outputMain(r.Level, r.Date, text)
+		},
+	}
+
+	destinations = []*destination{
+		{
+			name:     DestinationConsole,
+			format:   FormatConsole,
+			dflt:     FormatConsole,
+			minLevel: UNKNOWN,
+			builtin: func(r *Record, text string) {
+				if consoleAllowed(r.Facility) && !consoleIsStdout() {
+					consoleOutput(r, text)
+				}
+			},
+		},
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// AddDestination -- send records of minLevel or more severe rendered in the format to the sink. The destination
+// with the same name is replaced, nil format means FormatText. The sinks of the pre-registered file and console
+// destinations can't be replaced, the sink is ignored and only the format and the level are changed.
+func AddDestination(name string, format Format, sink Sink, minLevel Level) {
+	if format == nil {
+		format = FormatText
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if d := findDestination(name); d != nil {
+		d.format = format
+		d.minLevel = minLevel
+		if d.builtin == nil {
+			d.sink = sink
+		}
+		return
+	}
+
+	if sink == nil {
+		return
+	}
+
+	destinations = append(destinations,
+		&destination{
+			name:     name,
+			format:   format,
+			sink:     sink,
+			minLevel: minLevel,
+		},
+	)
+}
+
+// DelDestination -- remove the added destination, the pre-registered ones get the default format and all levels back
+func DelDestination(name string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	for i, d := range destinations {
+		if d.name == name && d.builtin == nil {
+			destinations = append(destinations[:i:i], destinations[i+1:]...)
+			return
+		}
+	}
+
+	if d := findDestination(name); d != nil {
+		d.format = d.dflt
+		d.minLevel = UNKNOWN
+	}
+}
+
+// Must be called under the mutex
+func findDestination(name string) *destination {
+	if name == DestinationFile {
+		return fileDestination
+	}
+
+	for _, d := range destinations {
+		if d.name == name {
+			return d
+		}
+	}
+	return nil
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Render -- the record in the format, the rendering is cached in the record
+func (r *Record) Render(f Format) string {
+	switch f.(type) {
+	case textFormat:
+		return r.Line
+	case consoleFormat:
+		if r.console != "" {
+			return r.console
+		}
+		return r.Line
+	}
+
+	id := f.ID()
+	for _, c := range r.cache {
+		if c.id == id {
+			return c.text
+		}
+	}
+
+	text := f.Render(*r)
+	r.cache = append(r.cache, renderedText{id: id, text: text})
+	return text
+}
+
+// output -- render the record and write it if the level passes. Must be called under the mutex.
+func (d *destination) output(r *Record) {
+	if !r.Raw && !r.Level.passes(d.minLevel) {
+		return
+	}
+
+	text := r.Render(d.format)
+
+	if d.builtin != nil {
+		d.builtin(r, text)
+		return
+	}
+
+	if _, err := d.sink.Write([]byte(text)); err != nil {
+		destinationFailed(d.name, 1, err)
+	} else {
+		destinationWritten(d.name)
+	}
+}
+
+// plain -- the file gets every classic line, so lines can be written together. Must be called under the mutex.
+func (d *destination) plain() bool {
+	_, ok := d.format.(textFormat)
+	return ok && d.minLevel == UNKNOWN
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func (textFormat) ID() string {
+	return "text"
+}
+
+func (textFormat) Render(r Record) string {
+	return r.Line
+}
+
+func (consoleFormat) ID() string {
+	return "console"
+}
+
+func (consoleFormat) Render(r Record) string {
+	if r.console != "" {
+		return r.console
+	}
+	return r.Line
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
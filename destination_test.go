@@ -0,0 +1,124 @@
+package log
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+type countingFormat struct {
+	id    string
+	count *atomic.Int64
+}
+
+func (f countingFormat) ID() string {
+	return f.id
+}
+
+func (f countingFormat) Render(r Record) string {
+	f.count.Add(1)
+	return f.id + ": " + r.Line
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestDestinationsRenderOnce(t *testing.T) {
+	console := resetLog(t)
+	setFakeClock(time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC))
+
+	shared := &atomic.Int64{}
+	severe := &atomic.Int64{}
+
+	a := &captureWriter{}
+	b := &captureWriter{}
+	c := &captureWriter{}
+
+	// Different instances of the same format
+	AddDestination("a", countingFormat{"shared", shared}, a, UNKNOWN)
+	AddDestination("b", countingFormat{"shared", shared}, b, UNKNOWN)
+	AddDestination("c", countingFormat{"severe", severe}, c, WARNING)
+	AddDestination(DestinationConsole, countingFormat{"shared", shared}, nil, UNKNOWN)
+
+	Message(INFO, "one")
+	Message(ERR, "two")
+	Message(DEBUG, "three")
+
+	if n := shared.Load(); n != 3 {
+		t.Errorf("the shared format is rendered %d times, expected 3", n)
+	}
+	if n := severe.Load(); n != 1 {
+		t.Errorf("the severe format is rendered %d times, expected 1", n)
+	}
+
+	for _, w := range []*captureWriter{a, b, console} {
+		lines := w.Lines()
+		if len(lines) != 3 || !strings.HasPrefix(lines[0], "shared: [") || !strings.HasSuffix(lines[2], " three") {
+			t.Errorf("unexpected lines %q", lines)
+		}
+	}
+	if lines := c.Lines(); len(lines) != 1 || !strings.HasPrefix(lines[0], "severe: [") || !strings.HasSuffix(lines[0], " two") {
+		t.Errorf("unexpected lines %q", lines)
+	}
+
+	DelDestination("a")
+	DelDestination(DestinationConsole)
+	Message(INFO, "four")
+
+	if n := len(a.Lines()); n != 3 {
+		t.Errorf("the removed destination got %d lines", n)
+	}
+	if lines := console.Lines(); !strings.HasPrefix(lines[3], "[") || !strings.HasSuffix(lines[3], " four") {
+		t.Errorf("the console format isn't restored %q", lines[3])
+	}
+	if n := shared.Load(); n != 4 {
+		t.Errorf("the shared format is rendered %d times, expected 4", n)
+	}
+}
+
+func TestFileDestinationJSON(t *testing.T) {
+	for _, mode := range []GroupCommitMode{GroupCommitOff, GroupCommitSync} {
+		console := resetLog(t)
+		setFakeClock(time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC))
+		SetFile(t.TempDir(), "", false, 4096, 0)
+		SetGroupCommit(mode)
+
+		AddDestination(DestinationFile, FormatJSON, nil, INFO)
+
+		GetFacility("db").Message(INFO, "stored %d", 1)
+		Message(DEBUG, "skipped")
+
+		SetGroupCommit(GroupCommitOff)
+		writerFlush()
+
+		data, err := os.ReadFile(FileName())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		last := lines[len(lines)-1]
+
+		var v struct {
+			Time     time.Time `json:"time"`
+			Level    string    `json:"level"`
+			Facility string    `json:"facility"`
+			Text     string    `json:"text"`
+		}
+		if err := json.Unmarshal([]byte(last), &v); err != nil {
+			t.Fatalf("%d: %s: %q", mode, err, last)
+		}
+		if v.Level != "INFO" || v.Facility != "db" || v.Text != "stored 1" || !v.Time.Equal(time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC)) {
+			t.Errorf("%d: unexpected record %+v", mode, v)
+		}
+		if strings.Contains(string(data), "skipped") || !strings.Contains(console.String(), " skipped\n") {
+			t.Errorf("%d: the level of the file destination is ignored:\n%s", mode, data)
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,61 @@
+package log
+
+import (
+	"context"
+	"fmt"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// DrainError -- the waiting is interrupted by the context, Pending lines of the group commit aren't written yet
+type DrainError struct {
+	Pending int
+	Err     error
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func (e *DrainError) Error() string {
+	return fmt.Sprintf("%s, %d lines are not written", e.Err, e.Pending)
+}
+
+func (e *DrainError) Unwrap() error {
+	return e.Err
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// FlushCtx -- write the group commit lines and flush the file buffer. The context limits the waiting, not the work itself.
+func FlushCtx(ctx context.Context) error {
+	return waitDrain(ctx, flush)
+}
+
+func flush() {
+	if r := groupCommitRing.Load(); r != nil {
+		r.commit(true)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	writerFlush()
+}
+
+// waitDrain -- run f in the goroutine and wait for it or for the context
+func waitDrain(ctx context.Context, f func()) error {
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		f()
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return &DrainError{Pending: int(commitPending.Load()), Err: ctx.Err()}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,124 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+type blockedTarget struct {
+	once    sync.Once
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (w *blockedTarget) Write(p []byte) (int, error) {
+	w.once.Do(func() { close(w.entered) })
+	<-w.release
+	return len(p), nil
+}
+
+func (w *blockedTarget) Close() error {
+	return nil
+}
+
+func checkDrainError(t *testing.T, name string, err error, pending int) {
+	t.Helper()
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("%s: got %v, expected deadline exceeded", name, err)
+	}
+
+	var de *DrainError
+	if !errors.As(err, &de) || de.Pending != pending {
+		t.Errorf("%s: got %v, expected %d pending lines", name, err, pending)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestDrainTimeout(t *testing.T) {
+	resetLog(t)
+
+	SetFile(t.TempDir(), "", false, 4096, 0)
+
+	target := &blockedTarget{
+		entered: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	AddTarget("blocked", target)
+
+	SetGroupCommit(GroupCommitAsync)
+	SetEnqueueTimeout(10 * time.Millisecond)
+
+	// The committer is blocked by the target
+	Message(INFO, "first")
+	select {
+	case <-target.entered:
+	case <-time.After(5 * time.Second):
+		t.Fatal("target isn't called")
+	}
+
+	for i := 0; i < commitRingSize; i++ {
+		Message(INFO, "msg %d", i)
+	}
+
+	dropped := GetStats().Dropped
+	t0 := time.Now()
+	for i := 0; i < 3; i++ {
+		Message(INFO, "dropped %d", i)
+	}
+	if d := time.Since(t0); d > time.Second {
+		t.Errorf("enqueue is blocked for %s", d)
+	}
+	if n := GetStats().Dropped - dropped; n != 3 {
+		t.Errorf("got %d dropped lines, expected 3", n)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	checkDrainError(t, "FlushCtx", FlushCtx(ctx), commitRingSize)
+	cancel()
+
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	checkDrainError(t, "ShutdownCtx", ShutdownCtx(ctx), commitRingSize)
+	cancel()
+
+	close(target.release)
+
+	// Waits for the interrupted one
+	if err := Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer Start()
+
+	data, _ := os.ReadFile(FileName())
+	if n := strings.Count(string(data), " msg "); n != commitRingSize || strings.Contains(string(data), " dropped ") {
+		t.Errorf("got %d lines, expected %d", n, commitRingSize)
+	}
+}
+
+func TestFlushCtx(t *testing.T) {
+	resetLog(t)
+
+	SetFile(t.TempDir(), "", false, 4096, 0)
+	SetGroupCommit(GroupCommitAsync)
+
+	Message(INFO, "flushed")
+
+	if err := FlushCtx(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(FileName())
+	if !strings.HasSuffix(string(data), " flushed\n") {
+		t.Errorf("unexpected file:\n%s", data)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,217 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/alrusov/misc"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Dump -- log the value as the indented JSON block, see Facility.Dump
+func Dump(level Level, label string, v any) {
+	stdFacility.SecuredDump(level, nil, label, v)
+}
+
+// DumpDiff -- log changed fields, see Facility.DumpDiff
+func DumpDiff(level Level, label string, old any, new any) {
+	stdFacility.SecuredDumpDiff(level, nil, label, old, new)
+}
+
+// Dump -- log the value as the indented JSON block (%+v for values JSON can't marshal).
+// Every line is prefixed by "label ▸ line n/N", so parsers can group them.
+func (f *Facility) Dump(level Level, label string, v any) {
+	f.SecuredDump(level, nil, label, v)
+}
+
+// SecuredDump -- Dump with the replace rules applied to every line
+func (f *Facility) SecuredDump(level Level, replace *misc.Replace, label string, v any) {
+	f, level, keep, ok := f.dumpTarget(1, level, label)
+	if !ok {
+		return
+	}
+
+	f.dumpBlock(1, level, keep, replace, label, strings.Split(dumpString(v), "\n"))
+}
+
+// DumpDiff -- log only fields changed between old and new as "path: old -> new", added as "+ path: new", removed as "- path: old"
+func (f *Facility) DumpDiff(level Level, label string, old any, new any) {
+	f.SecuredDumpDiff(level, nil, label, old, new)
+}
+
+// SecuredDumpDiff -- DumpDiff with the replace rules applied to every line
+func (f *Facility) SecuredDumpDiff(level Level, replace *misc.Replace, label string, old any, new any) {
+	f, level, keep, ok := f.dumpTarget(1, level, label)
+	if !ok {
+		return
+	}
+
+	oldV, errOld := dumpGeneric(old)
+	newV, errNew := dumpGeneric(new)
+
+	var lines []string
+	if errOld != nil || errNew != nil {
+		o := fmt.Sprintf("%+v", old)
+		n := fmt.Sprintf("%+v", new)
+		if o != n {
+			lines = append(lines, o+" -> "+n)
+		}
+	} else {
+		lines = dumpDiff("", oldV, newV, lines)
+	}
+
+	if len(lines) == 0 {
+		lines = []string{"no changes"}
+	}
+
+	f.dumpBlock(1, level, keep, replace, label, lines)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// dumpTarget -- the checks of messageEx done before the value is rendered: the facility and the level of the dump,
+// keep is true if the lines go to the look-behind only, false ok if the dump isn't logged
+func (f *Facility) dumpTarget(shift int, level Level, label string) (target *Facility, l Level, keep bool, ok bool) {
+	if f == stdFacility && autoFacility.Load() {
+		f = callerFacility()
+	}
+
+	if f.disabled.Load() {
+		return
+	}
+
+	// The negative level logs the dump regardless of the facility level
+	force := level < 0
+	if force {
+		level = -level
+	}
+	level = checkLevel(shift+1, f, level)
+
+	if force || (level.passes(f.token.Load()) && !f.sampledOut(level)) {
+		if stormDrop(f, level, "", label, nil) {
+			return
+		}
+		return f, level, false, true
+	}
+
+	if f.lookBehind.Load() != nil {
+		return f, level, true, true
+	}
+
+	return
+}
+
+func (f *Facility) dumpBlock(shift int, level Level, keep bool, replace *misc.Replace, label string, lines []string) {
+	mo := callerRedaction(replace)
+
+	format := "%s ▸ line %d/%d %s"
+	if scopeFrames.Load() != 0 {
+		format = scopeMessage(format, []any{label})
+	}
+
+	if keep {
+		for i, line := range lines {
+			f.keepSuppressed(shift+1, level, mo, format, label, i+1, len(lines), line)
+		}
+		return
+	}
+
+	// The lines enqueued by the group commit go first
+	defer commitBarrier()()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if lb := f.lookBehind.Load(); lb != nil && level.passes(lb.trigger) {
+		f.writeLookBehind(shift + 1)
+	}
+
+	for i, line := range lines {
+		logger(false, shift+1, f.name, level, mo, format, label, i+1, len(lines), line)
+	}
+}
+
+func dumpString(v any) string {
+	j, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Sprintf("%+v", v)
+	}
+	return string(j)
+}
+
+// dumpGeneric -- the value as the tree of maps, slices and scalars
+func dumpGeneric(v any) (any, error) {
+	j, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var g any
+	err = json.Unmarshal(j, &g)
+	return g, err
+}
+
+func dumpDiff(path string, old any, new any, lines []string) []string {
+	oldM, okOld := old.(map[string]any)
+	newM, okNew := new.(map[string]any)
+
+	if !okOld || !okNew {
+		if !reflect.DeepEqual(old, new) {
+			lines = append(lines, fmt.Sprintf("%s: %s -> %s", dumpPath(path), dumpValue(old), dumpValue(new)))
+		}
+		return lines
+	}
+
+	names := make([]string, 0, len(oldM)+len(newM))
+	for name := range oldM {
+		names = append(names, name)
+	}
+	for name := range newM {
+		if _, exists := oldM[name]; !exists {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		p := name
+		if path != "" {
+			p = path + "." + name
+		}
+
+		o, existsOld := oldM[name]
+		n, existsNew := newM[name]
+
+		switch {
+		case !existsOld:
+			lines = append(lines, fmt.Sprintf("+ %s: %s", p, dumpValue(n)))
+		case !existsNew:
+			lines = append(lines, fmt.Sprintf("- %s: %s", p, dumpValue(o)))
+		default:
+			lines = dumpDiff(p, o, n, lines)
+		}
+	}
+
+	return lines
+}
+
+func dumpPath(path string) string {
+	if path == "" {
+		return "."
+	}
+	return path
+}
+
+func dumpValue(v any) string {
+	j, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%+v", v)
+	}
+	return string(j)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,254 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alrusov/misc"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+type dumpInner struct {
+	Host   string `json:"host"`
+	Port   int    `json:"port"`
+	secret string
+}
+
+type dumpConfig struct {
+	Name    string               `json:"name"`
+	Servers map[string]dumpInner `json:"servers"`
+	Tags    []string             `json:"tags,omitempty"`
+}
+
+type dumpNode struct {
+	Name string
+	Next *dumpNode
+}
+
+type dumpCounter struct {
+	calls *int
+}
+
+func (c dumpCounter) MarshalJSON() ([]byte, error) {
+	*c.calls++
+	return []byte(`"x"`), nil
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestDump(t *testing.T) {
+	w := resetLog(t)
+
+	cfg := dumpConfig{
+		Name: "main",
+		Servers: map[string]dumpInner{
+			"a": {Host: "a.local", Port: 80, secret: "hidden"},
+		},
+	}
+
+	replace := misc.NewReplace()
+	replace.Add(`a\.local`, "***")
+
+	stdFacility.SecuredDump(INFO, replace, "cfg", cfg)
+
+	lines := w.Lines()
+	if len(lines) != 9 {
+		t.Fatalf("got %d lines, expected 9:\n%s", len(lines), w.String())
+	}
+	for i, expected := range []string{
+		`cfg ▸ line 1/9 {`,
+		`cfg ▸ line 2/9   "name": "main",`,
+		`cfg ▸ line 5/9       "host": "***",`,
+		`cfg ▸ line 9/9 }`,
+	} {
+		found := false
+		for _, line := range lines {
+			if strings.HasSuffix(line, expected) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("[%d] %q not found", i, expected)
+		}
+	}
+	if strings.Contains(w.String(), "hidden") {
+		t.Error("unexported field is dumped")
+	}
+}
+
+func TestDumpCyclic(t *testing.T) {
+	w := resetLog(t)
+
+	n := &dumpNode{Name: "loop"}
+	n.Next = n
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		Dump(INFO, "node", n)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("dump of the cyclic value hangs")
+	}
+
+	lines := w.Lines()
+	if len(lines) != 1 || !strings.Contains(lines[0], "node ▸ line 1/1 &{Name:loop Next:0x") {
+		t.Errorf("unexpected dump %q", lines)
+	}
+}
+
+func TestDumpMaxLen(t *testing.T) {
+	w := resetLog(t)
+
+	MaxLen(60)
+	Dump(INFO, "long", map[string]string{"key": strings.Repeat("x", 100)})
+
+	for _, line := range w.Lines() {
+		if len(line) > 60 {
+			t.Errorf("line is not truncated: %q", line)
+		}
+	}
+}
+
+func TestDumpFiltered(t *testing.T) {
+	w := resetLog(t)
+
+	calls := 0
+	f := GetFacility("dump")
+	f.SetLogLevel("INFO", FuncNameModeNone)
+	w.buf.Reset()
+
+	f.Dump(DEBUG, "v", dumpCounter{calls: &calls})
+	f.DumpDiff(DEBUG, "v", dumpCounter{calls: &calls}, dumpCounter{calls: &calls})
+	if calls != 0 || w.String() != "" {
+		t.Errorf("filtered dump is marshaled %d times, output %q", calls, w.String())
+	}
+
+	f.Dump(INFO, "v", dumpCounter{calls: &calls})
+	if calls != 1 {
+		t.Errorf("got %d calls, expected 1", calls)
+	}
+}
+
+func TestDumpDiff(t *testing.T) {
+	w := resetLog(t)
+
+	old := dumpConfig{
+		Name: "main",
+		Servers: map[string]dumpInner{
+			"a": {Host: "a.local", Port: 80},
+			"b": {Host: "b.local", Port: 80},
+		},
+		Tags: []string{"x"},
+	}
+	new := dumpConfig{
+		Name: "main",
+		Servers: map[string]dumpInner{
+			"a": {Host: "a.local", Port: 8080},
+			"c": {Host: "c.local", Port: 80},
+		},
+	}
+
+	DumpDiff(INFO, "reload", old, new)
+
+	expected := []string{
+		`reload ▸ line 1/4 servers.a.port: 80 -> 8080`,
+		`reload ▸ line 2/4 - servers.b: {"host":"b.local","port":80}`,
+		`reload ▸ line 3/4 + servers.c: {"host":"c.local","port":80}`,
+		`reload ▸ line 4/4 - tags: ["x"]`,
+	}
+
+	lines := w.Lines()
+	if len(lines) != len(expected) {
+		t.Fatalf("got %d lines, expected %d:\n%s", len(lines), len(expected), w.String())
+	}
+	for i, e := range expected {
+		if !strings.HasSuffix(lines[i], e) {
+			t.Errorf("[%d] got %q, expected suffix %q", i, lines[i], e)
+		}
+	}
+
+	w.buf.Reset()
+	DumpDiff(INFO, "reload", new, new)
+	if lines := w.Lines(); len(lines) != 1 || !strings.HasSuffix(lines[0], "reload ▸ line 1/1 no changes") {
+		t.Errorf("unexpected diff %q", lines)
+	}
+}
+
+func TestDumpGroupCommitOrder(t *testing.T) {
+	console := resetLog(t)
+	SetLogLevel("INFO", FuncNameModeNone)
+	SetGroupCommit(GroupCommitAsync)
+	defer SetGroupCommit(GroupCommitOff)
+	console.buf.Reset()
+
+	for i := 0; i < 100; i++ {
+		Message(INFO, "message %d", i)
+		Dump(INFO, "v", i)
+	}
+	SetGroupCommit(GroupCommitOff)
+
+	lines := console.Lines()
+	if len(lines) != 200 {
+		t.Fatalf("got %d lines, expected 200", len(lines))
+	}
+	for i := 0; i < 100; i++ {
+		if !strings.HasSuffix(lines[2*i], fmt.Sprintf(" message %d", i)) || !strings.HasSuffix(lines[2*i+1], fmt.Sprintf(" v ▸ line 1/1 %d", i)) {
+			t.Fatalf("[%d] unexpected order:\n%s\n%s", i, lines[2*i], lines[2*i+1])
+		}
+	}
+}
+
+func TestDumpLookBehind(t *testing.T) {
+	console := resetLog(t)
+
+	f := NewFacility("lb")
+	f.SetLogLevel("INFO", FuncNameModeNone)
+	f.EnableLookBehind(3, ERR)
+	console.buf.Reset()
+
+	f.Dump(DEBUG, "kept", 1)
+	f.Dump(ERR, "failed", 2)
+
+	expected := []string{
+		"--- look-behind begin ---",
+		"kept ▸ line 1/1 1",
+		"--- look-behind end ---",
+		"failed ▸ line 1/1 2",
+	}
+
+	lines := console.Lines()
+	if len(lines) != len(expected) {
+		t.Fatalf("got %d lines, expected %d\n%s", len(lines), len(expected), console.String())
+	}
+	for i, e := range expected {
+		if !strings.HasSuffix(lines[i], "<lb> "+e) {
+			t.Errorf("[%d] got %q, expected %q", i, lines[i], e)
+		}
+	}
+}
+
+func TestDumpSampling(t *testing.T) {
+	console := resetLog(t)
+
+	f := NewFacility("sampled")
+	f.SetSampling(INFO, 10)
+	console.buf.Reset()
+
+	for i := 0; i < 100; i++ {
+		f.Dump(INFO, "v", i)
+	}
+
+	if n := len(console.Lines()); n != 10 {
+		t.Errorf("got %d lines, expected 10", n)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
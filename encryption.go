@@ -0,0 +1,401 @@
+package log
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// The encrypted file starts with the header: magic, random salt and the key check. The file key is SHA-256 of the key
+// and the salt. The data follows as chunks, one per flush: 4 bytes of the big endian length with the final flag
+// in the high bit, the random nonce and the AES-GCM sealed data. The chunk is authenticated with the header, its number
+// in the file and the final flag, so the chunks can't be reordered, dropped or moved to another file. The chunk written
+// on the close is final, the file without the final chunk at the end is truncated or not closed yet.
+// With the compression the data is compressed then encrypted. The existing file is appended only if its header matches
+// the current key, otherwise the open fails, the numbering of the appended chunks continues.
+
+// EncryptionMode -- encryption of the log files
+type EncryptionMode string
+
+const (
+	// EncryptionNone --
+	EncryptionNone = EncryptionMode("")
+	// EncryptionAESGCM -- AES-GCM chunks, the key is 16, 24 or 32 bytes
+	EncryptionAESGCM = EncryptionMode("aes-gcm")
+)
+
+const (
+	encMagic      = "ALOGENC1"
+	encSaltSize   = 16
+	encCheckSize  = 16
+	encHeaderSize = len(encMagic) + encSaltSize + encCheckSize
+	encChunkSize  = 64 * 1024 // the largest chunk of the plain data
+	encFinalChunk = 1 << 31   // the final flag in the chunk length
+)
+
+var (
+	// ErrEncryptionKey -- the file is encrypted with another key
+	ErrEncryptionKey = errors.New("the encryption key doesn't match")
+	// ErrEncryptionTruncated -- the encrypted file has no final chunk, it is truncated or still written.
+	// All the data before the end is read.
+	ErrEncryptionTruncated = fmt.Errorf("the encrypted file has no final chunk: %w", io.ErrUnexpectedEOF)
+
+	encryptionKey []byte
+)
+
+// fileCipher -- the cipher of the file and its header
+type fileCipher struct {
+	aead   cipher.AEAD
+	header []byte
+	chunks uint64 // the number of the chunks in the file
+}
+
+type encryptFile struct {
+	file   *os.File
+	cipher *fileCipher
+	buf    []byte
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetFileEncryption -- encrypt the log files with the key. EncryptionNone switches the encryption off.
+// The current file is closed, the next message opens the file with the new settings.
+func SetFileEncryption(key []byte, mode EncryptionMode) error {
+	switch mode {
+	case EncryptionNone:
+		key = nil
+	case EncryptionAESGCM:
+		if _, err := aes.NewCipher(key); err != nil {
+			return err
+		}
+		key = bytes.Clone(key)
+	default:
+		return fmt.Errorf(`unsupported encryption "%s"`, mode)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	flushOpenPending()
+	closeLogFile()
+	lastWriteDate = ""
+
+	encryptionKey = key
+	return nil
+}
+
+// DecryptFile -- write the decrypted content of the log file to w. The content of the compressed file stays compressed.
+// ErrEncryptionTruncated is returned after the content if the file is truncated or not closed.
+func DecryptFile(path string, key []byte, w io.Writer) error {
+	fd, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+
+	header := make([]byte, encHeaderSize)
+	if _, err := io.ReadFull(fd, header); err != nil || string(header[:len(encMagic)]) != encMagic {
+		return fmt.Errorf("%s isn't encrypted", path)
+	}
+
+	c, err := headerCipher(header, key)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(w, &decryptReader{r: fd, cipher: c})
+	return err
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// newFileCipher -- cipher with the new random salt
+func newFileCipher(key []byte) (*fileCipher, error) {
+	header := make([]byte, encHeaderSize)
+	copy(header, encMagic)
+	if _, err := rand.Read(header[len(encMagic) : len(encMagic)+encSaltSize]); err != nil {
+		return nil, err
+	}
+
+	c, err := saltCipher(header, key)
+	if err != nil {
+		return nil, err
+	}
+
+	copy(header[len(encMagic)+encSaltSize:], encKeyCheck(c.aead, header))
+	return c, nil
+}
+
+// headerCipher -- cipher of the existing header, ErrEncryptionKey if the key doesn't match
+func headerCipher(header []byte, key []byte) (*fileCipher, error) {
+	c, err := saltCipher(header, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(header[len(encMagic)+encSaltSize:], encKeyCheck(c.aead, header)) {
+		return nil, ErrEncryptionKey
+	}
+
+	return c, nil
+}
+
+func saltCipher(header []byte, key []byte) (*fileCipher, error) {
+	h := sha256.New()
+	h.Write(key)
+	h.Write(header[len(encMagic) : len(encMagic)+encSaltSize])
+
+	block, err := aes.NewCipher(h.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &fileCipher{aead: aead, header: header}, nil
+}
+
+// encKeyCheck -- tag of the empty data sealed with the zero nonce, it depends on the file key only
+func encKeyCheck(aead cipher.AEAD, header []byte) []byte {
+	nonce := make([]byte, aead.NonceSize())
+	return aead.Seal(nil, nonce, nil, header[:len(encMagic)+encSaltSize])[:encCheckSize]
+}
+
+// prepareEncryption -- check the header of the opened file or write the new one.
+// An error is returned if the file is encrypted and the key doesn't match or it isn't encrypted and the key is set.
+func prepareEncryption(f *os.File, key []byte) (*fileCipher, error) {
+	st, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	if st.Size() == 0 {
+		if key == nil {
+			return nil, nil
+		}
+
+		c, err := newFileCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := f.Write(c.header); err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
+
+	// The file is opened for appending only
+	rd, err := os.Open(f.Name())
+	if err != nil {
+		return nil, err
+	}
+	defer rd.Close()
+
+	header := make([]byte, encHeaderSize)
+	n, _ := io.ReadFull(rd, header)
+	encrypted := n == encHeaderSize && string(header[:len(encMagic)]) == encMagic
+
+	switch {
+	case !encrypted && key == nil:
+		return nil, nil
+	case !encrypted:
+		return nil, fmt.Errorf("%s isn't encrypted", f.Name())
+	case key == nil:
+		return nil, fmt.Errorf("%s is encrypted", f.Name())
+	}
+
+	c, err := headerCipher(header, key)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", f.Name(), err)
+	}
+
+	if c.chunks, err = countChunks(rd); err != nil {
+		return nil, fmt.Errorf("%s: %w", f.Name(), err)
+	}
+	return c, nil
+}
+
+// countChunks -- the number of the chunks after the header
+func countChunks(r io.ReadSeeker) (uint64, error) {
+	n := uint64(0)
+	for {
+		var size [4]byte
+		if _, err := io.ReadFull(r, size[:]); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return n, nil
+			}
+			return 0, err
+		}
+
+		if _, err := r.Seek(int64(binary.BigEndian.Uint32(size[:])&^encFinalChunk), io.SeekCurrent); err != nil {
+			return 0, err
+		}
+		n++
+	}
+}
+
+// decryptingReader -- the plain content of the file, the encrypted one is decrypted with the key.
+// The reader of the file without the final chunk returns ErrEncryptionTruncated at the end.
+func decryptingReader(fd *os.File, key []byte) (io.Reader, error) {
+	header := make([]byte, encHeaderSize)
+	n, _ := io.ReadFull(fd, header)
+	if n < encHeaderSize || string(header[:len(encMagic)]) != encMagic {
+		_, err := fd.Seek(0, io.SeekStart)
+		return fd, err
+	}
+
+	c, err := headerCipher(header, key)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fd.Name(), err)
+	}
+
+	return &decryptReader{r: fd, cipher: c}, nil
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func (c *fileCipher) wrap(file *os.File) io.WriteCloser {
+	if c == nil {
+		return file
+	}
+
+	return &encryptFile{
+		file:   file,
+		cipher: c,
+	}
+}
+
+func (f *encryptFile) Write(p []byte) (int, error) {
+	f.buf = append(f.buf, p...)
+
+	for len(f.buf) >= encChunkSize {
+		if err := f.seal(f.buf[:encChunkSize], false); err != nil {
+			return 0, err
+		}
+		f.buf = f.buf[:copy(f.buf, f.buf[encChunkSize:])]
+	}
+
+	return len(p), nil
+}
+
+func (f *encryptFile) Flush() error {
+	if len(f.buf) == 0 {
+		return nil
+	}
+
+	err := f.seal(f.buf, false)
+	f.buf = f.buf[:0]
+	return err
+}
+
+func (f *encryptFile) Sync() error {
+	return f.file.Sync()
+}
+
+func (f *encryptFile) Close() error {
+	err := f.seal(f.buf, true)
+	f.buf = f.buf[:0]
+	if e := f.file.Close(); err == nil {
+		err = e
+	}
+	return err
+}
+
+// seal -- write the data as the next chunk, the final one may be empty
+func (f *encryptFile) seal(data []byte, final bool) error {
+	aead := f.cipher.aead
+	chunk := make([]byte, 4+aead.NonceSize(), 4+aead.NonceSize()+len(data)+aead.Overhead())
+	nonce := chunk[4:]
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	chunk = aead.Seal(chunk, nonce, data, f.cipher.chunkAAD(f.cipher.chunks, final))
+
+	size := uint32(len(chunk) - 4)
+	if final {
+		size |= encFinalChunk
+	}
+	binary.BigEndian.PutUint32(chunk, size)
+
+	if _, err := f.file.Write(chunk); err != nil {
+		return err
+	}
+
+	f.cipher.chunks++
+	return nil
+}
+
+// chunkAAD -- the additional data of the chunk: the header, the chunk number and the final flag
+func (c *fileCipher) chunkAAD(n uint64, final bool) []byte {
+	aad := make([]byte, len(c.header)+9)
+	copy(aad, c.header)
+	binary.BigEndian.PutUint64(aad[len(c.header):], n)
+	if final {
+		aad[len(aad)-1] = 1
+	}
+	return aad
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+type decryptReader struct {
+	r      io.Reader
+	cipher *fileCipher
+	plain  []byte
+	chunks uint64 // the number of the read chunks
+	final  bool   // the last read chunk is final
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.plain) == 0 {
+		var size [4]byte
+		if _, err := io.ReadFull(d.r, size[:]); err != nil {
+			if err == io.EOF && !d.final {
+				err = ErrEncryptionTruncated
+			}
+			return 0, err
+		}
+
+		n := binary.BigEndian.Uint32(size[:])
+		final := n&encFinalChunk != 0
+		n &^= encFinalChunk
+		aead := d.cipher.aead
+		if n < uint32(aead.NonceSize()+aead.Overhead()) || n > uint32(aead.NonceSize()+encChunkSize+aead.Overhead()) {
+			return 0, fmt.Errorf("bad encrypted chunk size %d", n)
+		}
+
+		chunk := make([]byte, n)
+		if _, err := io.ReadFull(d.r, chunk); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+
+		plain, err := aead.Open(chunk[aead.NonceSize():aead.NonceSize()], chunk[:aead.NonceSize()], chunk[aead.NonceSize():], d.cipher.chunkAAD(d.chunks, final))
+		if err != nil {
+			return 0, fmt.Errorf("encrypted chunk %d: %w", d.chunks, err)
+		}
+		d.plain = plain
+		d.chunks++
+		d.final = final
+	}
+
+	n := copy(p, d.plain)
+	d.plain = d.plain[n:]
+	return n, nil
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,226 @@
+package log
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+var (
+	testKey  = []byte("0123456789abcdef0123456789abcdef")
+	otherKey = []byte("fedcba9876543210fedcba9876543210")
+)
+
+func decryptFile(t *testing.T, path string, key []byte) string {
+	t.Helper()
+
+	var b bytes.Buffer
+	if err := DecryptFile(path, key, &b); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestEncryptedFile(t *testing.T) {
+	resetLog(t)
+
+	clock := setFakeClock(time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC))
+
+	if err := SetFileEncryption(testKey[:7], EncryptionAESGCM); err == nil {
+		t.Error("bad key is accepted")
+	}
+	if err := SetFileEncryption(testKey, EncryptionAESGCM); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	SetFile(dir, "", false, 4096, 0)
+
+	Message(INFO, "secret first")
+	for i := 0; i < 5000; i++ {
+		Message(DEBUG, "filler %d", i)
+	}
+	Message(INFO, "secret second")
+	writerFlush()
+
+	first := FileName()
+
+	raw, err := os.ReadFile(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(raw, []byte("secret")) || bytes.Contains(raw, []byte(" *** ")) {
+		t.Error("plain text in the file")
+	}
+
+	// The open file has no final chunk yet
+	var live bytes.Buffer
+	if err := DecryptFile(first, testKey, &live); !errors.Is(err, ErrEncryptionTruncated) {
+		t.Errorf("got %v for the open file, expected %v", err, ErrEncryptionTruncated)
+	}
+
+	s := live.String()
+	if !strings.Contains(s, " *** ") || !strings.Contains(s, "secret first\n") || !strings.HasSuffix(s, "secret second\n") {
+		t.Errorf("unexpected content:\n%.500s", s)
+	}
+
+	var b bytes.Buffer
+	if err := DecryptFile(first, otherKey, &b); !errors.Is(err, ErrEncryptionKey) || b.Len() != 0 {
+		t.Errorf("wrong key: got %v and %d bytes", err, b.Len())
+	}
+
+	var captured bytes.Buffer
+	if err := CaptureWindow(time.Date(2024, 5, 3, 0, 0, 0, 0, time.UTC), INFO, &captured); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(captured.String(), "secret second\n") {
+		t.Errorf("unexpected capture:\n%s", captured.String())
+	}
+
+	// The rotated file gets its own header
+	clock.Set(time.Date(2024, 5, 4, 12, 0, 0, 0, time.UTC))
+	Message(INFO, "next day")
+	closeLogFile()
+
+	if s := decryptFile(t, first, testKey); !strings.HasSuffix(s, "secret second\n") {
+		t.Errorf("unexpected closed content:\n%.500s", s)
+	}
+	if s := decryptFile(t, FileName(), testKey); !strings.HasSuffix(s, "next day\n") {
+		t.Errorf("unexpected next content:\n%s", s)
+	}
+
+	// The file of the same key is appended, the one of other key is refused
+	clock.Set(time.Date(2024, 5, 3, 13, 0, 0, 0, time.UTC))
+	lastStamp = time.Time{}
+	Message(INFO, "appended")
+	closeLogFile()
+
+	if s := decryptFile(t, first, testKey); !strings.HasSuffix(s, "appended\n") {
+		t.Errorf("unexpected appended content:\n%.500s", s)
+	}
+
+	SetFallbackDirectory(t.TempDir())
+	SetFileEncryption(otherKey, EncryptionAESGCM)
+	Message(INFO, "other key")
+
+	if st := Status(); st.Tier != TierFallback || !strings.Contains(st.LastError, ErrEncryptionKey.Error()) {
+		t.Errorf("the file of other key is not refused: %+v", st)
+	}
+
+	SetFileEncryption(nil, EncryptionNone)
+	Message(INFO, "plain")
+	closeLogFile()
+
+	if s := decryptFile(t, first, testKey); strings.Contains(s, "other key") || strings.Contains(s, "plain") {
+		t.Errorf("the encrypted file is changed:\n%.500s", s)
+	}
+}
+
+func TestEncryptedChunks(t *testing.T) {
+	resetLog(t)
+
+	setFakeClock(time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC))
+	SetFileEncryption(testKey, EncryptionAESGCM)
+	SetFile(t.TempDir(), "", false, 4096, 0)
+
+	for i := 0; i < 3; i++ {
+		Message(INFO, "chunk %d", i)
+		writerFlush()
+	}
+	closeLogFile()
+
+	raw, err := os.ReadFile(FileName())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header := raw[:encHeaderSize]
+	var chunks [][]byte
+	for p := raw[encHeaderSize:]; len(p) > 0; {
+		n := 4 + int(binary.BigEndian.Uint32(p)&^encFinalChunk)
+		chunks = append(chunks, p[:n])
+		p = p[n:]
+	}
+	if len(chunks) != 4 {
+		t.Fatalf("got %d chunks, expected 3 and the final one", len(chunks))
+	}
+
+	path := filepath.Join(t.TempDir(), "tampered.log")
+	decrypt := func(chunks ...[]byte) (string, error) {
+		data := bytes.Join(append([][]byte{header}, chunks...), nil)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		var b bytes.Buffer
+		err := DecryptFile(path, testKey, &b)
+		return b.String(), err
+	}
+
+	if s, err := decrypt(chunks...); err != nil || !strings.HasSuffix(s, "chunk 2\n") {
+		t.Errorf("intact: got %v\n%s", err, s)
+	}
+
+	notFinal := bytes.Clone(chunks[3])
+	notFinal[0] &^= encFinalChunk >> 24
+
+	list := []struct {
+		name   string
+		chunks [][]byte
+	}{
+		{"reordered", [][]byte{chunks[0], chunks[2], chunks[1], chunks[3]}},
+		{"dropped", [][]byte{chunks[0], chunks[2], chunks[3]}},
+		{"final moved", [][]byte{chunks[0], chunks[1], chunks[3]}},
+		{"final flag cleared", [][]byte{chunks[0], chunks[1], chunks[2], notFinal}},
+	}
+
+	for _, c := range list {
+		if _, err := decrypt(c.chunks...); err == nil || errors.Is(err, ErrEncryptionTruncated) {
+			t.Errorf("[%s] got %v, expected the authentication error", c.name, err)
+		}
+	}
+
+	// The truncated file is read up to the end with the error
+	s, err := decrypt(chunks[:3]...)
+	if !errors.Is(err, ErrEncryptionTruncated) || !strings.HasSuffix(s, "chunk 2\n") {
+		t.Errorf("truncated: got %v\n%s", err, s)
+	}
+	if _, err := decrypt(chunks[:2]...); !errors.Is(err, ErrEncryptionTruncated) {
+		t.Errorf("truncated: got %v", err)
+	}
+}
+
+func TestEncryptedGzipFile(t *testing.T) {
+	resetLog(t)
+
+	setFakeClock(time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC))
+	SetFileEncryption(testKey, EncryptionAESGCM)
+	SetFileEx(FileOptions{Directory: t.TempDir(), BufSize: 4096, Compression: CompressionGzip})
+
+	Message(INFO, "compressed secret")
+	closeLogFile()
+
+	r, err := gzip.NewReader(strings.NewReader(decryptFile(t, FileName(), testKey)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(string(s), "compressed secret\n") {
+		t.Errorf("unexpected content:\n%s", s)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,88 @@
+package log
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// EventRenderer -- render the typed event for the console and for the file and other destinations
+type EventRenderer func(ev any) (console string, file string)
+
+var (
+	renderersMutex sync.RWMutex
+	renderers      = map[reflect.Type]EventRenderer{}
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// RegisterRenderer -- register the renderer of events of the type T. The new renderer replaces the previous one.
+func RegisterRenderer[T any](fn func(T) (console string, file string)) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+
+	renderersMutex.Lock()
+	defer renderersMutex.Unlock()
+
+	if fn == nil {
+		delete(renderers, t)
+		return
+	}
+
+	renderers[t] = func(ev any) (string, string) {
+		return fn(ev.(T))
+	}
+}
+
+// Event -- log the typed event of the std facility, see Facility.Event
+func Event(level Level, ev any) {
+	stdFacility.eventEx(1, level, ev)
+}
+
+// Event -- log the typed event rendered by the registered renderer, %+v if there is no renderer.
+// Both renderings share the same timestamp.
+func (f *Facility) Event(level Level, ev any) {
+	f.eventEx(1, level, ev)
+}
+
+func (f *Facility) eventEx(shift int, level Level, ev any) {
+	if f.disabled.Load() || !(level < 0 || level.passes(f.token.Load())) || !enabled {
+		return
+	}
+
+	if level < 0 {
+		level = -level
+	}
+
+	console, file := renderEvent(ev)
+
+	file, ok := applyRules(f.name, level, file)
+	if !ok {
+		statDrop()
+		return
+	}
+	console, _ = applyRules(f.name, level, console)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	dt, prefix := linePrefix(shift+1, f.name, level, FuncNameInherit)
+	notifySevere(f.name, level, lastStamp, file)
+	outputEx(f.name, level, dt, finishLine(prefix+file), finishLine(prefix+console), nil)
+}
+
+func renderEvent(ev any) (console string, file string) {
+	renderersMutex.RLock()
+	fn, exists := renderers[reflect.TypeOf(ev)]
+	renderersMutex.RUnlock()
+
+	if !exists {
+		s := fmt.Sprintf("%+v", ev)
+		return s, s
+	}
+
+	return fn(ev)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,76 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+type testLoginEvent struct {
+	User string
+	OK   bool
+}
+
+type testStringer struct {
+	calls *int
+}
+
+func (s testStringer) String() string {
+	*s.calls++
+	return "stringer"
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestEvent(t *testing.T) {
+	w := resetLog(t)
+
+	RegisterRenderer(func(ev testLoginEvent) (string, string) {
+		return "login " + ev.User, fmt.Sprintf(`{"event":"login","user":%q,"ok":%t}`, ev.User, ev.OK)
+	})
+
+	f := GetFacility("auth")
+	f.Event(INFO, testLoginEvent{User: "joe", OK: true})
+	f.Event(INFO, &testLoginEvent{User: "ann"})
+
+	console := w.Lines()
+	if len(console) != 2 || len(beforeFileBuf) != 2 {
+		t.Fatalf("got %d console and %d file lines, expected 2 and 2", len(console), len(beforeFileBuf))
+	}
+
+	file := strings.TrimSpace(beforeFileBuf[0])
+	if !strings.HasSuffix(console[0], "<auth> login joe") || !strings.HasSuffix(file, `<auth> {"event":"login","user":"joe","ok":true}`) {
+		t.Errorf("unexpected renderings:\n%s\n%s", console[0], file)
+	}
+	if console[0][:strings.Index(console[0], "<")] != file[:strings.Index(file, "<")] {
+		t.Errorf("renderings have different prefixes:\n%s\n%s", console[0], file)
+	}
+
+	// Pointer type is not registered
+	if !strings.HasSuffix(console[1], "<auth> &{User:ann OK:false}") || strings.TrimSpace(beforeFileBuf[1]) != console[1] {
+		t.Errorf("unexpected fallback:\n%s\n%s", console[1], beforeFileBuf[1])
+	}
+}
+
+func TestEventFiltered(t *testing.T) {
+	w := resetLog(t)
+
+	calls := 0
+	f := GetFacility("auth")
+	f.SetLogLevel("INFO", FuncNameModeNone)
+	w.buf.Reset()
+
+	f.Event(DEBUG, testStringer{calls: &calls})
+	if calls != 0 || w.String() != "" {
+		t.Errorf("filtered event is rendered %d times, output %q", calls, w.String())
+	}
+
+	f.Event(INFO, testStringer{calls: &calls})
+	if calls != 1 || !strings.HasSuffix(strings.TrimSpace(w.String()), "<auth> stringer") {
+		t.Errorf("got %d calls, output %q", calls, w.String())
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
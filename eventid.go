@@ -0,0 +1,147 @@
+package log
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// The event ID is the stable identifier of the class of messages chosen by the developer ("DB-CONN-FAIL"). It is
+// written as the "{DB-CONN-FAIL}" token before the message text and as the "event_id" field of JSON records. Duplicates
+// of the storm protection and of the console deduplication are found by the event ID instead of the text.
+// Occurrences are counted when the level passes, the occurrences of IDs not registered at the moment are also counted
+// in total.
+
+// EventInfo -- the event ID with its counter
+type EventInfo struct {
+	ID          string `json:"id"`
+	Description string `json:"description,omitempty"`
+	Registered  bool   `json:"registered"`
+	Count       int64  `json:"count"`
+}
+
+type eventCounter struct {
+	description string // guarded by eventMutex
+	registered  atomic.Bool
+	count       atomic.Int64
+}
+
+var (
+	eventMutex   sync.Mutex
+	eventIDs     sync.Map // id -> *eventCounter
+	unregistered atomic.Int64
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// RegisterEventID -- add the known event ID with the description. The ID can't be empty or contain spaces and braces.
+func RegisterEventID(id string, description string) error {
+	if err := checkEventID(id); err != nil {
+		return err
+	}
+
+	v, _ := eventIDs.LoadOrStore(id, &eventCounter{})
+	c := v.(*eventCounter)
+
+	eventMutex.Lock()
+	c.description = description
+	eventMutex.Unlock()
+
+	c.registered.Store(true)
+	return nil
+}
+
+// EventIDs -- all event IDs used or registered, sorted by ID
+func EventIDs() (list []EventInfo) {
+	eventMutex.Lock()
+	defer eventMutex.Unlock()
+
+	eventIDs.Range(func(k, v any) bool {
+		c := v.(*eventCounter)
+		list = append(list,
+			EventInfo{
+				ID:          k.(string),
+				Description: c.description,
+				Registered:  c.registered.Load(),
+				Count:       c.count.Load(),
+			},
+		)
+		return true
+	})
+
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return
+}
+
+// MessageID -- add message with the event ID to the log. The invalid ID is ignored and the message is logged without it.
+func (f *Facility) MessageID(id string, level Level, message string, params ...any) {
+	f.messageID(1, id, level, message, params...)
+}
+
+// MessageID -- add message with the event ID to the log
+func MessageID(id string, level Level, message string, params ...any) {
+	stdFacility.messageID(1, id, level, message, params...)
+}
+
+// UnregisteredEvents -- number of messages with event IDs not registered
+func UnregisteredEvents() int64 {
+	return unregistered.Load()
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func (f *Facility) messageID(shift int, id string, level Level, message string, params ...any) {
+	if f.disabled.Load() || !checkLevel(shift+1, f, level).passes(f.token.Load()) {
+		return
+	}
+
+	if checkEventID(id) != nil {
+		id = ""
+	} else {
+		countEvent(id)
+	}
+
+	f.messageEx(shift+1, level, false, &messageOptions{eventID: id}, message, params...)
+}
+
+func checkEventID(id string) error {
+	if id == "" || strings.ContainsAny(id, " \t\r\n{}") {
+		return fmt.Errorf(`invalid event ID "%s"`, id)
+	}
+	return nil
+}
+
+func countEvent(id string) {
+	v, exists := eventIDs.Load(id)
+	if !exists {
+		v, _ = eventIDs.LoadOrStore(id, &eventCounter{})
+	}
+
+	c := v.(*eventCounter)
+	c.count.Add(1)
+	if !c.registered.Load() {
+		unregistered.Add(1)
+	}
+}
+
+// event -- the event ID of the message
+func (mo *messageOptions) event() string {
+	if mo == nil {
+		return ""
+	}
+	return mo.eventID
+}
+
+// eventToken -- "{ID} " written before the message text
+func eventToken(id string) string {
+	if id == "" {
+		return ""
+	}
+	return "{" + id + "} "
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,128 @@
+package log
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestEventIDRendering(t *testing.T) {
+	console := resetLog(t)
+
+	setFakeClock(time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC))
+
+	GetFacility("db").MessageID("DB-CONN-FAIL", ERR, "connection to %s failed", "db1")
+	MessageID("bad id", ERR, "invalid id")
+	Message(ERR, "plain")
+
+	lines := console.Lines()
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines:\n%s", len(lines), console)
+	}
+	if !strings.HasSuffix(lines[0], " <db> {DB-CONN-FAIL} connection to db1 failed") {
+		t.Errorf("unexpected line %q", lines[0])
+	}
+	if !strings.HasSuffix(lines[1], " invalid id") || strings.Contains(lines[1], "{") {
+		t.Errorf("unexpected line %q", lines[1])
+	}
+
+	r := Record{Time: time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC), Level: ERR, Facility: "db", Line: lines[0] + "\n", EventID: "DB-CONN-FAIL"}
+	expected := `{"v":1,"time":"2024-05-03T12:00:00Z","level":"ERR","facility":"db","text":"connection to db1 failed","event_id":"DB-CONN-FAIL"}` + "\n"
+	if s := FormatJSON.Render(r); s != expected {
+		t.Errorf("got\n%s\nexpected\n%s", s, expected)
+	}
+
+	f := mustJSONFormat(JSONOptions{Fields: JSONFieldNames{EventID: "code"}})
+	if s := f.Render(r); !strings.HasSuffix(s, `,"code":"DB-CONN-FAIL"}`+"\n") {
+		t.Errorf("unexpected renamed field %s", s)
+	}
+}
+
+func TestEventIDCounters(t *testing.T) {
+	resetLog(t)
+
+	if err := RegisterEventID("DB-CONN-FAIL", "database connection failed"); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"", "a b", "{x}"} {
+		if RegisterEventID(id, "") == nil {
+			t.Errorf("%q is registered", id)
+		}
+	}
+
+	SetLogLevel("INFO", FuncNameModeNone)
+
+	for i := 0; i < 3; i++ {
+		MessageID("DB-CONN-FAIL", ERR, "failed")
+	}
+	MessageID("CACHE-MISS", INFO, "miss")
+	MessageID("CACHE-MISS", DEBUG, "filtered by the level")
+	MessageID("bad id", INFO, "invalid")
+
+	expected := []EventInfo{
+		{ID: "CACHE-MISS", Count: 1},
+		{ID: "DB-CONN-FAIL", Description: "database connection failed", Registered: true, Count: 3},
+	}
+
+	st := Status()
+	if len(st.Events) != len(expected) {
+		t.Fatalf("got %+v", st.Events)
+	}
+	for i, e := range expected {
+		if st.Events[i] != e {
+			t.Errorf("[%d] got %+v, expected %+v", i, st.Events[i], e)
+		}
+	}
+	if st.UnregisteredEvents != 1 || UnregisteredEvents() != 1 {
+		t.Errorf("got %d unregistered events, expected 1", st.UnregisteredEvents)
+	}
+}
+
+func TestEventIDSuppression(t *testing.T) {
+	console := resetLog(t)
+
+	setFakeClock(time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC))
+
+	SetStormProtection(5, time.Minute, StormDropDuplicates)
+
+	// Different texts with the same ID are duplicates during the storm, only the first one of the storm passes
+	for i := 0; i < 20; i++ {
+		MessageID("RETRY", WARNING, "retry %d failed", i)
+	}
+
+	if n := strings.Count(console.String(), "{RETRY} retry"); n != 6 {
+		t.Errorf("got %d messages, expected 6:\n%s", n, console)
+	}
+
+	console = resetLog(t)
+	clock := setFakeClock(time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC))
+
+	SetConsoleDeduplication(5 * time.Second)
+
+	for i := 0; i < 10; i++ {
+		MessageID("RETRY", WARNING, "retry %d failed", i)
+		clock.Add(10 * time.Millisecond)
+	}
+	Message(WARNING, "retry failed")
+	Message(WARNING, "retry failed")
+
+	expected := []string{
+		" {RETRY} retry 0 failed",
+		" (repeated 9× in 90ms)",
+		" retry failed",
+	}
+
+	lines := console.Lines()
+	if len(lines) != len(expected) {
+		t.Fatalf("got %d console lines, expected %d:\n%s", len(lines), len(expected), console)
+	}
+	for i, e := range expected {
+		if !strings.HasSuffix(lines[i], e) {
+			t.Errorf("[%d] got %q, expected suffix %q", i, lines[i], e)
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,180 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// The exclusive file protects from two instances writing to the same daily files. The directory and the suffix are
+// owned by the process holding the advisory lock (flock, LockFileEx) of the "<suffix>.log.lock" file next to the logs,
+// the file keeps the pid of the owner. The lock is taken when the file is set and when it is opened again after
+// Shutdown, it is released by Shutdown, by the change of the file and by the OS when the process exits, so the lock
+// file left by the crashed process doesn't block. Where there is no advisory lock the recorded pid is probed.
+// If the files are owned by another process, SetFileChecked and MoveTo fail with ErrFileLocked or the pid is appended
+// to the suffix, depending on the policy. Off by default.
+
+// ExclusivePolicy -- what to do if the log files are owned by another process
+type ExclusivePolicy int
+
+const (
+	// ExclusiveFail -- don't set the file
+	ExclusiveFail ExclusivePolicy = iota
+	// ExclusivePidSuffix -- append the pid to the suffix
+	ExclusivePidSuffix
+)
+
+// fileLock -- the held lock of the file name pattern
+type fileLock struct {
+	pattern string
+	path    string
+	fd      *os.File
+}
+
+var (
+	// ErrFileLocked -- the log files are owned by another process
+	ErrFileLocked = errors.New("log file is owned by another process")
+
+	errLockBusy = errors.New("lock is busy")
+
+	exclusiveFile   = false
+	exclusivePolicy = ExclusiveFail
+	exclusiveLock   *fileLock
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetExclusiveFile -- own the log files by the advisory lock, the policy is applied if another process owns them.
+// It takes effect for the next SetFile.
+func SetExclusiveFile(enabled bool, policy ExclusivePolicy) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	exclusiveFile = enabled
+	exclusivePolicy = policy
+
+	if !enabled {
+		releaseExclusive()
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// exclusivePattern -- own the pattern, the pattern with the pid is returned by ExclusivePidSuffix if it is owned by
+// another process. Must be called under the mutex.
+func exclusivePattern(pattern string) (string, error) {
+	if !exclusiveFile || pattern == "" || pattern == "-" {
+		releaseExclusive()
+		return pattern, nil
+	}
+
+	if exclusiveLock != nil && exclusiveLock.pattern == pattern {
+		return pattern, nil
+	}
+
+	lock, err := acquireFileLock(pattern)
+	if errors.Is(err, ErrFileLocked) && exclusivePolicy == ExclusivePidSuffix {
+		pattern = pidPattern(pattern)
+		if exclusiveLock != nil && exclusiveLock.pattern == pattern {
+			return pattern, nil
+		}
+		lock, err = acquireFileLock(pattern)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	releaseExclusive()
+	exclusiveLock = lock
+	return pattern, nil
+}
+
+// releaseExclusive -- release the held lock. The lock file is kept: removed it could be locked by another process
+// after its opening. Must be called under the mutex.
+func releaseExclusive() {
+	if exclusiveLock == nil {
+		return
+	}
+
+	exclusiveLock.fd.Close()
+	exclusiveLock = nil
+}
+
+// acquireFileLock -- lock the lock file of the pattern and write the pid into it
+func acquireFileLock(pattern string) (*fileLock, error) {
+	path := lockFilePath(pattern)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	fd, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	err = lockFile(fd)
+	if errors.Is(err, errors.ErrUnsupported) {
+		err = nil
+		if owner := lockOwner(fd); owner > 0 && owner != pid && processAlive(owner) {
+			err = errLockBusy
+		}
+	}
+
+	if err != nil {
+		owner := lockOwner(fd)
+		fd.Close()
+
+		if !errors.Is(err, errLockBusy) {
+			return nil, fmt.Errorf("lock %s: %w", path, err)
+		}
+		if owner > 0 {
+			return nil, fmt.Errorf("%w: %s is held by pid %d", ErrFileLocked, path, owner)
+		}
+		return nil, fmt.Errorf("%w: %s is held", ErrFileLocked, path)
+	}
+
+	if err = fd.Truncate(0); err == nil {
+		_, err = fd.WriteAt([]byte(strconv.Itoa(pid)+"\n"), 0)
+	}
+	if err != nil {
+		fd.Close()
+		return nil, fmt.Errorf("lock %s: %w", path, err)
+	}
+
+	return &fileLock{pattern: pattern, path: path, fd: fd}, nil
+}
+
+// lockOwner -- the pid written into the lock file, 0 if unknown
+func lockOwner(fd *os.File) int {
+	buf := make([]byte, 32)
+	n, _ := fd.ReadAt(buf, 0)
+	owner, _ := strconv.Atoi(strings.TrimSpace(string(buf[:n])))
+	return owner
+}
+
+// lockFilePath -- "<suffix>.log.lock" ("log.lock" without the suffix) in the directory of the pattern
+func lockFilePath(pattern string) string {
+	dir, name := filepath.Split(pattern)
+	name = strings.TrimLeft(strings.Replace(name, "%s", "", 1), "-.")
+	if name == "" {
+		name = "log"
+	}
+	return filepath.Join(dir, name+".lock")
+}
+
+// pidPattern -- the pattern with the pid appended to the suffix
+func pidPattern(pattern string) string {
+	i := strings.LastIndex(pattern, ".log")
+	if i < 0 {
+		return pattern + "-" + strconv.Itoa(pid)
+	}
+	return pattern[:i] + "-" + strconv.Itoa(pid) + pattern[i:]
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
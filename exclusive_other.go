@@ -0,0 +1,21 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly || windows)
+
+package log
+
+import (
+	"errors"
+	"os"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func lockFile(fd *os.File) error {
+	return errors.ErrUnsupported
+}
+
+// processAlive -- the process is considered existing, the lock file is replaced only if it is empty
+func processAlive(pid int) bool {
+	return true
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
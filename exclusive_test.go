@@ -0,0 +1,177 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestExclusiveFileNames(t *testing.T) {
+	list := []struct{ pattern, lock, withPid string }{
+		{"/var/log/app/%s.log", "/var/log/app/log.lock", "/var/log/app/%s-" + strconv.Itoa(pid) + ".log"},
+		{"/var/log/app/%s-api.log.gz", "/var/log/app/api.log.gz.lock", "/var/log/app/%s-api-" + strconv.Itoa(pid) + ".log.gz"},
+	}
+
+	for _, c := range list {
+		if s := lockFilePath(c.pattern); s != filepath.FromSlash(c.lock) {
+			t.Errorf("%s: got the lock %s, expected %s", c.pattern, s, c.lock)
+		}
+		if s := pidPattern(c.pattern); s != c.withPid {
+			t.Errorf("%s: got the pattern %s, expected %s", c.pattern, s, c.withPid)
+		}
+	}
+}
+
+func TestExclusiveFile(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" && runtime.GOOS != "windows" {
+		t.Skip("no advisory lock on", runtime.GOOS)
+	}
+
+	resetLog(t)
+	dir := t.TempDir()
+	_, pattern := filePattern(dir, "api")
+
+	// The second instance is simulated by another lock of the same process with the pid of the other instance
+	other, err := acquireFileLock(pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other.fd.WriteAt([]byte("4242\n"), 0)
+
+	SetExclusiveFile(true, ExclusiveFail)
+
+	err = SetFileChecked(FileOptions{Directory: dir, Suffix: "api"})
+	if !errors.Is(err, ErrFileLocked) || !strings.Contains(err.Error(), "pid 4242") {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if LastError() != err || FileName() != "" {
+		t.Errorf("the file is set: %v %q", LastError(), FileName())
+	}
+
+	SetExclusiveFile(true, ExclusivePidSuffix)
+
+	if err := SetFileChecked(FileOptions{Directory: dir, Suffix: "api"}); err != nil {
+		t.Fatal(err)
+	}
+	Message(INFO, "own file")
+	writerFlush()
+
+	if name := filepath.Base(FileName()); !strings.HasSuffix(name, "-api-"+strconv.Itoa(pid)+".log") {
+		t.Errorf("unexpected file %s", name)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "api-"+strconv.Itoa(pid)+".log.lock")); string(data) != strconv.Itoa(pid)+"\n" {
+		t.Errorf("unexpected lock file %q", data)
+	}
+
+	// The crashed instance left the lock file with its pid, the OS released the lock
+	other.fd.Close()
+
+	if err := SetFileChecked(FileOptions{Directory: dir, Suffix: "api"}); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(lockFilePath(pattern)); string(data) != strconv.Itoa(pid)+"\n" {
+		t.Errorf("unexpected lock file %q", data)
+	}
+
+	// Now the lock is held by this instance
+	if _, err := acquireFileLock(pattern); !errors.Is(err, ErrFileLocked) {
+		t.Errorf("the lock is not held: %v", err)
+	}
+
+	// Released by Shutdown and taken again by the next opening
+	Message(INFO, "before shutdown")
+	if err := Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := acquireFileLock(pattern)
+	if err != nil {
+		t.Fatalf("the lock is not released: %v", err)
+	}
+	l.fd.Close()
+
+	Start()
+	Message(INFO, "after start")
+	writerFlush()
+
+	if _, err := acquireFileLock(pattern); !errors.Is(err, ErrFileLocked) {
+		t.Errorf("the lock is not taken again: %v", err)
+	}
+
+	// MoveTo fails if the new directory is owned
+	dir2 := t.TempDir()
+	_, pattern2 := filePattern(dir2, "api")
+	other, err = acquireFileLock(pattern2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.fd.Close()
+
+	SetExclusiveFile(true, ExclusiveFail)
+	if err := MoveTo(dir2, "api"); !errors.Is(err, ErrFileLocked) {
+		t.Errorf("unexpected error %v", err)
+	}
+	if !strings.HasPrefix(FileName(), dir) {
+		t.Errorf("the file is moved to %s", FileName())
+	}
+
+	// Off the lock is released
+	SetExclusiveFile(false, ExclusiveFail)
+	l, err = acquireFileLock(pattern)
+	if err != nil {
+		t.Fatalf("the lock is not released: %v", err)
+	}
+	l.fd.Close()
+}
+
+func TestExclusiveFileAsyncOpen(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" && runtime.GOOS != "windows" {
+		t.Skip("no advisory lock on", runtime.GOOS)
+	}
+
+	resetLog(t)
+	setFakeClock(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+	dir := t.TempDir()
+	_, pattern := filePattern(dir, "api")
+
+	SetExclusiveFile(true, ExclusivePidSuffix)
+	if err := SetFileChecked(FileOptions{Directory: dir, Suffix: "api"}); err != nil {
+		t.Fatal(err)
+	}
+	SetAsyncFileOpen(true)
+
+	Message(INFO, "before shutdown")
+	if err := Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another instance takes the released lock
+	other, err := acquireFileLock(pattern)
+	if err != nil {
+		t.Fatalf("the lock is not released: %v", err)
+	}
+	defer other.fd.Close()
+	other.fd.WriteAt([]byte("4242\n"), 0)
+
+	Start()
+	Message(INFO, "after start")
+
+	lines := waitFile(t, fmt.Sprintf(pidPattern(pattern), "2024-05-01"), 2)
+	if !strings.HasSuffix(lines[len(lines)-1], " after start") {
+		t.Errorf("unexpected content %q", lines)
+	}
+	if _, err := acquireFileLock(pidPattern(pattern)); !errors.Is(err, ErrFileLocked) {
+		t.Errorf("the lock is not taken: %v", err)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
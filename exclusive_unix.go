@@ -0,0 +1,28 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package log
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// lockFile -- the exclusive flock of the file, errLockBusy if another open file holds it
+func lockFile(fd *os.File) error {
+	err := syscall.Flock(int(fd.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return errLockBusy
+	}
+	return err
+}
+
+// processAlive -- does the process exist
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,52 @@
+//go:build windows
+
+package log
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+const (
+	lockfileFailImmediately = 0x1
+	lockfileExclusiveLock   = 0x2
+
+	errorLockViolation syscall.Errno = 33
+)
+
+var (
+	procLockFileEx = syscall.NewLazyDLL("kernel32.dll").NewProc("LockFileEx")
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// lockFile -- the exclusive LockFileEx of the byte beyond 4GB, so the pid stays readable by others.
+// errLockBusy if another handle holds it.
+func lockFile(fd *os.File) error {
+	ol := syscall.Overlapped{OffsetHigh: 1}
+
+	r, _, err := procLockFileEx.Call(fd.Fd(), lockfileExclusiveLock|lockfileFailImmediately, 0, 1, 0, uintptr(unsafe.Pointer(&ol)))
+	if r != 0 {
+		return nil
+	}
+	if errors.Is(err, errorLockViolation) {
+		return errLockBusy
+	}
+	return err
+}
+
+// processAlive -- does the process exist
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	p.Release()
+	return true
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,127 @@
+package log
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// The cap protects from facilities created from unbounded input (one per request path for example). When the number
+// of facilities reaches the cap, GetFacility and NewFacility return the standard facility for unknown names, existing
+// facilities are not affected. Rejections are counted, the first names are reported once with WARNING when there are
+// enough of them or by the flusher. The creation time of every facility is kept to find the source of the explosion.
+// No cap by default.
+
+// FacilityInfo -- the facility with its creation time
+type FacilityInfo struct {
+	Name    string    `json:"name"`
+	Created time.Time `json:"created"`
+}
+
+const (
+	rejectedNamesCount = 5
+)
+
+var (
+	maxFacilities    = 0
+	rejectedCount    = int64(0)
+	rejectedNames    []string
+	rejectedReported = false
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetMaxFacilities -- the maximal number of facilities including the standard one, 0 means no cap
+func SetMaxFacilities(n int) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	maxFacilities = n
+	rejectedNames = nil
+	rejectedReported = false
+}
+
+// FacilityCount -- number of facilities including the standard one
+func FacilityCount() int {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	return len(facilities)
+}
+
+// RejectedFacilities -- number of requests of unknown facilities rejected by the cap
+func RejectedFacilities() int64 {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	return rejectedCount
+}
+
+// RecentFacilities -- facilities created during the period, the oldest first
+func RecentFacilities(period time.Duration) []FacilityInfo {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	since := now().Add(-period)
+
+	list := []FacilityInfo{}
+	for name, f := range facilities {
+		if !f.created.Before(since) {
+			list = append(list, FacilityInfo{Name: name, Created: f.created})
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].Created.Equal(list[j].Created) {
+			return list[i].Created.Before(list[j].Created)
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// cappedFacility -- the standard facility if the new facility exceeds the cap. Must be called under the mutex.
+func cappedFacility(name string) *Facility {
+	if maxFacilities <= 0 || len(facilities) < maxFacilities {
+		return nil
+	}
+
+	if _, exists := facilities[name]; exists {
+		return nil
+	}
+
+	rejectedCount++
+	if len(rejectedNames) < rejectedNamesCount {
+		rejectedNames = append(rejectedNames, name)
+		if len(rejectedNames) == rejectedNamesCount {
+			reportRejected()
+		}
+	}
+
+	return stdFacility
+}
+
+// facilityCapTick -- called by the flusher to report the first rejected names
+func facilityCapTick() {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	reportRejected()
+}
+
+// reportRejected -- report the first rejected names once. Must be called under the mutex.
+func reportRejected() {
+	if rejectedReported || len(rejectedNames) == 0 {
+		return
+	}
+
+	rejectedReported = true
+	logger(false, 0, StdFacilityName, WARNING, nil, `Facility cap %d is reached, new facilities are replaced by the standard one: "%s"`,
+		maxFacilities, strings.Join(rejectedNames, `", "`))
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,106 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestFacilityCap(t *testing.T) {
+	console := resetLog(t)
+	// The standard facility is created earlier by the real clock
+	clock := setFakeClock(time.Now().Add(24 * time.Hour))
+
+	SetLogLevel("TRACE4", FuncNameModeNone)
+	console.buf.Reset()
+
+	base := FacilityCount()
+
+	old := NewFacility("old")
+	clock.Add(10 * time.Minute)
+
+	SetMaxFacilities(base + 3)
+
+	a := GetFacility("a")
+	b := NewFacility("b")
+	if a == stdFacility || b == stdFacility {
+		t.Fatalf("facilities under the cap are replaced")
+	}
+	if n := FacilityCount(); n != base+3 {
+		t.Fatalf("got %d facilities, expected %d", n, base+3)
+	}
+
+	for i := 0; i < 8; i++ {
+		if f := GetFacility(fmt.Sprintf("path%d", i)); f != stdFacility {
+			t.Errorf("[%d] got %q over the cap", i, f.Name())
+		}
+	}
+	if f := NewFacility("extra"); f != stdFacility {
+		t.Errorf("NewFacility got %q over the cap", f.Name())
+	}
+
+	// Existing facilities are not affected
+	if GetFacility("old") != old || NewFacility("a") != a {
+		t.Errorf("existing facilities are replaced")
+	}
+
+	if n := RejectedFacilities(); n != 9 {
+		t.Errorf("got %d rejected, expected 9", n)
+	}
+	if n := FacilityCount(); n != base+3 {
+		t.Errorf("got %d facilities after the rejections", n)
+	}
+
+	facilityCapTick()
+
+	warnings := 0
+	for _, line := range console.Lines() {
+		if strings.Contains(line, "Facility cap") {
+			warnings++
+			if !strings.Contains(line, `"path0", "path1", "path2", "path3", "path4"`) || strings.Contains(line, "path5") {
+				t.Errorf("unexpected warning %q", line)
+			}
+		}
+	}
+	if warnings != 1 {
+		t.Errorf("got %d warnings, expected 1\n%s", warnings, console.String())
+	}
+
+	recent := RecentFacilities(5 * time.Minute)
+	if len(recent) != 2 || recent[0].Name != "a" || recent[1].Name != "b" {
+		t.Errorf("unexpected recent facilities %v", recent)
+	}
+	if len(RecentFacilities(time.Hour)) != 3 {
+		t.Errorf("the old facility is not listed")
+	}
+
+	SetMaxFacilities(0)
+	if f := GetFacility("path0"); f == stdFacility {
+		t.Errorf("the facility is replaced without the cap")
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestFacilityCapLateWarning(t *testing.T) {
+	console := resetLog(t)
+
+	SetMaxFacilities(FacilityCount())
+	GetFacility("lonely")
+
+	if strings.Contains(console.String(), "Facility cap") {
+		t.Fatalf("warning before the tick")
+	}
+
+	facilityCapTick()
+	facilityCapTick()
+
+	if n := strings.Count(console.String(), `Facility cap`); n != 1 {
+		t.Errorf("got %d warnings, expected 1\n%s", n, console.String())
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
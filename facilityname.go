@@ -0,0 +1,76 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// The facility name is written inside "<" and ">" and the source of MessageWithSource inside "[" and "]", so they
+// must not break the line format or start a fake line. The facility name can have letters, digits, ".", "-", "_" and
+// "/" only, other runes are replaced by "_" and the replacement is reported once with WARNING. NewFacilityStrict returns
+// an error instead. In the source "]", CR and LF are replaced by "_".
+
+var (
+	// ErrInvalidFacilityName -- the facility name has runes not allowed
+	ErrInvalidFacilityName = errors.New("invalid facility name")
+
+	sanitizedWarned = map[string]bool{}
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// NewFacilityStrict -- NewFacility with the error for the name with runes not allowed
+func NewFacilityStrict(name string) (*Facility, error) {
+	if !validFacilityName(name) {
+		return nil, fmt.Errorf(`%w "%s"`, ErrInvalidFacilityName, name)
+	}
+	return NewFacility(name), nil
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func validFacilityName(name string) bool {
+	return strings.IndexFunc(name, invalidFacilityRune) < 0
+}
+
+func invalidFacilityRune(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune(".-_/", r)
+}
+
+// facilityName -- the name with runes not allowed replaced by "_". Must be called under the mutex.
+func facilityName(name string) string {
+	if validFacilityName(name) {
+		return name
+	}
+
+	safe := strings.Map(
+		func(r rune) rune {
+			if invalidFacilityRune(r) {
+				return '_'
+			}
+			return r
+		},
+		name,
+	)
+
+	if !sanitizedWarned[name] {
+		sanitizedWarned[name] = true
+		logger(false, 0, StdFacilityName, WARNING, nil, `Facility name %q is replaced by "%s"`, name, safe)
+	}
+
+	return safe
+}
+
+// sourceName -- the source of MessageWithSource without "]" and line breaks
+func sourceName(source string) string {
+	if !strings.ContainsAny(source, "]\r\n") {
+		return source
+	}
+	return strings.NewReplacer("]", "_", "\r", "_", "\n", "_").Replace(source)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,89 @@
+package log
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestFacilityNameInjection(t *testing.T) {
+	console := resetLog(t)
+
+	f := GetFacility("x> [1] EM fake")
+	if f.Name() != "x___1__EM_fake" {
+		t.Fatalf("unexpected name %q", f.Name())
+	}
+	if NewFacility("x> [1] EM fake") != f || GetFacility("x___1__EM_fake") != f {
+		t.Errorf("the sanitized name refers to another facility")
+	}
+
+	f.Message(INFO, "text")
+	GetFacility("plugin\n[1] EM 2024-05-03 12:00:00.000 fake%s").Message(INFO, "text")
+
+	for _, name := range []string{"db", "api.v2", "plugin/sub-x_1", "модуль"} {
+		if g := GetFacility(name); g.Name() != name {
+			t.Errorf("%q is changed to %q", name, g.Name())
+		}
+	}
+
+	lines := console.Lines()
+	if len(lines) != 4 {
+		t.Fatalf("got %d lines:\n%s", len(lines), console)
+	}
+
+	if !strings.Contains(lines[0], " WA ") || !strings.HasSuffix(lines[0], ` Facility name "x> [1] EM fake" is replaced by "x___1__EM_fake"`) {
+		t.Errorf("unexpected warning %q", lines[0])
+	}
+	for _, line := range lines {
+		info, ok := ParseLine(line)
+		if !ok {
+			t.Fatalf("can't parse %q", line)
+		}
+		if info.Level == EMERG || strings.ContainsAny(info.Facility, "<> []%") {
+			t.Errorf("fake line %q", line)
+		}
+	}
+	if info, _ := ParseLine(lines[1]); info.Facility != "x___1__EM_fake" || info.Text != "text" {
+		t.Errorf("unexpected line %+v", info)
+	}
+
+	// Warned once
+	GetFacility("x> [1] EM fake").Message(INFO, "again")
+	if n := strings.Count(console.String(), "is replaced by"); n != 2 {
+		t.Errorf("got %d warnings, expected 2", n)
+	}
+}
+
+func TestNewFacilityStrict(t *testing.T) {
+	resetLog(t)
+
+	for _, name := range []string{"a>b", "a%d", "a\nb", "a b", "a]"} {
+		if f, err := NewFacilityStrict(name); !errors.Is(err, ErrInvalidFacilityName) || f != nil {
+			t.Errorf("%q is accepted", name)
+		}
+	}
+
+	f, err := NewFacilityStrict("plugin/a-b_c.d")
+	if err != nil || f.Name() != "plugin/a-b_c.d" {
+		t.Errorf("unexpected result %v, %v", f, err)
+	}
+}
+
+func TestSourceLineBreaks(t *testing.T) {
+	console := resetLog(t)
+
+	MessageWithSource(INFO, "src] fake\n[1] EM 2024-05-03 12:00:00.000 x", "text %d", 1)
+	MessageWithSource(INFO, "100%", "plain")
+
+	lines := console.Lines()
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines:\n%s", len(lines), console)
+	}
+	if !strings.HasSuffix(lines[0], " [src_ fake_[1_ EM 2024-05-03 12:00:00.000 x] text 1") || !strings.HasSuffix(lines[1], " [100%] plain") {
+		t.Errorf("unexpected lines:\n%s", console)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
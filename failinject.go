@@ -0,0 +1,228 @@
+package log
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// The failure injector makes the file and console output fail on demand to test the error handling of the application.
+// Injected failures take the same way as the real ones: the write error is reported by LastError and the lines go to
+// the memory until the file is reopened, the open error switches to the fallback directory. Off by default, the file
+// output isn't wrapped at all while no injector is set.
+
+// FailureInjector -- decides the failures of the output operations, methods are called without the package mutex
+// for opening and under it for writing
+type FailureInjector interface {
+	// FileWrite -- delay and the error of the write into the log file, nil error means the write is done
+	FileWrite() (delay time.Duration, err error)
+	// FileOpen -- the error of the opening of the log file with the name
+	FileOpen(name string) error
+	// ConsoleDrop -- true if the console line is dropped
+	ConsoleDrop() bool
+}
+
+// FailureOp -- the operation of the ScriptedInjector step
+type FailureOp int
+
+const (
+	// FailWrite -- the file writes return the error
+	FailWrite FailureOp = iota
+	// DelayWrite -- the file writes are delayed
+	DelayWrite
+	// FailOpen -- the file opening returns the error
+	FailOpen
+	// DropConsole -- the console lines are dropped
+	DropConsole
+)
+
+// FailureStep -- the step of the ScriptedInjector
+type FailureStep struct {
+	Op    FailureOp
+	Count int           // number of the operations affected by the step, 0 means 1, negative means all
+	Err   error         // nil means ErrInjected
+	Delay time.Duration // DelayWrite only
+}
+
+// ScriptedInjector -- the injector that takes the steps in order. Steps of different operations are independent,
+// the operation without the step isn't affected.
+type ScriptedInjector struct {
+	mutex sync.Mutex
+	steps []FailureStep
+}
+
+type injectorHolder struct {
+	fi FailureInjector
+}
+
+type injectedFile struct {
+	io.WriteCloser
+	fi FailureInjector
+}
+
+var (
+	// ErrInjected -- the default error of the injected failure
+	ErrInjected = errors.New("injected failure")
+
+	failureInjector atomic.Pointer[injectorHolder]
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetFailureInjection -- use the injector for the file and console output, nil turns the injection off.
+// The current file is reopened to apply the injector.
+func SetFailureInjection(fi FailureInjector) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	flushOpenPending()
+
+	if fi == nil {
+		failureInjector.Store(nil)
+	} else {
+		failureInjector.Store(&injectorHolder{fi: fi})
+	}
+
+	if file != nil {
+		closeLogFile()
+		lastWriteDate = ""
+	}
+}
+
+func currentInjector() FailureInjector {
+	if h := failureInjector.Load(); h != nil {
+		return h.fi
+	}
+	return nil
+}
+
+// injectFailures -- wrap the file output if the injector is set
+func injectFailures(w io.WriteCloser) io.WriteCloser {
+	fi := currentInjector()
+	if fi == nil {
+		return w
+	}
+	return injectedFile{WriteCloser: w, fi: fi}
+}
+
+// injectedOpen -- the error of the opening injected for the name
+func injectedOpen(name string) error {
+	if fi := currentInjector(); fi != nil {
+		return fi.FileOpen(name)
+	}
+	return nil
+}
+
+// consoleDropped -- the console line is dropped by the injector
+func consoleDropped() bool {
+	fi := currentInjector()
+	return fi != nil && fi.ConsoleDrop()
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func (f injectedFile) Write(p []byte) (int, error) {
+	delay, err := f.fi.FileWrite()
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return f.WriteCloser.Write(p)
+}
+
+func (f injectedFile) Flush() error {
+	if s, ok := f.WriteCloser.(streamFlusher); ok {
+		return s.Flush()
+	}
+	return nil
+}
+
+func (f injectedFile) Sync() error {
+	if s, ok := f.WriteCloser.(interface{ Sync() error }); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// NewScriptedInjector -- the injector with the steps
+func NewScriptedInjector(steps ...FailureStep) *ScriptedInjector {
+	return &ScriptedInjector{steps: append([]FailureStep(nil), steps...)}
+}
+
+// Remaining -- number of the steps not completed yet
+func (s *ScriptedInjector) Remaining() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return len(s.steps)
+}
+
+// take -- the current step of one of the operations, the step is counted
+func (s *ScriptedInjector) take(ops ...FailureOp) (step FailureStep, ok bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for i := range s.steps {
+		st := &s.steps[i]
+		if st.Op != ops[0] && (len(ops) == 1 || st.Op != ops[1]) {
+			continue
+		}
+
+		step = *st
+		if st.Count >= 0 {
+			if st.Count <= 1 {
+				s.steps = append(s.steps[:i], s.steps[i+1:]...)
+			} else {
+				st.Count--
+			}
+		}
+		return step, true
+	}
+
+	return
+}
+
+func (step FailureStep) err() error {
+	if step.Err != nil {
+		return step.Err
+	}
+	return ErrInjected
+}
+
+// FileWrite -- FailureInjector interface
+func (s *ScriptedInjector) FileWrite() (time.Duration, error) {
+	step, ok := s.take(FailWrite, DelayWrite)
+	if !ok {
+		return 0, nil
+	}
+
+	if step.Op == DelayWrite {
+		return step.Delay, nil
+	}
+	return 0, step.err()
+}
+
+// FileOpen -- FailureInjector interface
+func (s *ScriptedInjector) FileOpen(name string) error {
+	step, ok := s.take(FailOpen)
+	if !ok {
+		return nil
+	}
+	return step.err()
+}
+
+// ConsoleDrop -- FailureInjector interface
+func (s *ScriptedInjector) ConsoleDrop() bool {
+	_, ok := s.take(DropConsole)
+	return ok
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
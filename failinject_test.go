@@ -0,0 +1,149 @@
+package log
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestFailureInjectionWrite(t *testing.T) {
+	console := resetLog(t)
+	setFakeClock(time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC))
+
+	SetFile(t.TempDir(), "", false, 0, 0)
+	Message(INFO, "first")
+
+	fi := NewScriptedInjector(FailureStep{Op: FailWrite, Count: 2})
+	SetFailureInjection(fi)
+
+	Message(INFO, "second")
+	Message(INFO, "third")
+
+	if err := LastError(); !errors.Is(err, ErrInjected) {
+		t.Errorf("unexpected last error %v", err)
+	}
+
+	Message(INFO, "fourth")
+
+	if n := fi.Remaining(); n != 0 {
+		t.Errorf("%d steps are left", n)
+	}
+
+	data, err := os.ReadFile(FileName())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := string(data)
+	prev := -1
+	for _, m := range []string{" first\n", " second\n", " third\n", " fourth\n"} {
+		i := strings.Index(s, m)
+		if i <= prev {
+			t.Fatalf("%q is lost or misordered:\n%s", m, s)
+		}
+		prev = i
+	}
+
+	if n := strings.Count(console.String(), "Log file write error (injected failure)"); n != 1 {
+		t.Errorf("the write error is reported %d times:\n%s", n, console)
+	}
+}
+
+func TestFailureInjectionOpen(t *testing.T) {
+	resetLog(t)
+	setFakeClock(time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC))
+
+	SetFailureInjection(NewScriptedInjector(FailureStep{Op: FailOpen}))
+	SetFile(t.TempDir(), "", false, 0, 0)
+
+	Message(INFO, "to fallback")
+
+	if !strings.HasPrefix(FileName(), fallbackDirectory) || Status().Tier != TierFallback {
+		t.Fatalf("unexpected file %s", FileName())
+	}
+	if err := LastError(); !errors.Is(err, ErrInjected) {
+		t.Errorf("unexpected last error %v", err)
+	}
+
+	data, _ := os.ReadFile(FileName())
+	if !strings.Contains(string(data), " to fallback\n") {
+		t.Errorf("unexpected fallback content:\n%s", data)
+	}
+}
+
+func TestFailureInjectionFlush(t *testing.T) {
+	console := resetLog(t)
+	setFakeClock(time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC))
+
+	// The flusher would write the buffer before the explicit flush
+	if BackgroundRunning() {
+		StopBackground()
+		t.Cleanup(StartBackground)
+	}
+
+	SetFile(t.TempDir(), "", false, 4096, 0)
+	SetFailureInjection(NewScriptedInjector(FailureStep{Op: FailWrite, Err: os.ErrDeadlineExceeded}))
+
+	Message(INFO, "buffered")
+
+	if err := LastError(); err != nil {
+		t.Fatalf("unexpected error before the flush: %v", err)
+	}
+
+	writerFlush()
+
+	if err := LastError(); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("the flush error isn't reported: %v", err)
+	}
+	if !strings.Contains(console.String(), " CR ") {
+		t.Errorf("no CRIT on the console:\n%s", console)
+	}
+
+	Message(INFO, "after")
+	writerFlush()
+
+	data, _ := os.ReadFile(FileName())
+	if !strings.Contains(string(data), " after\n") {
+		t.Errorf("the file isn't reopened:\n%s", data)
+	}
+}
+
+func TestFailureInjectionDelayAndConsole(t *testing.T) {
+	console := resetLog(t)
+	setFakeClock(time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC))
+
+	SetFile(t.TempDir(), "", false, 0, 0)
+	Message(INFO, "opened")
+
+	SetFailureInjection(NewScriptedInjector(
+		FailureStep{Op: DelayWrite, Count: 2, Delay: 20 * time.Millisecond},
+		FailureStep{Op: DropConsole},
+	))
+
+	start := time.Now()
+	Message(INFO, "dropped")
+	if d := time.Since(start); d < 40*time.Millisecond {
+		t.Errorf("the writes aren't delayed: %s", d)
+	}
+
+	Message(INFO, "shown")
+
+	s := console.String()
+	if strings.Contains(s, " dropped\n") || !strings.Contains(s, " shown\n") {
+		t.Errorf("unexpected console:\n%s", s)
+	}
+	if err := LastError(); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	data, _ := os.ReadFile(FileName())
+	if !strings.Contains(string(data), " dropped\n") {
+		t.Errorf("the delayed line is lost:\n%s", data)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,203 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// If the log file can't be opened the fallback directory is tried, if it fails too the lines are kept in the memory ring
+// until some file is opened. The primary directory is tried again on every rotation.
+
+const (
+	// TierPrimary -- the file is in the configured directory
+	TierPrimary = "primary"
+	// TierFallback -- the file is in the fallback directory
+	TierFallback = "fallback"
+	// TierMemory -- no file can be opened, lines are kept in the memory
+	TierMemory = "memory"
+)
+
+const openRetryPeriod = 5 * time.Second
+
+var (
+	fallbackDirectory = ""
+	fallbackBufSize   = 10000
+	fallbackBuf       = []string{}
+
+	fileTier        = TierPrimary
+	lastError       error
+	lastOpenAttempt time.Time
+	lastOpenDate    string
+
+	writeBroken  bool  // the file write failed, the file is to be reopened
+	writeFailing bool  // the write error is reported, reset by the successful write
+	fileWriteErr error // the error of the flush, guarded by fileWriterMutex
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetFallbackDirectory -- directory used when the log file can't be opened. Empty means os.TempDir().
+func SetFallbackDirectory(directory string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	fallbackDirectory = directory
+}
+
+// SetFallbackBufferSize -- number of lines kept in the memory when no file can be opened
+func SetFallbackBufferSize(size int) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if size <= 0 {
+		size = beforeFileBufSize
+	}
+	fallbackBufSize = size
+}
+
+// LastError -- the last error of the file output
+func LastError() error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	checkWriteError()
+	return lastError
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// openedFile -- result of the file opening, tier is the one to be set
+type openedFile struct {
+	name   string
+	file   *os.File
+	cipher *fileCipher
+	tier   string
+	err    error
+}
+
+// openFile -- opening function, replaced in tests
+var openFile = openFileInDir
+
+// openFileWithFallback -- must be called under the mutex
+func openFileWithFallback(name string) openedFile {
+	o := tryOpenFile(fileDirectory, name, fallbackDirectory, encryptionKey)
+	setFileTier(o.tier, o.name, o.err)
+	return o
+}
+
+// tryOpenFile -- open the file in the directory or in the fallback one, the package state isn't used
+func tryOpenFile(directory string, name string, fbDirectory string, key []byte) openedFile {
+	f, c, err := openEncryptedFile(directory, name, key)
+	if err == nil {
+		return openedFile{name: name, file: f, cipher: c, tier: TierPrimary}
+	}
+
+	if fbDirectory == "" {
+		fbDirectory = os.TempDir()
+	}
+
+	fbName := filepath.Join(fbDirectory, filepath.Base(name))
+	f, c, fbErr := openEncryptedFile(fbDirectory, fbName, key)
+	if fbErr == nil {
+		return openedFile{name: fbName, file: f, cipher: c, tier: TierFallback, err: err}
+	}
+
+	return openedFile{name: name, tier: TierMemory, err: fmt.Errorf("%s; fallback: %s", err, fbErr)}
+}
+
+// openEncryptedFile -- the file is refused if its encryption doesn't match the key
+func openEncryptedFile(dir string, name string, key []byte) (*os.File, *fileCipher, error) {
+	if err := injectedOpen(name); err != nil {
+		return nil, nil, err
+	}
+
+	f, err := openFile(dir, name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c, err := prepareEncryption(f, key)
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+
+	return f, c, nil
+}
+
+func openFileInDir(dir string, name string) (*os.File, error) {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		os.MkdirAll(dir, 0755)
+	}
+
+	return os.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+}
+
+// Must be called under the mutex
+func setFileTier(tier string, name string, err error) {
+	if err != nil {
+		lastError = err
+	}
+
+	if tier == fileTier {
+		return
+	}
+
+	var msg string
+	switch tier {
+	case TierPrimary:
+		msg = fmt.Sprintf("Log file is restored in the primary directory: %s", name)
+		lastError = nil
+	case TierFallback:
+		msg = fmt.Sprintf("Log file can't be opened (%s), using the fallback file %s", err, name)
+	default:
+		msg = fmt.Sprintf("Log file can't be opened (%s), lines are kept in the memory (up to %d)", err, fallbackBufSize)
+	}
+
+	fileTier = tier
+	writeToConsole(formatDirectLine(CRIT, msg))
+}
+
+// writeFailed -- the write of the lines into the file failed, the following lines of the real file go to the memory
+// until the file is reopened. Must be called under the mutex.
+func writeFailed(lines int, err error) {
+	lastError = err
+	statDrop()
+	destinationFailed(DestinationFile, lines, err)
+
+	if file != nil {
+		writeBroken = true
+	}
+
+	if !writeFailing {
+		writeFailing = true
+		writeToConsole(formatDirectLine(CRIT, fmt.Sprintf("Log file write error (%s): %s", err, fileName)))
+	}
+}
+
+// checkWriteError -- report the error of the last flush. Must be called under the mutex.
+func checkWriteError() {
+	fileWriterMutex.Lock()
+	err := fileWriteErr
+	fileWriteErr = nil
+	fileWriterMutex.Unlock()
+
+	if err != nil {
+		writeFailed(0, err)
+	}
+}
+
+// Must be called under the mutex
+func fallbackAppend(text string) {
+	if len(fallbackBuf) >= fallbackBufSize {
+		fallbackBuf = fallbackBuf[len(fallbackBuf)-fallbackBufSize+1:]
+		statDrop()
+	}
+	fallbackBuf = append(fallbackBuf, text)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,111 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// brokenDir -- directory which can't be created because its parent is a regular file
+func brokenDir(t *testing.T) (dir string, fix func()) {
+	t.Helper()
+
+	base := t.TempDir()
+	blocker := filepath.Join(base, "logs")
+	if err := os.WriteFile(blocker, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return filepath.Join(blocker, "app"), func() {
+		os.Remove(blocker)
+	}
+}
+
+func TestFallbackDirectory(t *testing.T) {
+	console := resetLog(t)
+
+	clock := setFakeClock(time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC))
+
+	dir, fix := brokenDir(t)
+	SetFile(dir, "", false, 0, 0)
+
+	Message(INFO, "to fallback")
+
+	if !strings.HasPrefix(FileName(), fallbackDirectory) {
+		t.Fatalf("unexpected file %s", FileName())
+	}
+	data, _ := os.ReadFile(FileName())
+	if !strings.Contains(string(data), "to fallback") {
+		t.Errorf("unexpected fallback content:\n%s", data)
+	}
+
+	st := Status()
+	if st.Tier != TierFallback || st.LastError == "" || LastError() == nil {
+		t.Errorf("unexpected status %+v", st)
+	}
+	if !strings.Contains(console.String(), " CR ") {
+		t.Errorf("no CRIT on the console:\n%s", console)
+	}
+
+	fix()
+	clock.Add(24 * time.Hour)
+	Message(INFO, "to primary")
+
+	if FileName() != filepath.Join(dir, "2024-05-04.log") {
+		t.Fatalf("unexpected file %s", FileName())
+	}
+	data, _ = os.ReadFile(FileName())
+	if !strings.Contains(string(data), "to primary") {
+		t.Errorf("unexpected primary content:\n%s", data)
+	}
+	if st := Status(); st.Tier != TierPrimary || LastError() != nil {
+		t.Errorf("unexpected status %+v", st)
+	}
+	if n := strings.Count(console.String(), " CR "); n != 2 {
+		t.Errorf("got %d CRIT transitions, expected 2", n)
+	}
+}
+
+func TestFallbackMemory(t *testing.T) {
+	console := resetLog(t)
+
+	clock := setFakeClock(time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC))
+
+	dir, fix := brokenDir(t)
+	fallbackDirectory, _ = brokenDir(t)
+	SetFallbackBufferSize(3)
+	defer SetFallbackBufferSize(0)
+
+	SetFile(dir, "", false, 0, 0)
+
+	for i := 0; i < 5; i++ {
+		Message(INFO, "kept %d", i)
+		clock.Add(time.Second)
+	}
+
+	if st := Status(); st.Tier != TierMemory {
+		t.Errorf("unexpected tier %s", st.Tier)
+	}
+	if !strings.Contains(console.String(), "lines are kept in the memory") {
+		t.Errorf("no transition message:\n%s", console)
+	}
+
+	fix()
+	clock.Add(24 * time.Hour)
+	Message(INFO, "recovered")
+
+	data, err := os.ReadFile(FileName())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := string(data)
+	if strings.Contains(s, "kept 1") || !strings.Contains(s, "kept 2\n") || !strings.Contains(s, "kept 4\n") || !strings.HasSuffix(s, "recovered\n") {
+		t.Errorf("unexpected content:\n%s", s)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,114 @@
+package log
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alrusov/misc"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// The daily file covers the day of its name from the rotation boundary to the boundary of the next day, so with 06:00
+// the file 2024-05-03 has the lines from 2024-05-03 06:00 to 2024-05-04 06:00. The files are found by the current
+// name pattern with and without the compression extension, the same way as the retention finds them.
+
+// logFileReader -- the reader of the log file closing the file and the decompressor
+type logFileReader struct {
+	io.Reader
+	closers []io.Closer
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// FindLogFiles -- the daily files covering any part of [from, to], the oldest first
+func FindLogFiles(from time.Time, to time.Time) ([]string, error) {
+	mutex.Lock()
+	mode := currentMode()
+	st := currentRetention()
+	mutex.Unlock()
+
+	if mode != ModeFile {
+		return nil, ErrNoLogDirectory
+	}
+
+	files, err := st.dailyFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		if files[i].Date != files[j].Date {
+			return files[i].Date < files[j].Date
+		}
+		return files[i].Name < files[j].Name
+	})
+
+	list := []string{}
+	for _, f := range files {
+		day, err := time.ParseInLocation(misc.DateFormatRev, f.Date, st.now.Location())
+		if err != nil {
+			continue
+		}
+
+		start := day.Add(st.boundary)
+		end := start.AddDate(0, 0, 1)
+		if start.After(to) || !end.After(from) {
+			continue
+		}
+
+		list = append(list, f.Name)
+	}
+
+	return list, nil
+}
+
+// OpenLogReader -- the plain content of the log file, the compressed file is decompressed and the encrypted one is
+// decrypted with the current key. Reading the encrypted file not closed yet ends with ErrEncryptionTruncated.
+func OpenLogReader(path string) (io.ReadCloser, error) {
+	mutex.Lock()
+	key := encryptionKey
+	mutex.Unlock()
+
+	fd, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &logFileReader{closers: []io.Closer{fd}}
+
+	r.Reader, err = decryptingReader(fd, key)
+	if err != nil {
+		fd.Close()
+		return nil, err
+	}
+
+	if strings.HasSuffix(path, CompressionGzip.extension()) {
+		gz, err := gzip.NewReader(r.Reader)
+		if err != nil {
+			fd.Close()
+			return nil, err
+		}
+		r.Reader = gz
+		r.closers = append([]io.Closer{gz}, r.closers...)
+	}
+
+	return r, nil
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func (r *logFileReader) Close() (err error) {
+	for _, c := range r.closers {
+		if e := c.Close(); err == nil {
+			err = e
+		}
+	}
+	return
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,130 @@
+package log
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestFindLogFiles(t *testing.T) {
+	resetLog(t)
+	setFakeClock(time.Date(2024, 5, 4, 12, 0, 0, 0, time.UTC))
+
+	if _, err := FindLogFiles(time.Time{}, time.Now()); !errors.Is(err, ErrNoLogDirectory) {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	dir := t.TempDir()
+	for _, name := range []string{"2024-05-03.log", "2024-05-02.log.gz", "2024-05-01.log", "2024-04-20.log", "2024-05-02-api.log", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("12345"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	SetFile(dir, "", false, 0, 0)
+
+	at := func(day int, hour int, minute int) time.Time {
+		return time.Date(2024, 5, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	cases := []struct {
+		boundary int
+		from     time.Time
+		to       time.Time
+		expected []string
+	}{
+		{0, at(3, 14, 22), at(3, 14, 22), []string{"2024-05-03.log"}},
+		{0, at(2, 0, 0), at(3, 0, 0), []string{"2024-05-02.log.gz", "2024-05-03.log"}},
+		{0, at(1, 23, 59), at(2, 0, 0), []string{"2024-05-01.log", "2024-05-02.log.gz"}},
+		{0, at(1, 0, 0), at(30, 0, 0), []string{"2024-05-01.log", "2024-05-02.log.gz", "2024-05-03.log"}},
+		{0, time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), at(1, 0, 0), []string{"2024-04-20.log", "2024-05-01.log"}},
+		{0, at(5, 0, 0), at(6, 0, 0), []string{}},
+		{6, at(3, 5, 59), at(3, 5, 59), []string{"2024-05-02.log.gz"}},
+		{6, at(3, 6, 0), at(3, 6, 0), []string{"2024-05-03.log"}},
+		{6, at(4, 3, 0), at(4, 5, 0), []string{"2024-05-03.log"}},
+	}
+
+	for i, c := range cases {
+		if err := SetRotationBoundary(c.boundary, 0); err != nil {
+			t.Fatal(err)
+		}
+
+		list, err := FindLogFiles(c.from, c.to)
+		if err != nil {
+			t.Fatalf("[%d] %s", i, err)
+		}
+
+		names := make([]string, len(list))
+		for j, name := range list {
+			names[j] = filepath.Base(name)
+		}
+		if !slices.Equal(names, c.expected) {
+			t.Errorf("[%d] got %q, expected %q", i, names, c.expected)
+		}
+	}
+}
+
+func TestOpenLogReader(t *testing.T) {
+	resetLog(t)
+	setFakeClock(time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC))
+
+	dir := t.TempDir()
+
+	gzName := filepath.Join(dir, "2024-05-02.log.gz")
+	fd, err := os.Create(gzName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(fd)
+	gz.Write([]byte("compressed line\n"))
+	gz.Close()
+	fd.Close()
+
+	SetFile(dir, "", false, 0, 0)
+	if err := SetFileEncryption([]byte("0123456789abcdef"), EncryptionAESGCM); err != nil {
+		t.Fatal(err)
+	}
+	Message(INFO, "encrypted line")
+	writerFlush()
+
+	read := func(name string) string {
+		r, err := OpenLogReader(name)
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		defer r.Close()
+
+		// The open encrypted file has no final chunk yet
+		data, err := io.ReadAll(r)
+		if err != nil && !errors.Is(err, ErrEncryptionTruncated) {
+			t.Fatalf("%s: %s", name, err)
+		}
+		return string(data)
+	}
+
+	if s := read(gzName); s != "compressed line\n" {
+		t.Errorf("got %q", s)
+	}
+
+	raw, _ := os.ReadFile(FileName())
+	if strings.Contains(string(raw), "encrypted line") {
+		t.Fatal("the file isn't encrypted")
+	}
+	if s := read(FileName()); !strings.Contains(s, " encrypted line\n") {
+		t.Errorf("got %q", s)
+	}
+
+	if _, err := OpenLogReader(filepath.Join(dir, "absent.log")); !os.IsNotExist(err) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
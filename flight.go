@@ -0,0 +1,178 @@
+package log
+
+import (
+	"encoding/binary"
+	"io"
+	"os"
+	"time"
+
+	"github.com/alrusov/misc"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// The flight recorder keeps the newest formatted lines in the fixed byte ring. Every line is stored as the record of
+// the 4 byte length and the text, the oldest records are evicted to make room, so the update copies the line only and
+// allocates nothing. The line longer than the ring keeps its beginning. A message of CRIT or more severe dumps the ring
+// to the timestamped file next to the unsaved lines file, not more often than once per the dump interval.
+// Off by default.
+
+// flightRing -- the byte ring of the length-prefixed records
+type flightRing struct {
+	buf   []byte
+	head  int // offset of the oldest record
+	used  int // bytes used by the records
+	count int // number of the records
+}
+
+const (
+	flightHeaderSize = 4
+
+	// DefaultFlightDumpInterval -- the minimal interval between the automatic dumps
+	DefaultFlightDumpInterval = 5 * time.Minute
+)
+
+var (
+	flight             *flightRing // nil if off
+	flightDumpInterval = DefaultFlightDumpInterval
+	flightLastDump     time.Time
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// EnableFlightRecorder -- keep the newest lines up to maxBytes in the memory, 0 switches the recorder off.
+// The kept lines are lost.
+func EnableFlightRecorder(maxBytes int) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if maxBytes <= flightHeaderSize {
+		flight = nil
+		return
+	}
+
+	flight = &flightRing{buf: make([]byte, maxBytes)}
+}
+
+// SetFlightRecorderDumpInterval -- the minimal interval between the automatic dumps, 0 switches them off
+func SetFlightRecorderDumpInterval(interval time.Duration) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	flightDumpInterval = interval
+}
+
+// DumpFlightRecorder -- write the kept lines to w, the oldest first
+func DumpFlightRecorder(w io.Writer) error {
+	mutex.Lock()
+	data := flight.snapshot()
+	mutex.Unlock()
+
+	_, err := w.Write(data)
+	return err
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// flightRecord -- keep the line and dump the ring on the severe message. Must be called under the mutex.
+func flightRecord(level Level, line string) {
+	if flight == nil {
+		return
+	}
+
+	flight.add(line)
+
+	if level > CRIT || flightDumpInterval <= 0 {
+		return
+	}
+
+	t := now()
+	if !flightLastDump.IsZero() && t.Sub(flightLastDump) < flightDumpInterval {
+		return
+	}
+	flightLastDump = t
+
+	name, _ := misc.AbsPath("@" + misc.AppName() + "_flight_" + t.Format("20060102-150405.000") + ".log")
+	if err := os.WriteFile(name, flight.snapshot(), 0644); err != nil {
+		logger(false, 0, StdFacilityName, WARNING, nil, "Flight recorder dump to %s failed: %s", name, err)
+		return
+	}
+	logger(false, 0, StdFacilityName, NOTICE, nil, "Flight recorder is dumped to %s", name)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// add -- store the line evicting the oldest records
+func (r *flightRing) add(line string) {
+	size := len(r.buf)
+	if len(line) > size-flightHeaderSize {
+		line = line[:size-flightHeaderSize]
+	}
+
+	need := flightHeaderSize + len(line)
+	for size-r.used < need {
+		n := int(r.uint32At(r.head))
+		r.head = (r.head + flightHeaderSize + n) % size
+		r.used -= flightHeaderSize + n
+		r.count--
+	}
+
+	tail := (r.head + r.used) % size
+
+	var header [flightHeaderSize]byte
+	binary.BigEndian.PutUint32(header[:], uint32(len(line)))
+	tail = r.copyAt(tail, header[:])
+
+	n := copy(r.buf[tail:], line)
+	copy(r.buf, line[n:])
+
+	r.used += need
+	r.count++
+}
+
+// snapshot -- the texts of the records, the oldest first
+func (r *flightRing) snapshot() []byte {
+	if r == nil {
+		return nil
+	}
+
+	size := len(r.buf)
+	data := make([]byte, 0, r.used-r.count*flightHeaderSize)
+
+	p := r.head
+	for i := 0; i < r.count; i++ {
+		n := int(r.uint32At(p))
+		p = (p + flightHeaderSize) % size
+
+		end := p + n
+		if end <= size {
+			data = append(data, r.buf[p:end]...)
+		} else {
+			data = append(data, r.buf[p:]...)
+			data = append(data, r.buf[:end-size]...)
+		}
+		p = end % size
+	}
+
+	return data
+}
+
+// uint32At -- the record header at the offset
+func (r *flightRing) uint32At(p int) uint32 {
+	var header [flightHeaderSize]byte
+	for i := range header {
+		header[i] = r.buf[(p+i)%len(r.buf)]
+	}
+	return binary.BigEndian.Uint32(header[:])
+}
+
+// copyAt -- copy the bytes to the offset with the wrapping, the offset after them is returned
+func (r *flightRing) copyAt(p int, data []byte) int {
+	for _, b := range data {
+		r.buf[p] = b
+		p = (p + 1) % len(r.buf)
+	}
+	return p
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
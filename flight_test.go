@@ -0,0 +1,130 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// newestFitting -- the newest lines fitting into the ring of the size
+func newestFitting(lines []string, size int) string {
+	used := 0
+	i := len(lines)
+	for i > 0 && used+flightHeaderSize+len(lines[i-1]) <= size {
+		used += flightHeaderSize + len(lines[i-1])
+		i--
+	}
+	return strings.Join(lines[i:], "")
+}
+
+func TestFlightRing(t *testing.T) {
+	for _, size := range []int{16, 64, 100, 1000} {
+		r := &flightRing{buf: make([]byte, size)}
+
+		var all []string
+		for i := 0; i < 500; i++ {
+			line := fmt.Sprintf("%d:%s\n", i, strings.Repeat("x", i%23))
+			if len(line) > size-flightHeaderSize {
+				continue
+			}
+			r.add(line)
+			all = append(all, line)
+
+			if s, expected := string(r.snapshot()), newestFitting(all, size); s != expected {
+				t.Fatalf("[%d/%d] got\n%q\nexpected\n%q", size, i, s, expected)
+			}
+		}
+	}
+
+	r := &flightRing{buf: make([]byte, 16)}
+	r.add("short\n")
+	r.add(strings.Repeat("y", 100))
+	if s := string(r.snapshot()); s != strings.Repeat("y", 12) {
+		t.Errorf("the long line is kept as %q", s)
+	}
+
+	r = &flightRing{buf: make([]byte, 1024)}
+	line := strings.Repeat("z", 100)
+	if n := testing.AllocsPerRun(1000, func() { r.add(line) }); n != 0 {
+		t.Errorf("got %v allocations per line", n)
+	}
+}
+
+func TestFlightRecorder(t *testing.T) {
+	console := resetLog(t)
+	setFakeClock(time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC))
+
+	var buf bytes.Buffer
+	if err := DumpFlightRecorder(&buf); err != nil || buf.Len() != 0 {
+		t.Fatalf("the recorder is off: %q, %v", buf.String(), err)
+	}
+
+	EnableFlightRecorder(2000)
+	for i := 0; i < 200; i++ {
+		Message(INFO, "message %d", i)
+	}
+
+	if err := DumpFlightRecorder(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.SplitAfter(console.String(), "\n")
+	lines = lines[:len(lines)-1]
+	expected := newestFitting(lines, 2000)
+	if buf.String() != expected || !strings.HasSuffix(expected, " message 199\n") || len(expected) < 1000 {
+		t.Errorf("got\n%s\nexpected\n%s", buf.String(), expected)
+	}
+}
+
+func TestFlightRecorderAutoDump(t *testing.T) {
+	console := resetLog(t)
+	clock := setFakeClock(time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC))
+
+	EnableFlightRecorder(10000)
+	SetFlightRecorderDumpInterval(time.Minute)
+
+	dumped := func() []string {
+		var names []string
+		for _, line := range console.Lines() {
+			if _, name, ok := strings.Cut(line, "Flight recorder is dumped to "); ok {
+				names = append(names, name)
+			}
+		}
+		return names
+	}
+
+	Message(INFO, "before")
+	Message(CRIT, "failure 1")
+	clock.Add(30 * time.Second)
+	Message(ALERT, "failure 2")
+	Message(ERR, "not severe")
+	clock.Add(31 * time.Second)
+	Message(CRIT, "failure 3")
+
+	names := dumped()
+	if len(names) != 2 {
+		t.Fatalf("got %d dumps:\n%s", len(names), console)
+	}
+
+	for i, name := range names {
+		defer os.Remove(name)
+
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		s := string(data)
+		last := fmt.Sprintf(" failure %d\n", 2*i+1)
+		if !strings.Contains(s, " before\n") || !strings.HasSuffix(s, last) {
+			t.Errorf("[%d] unexpected dump:\n%s", i, s)
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,70 @@
+package log
+
+import (
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Programs terminated by os.Exit or by a fatal signal skip the exit handler, so the buffered part of the log is lost.
+// SetFlushOnSevere puts the last severe messages on the disk immediately, SetPeriodicSync limits the loss by the OS cache.
+// Both are off by default.
+
+var (
+	flushOnSevere = Level(-1)
+
+	syncPeriod   = 0 * time.Second
+	lastSyncTime time.Time
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetFlushOnSevere -- flush the file buffer immediately after the message with the level or more severe. A negative level disables it.
+func SetFlushOnSevere(level Level) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	flushOnSevere = level
+}
+
+// SetPeriodicSync -- fsync the file every period by the flusher. Zero disables it.
+func SetPeriodicSync(period time.Duration) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if period < 0 {
+		period = 0
+	}
+	syncPeriod = period
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Must be called under the mutex
+func flushIfSevere(level Level) {
+	if flushOnSevere >= 0 && level.passes(flushOnSevere) {
+		writerFlush()
+	}
+}
+
+func periodicSync() {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if syncPeriod == 0 || dst == nil {
+		return
+	}
+
+	t := now()
+	if t.Sub(lastSyncTime) < syncPeriod {
+		return
+	}
+	lastSyncTime = t
+
+	writerFlush()
+	if s, ok := dst.(interface{ Sync() error }); ok {
+		s.Sync()
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
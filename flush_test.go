@@ -0,0 +1,43 @@
+package log
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestFlushOnSevere(t *testing.T) {
+	resetLog(t)
+
+	SetFile(t.TempDir(), "", false, 64*1024, 0)
+	SetFlushOnSevere(ERR)
+
+	Message(INFO, "buffered info")
+
+	read := func() string {
+		// The same as reading the file after the process was killed: nothing flushes the buffer
+		data, err := os.ReadFile(FileName())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	if s := read(); strings.Contains(s, "buffered info") {
+		t.Fatalf("info is flushed unexpectedly:\n%s", s)
+	}
+
+	Message(ERR, "severe error")
+
+	s := read()
+	if !strings.Contains(s, "severe error") {
+		t.Errorf("error is not on the disk:\n%s", s)
+	}
+	if !strings.Contains(s, "buffered info") {
+		t.Errorf("preceding info is not on the disk:\n%s", s)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
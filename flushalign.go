@@ -0,0 +1,48 @@
+package log
+
+import (
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// With the flush alignment the flusher wakes up at the wall clock multiples of its period (:00.000, :05.000, ... for
+// 5 seconds) instead of the fixed period after the previous tick, so a tailer sampling the file on the second gets
+// the lines at once. The sleep is computed from the current time on every tick, so the flusher realigns itself after
+// the clock step. Off by default.
+
+var (
+	flushAlign = false
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetFlushAlignment -- flush at the wall clock multiples of the flush period
+func SetFlushAlignment(align bool) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	flushAlign = align
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// flusherDelay -- the sleep before the next flusher tick
+func flusherDelay(period time.Duration) time.Duration {
+	mutex.Lock()
+	align := flushAlign
+	t := now()
+	mutex.Unlock()
+
+	if !align {
+		return period
+	}
+	return alignedDelay(t, period)
+}
+
+// alignedDelay -- the time from t to the next multiple of the period
+func alignedDelay(t time.Time, period time.Duration) time.Duration {
+	return t.Truncate(period).Add(period).Sub(t)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
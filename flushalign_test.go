@@ -0,0 +1,60 @@
+package log
+
+import (
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestFlushAlignment(t *testing.T) {
+	resetLog(t)
+
+	clock := setFakeClock(time.Date(2024, 5, 3, 23, 59, 41, 300_000_000, time.UTC))
+
+	const (
+		period    = 5 * time.Second
+		lateness  = 3 * time.Millisecond // the wake up is always a bit late
+		tolerance = 10 * time.Millisecond
+	)
+
+	if d := flusherDelay(period); d != period {
+		t.Errorf("got %s without the alignment", d)
+	}
+
+	SetFlushAlignment(true)
+
+	onBoundary := func(tick int) {
+		t.Helper()
+		if off := now().Sub(now().Truncate(period)); off > tolerance {
+			t.Errorf("[%d] the tick at %s is %s off the boundary", tick, now().Format(time.TimeOnly+".000"), off)
+		}
+	}
+
+	if d := flusherDelay(period); d != 3700*time.Millisecond {
+		t.Fatalf("got the first delay %s", d)
+	}
+
+	days := map[string]bool{}
+	for i := 0; i < 10; i++ {
+		clock.Add(flusherDelay(period) + lateness)
+		onBoundary(i)
+		days[fileDate(now())] = true
+
+		if i == 4 {
+			// The clock is stepped forward in the middle of the sleep
+			clock.Add(2 * time.Second)
+		}
+	}
+
+	// The ticks passed the midnight
+	if !days["2024-05-03"] || !days["2024-05-04"] {
+		t.Errorf("unexpected days %v", days)
+	}
+
+	if d := alignedDelay(time.Date(2024, 5, 3, 12, 0, 5, 0, time.UTC), period); d != period {
+		t.Errorf("got %s on the boundary", d)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,18 @@
+package log
+
+import (
+	"syscall"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// freeSpace -- bytes available to the unprivileged user on the file system of the directory
+func freeSpace(dir string) (uint64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return 0, err
+	}
+	return st.Bavail * uint64(st.Bsize), nil
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
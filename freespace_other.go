@@ -0,0 +1,15 @@
+//go:build !linux
+
+package log
+
+import (
+	"errors"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func freeSpace(dir string) (uint64, error) {
+	return 0, errors.ErrUnsupported
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,453 @@
+package log
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Group commit: the message is formatted outside of the mutex and put into the ring, the committer drains the ring
+// and writes the whole batch to the file at once. Lines are written in the enqueue order. The timestamp is taken
+// before enqueueing and it is checked on the commit as the direct messages do: the line enqueued after a later one
+// gets the time of the previous line, so timestamps within the file never decrease.
+// TIME level messages always go the usual way. Parameters are never retained by the ring or by the
+// asynchronous file opening queue, so the caller can reuse or modify them as soon as Message returns.
+
+// GroupCommitMode --
+type GroupCommitMode int
+
+const (
+	// GroupCommitOff -- every message is written under the mutex by its goroutine (default)
+	GroupCommitOff GroupCommitMode = iota
+	// GroupCommitSync -- the goroutine that acquires the committer lock writes the pending messages of all goroutines
+	GroupCommitSync
+	// GroupCommitAsync -- the dedicated goroutine writes the pending messages
+	GroupCommitAsync
+)
+
+const commitRingSize = 4096
+
+type commitEntry struct {
+	facility string
+	level    Level
+	t        time.Time
+	dt       string
+	text     string
+	console  string // the console text if it differs
+	eventID  string
+	funcName FuncNameOverride
+}
+
+type commitSlot struct {
+	seq   atomic.Uint64
+	entry commitEntry
+}
+
+// commitRing -- bounded multi-producer queue, the consumer holds commitMutex
+type commitRing struct {
+	slots []commitSlot
+	head  atomic.Uint64
+	tail  atomic.Uint64
+	batch []commitEntry
+
+	async  bool
+	closed atomic.Bool
+	signal chan struct{}
+	stop   chan struct{}
+	done   chan struct{}
+	space  atomic.Pointer[chan struct{}] // closed and replaced when entries are popped
+}
+
+var (
+	groupCommitMutex sync.Mutex
+	groupCommitRing  atomic.Pointer[commitRing]
+
+	commitMutex sync.Mutex
+
+	enqueueTimeout atomic.Int64
+	commitPending  atomic.Int64
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetGroupCommit -- set the group commit mode. Pending messages are written before the mode is changed.
+func SetGroupCommit(mode GroupCommitMode) {
+	groupCommitMutex.Lock()
+	defer groupCommitMutex.Unlock()
+
+	if r := groupCommitRing.Swap(nil); r != nil {
+		r.close()
+	}
+
+	if mode == GroupCommitOff {
+		return
+	}
+
+	r := newCommitRing(mode == GroupCommitAsync)
+	groupCommitRing.Store(r)
+}
+
+// currentGroupCommit -- the group commit mode in effect
+func currentGroupCommit() GroupCommitMode {
+	switch r := groupCommitRing.Load(); {
+	case r == nil:
+		return GroupCommitOff
+	case r.async:
+		return GroupCommitAsync
+	default:
+		return GroupCommitSync
+	}
+}
+
+// SetEnqueueTimeout -- how long the message waits for the space in the full group commit ring, then it is dropped.
+// Zero (default) means the message is written by its goroutine and never dropped.
+func SetEnqueueTimeout(d time.Duration) {
+	enqueueTimeout.Store(int64(max(d, 0)))
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func newCommitRing(async bool) *commitRing {
+	r := &commitRing{
+		slots: make([]commitSlot, commitRingSize),
+		batch: make([]commitEntry, 0, commitRingSize),
+		async: async,
+	}
+
+	for i := range r.slots {
+		r.slots[i].seq.Store(uint64(i))
+	}
+
+	space := make(chan struct{})
+	r.space.Store(&space)
+
+	if async {
+		r.signal = make(chan struct{}, 1)
+		r.stop = make(chan struct{})
+		r.done = make(chan struct{})
+		go r.committer()
+	}
+
+	return r
+}
+
+// close -- stop the committer goroutine and write pending messages
+func (r *commitRing) close() {
+	r.closed.Store(true)
+
+	if r.async {
+		close(r.stop)
+		<-r.done
+	}
+
+	r.commit(true)
+}
+
+func (r *commitRing) committer() {
+	defer close(r.done)
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-r.signal:
+			r.commit(true)
+		}
+	}
+}
+
+// push -- false if the ring is full
+func (r *commitRing) push(e commitEntry) bool {
+	for {
+		pos := r.head.Load()
+		slot := &r.slots[pos%commitRingSize]
+		seq := slot.seq.Load()
+
+		switch {
+		case seq == pos:
+			if r.head.CompareAndSwap(pos, pos+1) {
+				commitPending.Add(1)
+				slot.entry = e
+				slot.seq.Store(pos + 1)
+				return true
+			}
+		case seq < pos:
+			return false
+		}
+	}
+}
+
+// pop -- false if the ring is empty or the next entry isn't published yet. Must be called under commitMutex.
+func (r *commitRing) pop() (e commitEntry, ok bool) {
+	pos := r.tail.Load()
+	slot := &r.slots[pos%commitRingSize]
+	if slot.seq.Load() != pos+1 {
+		return
+	}
+
+	e = slot.entry
+	slot.entry = commitEntry{}
+	slot.seq.Store(pos + commitRingSize)
+	r.tail.Store(pos + 1)
+	commitPending.Add(-1)
+	return e, true
+}
+
+func (r *commitRing) pending() bool {
+	return r.head.Load() != r.tail.Load()
+}
+
+// put -- enqueue the entry and commit or wake up the committer
+func (r *commitRing) put(e commitEntry) {
+	if !r.enqueue(e) {
+		statDrop()
+		return
+	}
+
+	if r.async {
+		select {
+		case r.signal <- struct{}{}:
+		default:
+		}
+		if !r.closed.Load() {
+			return
+		}
+	}
+
+	r.commit(false)
+}
+
+// enqueue -- false if the ring is still full after the enqueue timeout
+func (r *commitRing) enqueue(e commitEntry) bool {
+	timeout := time.Duration(enqueueTimeout.Load())
+
+	var deadline *time.Timer
+
+	for {
+		space := *r.space.Load()
+		if r.push(e) {
+			return true
+		}
+
+		if timeout == 0 {
+			r.commit(true)
+			continue
+		}
+
+		r.commit(false)
+
+		if deadline == nil {
+			deadline = time.NewTimer(timeout)
+			defer deadline.Stop()
+		}
+
+		select {
+		case <-space:
+		case <-deadline.C:
+			return false
+		}
+	}
+}
+
+// commit -- write pending entries. Without wait it returns at once if another goroutine is committing,
+// that goroutine writes the entry after its batch.
+func (r *commitRing) commit(wait bool) {
+	for r.pending() {
+		if wait {
+			commitMutex.Lock()
+		} else if !commitMutex.TryLock() {
+			return
+		}
+
+		n := r.drain()
+		commitMutex.Unlock()
+
+		if n == 0 {
+			// The next slot is reserved but not published yet
+			runtime.Gosched()
+		}
+	}
+}
+
+// commitBarrier -- write the entries enqueued before the call and hold commitMutex, so nothing is committed until the release.
+// Lines written directly under the mutex between the call and the release keep the program order of their goroutine.
+func commitBarrier() (release func()) {
+	r := groupCommitRing.Load()
+	if r == nil {
+		return func() {}
+	}
+
+	commitMutex.Lock()
+
+	for r.pending() {
+		if r.drain() == 0 {
+			// The next slot is reserved but not published yet
+			runtime.Gosched()
+		}
+	}
+
+	return commitMutex.Unlock
+}
+
+// drain -- write the batch of published entries. Must be called under commitMutex.
+func (r *commitRing) drain() int {
+	batch := r.batch[:0]
+	for len(batch) < commitRingSize {
+		e, ok := r.pop()
+		if !ok {
+			break
+		}
+		batch = append(batch, e)
+	}
+
+	n := len(batch)
+	if n == 0 {
+		return 0
+	}
+
+	space := make(chan struct{})
+	close(*r.space.Swap(&space))
+
+	mutex.Lock()
+	outputBatch(batch)
+	mutex.Unlock()
+
+	clear(batch)
+	return n
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func (f *Facility) commitMessage(r *commitRing, shift int, level Level, mo *messageOptions, message string, params ...any) {
+	if !enabled {
+		return
+	}
+
+	t := messageTime(level)
+
+	msg, ok := redactMessage(f.name, level, formatMessage(message, params), mo)
+	if !ok {
+		statDrop()
+		return
+	}
+
+	id := mo.event()
+	msg = eventToken(id) + msg
+
+	dt, prefix := formatPrefix(shift+1, f.name, level, t, mo.funcNameOverride())
+	notifySevere(f.name, level, t, msg)
+
+	text, console := stripForFile(finishLine(prefix+msg), "")
+	r.put(
+		commitEntry{
+			facility: f.name,
+			level:    level,
+			t:        t,
+			dt:       dt,
+			text:     text,
+			console:  console,
+			eventID:  id,
+			funcName: mo.funcNameOverride(),
+		},
+	)
+}
+
+// outputBatch -- lines with the same date go to the file by one write. Must be called under the mutex.
+func outputBatch(batch []commitEntry) {
+	ensureStarted()
+
+	for i := 0; i < len(batch); {
+		e := &batch[i]
+		e.restamp()
+		e.dt = forwardDate(e.dt)
+		rotationCheck(e.dt)
+
+		if len(quotas) != 0 || !fileDestination.plain() {
+			// Every line is checked against the quota of its facility and the file destination
+			outputRecord(e.record())
+			i++
+			continue
+		}
+
+		j := i + 1
+
+		if batchWritable(e.dt) {
+			level := e.level
+			for j < len(batch) {
+				batch[j].restamp()
+				if forwardDate(batch[j].dt) != e.dt {
+					break
+				}
+				if batch[j].level.rank() < level.rank() {
+					level = batch[j].level
+				}
+				j++
+			}
+
+			var sb strings.Builder
+			for _, e := range batch[i:j] {
+				sb.WriteString(e.text)
+			}
+
+			write(sb.String())
+			flushIfSevere(level)
+			lastWriteDate = e.dt
+		} else {
+			outputMain(e.level, e.dt, e.text)
+		}
+
+		for _, e := range batch[i:j] {
+			outputCopies(e.record())
+		}
+
+		i = j
+	}
+}
+
+// restamp -- pass the time of the entry through the stamping of the direct messages, the time held at the previous
+// line replaces the one in the text. Must be called under the mutex.
+func (e *commitEntry) restamp() {
+	t := nextStamp(e.t)
+	if t.Equal(e.t) {
+		return
+	}
+
+	oldDate, oldTm := formatStamp(e.t)
+	date, tm := formatStamp(t)
+	if oldStamp, stamp := oldDate+" "+oldTm, date+" "+tm; oldStamp != stamp {
+		e.text = strings.Replace(e.text, oldStamp, stamp, 1)
+		if e.console != "" {
+			e.console = strings.Replace(e.console, oldStamp, stamp, 1)
+		}
+	}
+
+	e.t = t
+	e.dt = fileDate(t)
+}
+
+func (e *commitEntry) record() *Record {
+	return &Record{Time: e.t, Level: e.level, Facility: e.facility, Date: e.dt, Line: e.text, EventID: e.eventID, console: e.console, funcName: e.funcName}
+}
+
+// batchWritable -- the file is open and the lines can be written together. Must be called under the mutex.
+func batchWritable(dt string) bool {
+	if !active || memoryMode || lineChecksums || (outputWriter == nil && fileNamePattern == "") {
+		return false
+	}
+
+	if asyncOpen && (opening || dst == nil || lastWriteDate != dt || writeBroken) {
+		return false
+	}
+
+	if (dst == nil) || (lastWriteDate != dt) || writeBroken {
+		rotateLogFile(dt)
+	}
+
+	return dst != nil
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
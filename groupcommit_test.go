@@ -0,0 +1,252 @@
+package log
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func groupCommitFile(t testing.TB, mode GroupCommitMode) {
+	t.Helper()
+
+	SetConsoleWriter(io.Discard)
+	SetFile(t.TempDir(), "", false, 64*1024, 0)
+	SetGroupCommit(mode)
+}
+
+func checkGroupCommitFile(t *testing.T, writers int, count int) {
+	t.Helper()
+
+	SetGroupCommit(GroupCommitOff)
+	writerFlush()
+
+	fd, err := os.Open(FileName())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+
+	last := make([]int, writers)
+	for i := range last {
+		last[i] = -1
+	}
+
+	total := 0
+	scanner := bufio.NewScanner(fd)
+	for scanner.Scan() {
+		line := scanner.Text()
+		_, msg, ok := strings.Cut(line, " msg ")
+		if !ok {
+			continue
+		}
+
+		var g, n int
+		if _, err := fmt.Sscanf(msg, "%d %d", &g, &n); err != nil {
+			t.Fatalf("bad line %q", line)
+		}
+		if n != last[g]+1 {
+			t.Fatalf("writer %d: got %d after %d", g, n, last[g])
+		}
+		last[g] = n
+		total++
+	}
+
+	if total != writers*count {
+		t.Errorf("got %d lines, expected %d", total, writers*count)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestGroupCommitStress(t *testing.T) {
+	const writers = 64
+
+	count := 1000000 / writers
+	if testing.Short() {
+		count /= 10
+	}
+
+	for _, mode := range []GroupCommitMode{GroupCommitSync, GroupCommitAsync} {
+		t.Run(fmt.Sprintf("mode%d", mode), func(t *testing.T) {
+			resetLog(t)
+			groupCommitFile(t, mode)
+
+			var wg sync.WaitGroup
+			for g := 0; g < writers; g++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for n := 0; n < count; n++ {
+						Message(INFO, "msg %d %d", g, n)
+					}
+				}()
+			}
+			wg.Wait()
+
+			checkGroupCommitFile(t, writers, count)
+		})
+	}
+}
+
+// TestGroupCommitSettings -- the format settings are changed while the messages are formatted without the mutex
+func TestGroupCommitSettings(t *testing.T) {
+	resetLog(t)
+	groupCommitFile(t, GroupCommitAsync)
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			MaxLen(i % 2 * 1000)
+			SetLegacyFormatting(i%2 == 0)
+			SetFuncNameMode([]FuncNameMode{FuncNameModeNone, FuncNameModeShort, FuncNameModeFull}[i%3])
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < 1000; n++ {
+				Message(INFO, "msg %d %d", g, n)
+			}
+		}()
+	}
+	wg.Wait()
+
+	close(stop)
+	<-done
+	SetGroupCommit(GroupCommitOff)
+}
+
+func TestGroupCommitCopies(t *testing.T) {
+	console := resetLog(t)
+	SetLogLevel("INFO", FuncNameModeNone)
+	SetGroupCommit(GroupCommitSync)
+	console.buf.Reset()
+
+	ch, cancel := SubscribeAll(10)
+	defer cancel()
+
+	Message(INFO, "first")
+	Message(DEBUG, "filtered")
+	Message(INFO, "second")
+
+	if s := <-ch; !strings.HasSuffix(s, " first") {
+		t.Errorf("unexpected subscribed line %q", s)
+	}
+
+	if lines := console.Lines(); len(lines) != 2 || !strings.HasSuffix(lines[0], " first") || !strings.HasSuffix(lines[1], " second") {
+		t.Errorf("unexpected console %q", lines)
+	}
+	if last := GetLastLog(); len(last) != 3 || !strings.HasSuffix(last[2], " second") {
+		t.Errorf("unexpected last lines %q", last)
+	}
+}
+
+func TestGroupCommitTimestamps(t *testing.T) {
+	console := resetLog(t)
+	clock := setFakeClock(time.Date(2024, 5, 3, 12, 0, 2, 0, time.UTC))
+	SetLogLevel("INFO", FuncNameModeNone)
+	SetGroupCommit(GroupCommitSync)
+	defer SetGroupCommit(GroupCommitOff)
+	console.buf.Reset()
+
+	// The later message is enqueued first while another goroutine is committing
+	commitMutex.Lock()
+	Message(INFO, "later")
+	clock.Add(-500 * time.Millisecond)
+	Message(INFO, "earlier")
+	commitMutex.Unlock()
+
+	SetGroupCommit(GroupCommitOff)
+
+	lines := console.Lines()
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, expected 2:\n%s", len(lines), console.String())
+	}
+	for i, e := range []string{"later", "earlier"} {
+		if !strings.HasSuffix(lines[i], " 2024-05-03 12:00:02.000 "+e) {
+			t.Errorf("[%d] unexpected line %q", i, lines[i])
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func BenchmarkParallelMessage(b *testing.B) {
+	modes := []struct {
+		name string
+		mode GroupCommitMode
+	}{
+		{"Off", GroupCommitOff},
+		{"Sync", GroupCommitSync},
+		{"Async", GroupCommitAsync},
+	}
+
+	for _, m := range modes {
+		b.Run(m.name, func(b *testing.B) {
+			groupCommitFile(b, m.mode)
+			defer SetGroupCommit(GroupCommitOff)
+
+			b.SetParallelism(max(1, 64/runtime.GOMAXPROCS(0)))
+			b.ResetTimer()
+
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					Message(INFO, "benchmark %d", 12345)
+				}
+			})
+		})
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestGroupCommitParamsNotRetained(t *testing.T) {
+	console := resetLog(t)
+	setFakeClock(time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC))
+	SetLogLevel("INFO", FuncNameModeNone)
+	SetGroupCommit(GroupCommitAsync)
+	defer SetGroupCommit(GroupCommitOff)
+
+	release := slowOpen(t)
+	dir := t.TempDir()
+	SetFile(dir, "", false, 4096, 0)
+	SetAsyncFileOpen(true)
+	defer SetAsyncFileOpen(false)
+	console.buf.Reset()
+
+	buf := []byte("before")
+	m := map[string]int{"n": 1}
+	Message(INFO, "%s %v", buf, m)
+	copy(buf, "AFTER!")
+	m["n"] = 2
+
+	close(release)
+	SetGroupCommit(GroupCommitOff)
+
+	lines := waitFile(t, filepath.Join(dir, "2024-05-03.log"), 2)
+	if !strings.HasSuffix(lines[len(lines)-1], " before map[n:1]") {
+		t.Errorf("unexpected file line %q", lines[len(lines)-1])
+	}
+	if lines := console.Lines(); len(lines) == 0 || !strings.HasSuffix(lines[0], " before map[n:1]") {
+		t.Errorf("unexpected console %q", lines)
+	}
+}
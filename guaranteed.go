@@ -0,0 +1,184 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/alrusov/misc"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// The guaranteed message is never dropped: the level, storms, drop rules, quotas, the group commit and the asynchronous
+// opening are bypassed. The file is flushed and synced before the return. If the file can't take the line it's appended
+// to the dump file at once, the dump file isn't removed until the line is synced into the reopened file. The dump file left
+// by the previous run (the crash, the restart before the file was reopened) is written into the opened file.
+
+var (
+	// ErrNotDurable -- the guaranteed message isn't stored in the log file, it's stored in the dump file
+	ErrNotDurable = errors.New("the message isn't stored in the log file")
+	// ErrMessageLost -- the guaranteed message is stored neither in the log file nor in the dump file
+	ErrMessageLost = errors.New("the message isn't stored")
+
+	dumpedLines = map[string]bool{} // lines appended to the dump file by the guaranteed messages
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// MessageGuaranteed -- add the message which must not be lost, nil means the line is synced to the log file.
+// ErrNotDurable means the line is in the dump file only, ErrMessageLost means it isn't stored at all.
+func (f *Facility) MessageGuaranteed(level Level, message string, params ...any) error {
+	return f.messageGuaranteed(1, level, message, params...)
+}
+
+// MessageGuaranteed -- add the message of the standard facility which must not be lost
+func MessageGuaranteed(level Level, message string, params ...any) error {
+	return stdFacility.messageGuaranteed(1, level, message, params...)
+}
+
+func (f *Facility) messageGuaranteed(shift int, level Level, message string, params ...any) error {
+	if f == stdFacility && autoFacility.Load() {
+		f = callerFacility()
+	}
+
+	level = checkLevel(shift+1, f, level)
+
+	if scopeFrames.Load() != 0 {
+		message = scopeMessage(message, params)
+	}
+
+	defer commitBarrier()()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	flushOpenPending()
+
+	msg := redactRules(activeRules.Load(), formatMessage(message, params), nil)
+
+	dt, prefix := linePrefix(shift+1, f.name, level, FuncNameInherit)
+	notifySevere(f.name, level, lastStamp, msg)
+
+	ensureStarted()
+
+	text, console := stripForFile(finishLine(prefix+msg), "")
+	r := &Record{Time: lastStamp, Level: level, Facility: f.name, Date: dt, Line: text, console: console}
+	err := outputGuaranteed(r)
+	outputCopies(r)
+
+	return err
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// outputGuaranteed -- write the line to the file and sync it, the dump file is used if it fails. Must be called under the mutex.
+func outputGuaranteed(r *Record) error {
+	text := r.Render(fileDestination.format)
+
+	var cause error
+
+	switch {
+	case !enabled || !active:
+		cause = errors.New("the log is disabled")
+	case memoryMode:
+		memoryAppend(r.Level, text)
+		cause = errors.New("the log is in the memory mode")
+	case outputWriter == nil && fileNamePattern == "":
+		beforeFileBuf = append(beforeFileBuf, text)
+		cause = errors.New("the log file isn't set")
+	default:
+		outputFile(r.Level, r.Date, text)
+		cause = syncOutput()
+		if cause == nil {
+			return nil
+		}
+	}
+
+	return dumpLine(text, cause)
+}
+
+// syncOutput -- flush and sync the current output. Must be called under the mutex.
+func syncOutput() error {
+	if dst == nil || writeBroken {
+		return notOpenError()
+	}
+
+	writerFlush()
+	checkWriteError()
+	if writeBroken {
+		return notOpenError()
+	}
+
+	if s, ok := dst.(interface{ Sync() error }); ok {
+		if err := s.Sync(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func notOpenError() error {
+	if lastError != nil {
+		return lastError
+	}
+	return errors.New("the log file isn't open")
+}
+
+// dumpLine -- append the line to the dump file and sync it. Must be called under the mutex.
+func dumpLine(text string, cause error) error {
+	fd, err := os.OpenFile(dumpFileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err == nil {
+		_, err = fd.Write([]byte(text))
+		if err == nil {
+			err = fd.Sync()
+		}
+		if e := fd.Close(); err == nil {
+			err = e
+		}
+	}
+
+	if err != nil {
+		return fmt.Errorf("%w (%s), dump file: %s", ErrMessageLost, cause, err)
+	}
+
+	dumpedLines[text] = true
+	return fmt.Errorf("%w (%s), it's saved to %s", ErrNotDurable, cause, dumpFileName)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// replayDump -- write the lines of the dump file which aren't dumped by this process, false if there are none.
+// The lines dumped by this process are written from the buffers. Must be called under the mutex.
+func replayDump() bool {
+	data, err := os.ReadFile(dumpFileName)
+	if err != nil {
+		return false
+	}
+
+	text := string(data)
+	for s := range dumpedLines {
+		text = strings.Replace(text, s, "", 1)
+	}
+	if text == "" {
+		return false
+	}
+	if !strings.HasSuffix(text, misc.EOS) {
+		text += misc.EOS
+	}
+
+	write(fmt.Sprintf("[%d] %s %s *** Unsaved lines of the previous run from %s:%s",
+		pid,
+		levels[NOTICE].shortName,
+		lastStamp.Format(misc.DateTimeFormatRevWithMS),
+		dumpFileName,
+		misc.EOS,
+	))
+	write(text)
+
+	return true
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
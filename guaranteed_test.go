@@ -0,0 +1,112 @@
+package log
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestGuaranteedHealthyFile(t *testing.T) {
+	resetLog(t)
+	setFakeClock(time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC))
+
+	SetFile(t.TempDir(), "", false, 64*1024, 0)
+
+	f := GetFacility("billing")
+	f.SetLogLevel("ERR", FuncNameModeNone)
+	f.SetDailyQuota(1, QuotaDrop)
+	f.Message(ERR, "over the quota")
+
+	if err := f.MessageGuaranteed(INFO, "invoice %d finalized", 42); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(FileName())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), " <billing> invoice 42 finalized\n") {
+		t.Errorf("the line isn't on the disk:\n%s", data)
+	}
+	if _, err := os.Stat(dumpFileName); err == nil {
+		t.Errorf("the dump file is created")
+	}
+}
+
+func TestGuaranteedBrokenFile(t *testing.T) {
+	resetLog(t)
+	setFakeClock(time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC))
+
+	SetFile(t.TempDir(), "", false, 0, 0)
+	Message(INFO, "opened")
+
+	SetFailureInjection(NewScriptedInjector(FailureStep{Op: FailWrite, Count: -1}))
+
+	err := MessageGuaranteed(NOTICE, "invoice 7 finalized")
+	if !errors.Is(err, ErrNotDurable) || !strings.Contains(err.Error(), "injected failure") {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	data, _ := os.ReadFile(dumpFileName)
+	if n := strings.Count(string(data), " invoice 7 finalized\n"); n != 1 {
+		t.Fatalf("the dump file has the line %d times:\n%s", n, data)
+	}
+
+	SetFailureInjection(nil)
+	Message(INFO, "recovered")
+
+	data, _ = os.ReadFile(FileName())
+	if !strings.Contains(string(data), " invoice 7 finalized\n") {
+		t.Errorf("the line isn't moved to the reopened file:\n%s", data)
+	}
+	if _, err := os.Stat(dumpFileName); err == nil {
+		t.Errorf("the dump file isn't removed")
+	}
+}
+
+func TestGuaranteedRestart(t *testing.T) {
+	resetLog(t)
+	setFakeClock(time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC))
+
+	// The file isn't set, the line is in the dump file only
+	if err := MessageGuaranteed(NOTICE, "invoice 9 finalized"); !errors.Is(err, ErrNotDurable) {
+		t.Fatalf("unexpected error %v", err)
+	}
+	dump := dumpFileName
+
+	// The restart: the dump file is left, the memory of the process is lost
+	resetLog(t)
+	dumpFileName = dump
+
+	SetFile(t.TempDir(), "", false, 0, 0)
+	Message(INFO, "after restart")
+	writerFlush()
+
+	data, _ := os.ReadFile(FileName())
+	s := string(data)
+	if !strings.Contains(s, " *** Unsaved lines of the previous run from "+dump+":\n") ||
+		strings.Count(s, " invoice 9 finalized\n") != 1 || !strings.HasSuffix(s, " after restart\n") {
+		t.Errorf("the line isn't restored:\n%s", s)
+	}
+	if _, err := os.Stat(dump); err == nil {
+		t.Errorf("the dump file isn't removed")
+	}
+}
+
+func TestGuaranteedLost(t *testing.T) {
+	resetLog(t)
+
+	dumpFileName = filepath.Join(t.TempDir(), "missing", "unsaved.log")
+
+	err := MessageGuaranteed(INFO, "invoice 8 finalized")
+	if !errors.Is(err, ErrMessageLost) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
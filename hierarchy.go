@@ -0,0 +1,93 @@
+package log
+
+import (
+	"strings"
+	"sync/atomic"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// With the hierarchical levels the dotted facility ("db.pool.conn") at the default level inherits the level of
+// the nearest ancestor ("db.pool", then "db") having another level. The level set equal to the default one can't be
+// distinguished from the not set one, so such a facility follows its ancestors too. The listings and the persistence
+// show the own levels, the messages, V(n), CurrentLogLevel and the level token use the inherited one. Off by default.
+//
+// Every token is linked to the token of the nearest existing ancestor. The resolved level is cached in the token with
+// the generation it was resolved at, any level change and new facility increase the generation, so the message path
+// does the walk only once after the change and then only loads the cached level.
+
+var (
+	hierarchicalLevels atomic.Bool
+	levelGeneration    atomic.Uint64
+)
+
+// resolvedLevel -- the inherited level cached in the token
+type resolvedLevel struct {
+	generation uint64
+	level      Level
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetHierarchicalLevels -- inherit the levels of the dotted facilities from their ancestors
+func SetHierarchicalLevels(on bool) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	hierarchicalLevels.Store(on)
+	levelGeneration.Add(1)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// linkFacility -- link the new facility to the nearest existing ancestor, its descendants linked above it are relinked
+// to it. Must be called under the mutex.
+func linkFacility(f *Facility) {
+	name := f.name
+	for {
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			break
+		}
+		name = name[:i]
+		if p, exists := facilities[name]; exists {
+			f.token.parent.Store(&p.token)
+			break
+		}
+	}
+
+	prefix := f.name + "."
+	parent := f.token.parent.Load()
+	for name, d := range facilities {
+		if strings.HasPrefix(name, prefix) && d.token.parent.Load() == parent {
+			d.token.parent.Store(&f.token)
+		}
+	}
+
+	levelGeneration.Add(1)
+}
+
+// resolve -- the level inherited by the token, cached until the next change
+func (t *LevelToken) resolve() Level {
+	gen := levelGeneration.Load()
+	if r := t.resolved.Load(); r != nil && r.generation == gen {
+		return r.level
+	}
+
+	level := t.walk()
+	t.resolved.Store(&resolvedLevel{generation: gen, level: level})
+	return level
+}
+
+// walk -- the own level of the nearest token having not the default level
+func (t *LevelToken) walk() Level {
+	def := Level(stdFacility.token.level.Load())
+	for p := t; p != nil; p = p.parent.Load() {
+		if level := Level(p.level.Load()); level != def {
+			return level
+		}
+	}
+	return def
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,135 @@
+package log
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/alrusov/misc"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestHierarchicalLevels(t *testing.T) {
+	console := resetLog(t)
+
+	if err := SetLogLevels("INFO", misc.StringMap{}, FuncNameModeNone); err != nil {
+		t.Fatal(err)
+	}
+	SetHierarchicalLevels(true)
+
+	db := NewFacility("db")
+	conn := NewFacility("db.pool.conn")
+	tok := conn.LevelToken()
+
+	check := func(step string, expected Level) {
+		t.Helper()
+		if l := conn.CurrentLogLevel(); l != expected {
+			t.Errorf("[%s] got %s, expected %s", step, levels[l].name, levels[expected].name)
+		}
+		if tok.Load() != expected {
+			t.Errorf("[%s] the token has %s, expected %s", step, levels[tok.Load()].name, levels[expected].name)
+		}
+	}
+
+	check("default", INFO)
+
+	db.SetLogLevel("TRACE2", FuncNameModeKeep)
+	check("ancestor changed", TRACE2)
+
+	conn.Message(TRACE2, "inherited")
+	if !conn.V(2) || conn.V(3) {
+		t.Errorf("V(2) %t, V(3) %t", conn.V(2), conn.V(3))
+	}
+
+	// The facility created between them becomes the nearest ancestor
+	pool := NewFacility("db.pool")
+	check("intermediate created", TRACE2)
+	pool.SetLogLevel("ERR", FuncNameModeKeep)
+	check("intermediate changed", ERR)
+
+	conn.SetLogLevel("WARNING", FuncNameModeKeep)
+	check("own level", WARNING)
+	pool.SetLogLevel("DEBUG", FuncNameModeKeep)
+	check("own level kept", WARNING)
+
+	// The default level can't be told from the not set one
+	conn.SetLogLevel("INFO", FuncNameModeKeep)
+	check("own default level", DEBUG)
+
+	SetLogLevels("NOTICE", misc.StringMap{"db": "TRACE1"}, FuncNameModeNone)
+	check("config", TRACE1)
+	if l := CurrentLogLevelOfAll()["db.pool.conn"]; l != NOTICE {
+		t.Errorf("got the own level %s, expected NOTICE", levels[l].name)
+	}
+
+	SetHierarchicalLevels(false)
+	check("off", NOTICE)
+	conn.Message(TRACE2, "not inherited")
+
+	if s := console.String(); !strings.Contains(s, "<db.pool.conn> inherited") || strings.Contains(s, "not inherited") {
+		t.Errorf("unexpected output:\n%s", s)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func BenchmarkHierarchicalLevels(b *testing.B) {
+	defer func() {
+		SetHierarchicalLevels(false)
+		SetConsoleWriter(nil)
+	}()
+
+	SetConsoleWriter(io.Discard)
+
+	// 100 roots with 10 levels deep descendants, only the roots have their levels
+	leaves := make([]*Facility, 0, 100)
+	for i := 0; i < 100; i++ {
+		name := fmt.Sprintf("h%d", i)
+		NewFacility(name).SetLogLevel("TRACE1", FuncNameModeKeep)
+		for d := 1; d < 10; d++ {
+			name = fmt.Sprintf("%s.l%d", name, d)
+			NewFacility(name)
+		}
+		leaves = append(leaves, GetFacility(name))
+	}
+
+	// walk -- the dotted ancestors by the map lookups
+	walk := func(name string) Level {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		def := stdFacility.level
+		for {
+			if f, exists := facilities[name]; exists && f.level != def {
+				return f.level
+			}
+			i := strings.LastIndexByte(name, '.')
+			if i < 0 {
+				return def
+			}
+			name = name[:i]
+		}
+	}
+
+	SetHierarchicalLevels(true)
+
+	b.Run("Walk", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if walk(leaves[i%len(leaves)].name) != TRACE1 {
+				b.Fatal("wrong level")
+			}
+		}
+	})
+
+	b.Run("Cached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if leaves[i%len(leaves)].token.Load() != TRACE1 {
+				b.Fatal("wrong level")
+			}
+		}
+	})
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,101 @@
+package log
+
+import (
+	"fmt"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// LevelChange -- log level change record
+type LevelChange struct {
+	Time     time.Time `json:"time"`
+	Facility string    `json:"facility"`
+	Old      Level     `json:"old"`
+	New      Level     `json:"new"`
+	Actor    string    `json:"actor"`
+	Reason   string    `json:"reason"`
+}
+
+// LevelChangeFunc -- extended level change subscriber
+type LevelChangeFunc func(change LevelChange)
+
+const levelHistorySize = 100
+
+var (
+	levelHistory = []LevelChange{}
+
+	levelChangeSubscriberID = int64(0)
+	levelChangeSubscribers  = map[int64]LevelChangeFunc{}
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetLogLevelWithReason -- set log level with the audit info
+func (f *Facility) SetLogLevelWithReason(levelName string, funcNameMode FuncNameMode, actor string, reason string) (oldLevel Level, err error) {
+	var notify alertNotifications
+	defer notify.call()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	return f.setLogLevel(levelName, funcNameMode, actor, reason, &notify)
+}
+
+// SetLogLevelWithReason -- set log level with the audit info
+func SetLogLevelWithReason(levelName string, funcNameMode FuncNameMode, actor string, reason string) (oldLevel Level, err error) {
+	return stdFacility.SetLogLevelWithReason(levelName, funcNameMode, actor, reason)
+}
+
+// LevelChangeHistory -- last log level changes, the oldest first
+func LevelChangeHistory() []LevelChange {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	list := make([]LevelChange, len(levelHistory))
+	copy(list, levelHistory)
+	return list
+}
+
+// AddLevelChangeFunc --
+func AddLevelChangeFunc(f LevelChangeFunc) int64 {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	levelChangeSubscriberID++
+	levelChangeSubscribers[levelChangeSubscriberID] = f
+	return levelChangeSubscriberID
+}
+
+// DelLevelChangeFunc --
+func DelLevelChangeFunc(id int64) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	delete(levelChangeSubscribers, id)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Must be called under the mutex
+func addLevelChange(change LevelChange) {
+	if len(levelHistory) >= levelHistorySize {
+		levelHistory = levelHistory[1:]
+	}
+	levelHistory = append(levelHistory, change)
+}
+
+func (c LevelChange) by() string {
+	switch {
+	case c.Actor == "" && c.Reason == "":
+		return ""
+	case c.Reason == "":
+		return fmt.Sprintf(" (changed by %s)", c.Actor)
+	case c.Actor == "":
+		return fmt.Sprintf(" (reason: %s)", c.Reason)
+	default:
+		return fmt.Sprintf(" (changed by %s, reason: %s)", c.Actor, c.Reason)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
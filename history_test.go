@@ -0,0 +1,56 @@
+package log
+
+import (
+	"strings"
+	"testing"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestLevelChangeHistory(t *testing.T) {
+	console := resetLog(t)
+
+	var got []LevelChange
+	id := AddLevelChangeFunc(func(c LevelChange) {
+		got = append(got, c)
+	})
+	defer DelLevelChangeFunc(id)
+
+	f := GetFacility("cache")
+
+	SetLogLevel("INFO", FuncNameModeNone)
+	f.SetLogLevelWithReason("TRACE1", FuncNameModeNone, "admin", "debugging")
+	f.SetLogLevelWithReason("TRACE1", FuncNameModeNone, "admin", "no change")
+	SetLogLevelWithReason("ERR", FuncNameModeNone, "", "incident")
+
+	history := LevelChangeHistory()
+	expected := []LevelChange{
+		{Facility: StdFacilityName, Old: DEBUG, New: INFO},
+		{Facility: "cache", Old: DEBUG, New: TRACE1, Actor: "admin", Reason: "debugging"},
+		{Facility: StdFacilityName, Old: INFO, New: ERR, Reason: "incident"},
+	}
+
+	if len(history) != len(expected) || len(got) != len(expected) {
+		t.Fatalf("got %d history records and %d notifications, expected %d", len(history), len(got), len(expected))
+	}
+
+	for i, exp := range expected {
+		h := history[i]
+		h.Time = exp.Time
+		if h != exp {
+			t.Errorf("[%d] got %+v, expected %+v", i, h, exp)
+		}
+		if got[i] != history[i] {
+			t.Errorf("[%d] notification %+v differs from the history %+v", i, got[i], history[i])
+		}
+		if i > 0 && history[i].Time.Before(history[i-1].Time) {
+			t.Errorf("[%d] history is not ordered", i)
+		}
+	}
+
+	if !strings.Contains(console.String(), `<cache> Log level is "TRACE1" (changed by admin, reason: debugging)`) {
+		t.Errorf("audit info not found:\n%s", console)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
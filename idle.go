@@ -0,0 +1,96 @@
+package log
+
+import (
+	"sync/atomic"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// After a startup burst the file buffer is released by the flusher when nothing was written for idleShrinkPeriods
+// flusher periods in a row. The next write allocates it again.
+
+var (
+	idleShrinkPeriods = 60
+
+	writeCount  int64
+	idlePeriods = 0
+)
+
+// MemoryFootprintInfo -- current sizes of the log buffers in bytes
+type MemoryFootprintInfo struct {
+	FileBuffer       int `json:"fileBuffer"`
+	BeforeFileBuffer int `json:"beforeFileBuffer"`
+	FallbackBuffer   int `json:"fallbackBuffer"`
+	LastBuffer       int `json:"lastBuffer"`
+	MemoryBuffer     int `json:"memoryBuffer"`
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetIdleShrink -- release the file buffer after the number of idle flusher periods. Zero disables it.
+func SetIdleShrink(periods int) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if periods < 0 {
+		periods = 0
+	}
+	idleShrinkPeriods = periods
+}
+
+// MemoryFootprint -- get current sizes of the log buffers
+func MemoryFootprint() (info MemoryFootprintInfo) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	fileWriterMutex.Lock()
+	if fileWriter != nil {
+		info.FileBuffer = fileWriter.Size()
+	}
+	fileWriterMutex.Unlock()
+
+	info.BeforeFileBuffer = linesSize(beforeFileBuf)
+	info.FallbackBuffer = linesSize(fallbackBuf)
+	info.LastBuffer = linesSize(lastBuf)
+	for _, m := range memoryBuf {
+		info.MemoryBuffer += len(m.text)
+	}
+
+	return
+}
+
+func linesSize(list []string) (n int) {
+	for _, s := range list {
+		n += len(s)
+	}
+	return
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// idleTick -- called by the flusher every period
+func idleTick() {
+	mutex.Lock()
+	periods := idleShrinkPeriods
+	mutex.Unlock()
+
+	if atomic.SwapInt64(&writeCount, 0) != 0 || periods == 0 {
+		idlePeriods = 0
+		return
+	}
+
+	idlePeriods++
+	if idlePeriods < periods {
+		return
+	}
+
+	fileWriterMutex.Lock()
+	defer fileWriterMutex.Unlock()
+
+	if fileWriter != nil {
+		fileWriter.Flush()
+		fileWriter = nil
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
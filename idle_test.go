@@ -0,0 +1,97 @@
+package log
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestIdleShrink(t *testing.T) {
+	resetLog(t)
+
+	const bufSize = 1 << 20
+
+	SetFile(t.TempDir(), "", false, bufSize, 0)
+	SetIdleShrink(3)
+
+	for i := 0; i < 1000; i++ {
+		Message(INFO, "burst %d", i)
+	}
+
+	if n := MemoryFootprint().FileBuffer; n != bufSize {
+		t.Fatalf("got file buffer %d, expected %d", n, bufSize)
+	}
+	if n := MemoryFootprint().BeforeFileBuffer; n != 0 {
+		t.Errorf("got before file buffer %d, expected 0", n)
+	}
+
+	for i := 0; i < 3; i++ {
+		idleTick()
+		if n := MemoryFootprint().FileBuffer; n != bufSize {
+			t.Fatalf("[%d] buffer is released too early", i)
+		}
+	}
+	idleTick()
+	if n := MemoryFootprint().FileBuffer; n != 0 {
+		t.Fatalf("got file buffer %d after idle, expected 0", n)
+	}
+
+	// Concurrent writes with the shrink
+	wg := new(sync.WaitGroup)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				Message(INFO, "after idle %d.%d", i, j)
+			}
+		}(i)
+	}
+	for i := 0; i < 20; i++ {
+		idleTick()
+	}
+	wg.Wait()
+
+	Message(INFO, "after idle 4.0")
+	if n := MemoryFootprint().FileBuffer; n != bufSize {
+		t.Errorf("got file buffer %d after the burst, expected %d", n, bufSize)
+	}
+
+	writerFlush()
+
+	data, err := os.ReadFile(FileName())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := string(data)
+
+	for _, m := range []string{"burst 0", "burst 999", "after idle 0.0", "after idle 3.99"} {
+		if !strings.Contains(s, m+"\n") {
+			t.Errorf("%q is lost", m)
+		}
+	}
+	if n := strings.Count(s, "after idle "); n != 401 {
+		t.Errorf("got %d messages after idle, expected 401", n)
+	}
+}
+
+func TestIdleShrinkDisabled(t *testing.T) {
+	resetLog(t)
+
+	SetFile(t.TempDir(), "", false, 4096, 0)
+	SetIdleShrink(0)
+	Message(INFO, "message")
+
+	for i := 0; i < 100; i++ {
+		idleTick()
+	}
+
+	if n := MemoryFootprint().FileBuffer; n != 4096 {
+		t.Errorf("got file buffer %d, expected 4096", n)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,17 @@
+/*
+Package alpha is a helper for the facility derived from the caller package
+*/
+package alpha
+
+import (
+	"github.com/alrusov/log"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Log -- log the message via the std facility
+func Log(level log.Level, message string) {
+	log.Message(level, "%s", message)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,280 @@
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alrusov/misc"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// The JSON record is encoded by hand in the fixed order: the schema version, the time, the level, the facility,
+// the function, the text, the event ID and then the extra fields sorted by name. New optional fields are added after
+// the existing ones and only when they are not empty, so records that don't use them stay byte to byte the same.
+// The schema version is increased only by incompatible changes. The function is taken from the line when the function name mode is on.
+// The options are checked by NewJSONFormat, Render never fails. The raw line of WriteRaw has no level and no prefix,
+// its record is the schema version, the line untouched and the extra fields: {"v":1,"raw":"..."}.
+
+// JSONSchemaVersion -- the "v" field of every JSON record
+const JSONSchemaVersion = 1
+
+// JSONLevelMode -- how the level is emitted
+type JSONLevelMode string
+
+const (
+	// JSONLevelName -- "INFO" (default)
+	JSONLevelName = JSONLevelMode("name")
+	// JSONLevelShort -- "IN"
+	JSONLevelShort = JSONLevelMode("short")
+	// JSONLevelNumber -- 6
+	JSONLevelNumber = JSONLevelMode("number")
+)
+
+// JSONFieldNames -- names of the record fields, the empty name means the default one
+type JSONFieldNames struct {
+	Version  string // "v"
+	Time     string // "time", "@timestamp" for Elastic
+	Level    string // "level"
+	Facility string // "facility"
+	Func     string // "func"
+	Text     string // "text"
+	EventID  string // "event_id", only if the record has it
+	Raw      string // "raw", the line of WriteRaw
+}
+
+// JSONOptions -- options of the JSON format
+type JSONOptions struct {
+	Fields      JSONFieldNames
+	Level       JSONLevelMode  // JSONLevelName if empty
+	KeepEmpty   bool           // emit the empty facility and function, they are omitted by default
+	ExtraFields map[string]any // added to every record
+}
+
+type jsonFormat struct {
+	id        string
+	keys      [jsonFieldsCount]string // quoted names with ':'
+	level     JSONLevelMode
+	keepEmpty bool
+	extra     string // `,"name":value...` of the extra fields
+}
+
+const (
+	jsonVersion = iota
+	jsonTime
+	jsonLevel
+	jsonFacility
+	jsonFunc
+	jsonText
+	jsonEventID
+	jsonRaw
+	jsonFieldsCount
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// NewJSONFormat -- the JSON format with the options. Unknown level mode, duplicate field names and extra fields that
+// can't be marshaled are errors.
+func NewJSONFormat(opts JSONOptions) (Format, error) {
+	f := jsonFormat{
+		level:     opts.Level,
+		keepEmpty: opts.KeepEmpty,
+	}
+
+	switch f.level {
+	case "":
+		f.level = JSONLevelName
+	case JSONLevelName, JSONLevelShort, JSONLevelNumber:
+	default:
+		return nil, fmt.Errorf(`unknown JSON level mode "%s"`, opts.Level)
+	}
+
+	names := [jsonFieldsCount]string{
+		jsonName(opts.Fields.Version, "v"),
+		jsonName(opts.Fields.Time, "time"),
+		jsonName(opts.Fields.Level, "level"),
+		jsonName(opts.Fields.Facility, "facility"),
+		jsonName(opts.Fields.Func, "func"),
+		jsonName(opts.Fields.Text, "text"),
+		jsonName(opts.Fields.EventID, "event_id"),
+		jsonName(opts.Fields.Raw, "raw"),
+	}
+
+	used := make(map[string]bool, len(names)+len(opts.ExtraFields))
+	for i, name := range names {
+		if used[name] {
+			return nil, fmt.Errorf(`duplicate JSON field "%s"`, name)
+		}
+		used[name] = true
+		f.keys[i] = jsonString(name) + ":"
+	}
+
+	extra := make([]string, 0, len(opts.ExtraFields))
+	for name := range opts.ExtraFields {
+		extra = append(extra, name)
+	}
+	sort.Strings(extra)
+
+	var b strings.Builder
+	for _, name := range extra {
+		if name == "" {
+			return nil, errors.New("empty JSON extra field name")
+		}
+		if used[name] {
+			return nil, fmt.Errorf(`JSON extra field "%s" duplicates the record field`, name)
+		}
+
+		v, err := json.Marshal(opts.ExtraFields[name])
+		if err != nil {
+			return nil, fmt.Errorf(`JSON extra field "%s": %s`, name, err)
+		}
+
+		b.WriteString(",")
+		b.WriteString(jsonString(name))
+		b.WriteString(":")
+		b.Write(v)
+	}
+	f.extra = b.String()
+
+	f.id = fmt.Sprintf("json:%s|%s|%t|%s", strings.Join(names[:], ","), f.level, f.keepEmpty, f.extra)
+	return f, nil
+}
+
+// mustJSONFormat -- for the predefined formats
+func mustJSONFormat(opts JSONOptions) Format {
+	f, err := NewJSONFormat(opts)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func (f jsonFormat) ID() string {
+	return f.id
+}
+
+func (f jsonFormat) Render(r Record) string {
+	if r.Raw {
+		return f.renderRaw(r)
+	}
+
+	text := r.Message
+	fn := ""
+	if text == "" {
+		text = strings.TrimSuffix(r.Line, misc.EOS)
+		if info, ok := ParseLine(r.Line); ok {
+			text = info.Text
+			fn, text = splitFuncName(r.Level, r.funcName, text)
+			text = strings.TrimPrefix(text, eventToken(r.EventID))
+		}
+	}
+
+	var b strings.Builder
+	b.Grow(len(text) + len(f.extra) + 128)
+
+	b.WriteString("{")
+	b.WriteString(f.keys[jsonVersion])
+	b.WriteString(strconv.Itoa(JSONSchemaVersion))
+
+	b.WriteString(",")
+	b.WriteString(f.keys[jsonTime])
+	t, _ := r.Time.MarshalJSON()
+	b.Write(t)
+
+	b.WriteString(",")
+	b.WriteString(f.keys[jsonLevel])
+	short, long := GetLogLevelName(r.Level)
+	switch f.level {
+	case JSONLevelShort:
+		b.WriteString(jsonString(short))
+	case JSONLevelNumber:
+		b.WriteString(strconv.Itoa(int(r.Level)))
+	default:
+		b.WriteString(jsonString(long))
+	}
+
+	if r.Facility != "" || f.keepEmpty {
+		b.WriteString(",")
+		b.WriteString(f.keys[jsonFacility])
+		b.WriteString(jsonString(r.Facility))
+	}
+
+	if fn != "" || f.keepEmpty {
+		b.WriteString(",")
+		b.WriteString(f.keys[jsonFunc])
+		b.WriteString(jsonString(fn))
+	}
+
+	b.WriteString(",")
+	b.WriteString(f.keys[jsonText])
+	b.WriteString(jsonString(text))
+
+	if r.EventID != "" {
+		b.WriteString(",")
+		b.WriteString(f.keys[jsonEventID])
+		b.WriteString(jsonString(r.EventID))
+	}
+
+	b.WriteString(f.extra)
+	b.WriteString("}")
+	b.WriteString(misc.EOS)
+
+	return b.String()
+}
+
+// renderRaw -- the raw line without the parsing
+func (f jsonFormat) renderRaw(r Record) string {
+	var b strings.Builder
+	b.Grow(len(r.Line) + len(f.extra) + 32)
+
+	b.WriteString("{")
+	b.WriteString(f.keys[jsonVersion])
+	b.WriteString(strconv.Itoa(JSONSchemaVersion))
+
+	b.WriteString(",")
+	b.WriteString(f.keys[jsonRaw])
+	b.WriteString(jsonString(strings.TrimSuffix(r.Line, misc.EOS)))
+
+	b.WriteString(f.extra)
+	b.WriteString("}")
+	b.WriteString(misc.EOS)
+
+	return b.String()
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// splitFuncName -- the function name and the text of the parsed line
+func splitFuncName(level Level, mode FuncNameOverride, text string) (string, string) {
+	if with, _ := mode.withFuncName(level); !with {
+		return "", text
+	}
+
+	fn, rest, found := strings.Cut(text, ": ")
+	if !found {
+		return "", text
+	}
+	return fn, rest
+}
+
+// jsonName -- the field name or the default one
+func jsonName(name string, dflt string) string {
+	if name == "" {
+		return dflt
+	}
+	return name
+}
+
+// jsonString -- the quoted and escaped string
+func jsonString(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
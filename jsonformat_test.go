@@ -0,0 +1,133 @@
+package log
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestJSONFormatGolden(t *testing.T) {
+	resetLog(t)
+
+	ts := time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC)
+	db := Record{Time: ts, Level: INFO, Facility: "db", Line: "[123] IN 2024-05-03 12:00:00.000 <db> stored <1>\n"}
+	std := Record{Time: ts, Level: ERR, Line: "[123] ER 2024-05-03 12:00:00.000 failed\n"}
+	fn := Record{Time: ts, Level: INFO, Facility: "db", Line: "[123] IN 2024-05-03 12:00:00.000 <db> store.Put: stored 1\n"}
+
+	tests := []struct {
+		name     string
+		opts     JSONOptions
+		funcMode FuncNameMode
+		r        Record
+		expected string
+	}{
+		{
+			"default", JSONOptions{}, FuncNameModeNone, db,
+			`{"v":1,"time":"2024-05-03T12:00:00Z","level":"INFO","facility":"db","text":"stored \u003c1\u003e"}`,
+		},
+		{
+			"default-std", JSONOptions{}, FuncNameModeNone, std,
+			`{"v":1,"time":"2024-05-03T12:00:00Z","level":"ERR","text":"failed"}`,
+		},
+		{
+			"elastic-short", JSONOptions{Fields: JSONFieldNames{Time: "@timestamp", Text: "message"}, Level: JSONLevelShort}, FuncNameModeNone, db,
+			`{"v":1,"@timestamp":"2024-05-03T12:00:00Z","level":"IN","facility":"db","message":"stored \u003c1\u003e"}`,
+		},
+		{
+			"number-renamed", JSONOptions{Fields: JSONFieldNames{Version: "schema", Level: "severity", Facility: "logger"}, Level: JSONLevelNumber}, FuncNameModeNone, db,
+			`{"schema":1,"time":"2024-05-03T12:00:00Z","severity":6,"logger":"db","text":"stored \u003c1\u003e"}`,
+		},
+		{
+			"keep-empty", JSONOptions{KeepEmpty: true}, FuncNameModeNone, std,
+			`{"v":1,"time":"2024-05-03T12:00:00Z","level":"ERR","facility":"","func":"","text":"failed"}`,
+		},
+		{
+			"func", JSONOptions{}, FuncNameModeShort, fn,
+			`{"v":1,"time":"2024-05-03T12:00:00Z","level":"INFO","facility":"db","func":"store.Put","text":"stored 1"}`,
+		},
+		{
+			"func-off", JSONOptions{}, FuncNameModeNone, fn,
+			`{"v":1,"time":"2024-05-03T12:00:00Z","level":"INFO","facility":"db","text":"store.Put: stored 1"}`,
+		},
+		{
+			"extra", JSONOptions{ExtraFields: map[string]any{"service": "api", "dc": 1, "tags": []string{"a", "b"}}}, FuncNameModeNone, std,
+			`{"v":1,"time":"2024-05-03T12:00:00Z","level":"ERR","text":"failed","dc":1,"service":"api","tags":["a","b"]}`,
+		},
+		{
+			"notifier", JSONOptions{}, FuncNameModeShort, Record{Time: ts, Level: WARNING, Facility: "db", Message: "slow: 5s"},
+			`{"v":1,"time":"2024-05-03T12:00:00Z","level":"WARNING","facility":"db","text":"slow: 5s"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		SetFuncNameMode(tc.funcMode)
+
+		f, err := NewJSONFormat(tc.opts)
+		if err != nil {
+			t.Fatalf("[%s] %s", tc.name, err)
+		}
+
+		// The encoding doesn't depend on the map iteration order
+		for i := 0; i < 10; i++ {
+			if s := f.Render(tc.r); s != tc.expected+"\n" {
+				t.Fatalf("[%s] got\n%s\nexpected\n%s", tc.name, s, tc.expected)
+			}
+		}
+
+		if !json.Valid([]byte(tc.expected)) {
+			t.Errorf("[%s] invalid JSON", tc.name)
+		}
+	}
+
+	if FormatJSON.Render(db) != tests[0].expected+"\n" {
+		t.Errorf("FormatJSON differs from the default options")
+	}
+}
+
+func TestJSONFormatOptionalFields(t *testing.T) {
+	resetLog(t)
+
+	// The optional fields absent in the record don't change the output of the records without them
+	r := Record{Time: time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC), Level: INFO, Line: "[123] IN 2024-05-03 12:00:00.000 plain\n"}
+	base := `{"v":1,"time":"2024-05-03T12:00:00Z","level":"INFO","text":"plain"}` + "\n"
+
+	for _, mode := range []FuncNameMode{FuncNameModeNone, FuncNameModeShort} {
+		SetFuncNameMode(mode)
+		if s := FormatJSON.Render(r); s != base {
+			t.Errorf("[%s] got %s", mode, s)
+		}
+	}
+
+	extra := mustJSONFormat(JSONOptions{ExtraFields: map[string]any{"host": "h1"}}).Render(r)
+	if !strings.HasPrefix(extra, strings.TrimSuffix(base, "}\n")+",") {
+		t.Errorf("the extra fields change the standard ones: %s", extra)
+	}
+}
+
+func TestJSONFormatErrors(t *testing.T) {
+	tests := []JSONOptions{
+		{Level: "code"},
+		{Fields: JSONFieldNames{Time: "text"}},
+		{Fields: JSONFieldNames{Version: "level"}},
+		{ExtraFields: map[string]any{"time": "x"}},
+		{ExtraFields: map[string]any{"": "x"}},
+		{ExtraFields: map[string]any{"ch": make(chan int)}},
+	}
+
+	for i, opts := range tests {
+		if f, err := NewJSONFormat(opts); err == nil || f != nil {
+			t.Errorf("[%d] the options are accepted", i)
+		}
+	}
+
+	a := mustJSONFormat(JSONOptions{Fields: JSONFieldNames{Time: "@timestamp"}})
+	b := mustJSONFormat(JSONOptions{})
+	if a.ID() == b.ID() || b.ID() != FormatJSON.ID() {
+		t.Errorf("unexpected IDs %q, %q", a.ID(), b.ID())
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
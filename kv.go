@@ -0,0 +1,104 @@
+package log
+
+import (
+	"strconv"
+	"strings"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Key-value fields come from several layers: the call site, the facility context and the static fields.
+// The order is stable: call site pairs in the call order, then the context fields in the insertion order,
+// then the static fields in the registration order. A key is printed once, the layer closest to the call site wins.
+// Within one layer the repeated key keeps its first position and gets the last value.
+
+// KVPair -- the key with the value
+type KVPair struct {
+	Key   string
+	Value any
+}
+
+// BadKey -- key of the value without the string key
+const BadKey = "!BADKEY"
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// NormalizeKV -- pairs of the call site kv followed by the pairs of outer layers from the closest to the farthest.
+// Every layer is a list of alternating keys and values or KVPair elements. A value without the string key
+// (including the last element of the odd length list) is kept with BadKey.
+func NormalizeKV(kv []any, outer ...[]any) []KVPair {
+	pairs := []KVPair{}
+	index := map[string]int{}
+
+	add := func(layer []any) {
+		seen := map[string]bool{}
+
+		for i := 0; i < len(layer); i++ {
+			var p KVPair
+
+			switch v := layer[i].(type) {
+			case KVPair:
+				p = v
+			case string:
+				if i+1 >= len(layer) {
+					p = KVPair{Key: BadKey, Value: v}
+					break
+				}
+				i++
+				p = KVPair{Key: v, Value: layer[i]}
+			default:
+				p = KVPair{Key: BadKey, Value: v}
+			}
+
+			if p.Key == BadKey {
+				pairs = append(pairs, p)
+				continue
+			}
+
+			if n, exists := index[p.Key]; exists {
+				if seen[p.Key] {
+					// The same layer
+					pairs[n].Value = p.Value
+				}
+				continue
+			}
+
+			index[p.Key] = len(pairs)
+			seen[p.Key] = true
+			pairs = append(pairs, p)
+		}
+	}
+
+	add(kv)
+	for _, layer := range outer {
+		add(layer)
+	}
+
+	return pairs
+}
+
+// RenderKV -- pairs as key=value separated by spaces, values with spaces, quotes, "=" or line breaks are quoted.
+// The durations, sizes and counts are rendered with the human units.
+func RenderKV(pairs []KVPair) string {
+	var b strings.Builder
+	for _, p := range pairs {
+		writeKV(&b, p.Key, kvValue(p.Value))
+	}
+	return b.String()
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func writeKV(b *strings.Builder, key string, v string) {
+	if b.Len() > 0 {
+		b.WriteByte(' ')
+	}
+	b.WriteString(key)
+	b.WriteByte('=')
+	if v == "" || strings.ContainsAny(v, " \t\r\n\"=") {
+		v = strconv.Quote(v)
+	}
+	b.WriteString(v)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
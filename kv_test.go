@@ -0,0 +1,72 @@
+package log
+
+import (
+	"errors"
+	"testing"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestNormalizeKV(t *testing.T) {
+	tests := []struct {
+		name     string
+		kv       []any
+		outer    [][]any
+		expected string
+	}{
+		{"empty", nil, nil, ""},
+		{"call site order", []any{"b", 1, "a", 2, "c", 3}, nil, "b=1 a=2 c=3"},
+		{"layers order",
+			[]any{"call", 1},
+			[][]any{{"ctx2", 2, "ctx1", 3}, {"static2", 4, "static1", 5}},
+			"call=1 ctx2=2 ctx1=3 static2=4 static1=5",
+		},
+		{"call site over context",
+			[]any{"user", "call"},
+			[][]any{{"req", 1, "user", "ctx"}},
+			"user=call req=1",
+		},
+		{"call site over static",
+			[]any{"app", "call"},
+			[][]any{nil, {"host", "h1", "app", "static"}},
+			"app=call host=h1",
+		},
+		{"context over static",
+			[]any{"x", 1},
+			[][]any{{"env", "ctx"}, {"env", "static", "ver", 2}},
+			"x=1 env=ctx ver=2",
+		},
+		{"all layers",
+			[]any{"k", "call"},
+			[][]any{{"k", "ctx"}, {"k", "static"}},
+			"k=call",
+		},
+		{"same layer", []any{"a", 1, "b", 2, "a", 3}, nil, "a=3 b=2"},
+		{"same outer layer",
+			[]any{"a", 1},
+			[][]any{{"b", 2, "a", 3, "b", 4}},
+			"a=1 b=4",
+		},
+		{"odd length", []any{"a", 1, "dangling"}, nil, `a=1 !BADKEY=dangling`},
+		{"odd length layers",
+			[]any{"a", 1, "dangling"},
+			[][]any{{"b", 2, "c"}, {"d", 4}},
+			`a=1 !BADKEY=dangling b=2 !BADKEY=c d=4`,
+		},
+		{"non-string key", []any{42, "a", 1}, nil, `!BADKEY=42 a=1`},
+		{"pairs", []any{KVPair{Key: "a", Value: 1}, "b", 2}, [][]any{{KVPair{Key: "a", Value: 3}}}, "a=1 b=2"},
+		{"quoting",
+			[]any{"s", "two words", "e", errors.New("x=y"), "empty", "", "nl", "a\nb", "nil", nil},
+			nil,
+			`s="two words" e="x=y" empty="" nl="a\nb" nil=<nil>`,
+		},
+	}
+
+	for _, test := range tests {
+		if s := RenderKV(NormalizeKV(test.kv, test.outer...)); s != test.expected {
+			t.Errorf("%s: got %q, expected %q", test.name, s, test.expected)
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,103 @@
+package log
+
+import (
+	"strings"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// LogEntry -- the line kept for LastLogQuery
+type LogEntry struct {
+	Time     time.Time
+	Level    Level
+	Facility string
+	Text     string
+}
+
+// LastLogFilter -- conditions of LastLogQuery, all set conditions must match
+type LastLogFilter struct {
+	// MinLevel -- entries of this level and more severe ones. The zero value is EMERG, UNKNOWN passes all levels.
+	MinLevel Level
+	// Facilities -- facility names, the trailing "*" and StdFacilityAlias are allowed as in SetConsoleFacilityFilter. Empty for all.
+	Facilities []string
+	// Contains -- substring of the text
+	Contains string
+	// Limit -- return no more than Limit newest entries, 0 for all
+	Limit int
+}
+
+const defaultLastLogSize = 200
+
+var (
+	lastLog      = make([]LogEntry, defaultLastLogSize)
+	lastLogStart = 0
+	lastLogLen   = 0
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetLastLogSize -- number of entries kept for LastLogQuery, 0 for the default. The kept entries are discarded.
+func SetLastLogSize(size int) {
+	if size <= 0 {
+		size = defaultLastLogSize
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	lastLog = make([]LogEntry, size)
+	lastLogStart = 0
+	lastLogLen = 0
+}
+
+// LastLogQuery -- kept entries matching the filter, the oldest first
+func LastLogQuery(q LastLogFilter) []LogEntry {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	list := []LogEntry{}
+
+	for i := lastLogLen - 1; i >= 0; i-- {
+		if q.Limit > 0 && len(list) >= q.Limit {
+			break
+		}
+
+		e := &lastLog[(lastLogStart+i)%len(lastLog)]
+
+		if !e.Level.passes(q.MinLevel) ||
+			(len(q.Facilities) != 0 && !matchFacility(q.Facilities, e.Facility)) ||
+			(q.Contains != "" && !strings.Contains(e.Text, q.Contains)) {
+			continue
+		}
+
+		list = append(list, *e)
+	}
+
+	for i, j := 0, len(list)-1; i < j; i, j = i+1, j-1 {
+		list[i], list[j] = list[j], list[i]
+	}
+
+	return list
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// lastLogAdd -- Must be called under the mutex
+func lastLogAdd(facility string, level Level, t time.Time, text string) {
+	i := (lastLogStart + lastLogLen) % len(lastLog)
+	if lastLogLen < len(lastLog) {
+		lastLogLen++
+	} else {
+		lastLogStart = (lastLogStart + 1) % len(lastLog)
+	}
+
+	lastLog[i] = LogEntry{
+		Time:     t,
+		Level:    level,
+		Facility: facility,
+		Text:     strings.TrimSpace(text),
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,101 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestLastLogQuery(t *testing.T) {
+	resetLog(t)
+
+	scheduler := GetFacility("scheduler")
+	http := GetFacility("http")
+
+	Message(ERR, "disk failed")
+	scheduler.Message(INFO, "job started")
+	scheduler.Message(ERR, "job failed")
+	http.Message(WARNING, "slow request")
+	Message(INFO, "job done")
+
+	all := LastLogFilter{MinLevel: UNKNOWN}
+
+	tests := []struct {
+		name     string
+		filter   func(q *LastLogFilter)
+		expected []string
+	}{
+		{"all", func(q *LastLogFilter) {}, []string{"disk failed", "job started", "job failed", "slow request", "job done"}},
+		{"level", func(q *LastLogFilter) { q.MinLevel = ERR }, []string{"disk failed", "job failed"}},
+		{"facility", func(q *LastLogFilter) { q.Facilities = []string{"scheduler"} }, []string{"job started", "job failed"}},
+		{"std facility", func(q *LastLogFilter) { q.Facilities = []string{StdFacilityAlias, "ht*"} }, []string{"disk failed", "slow request", "job done"}},
+		{"contains", func(q *LastLogFilter) { q.Contains = "job" }, []string{"job started", "job failed", "job done"}},
+		{"limit", func(q *LastLogFilter) { q.Limit = 2 }, []string{"slow request", "job done"}},
+		{"combination", func(q *LastLogFilter) { q.MinLevel = ERR; q.Facilities = []string{"scheduler"}; q.Contains = "job" }, []string{"job failed"}},
+		{"combination limit", func(q *LastLogFilter) { q.Contains = "job"; q.Limit = 1 }, []string{"job done"}},
+		{"nothing", func(q *LastLogFilter) { q.Facilities = []string{"db"} }, []string{}},
+	}
+
+	for _, test := range tests {
+		q := all
+		test.filter(&q)
+
+		list := LastLogQuery(q)
+		if len(list) != len(test.expected) {
+			t.Errorf("%s: got %d entries, expected %d", test.name, len(list), len(test.expected))
+			continue
+		}
+		for i, e := range list {
+			if !strings.HasSuffix(e.Text, " "+test.expected[i]) {
+				t.Errorf("%s: got %q, expected %q", test.name, e.Text, test.expected[i])
+			}
+		}
+	}
+
+	list := LastLogQuery(LastLogFilter{MinLevel: ERR, Facilities: []string{"scheduler"}})
+	if len(list) != 1 || list[0].Level != ERR || list[0].Facility != "scheduler" || list[0].Time.IsZero() {
+		t.Fatalf("unexpected entries %+v", list)
+	}
+
+	list[0].Text = "changed"
+	if list = LastLogQuery(LastLogFilter{MinLevel: ERR, Facilities: []string{"scheduler"}}); list[0].Text == "changed" {
+		t.Error("query result shares the data with the buffer")
+	}
+
+	if n := len(GetLastLog()); n != 5 {
+		t.Errorf("got %d lines in GetLastLog, expected 5", n)
+	}
+}
+
+func TestLastLogSize(t *testing.T) {
+	resetLog(t)
+
+	for i := 0; i < defaultLastLogSize+10; i++ {
+		Message(INFO, "message %d", i)
+	}
+
+	list := LastLogQuery(LastLogFilter{MinLevel: UNKNOWN})
+	if len(list) != defaultLastLogSize || !strings.HasSuffix(list[0].Text, " message 10") {
+		t.Errorf("got %d entries starting with %q", len(list), list[0].Text)
+	}
+
+	SetLastLogSize(3)
+
+	for i := 0; i < 5; i++ {
+		Message(INFO, "message %d", i)
+	}
+
+	list = LastLogQuery(LastLogFilter{MinLevel: UNKNOWN})
+	if len(list) != 3 {
+		t.Fatalf("got %d entries, expected 3", len(list))
+	}
+	for i, e := range list {
+		if expected := fmt.Sprintf(" message %d", i+2); !strings.HasSuffix(e.Text, expected) {
+			t.Errorf("got %q, expected %q", e.Text, expected)
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
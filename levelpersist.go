@@ -0,0 +1,205 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// The level persistence keeps the levels changed at runtime over restarts. Every successful level change writes
+// the ExportLevels snapshot to the file (temp file + rename) unless the file has it already, so the restart doesn't
+// refresh the file time the staleness is counted from. The restored levels are put on top of the config ones:
+// when the persistence is enabled and once more after the first SetLogLevels (SetLogLevelsEx, ImportLevels), so the
+// config applied later doesn't override them. The default level is restored to the standard facility only,
+// the facilities absent in the file keep their config levels. The corrupted file and the file not changed for
+// the max age are ignored with WARNING. Off by default.
+
+const (
+	// DefaultLevelPersistenceMaxAge -- the older file is stale
+	DefaultLevelPersistenceMaxAge = 7 * 24 * time.Hour
+
+	levelPersistenceActor = "level persistence"
+)
+
+var (
+	levelPersistPath    = ""
+	levelPersistMaxAge  = DefaultLevelPersistenceMaxAge
+	levelPersistPending = false // restore again after the first SetLogLevels
+	levelsConfigured    = false
+
+	levelPersistDirty atomic.Bool
+	levelPersistMutex sync.Mutex
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// EnableLevelPersistence -- restore the levels from the file and save them there on every change, "" switches it off
+func EnableLevelPersistence(path string) {
+	var notify alertNotifications
+	defer notify.call()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	levelPersistPath = path
+	levelPersistPending = false
+
+	if path == "" {
+		return
+	}
+
+	levelPersistPending = restoreLevels(&notify) && !levelsConfigured
+}
+
+// SetLevelPersistenceMaxAge -- the persisted file not changed for this time is ignored, not positive means never
+func SetLevelPersistenceMaxAge(age time.Duration) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	levelPersistMaxAge = age
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// levelsSet -- the config levels are set, restore the persisted ones on top of them once.
+// The file is read again, it has the changes made since the persistence was enabled.
+// Must be called under the mutex.
+func levelsSet(notify *alertNotifications) {
+	levelsConfigured = true
+
+	if levelPersistPending {
+		levelPersistPending = false
+		restoreLevels(notify)
+	}
+}
+
+// restoreLevels -- apply the persisted levels, false if there are no valid ones. Must be called under the mutex.
+func restoreLevels(notify *alertNotifications) bool {
+	cfg, err := loadLevels(levelPersistPath)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			logger(false, 0, StdFacilityName, WARNING, nil, "Persisted log levels are ignored: %s", err)
+		}
+		return false
+	}
+
+	names := make([]string, 0, len(cfg.Facilities))
+	for name := range cfg.Facilities {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	restored := make([]string, 0, len(names)+1)
+
+	if cfg.Default != "" {
+		stdFacility.setLogLevel(cfg.Default, FuncNameModeKeep, levelPersistenceActor, levelPersistPath, notify)
+		restored = append(restored, "*="+cfg.Default)
+	}
+
+	for _, name := range names {
+		f := newFacility(name)
+		f.setLogLevel(cfg.Facilities[name], FuncNameModeKeep, levelPersistenceActor, levelPersistPath, notify)
+		restored = append(restored, f.name+"="+cfg.Facilities[name])
+	}
+
+	if len(restored) > 0 {
+		logger(false, 0, StdFacilityName, NOTICE, nil, "Log levels restored from %s: %s", levelPersistPath, strings.Join(restored, ", "))
+	}
+
+	return true
+}
+
+// loadLevels -- the persisted levels, all of them are valid
+func loadLevels(path string) (*LevelsConfig, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if levelPersistMaxAge > 0 {
+		if age := now().Sub(fi.ModTime()); age > levelPersistMaxAge {
+			return nil, fmt.Errorf("%s is stale, saved %s ago", path, age.Round(time.Second))
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &LevelsConfig{}
+	if err = json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("%s is corrupted: %w", path, err)
+	}
+
+	if cfg.Default != "" {
+		if _, ok := Str2Level(cfg.Default); !ok {
+			return nil, fmt.Errorf(`%s is corrupted: invalid default level "%s"`, path, cfg.Default)
+		}
+	}
+	for name, level := range cfg.Facilities {
+		if _, ok := Str2Level(level); !ok {
+			return nil, fmt.Errorf(`%s is corrupted: invalid level "%s" of "%s"`, path, level, name)
+		}
+	}
+
+	return cfg, nil
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// persistLevels -- save the levels after the mutex is released, once for all changes of the call.
+// Must be called under the mutex.
+func persistLevels(notify *alertNotifications) {
+	if levelPersistPath == "" || !levelPersistDirty.CompareAndSwap(false, true) {
+		return
+	}
+
+	*notify = append(*notify, saveLevels)
+}
+
+// saveLevels -- write the levels snapshot, must be called without the mutex
+func saveLevels() {
+	levelPersistMutex.Lock()
+	defer levelPersistMutex.Unlock()
+
+	levelPersistDirty.Store(false)
+
+	mutex.Lock()
+	path := levelPersistPath
+	mutex.Unlock()
+
+	if path == "" {
+		return
+	}
+
+	data, err := json.MarshalIndent(ExportLevels(), "", "\t")
+	if err == nil {
+		if old, e := os.ReadFile(path); e == nil && bytes.Equal(old, data) {
+			return
+		}
+
+		tmp := path + ".tmp"
+		if err = os.WriteFile(tmp, data, 0644); err == nil {
+			err = os.Rename(tmp, path)
+		}
+		if err != nil {
+			os.Remove(tmp)
+		}
+	}
+
+	if err != nil {
+		Message(WARNING, "Log levels are not persisted: %s", err)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
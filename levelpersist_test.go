@@ -0,0 +1,205 @@
+package log
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alrusov/misc"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestLevelPersistence(t *testing.T) {
+	resetLog(t)
+	path := filepath.Join(t.TempDir(), "levels.json")
+
+	// The first run: the config levels and the runtime change by the admin endpoint
+	if err := SetLogLevels("INFO", misc.StringMap{"db": "INFO"}, FuncNameModeNone); err != nil {
+		t.Fatal(err)
+	}
+	EnableLevelPersistence(path)
+	NewFacility("db")
+	NewFacility("api")
+
+	if _, err := GetFacility("db").SetLogLevelWithReason("TRACE2", FuncNameModeKeep, "admin", "incident"); err != nil {
+		t.Fatal(err)
+	}
+
+	var saved LevelsConfig
+	data, err := os.ReadFile(path)
+	if err == nil {
+		err = json.Unmarshal(data, &saved)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Default != "INFO" || saved.Facilities["db"] != "TRACE2" || len(saved.Facilities) != 1 {
+		t.Errorf("unexpected saved levels %+v", saved)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("the temp file is left: %v", err)
+	}
+
+	// The restart: the persistence is enabled before the config is applied
+	console := resetLog(t)
+	EnableLevelPersistence(path)
+
+	if err := SetLogLevelsEx("INFO", misc.StringMap{"db": "ERR", "api": "WARNING"}, FuncNameModeNone, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if l := GetFacility("db").CurrentLogLevel(); l != TRACE2 {
+		t.Errorf("db: got %s, expected the persisted TRACE2", levels[l].name)
+	}
+	if l := GetFacility("api").CurrentLogLevel(); l != WARNING {
+		t.Errorf("api: got %s, expected the config WARNING", levels[l].name)
+	}
+	if n := strings.Count(console.String(), "Log levels restored from "+path+": *=INFO, db=TRACE2"); n != 2 {
+		t.Errorf("got %d notices\n%s", n, console.String())
+	}
+
+	// The config applied again isn't overridden
+	if err := SetLogLevelsEx("INFO", misc.StringMap{"db": "ERR"}, FuncNameModeNone, true); err != nil {
+		t.Fatal(err)
+	}
+	if l := GetFacility("db").CurrentLogLevel(); l != ERR {
+		t.Errorf("db: got %s, expected the config ERR", levels[l].name)
+	}
+
+	h := LevelChangeHistory()
+	found := false
+	for _, c := range h {
+		if c.Facility == "db" && c.New == TRACE2 && c.Actor == levelPersistenceActor {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("no restoration in the history %+v", h)
+	}
+}
+
+func TestLevelPersistenceRestartKeepsFile(t *testing.T) {
+	resetLog(t)
+	path := filepath.Join(t.TempDir(), "levels.json")
+
+	config := func() {
+		if err := SetLogLevelsEx("INFO", misc.StringMap{"db": "INFO"}, FuncNameModeNone, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config()
+	EnableLevelPersistence(path)
+	if _, err := GetFacility("db").SetLogLevelWithReason("TRACE2", FuncNameModeKeep, "admin", "incident"); err != nil {
+		t.Fatal(err)
+	}
+
+	mt := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(path, mt, mt); err != nil {
+		t.Fatal(err)
+	}
+
+	// The restarts restore the levels and don't refresh the file
+	for i := 0; i < 2; i++ {
+		resetLog(t)
+		EnableLevelPersistence(path)
+		config()
+		writerFlush()
+
+		if l := GetFacility("db").CurrentLogLevel(); l != TRACE2 {
+			t.Errorf("[%d] got %s, expected the persisted TRACE2", i, levels[l].name)
+		}
+
+		fi, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !fi.ModTime().Equal(mt) {
+			t.Errorf("[%d] the file is rewritten at %s", i, fi.ModTime())
+		}
+	}
+
+	// The real change is still saved
+	if _, err := GetFacility("db").SetLogLevel("DEBUG", FuncNameModeKeep); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); !strings.Contains(string(data), `"db": "DEBUG"`) {
+		t.Errorf("not saved: %s", data)
+	}
+}
+
+func TestLevelPersistenceIgnored(t *testing.T) {
+	dir := t.TempDir()
+
+	list := []struct {
+		name     string
+		data     string
+		age      time.Duration
+		expected string
+	}{
+		{"corrupted", `{"default":"INFO","facilities":{"db":`, 0, "is corrupted"},
+		{"invalid", `{"default":"INFO","facilities":{"db":"LOUD"}}`, 0, `is corrupted: invalid level "LOUD" of "db"`},
+		{"stale", `{"default":"INFO","facilities":{"db":"TRACE2"}}`, 8 * 24 * time.Hour, "is stale"},
+	}
+
+	for _, c := range list {
+		console := resetLog(t)
+
+		path := filepath.Join(dir, c.name+".json")
+		if err := os.WriteFile(path, []byte(c.data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		mt := time.Now().Add(-c.age)
+		if err := os.Chtimes(path, mt, mt); err != nil {
+			t.Fatal(err)
+		}
+
+		EnableLevelPersistence(path)
+		if err := SetLogLevelsEx("INFO", misc.StringMap{"db": "ERR"}, FuncNameModeNone, true); err != nil {
+			t.Fatal(err)
+		}
+
+		if l := GetFacility("db").CurrentLogLevel(); l != ERR {
+			t.Errorf("[%s] got %s, expected the config ERR", c.name, levels[l].name)
+		}
+
+		s := console.String()
+		if strings.Count(s, "Persisted log levels are ignored: "+path+" "+c.expected) != 1 || !strings.Contains(s, "] WA ") {
+			t.Errorf("[%s] no warning\n%s", c.name, s)
+		}
+		if strings.Contains(s, "restored") {
+			t.Errorf("[%s] restored\n%s", c.name, s)
+		}
+
+		// The ignored file is replaced by the current levels on the change
+		writerFlush()
+		if data, _ := os.ReadFile(path); !strings.Contains(string(data), `"db": "ERR"`) {
+			t.Errorf("[%s] not saved: %s", c.name, data)
+		}
+	}
+
+	// Never stale without the max age
+	resetLog(t)
+
+	path := filepath.Join(dir, "old.json")
+	mt := time.Now().Add(-365 * 24 * time.Hour)
+	if err := os.WriteFile(path, []byte(list[2].data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, mt, mt); err != nil {
+		t.Fatal(err)
+	}
+
+	SetLevelPersistenceMaxAge(0)
+	EnableLevelPersistence(path)
+
+	if l := GetFacility("db").CurrentLogLevel(); l != TRACE2 {
+		t.Errorf("got %s, expected the persisted TRACE2", levels[l].name)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,237 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/alrusov/misc"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// LevelError -- invalid level of the facility
+type LevelError struct {
+	Facility string
+	Level    string
+}
+
+// LevelsError -- all invalid levels found by SetLogLevelsEx
+type LevelsError struct {
+	details []LevelError
+}
+
+var (
+	unknownFacilitiesWarned = map[string]bool{}
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func (e LevelError) Error() string {
+	name := e.Facility
+	if name == StdFacilityName {
+		name = StdFacilityAlias
+	}
+	return fmt.Sprintf(`invalid log level "%s" for facility "%s"`, e.Level, name)
+}
+
+func (e *LevelsError) Error() string {
+	list := make([]string, len(e.details))
+	for i, d := range e.details {
+		list[i] = d.Error()
+	}
+	return strings.Join(list, "; ")
+}
+
+// Details -- list of the invalid levels
+func (e *LevelsError) Details() []LevelError {
+	list := make([]LevelError, len(e.details))
+	copy(list, e.details)
+	return list
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetLogLevelsEx -- validate all levels and set them only if all are valid, otherwise *LevelsError is returned.
+// Facilities named in levels but not existing yet are reported once with WARNING or created if createMissing is set.
+func SetLogLevelsEx(defaultLevelName string, levels misc.StringMap, logFunc FuncNameMode, createMissing bool) error {
+	var notify alertNotifications
+	defer notify.call()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	return setLogLevelsEx(defaultLevelName, levels, logFunc, createMissing, &notify)
+}
+
+// setLogLevelsEx -- must be called under the mutex, notify must be called after the mutex is released
+func setLogLevelsEx(defaultLevelName string, levels misc.StringMap, logFunc FuncNameMode, createMissing bool, notify *alertNotifications) error {
+	var details []LevelError
+
+	if _, ok := Str2Level(defaultLevelName); !ok {
+		details = append(details, LevelError{Facility: "*", Level: defaultLevelName})
+	}
+
+	names := make([]string, 0, len(levels))
+	for name := range levels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if _, ok := Str2Level(levels[name]); !ok {
+			details = append(details, LevelError{Facility: name, Level: levels[name]})
+		}
+	}
+
+	if len(details) > 0 {
+		return &LevelsError{details: details}
+	}
+
+	for _, name := range names {
+		if _, exists := facilities[name]; exists {
+			continue
+		}
+
+		if createMissing {
+			newFacility(name)
+			continue
+		}
+
+		if !unknownFacilitiesWarned[name] {
+			unknownFacilitiesWarned[name] = true
+			logger(false, 0, StdFacilityName, WARNING, nil, `Log level is set for unknown facility "%s"`, name)
+		}
+	}
+
+	for _, f := range facilities {
+		level, exists := levels[f.name]
+		if !exists {
+			level = defaultLevelName
+		}
+		_, _ = f.setLogLevel(level, logFunc, "", "", notify)
+	}
+
+	levelsSet(notify)
+	return nil
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Registered levels get codes after UNKNOWN and a rank between the neighbours,
+// so the comparison of levels is always done by rank rather than by code.
+
+const (
+	maxLevels     = 64
+	levelRankStep = 1 << 16
+)
+
+var (
+	firstLogged atomic.Bool
+
+	registeredRanks = [maxLevels]int{}
+
+	invalidLevelWarned sync.Map
+)
+
+// RegisterLevel -- register the new level right after (less severe than) the given one.
+// It is possible only before the first message is logged.
+func RegisterLevel(after Level, name string, shortName string) (Level, error) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if firstLogged.Load() {
+		return UNKNOWN, errors.New("levels can't be registered after the first message is logged")
+	}
+
+	if after < EMERG || int(after) >= len(levels) || after == UNKNOWN {
+		return UNKNOWN, fmt.Errorf("unknown level %d", after)
+	}
+
+	if name == "" || shortName == "" {
+		return UNKNOWN, errors.New("empty level name")
+	}
+
+	for _, def := range levels {
+		if def.name == name || def.shortName == name || def.name == shortName || def.shortName == shortName {
+			return UNKNOWN, fmt.Errorf(`level "%s" (%s) is already registered`, name, shortName)
+		}
+	}
+
+	if len(levels) >= maxLevels {
+		return UNKNOWN, errors.New("too many levels")
+	}
+
+	ordered := orderedLevels()
+	i := slices.IndexFunc(ordered, func(def logLevelDef) bool { return def.code == after })
+	lo, hi := after.rank(), ordered[i+1].code.rank()
+	if hi-lo < 2 {
+		return UNKNOWN, fmt.Errorf("no room for the level after %s", levels[after].name)
+	}
+
+	level := Level(len(levels))
+	levels = append(levels,
+		logLevelDef{
+			code:      level,
+			name:      name,
+			shortName: shortName,
+		},
+	)
+	registeredRanks[level] = lo + (hi-lo)/2
+
+	return level, nil
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func (l Level) rank() int {
+	if l > UNKNOWN && int(l) < len(levels) {
+		return registeredRanks[l]
+	}
+	return int(l) * levelRankStep
+}
+
+// passes -- is the level the same or more severe than the limit
+func (l Level) passes(limit Level) bool {
+	return l.rank() <= limit.rank()
+}
+
+// checkLevel -- clamp the level outside of the known ones to EMERG or TRACE4, the WARNING is logged once per caller
+func checkLevel(shift int, f *Facility, l Level) Level {
+	if (l >= EMERG && l < UNKNOWN) || (l > UNKNOWN && int(l) < len(levels)) {
+		return l
+	}
+
+	clamped := TRACE4
+	if l < EMERG {
+		clamped = EMERG
+	}
+
+	caller := "?"
+	pc := make([]uintptr, 1)
+	if runtime.Callers(shift+2, pc) > 0 {
+		frame, _ := runtime.CallersFrames(pc).Next()
+		caller = filepath.Base(frame.Function)
+	}
+
+	if _, warned := invalidLevelWarned.LoadOrStore(caller, true); !warned {
+		logger(true, 0, f.name, WARNING, nil, "Invalid log level %d from %s, %s is used", l, caller, levels[clamped].name)
+	}
+
+	return clamped
+}
+
+// orderedLevels -- level definitions from the most severe
+func orderedLevels() []logLevelDef {
+	list := slices.Clone(levels)
+	slices.SortStableFunc(list, func(a, b logLevelDef) int { return a.code.rank() - b.code.rank() })
+	return list
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
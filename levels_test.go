@@ -0,0 +1,159 @@
+package log
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/alrusov/misc"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestSetLogLevelsEx(t *testing.T) {
+	console := resetLog(t)
+
+	a := GetFacility("a")
+	b := GetFacility("b")
+
+	err := SetLogLevelsEx("INFO",
+		misc.StringMap{
+			"a": "ERR",
+			"b": "VERBOSE",
+			"c": "WARNING",
+			"d": "LOUD",
+		},
+		FuncNameModeNone, false,
+	)
+
+	var le *LevelsError
+	if !errors.As(err, &le) {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	exp := []LevelError{{"b", "VERBOSE"}, {"d", "LOUD"}}
+	d := le.Details()
+	if len(d) != len(exp) || d[0] != exp[0] || d[1] != exp[1] {
+		t.Errorf("got %+v, expected %+v", d, exp)
+	}
+
+	if a.CurrentLogLevel() != DEBUG || b.CurrentLogLevel() != DEBUG || CurrentLogLevel() != DEBUG {
+		t.Error("levels are changed")
+	}
+
+	levels := misc.StringMap{"a": "ERR", "c": "WARNING"}
+
+	for i := 0; i < 2; i++ {
+		if err := SetLogLevelsEx("INFO", levels, FuncNameModeNone, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if a.CurrentLogLevel() != ERR || b.CurrentLogLevel() != INFO {
+		t.Error("levels are not changed")
+	}
+	if n := strings.Count(console.String(), `unknown facility "c"`); n != 1 {
+		t.Errorf("unknown facility is reported %d times", n)
+	}
+
+	if err := SetLogLevelsEx("INFO", levels, FuncNameModeNone, true); err != nil {
+		t.Fatal(err)
+	}
+	if GetFacility("c").CurrentLogLevel() != WARNING {
+		t.Error("missing facility is not created")
+	}
+}
+
+func TestRegisterLevel(t *testing.T) {
+	console := resetLog(t)
+
+	audit, err := RegisterLevel(NOTICE, "AUDIT", "AU")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := RegisterLevel(INFO, "AUDIT", "A2"); err == nil {
+		t.Error("duplicate name is registered")
+	}
+	if _, err := RegisterLevel(INFO, "INFO2", "IN"); err == nil {
+		t.Error("duplicate short name is registered")
+	}
+
+	if l, ok := Str2Level("AU"); !ok || l != audit {
+		t.Errorf("Str2Level returned %d, %v", l, ok)
+	}
+	if list := strings.Join(GetLogLevels(), ","); !strings.Contains(list, "WARNING,NOTICE,AUDIT,INFO,TIME") {
+		t.Errorf("unexpected level list %s", list)
+	}
+	if short, long := GetLogLevelName(audit); short != "AU" || long != "AUDIT" {
+		t.Errorf("unexpected names %s %s", short, long)
+	}
+
+	f := GetFacility("billing")
+	if _, err := f.SetLogLevel("AUDIT", FuncNameModeNone); err != nil {
+		t.Fatal(err)
+	}
+
+	console.buf.Reset()
+
+	f.Message(NOTICE, "notice")
+	f.Message(audit, "audit")
+	f.Message(INFO, "info")
+
+	lines := console.Lines()
+	if len(lines) != 2 || !strings.Contains(lines[0], " NO ") || !strings.Contains(lines[1], " AU ") || !strings.HasSuffix(lines[1], "audit") {
+		t.Errorf("unexpected output %q", lines)
+	}
+
+	if _, err := RegisterLevel(INFO, "LATE", "LA"); err == nil {
+		t.Error("level is registered after logging")
+	}
+}
+
+func TestLevelRange(t *testing.T) {
+	console := resetLog(t)
+
+	f := GetFacility("calc")
+	f.SetLogLevel("ERR", FuncNameModeNone)
+	console.buf.Reset()
+
+	f.Message(-1*INFO, "negative")
+	f.ForceMessage(DEBUG, "forced")
+	f.Message(DEBUG, "filtered")
+	f.MessageEx(0, Level(-100), nil, "negative too large")
+	f.ForceMessage(Level(-5), "forced negative")
+	f.Message(Level(100), "too large")
+	f.Message(UNKNOWN, "unknown")
+
+	// The warning is logged once per caller
+	expected := [][2]string{
+		{"IN", "negative"},
+		{"DE", "forced"},
+		{"WA", "Invalid log level 100 from log.TestLevelRange, TRACE4 is used"},
+		{"T4", "negative too large"},
+		{"EM", "forced negative"},
+	}
+
+	lines := console.Lines()
+	if len(lines) != len(expected) {
+		t.Fatalf("got %d lines, expected %d:\n%s", len(lines), len(expected), console.String())
+	}
+	for i, e := range expected {
+		if !strings.Contains(lines[i], "] "+e[0]+" ") || !strings.Contains(lines[i], "<calc> ") || !strings.HasSuffix(lines[i], " "+e[1]) {
+			t.Errorf("[%d] got %q, expected %q", i, lines[i], e)
+		}
+	}
+
+	f.SetLogLevel("TRACE4", FuncNameModeNone)
+	console.buf.Reset()
+
+	f.Message(UNKNOWN, "unknown")
+	f.Message(Level(100), "too large")
+
+	lines = console.Lines()
+	if len(lines) != 2 || !strings.Contains(lines[0], " T4 ") || !strings.HasSuffix(lines[0], "<calc> unknown") || !strings.HasSuffix(lines[1], "<calc> too large") {
+		t.Errorf("unexpected output %q", lines)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,67 @@
+package log
+
+import (
+	"fmt"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// The exported config lists only facilities with the level different from the default one (the level of the standard
+// facility, new facilities get it), so facilities created after the export or absent in another instance don't break
+// the round trip: Export -> Import -> Export gives the same config. Synonyms are informational and ignored by Import.
+
+// LevelsConfig -- levels in effect, importable by ImportLevels
+type LevelsConfig struct {
+	Default      string            `json:"default" toml:"default"`
+	FuncNameMode FuncNameMode      `json:"funcNameMode" toml:"func-name-mode"`
+	Facilities   map[string]string `json:"facilities" toml:"facilities"`
+	Synonyms     map[string]string `json:"synonyms,omitempty" toml:"synonyms,omitempty"` // "V0".."V4" -> level name
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// ExportLevels -- the current levels of all facilities
+func ExportLevels() LevelsConfig {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	cfg := LevelsConfig{
+		Default:      levels[stdFacility.level].name,
+		FuncNameMode: currentFuncNameMode(),
+		Facilities:   make(map[string]string, len(facilities)),
+		Synonyms:     make(map[string]string, MaxVerbosity+1),
+	}
+
+	for name, f := range facilities {
+		if f == stdFacility || f.level == stdFacility.level {
+			continue
+		}
+		cfg.Facilities[name] = levels[f.level].name
+	}
+
+	for v := 0; v <= MaxVerbosity; v++ {
+		cfg.Synonyms[fmt.Sprintf("V%d", v)] = levels[verbosityLevel(v)].name
+	}
+
+	return cfg
+}
+
+// ImportLevels -- apply the config exported by ExportLevels. Nothing is changed if anything is invalid,
+// the levels are validated the same way as by SetLogLevelsEx. Alerts are sent for changed facilities only.
+func ImportLevels(cfg LevelsConfig, createMissing bool) error {
+	var notify alertNotifications
+	defer notify.call()
+
+	switch cfg.FuncNameMode {
+	case "", FuncNameModeKeep, FuncNameModeNone, FuncNameModeShort, FuncNameModeFull:
+	default:
+		return fmt.Errorf(`invalid function name mode "%s"`, cfg.FuncNameMode)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	return setLogLevelsEx(cfg.Default, cfg.Facilities, cfg.FuncNameMode, createMissing, &notify)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,94 @@
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestExportImportLevels(t *testing.T) {
+	resetLog(t)
+
+	GetFacility("a")
+	GetFacility("b")
+	if err := SetLogLevelsEx("INFO", map[string]string{"a": "ERR", "b": "V2"}, FuncNameModeShort, false); err != nil {
+		t.Fatal(err)
+	}
+
+	exported := ExportLevels()
+	expected := LevelsConfig{
+		Default:      "INFO",
+		FuncNameMode: FuncNameModeShort,
+		Facilities:   map[string]string{"a": "ERR", "b": "TRACE2"},
+		Synonyms:     map[string]string{"V0": "DEBUG", "V1": "TRACE1", "V2": "TRACE2", "V3": "TRACE3", "V4": "TRACE4"},
+	}
+	if !reflect.DeepEqual(exported, expected) {
+		t.Fatalf("got %+v, expected %+v", exported, expected)
+	}
+
+	data, err := json.Marshal(exported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cfg LevelsConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	// The facility created between the exports and the changed levels
+	GetFacility("c")
+	if err := SetLogLevelsEx("DEBUG", map[string]string{"a": "ERR", "b": "WARNING"}, FuncNameModeNone, false); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		mu      sync.Mutex
+		changed []string
+	)
+	id := AddAlertFunc(func(facility string, old Level, new Level) {
+		mu.Lock()
+		changed = append(changed, facility)
+		mu.Unlock()
+	})
+	defer DelAlertFunc(id)
+
+	if err := ImportLevels(cfg, true); err != nil {
+		t.Fatal(err)
+	}
+
+	sort.Strings(changed)
+	if !reflect.DeepEqual(changed, []string{StdFacilityName, "b", "c"}) {
+		t.Errorf("alerts are sent for %q", changed)
+	}
+
+	if again := ExportLevels(); !reflect.DeepEqual(again, exported) {
+		t.Errorf("round trip changed the config: %+v, expected %+v", again, exported)
+	}
+}
+
+func TestImportLevelsInvalid(t *testing.T) {
+	resetLog(t)
+
+	a := GetFacility("a")
+
+	err := ImportLevels(LevelsConfig{Default: "INFO", Facilities: map[string]string{"a": "LOUD"}}, false)
+	var le *LevelsError
+	if !errors.As(err, &le) || len(le.Details()) != 1 {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	if err := ImportLevels(LevelsConfig{Default: "INFO", FuncNameMode: "long"}, false); err == nil {
+		t.Error("the bad function name mode is accepted")
+	}
+
+	if a.CurrentLogLevel() != DEBUG || CurrentLogLevel() != DEBUG || CurrentFuncNameMode() != FuncNameModeNone {
+		t.Error("levels are changed")
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
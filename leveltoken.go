@@ -0,0 +1,49 @@
+package log
+
+import (
+	"sync/atomic"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// The level token is the facility level readable by a single atomic load, for the generated code that checks the level
+// before every call. The token is taken once and stays valid for the facility's lifetime (facilities are never deleted),
+// level changes are visible through it immediately.
+//
+//	tok := f.LevelToken()
+//	...
+//	if tok.Passes(log.TRACE2) {
+//		f.Message(log.TRACE2, ...)
+//	}
+//
+// Load() >= log.TRACE2 is the same for the standard levels, the registered ones must be checked by Passes.
+// With the hierarchical levels the token has the inherited level.
+
+// LevelToken --
+type LevelToken struct {
+	level    atomic.Int32
+	parent   atomic.Pointer[LevelToken]    // the token of the nearest existing ancestor
+	resolved atomic.Pointer[resolvedLevel] // the inherited level
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// LevelToken -- the level token of the facility
+func (f *Facility) LevelToken() *LevelToken {
+	return &f.token
+}
+
+// Load -- the current level of the facility
+func (t *LevelToken) Load() Level {
+	if hierarchicalLevels.Load() {
+		return t.resolve()
+	}
+	return Level(t.level.Load())
+}
+
+// Passes -- is the level logged by the facility. Doesn't check whether the facility is disabled.
+func (t *LevelToken) Passes(level Level) bool {
+	return level.passes(t.Load())
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,90 @@
+package log
+
+import (
+	"sync"
+	"testing"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestLevelToken(t *testing.T) {
+	resetLog(t)
+
+	f := GetFacility("token")
+	tok := f.LevelToken()
+
+	if tok != f.LevelToken() {
+		t.Error("the token isn't the same")
+	}
+	if tok.Load() != DEBUG || tok.Passes(TRACE2) || !tok.Passes(INFO) {
+		t.Errorf("unexpected level %d", tok.Load())
+	}
+
+	f.SetVerbosity(2)
+	if tok.Load() != TRACE2 || !tok.Passes(TRACE2) || tok.Passes(TRACE3) {
+		t.Errorf("the change isn't visible, got level %d", tok.Load())
+	}
+
+	if err := SetLogLevels("ERR", nil, FuncNameModeKeep); err != nil {
+		t.Fatal(err)
+	}
+	if tok.Load() != ERR || tok.Passes(WARNING) {
+		t.Errorf("the change isn't visible, got level %d", tok.Load())
+	}
+}
+
+func TestLevelChangeWhileLogging(t *testing.T) {
+	resetLog(t)
+
+	// The message path reads the level without the mutex, -race checks it
+	f := GetFacility("token.race")
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			f.Message(INFO, "message %d", i)
+			f.MessageBlock(INFO, "block", []string{"line"})
+			f.Dump(INFO, "dump", i)
+		}
+	}()
+
+	for i := 0; i < 200; i++ {
+		level := "INFO"
+		if i%2 == 0 {
+			level = "ERR"
+		}
+		if _, err := f.SetLogLevel(level, FuncNameModeKeep); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wg.Wait()
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func BenchmarkLevelToken(b *testing.B) {
+	f := GetFacility("token.bench")
+	if _, err := f.SetLogLevel("INFO", FuncNameModeKeep); err != nil {
+		b.Fatal(err)
+	}
+	tok := f.LevelToken()
+
+	b.Run("Token", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if tok.Load() >= TRACE2 {
+				f.Message(TRACE2, "call %d", i)
+			}
+		}
+	})
+
+	b.Run("Message", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			f.Message(TRACE2, "call %d", i)
+		}
+	})
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
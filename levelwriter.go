@@ -0,0 +1,97 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// LevelWriter -- io.WriteCloser splitting the stream into lines logged with the level.
+// The incomplete last line is kept until the next write or Flush.
+type LevelWriter struct {
+	mutex    sync.Mutex
+	facility *Facility
+	level    Level
+	prefix   string
+	buf      []byte
+}
+
+const (
+	// levelWriterMaxLine -- longer lines are split, all parts except the last one end with continuationMarker
+	levelWriterMaxLine = 64 * 1024
+	continuationMarker = " ↩"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// NewLevelWriter -- writer logging lines to the facility (std if nil) with the level and the prefix
+func NewLevelWriter(f *Facility, level Level, prefix string) *LevelWriter {
+	if f == nil {
+		f = stdFacility
+	}
+
+	return &LevelWriter{
+		facility: f,
+		level:    level,
+		prefix:   prefix,
+	}
+}
+
+// Write --
+func (w *LevelWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.buf = append(w.buf, p...)
+
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.line(w.buf[:i], false)
+		w.buf = w.buf[i+1:]
+	}
+
+	for len(w.buf) >= levelWriterMaxLine {
+		w.line(w.buf[:levelWriterMaxLine], true)
+		w.buf = w.buf[levelWriterMaxLine:]
+	}
+
+	if len(w.buf) == 0 {
+		w.buf = nil
+	}
+
+	return len(p), nil
+}
+
+// Flush -- log the incomplete last line
+func (w *LevelWriter) Flush() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if len(w.buf) > 0 {
+		w.line(w.buf, false)
+		w.buf = nil
+	}
+	return nil
+}
+
+// Close -- the same as Flush
+func (w *LevelWriter) Close() error {
+	return w.Flush()
+}
+
+// Must be called under w.mutex
+func (w *LevelWriter) line(b []byte, continued bool) {
+	s := strings.TrimSuffix(string(b), "\r")
+	if continued {
+		s += continuationMarker
+	}
+
+	w.facility.MessageEx(2, w.level, nil, "%s%s", w.prefix, s)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
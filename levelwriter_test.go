@@ -0,0 +1,35 @@
+package log
+
+import (
+	"strings"
+	"testing"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestLevelWriter(t *testing.T) {
+	console := resetLog(t)
+	MaxLen(0)
+
+	w := NewLevelWriter(GetFacility("child"), WARNING, "> ")
+
+	w.Write([]byte("first\nsec"))
+	w.Write([]byte("ond\r\n%s tail"))
+
+	long := strings.Repeat("x", levelWriterMaxLine+10)
+	w.Write([]byte("\n" + long))
+	w.Close()
+
+	lines := console.Lines()
+	expected := []string{"first", "second", "%s tail", strings.Repeat("x", levelWriterMaxLine) + continuationMarker, "xxxxxxxxxx"}
+	if len(lines) != len(expected) {
+		t.Fatalf("got %d lines, expected %d", len(lines), len(expected))
+	}
+	for i, e := range expected {
+		if !strings.Contains(lines[i], " WA ") || !strings.HasSuffix(lines[i], "<child> > "+e) {
+			t.Errorf("[%d] unexpected line %.100q", i, lines[i])
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -5,13 +5,15 @@ package log
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -74,7 +76,7 @@ const (
 )
 
 const (
-	logFuncNameNone int = iota
+	logFuncNameNone int32 = iota
 	logFuncNameShort
 	logFuncNameFull
 )
@@ -82,6 +84,8 @@ const (
 const (
 	beforeFileBufSize = 500
 	lastBufSize       = 30
+
+	exitTimeout = 5 * time.Second
 )
 
 // StdFacilityName --
@@ -89,14 +93,22 @@ const StdFacilityName = ""
 
 // Facility --
 type Facility struct {
-	name  string
-	level Level
+	name             string
+	level            Level // guarded by the mutex, the lock-free readers use the token
+	alertSubscribers map[int64]ChangeLevelAlertFunc
+	storm            stormState
+	disabled         atomic.Bool
+	verbosity        atomic.Int32 // the highest n passing V(n), -1 if DEBUG isn't logged
+	token            LevelToken
+	created          time.Time
+	lookBehind       atomic.Pointer[lookBehindRing] // nil if off, the content is guarded by mutex
+	sampling         samplingState
 }
 
 type sysWriter struct{}
 
 var (
-	mutex sync.Mutex
+	mutex outputMutex
 
 	levels = []logLevelDef{
 		{EMERG, "EMERG", "EM"},
@@ -122,6 +134,7 @@ var (
 
 	enabled   = true
 	active    = true
+	exiting   = false
 	firstTime = true
 
 	dumpFileName  = "unsaved.log"
@@ -129,15 +142,18 @@ var (
 
 	lastBuf = []string{}
 
-	logFuncName = logFuncNameNone
+	logFuncName atomic.Int32 // the function name mode, read without the mutex by the group commit
 
 	localTime     = false
+	lastStamp     time.Time
 	lastWriteDate string
 
 	fileDirectory   string
 	fileNamePattern string
 	fileName        string
 	file            *os.File
+	outputWriter    io.WriteCloser
+	dst             io.WriteCloser
 
 	writer = &sysWriter{}
 
@@ -146,7 +162,9 @@ var (
 	fileWriterMutex       = new(sync.Mutex)
 	fileWriterFlushPeriod = 0 * time.Second
 
-	maxLen = 0
+	maxLen atomic.Int64 // read without the mutex by the group commit
+
+	legacyFormatting atomic.Bool // read without the mutex by the group commit
 
 	pid int
 )
@@ -163,24 +181,21 @@ var (
 
 func init() {
 	pid = os.Getpid()
-	misc.AddExitFunc("log.exit", exit, nil)
 
 	stdFacility = NewFacility(StdFacilityName)
 
 	consoleWriter = &ConsoleWriter{}
 
-	log.SetFlags(0)
-	log.SetOutput(writer)
-
 	dumpFileName, _ = misc.AbsPath("@" + misc.AppName() + "_" + dumpFileName)
-
-	go writerFlusher()
 }
 
 //----------------------------------------------------------------------------------------------------------------------------//
 
+// timeNow -- source of the current time, replaced in tests
+var timeNow = time.Now
+
 func now() time.Time {
-	t := time.Now()
+	t := timeNow()
 
 	if !localTime {
 		return t.UTC()
@@ -197,7 +212,15 @@ func Writer() io.Writer {
 }
 
 func (l *sysWriter) Write(p []byte) (int, error) {
-	MessageEx(1, NOTICE, nil, "%s", strings.TrimSpace(string(p)))
+	f, level := stdlogRoute()
+
+	text := strings.TrimSpace(string(p))
+	if !strings.Contains(text, "\n") {
+		f.MessageEx(1, level, nil, "%s", text)
+		return len(p), nil
+	}
+
+	writeBlock(f, level, strings.Split(text, "\n"))
 	return len(p), nil
 }
 
@@ -236,62 +259,121 @@ func SetTestWriter(stream *testing.T) {
 //----------------------------------------------------------------------------------------------------------------------------//
 
 func writerFlush() {
+	fileWriterMutex.Lock()
+	defer fileWriterMutex.Unlock()
+
 	if fileWriter != nil {
-		fileWriterMutex.Lock()
-		fileWriter.Flush()
-		fileWriterMutex.Unlock()
+		if err := fileWriter.Flush(); err != nil {
+			fileWriteErr = err
+		}
+	}
+
+	if f, ok := dst.(streamFlusher); ok {
+		if err := f.Flush(); err != nil {
+			fileWriteErr = err
+		}
 	}
 }
 
 func exit(code int, p any) {
+	// Pending lines go before the summary
+	SetGroupCommit(GroupCommitOff)
+
+	mutex.Lock()
+	asyncOpen = false
+	flushOpenPending()
+	mutex.Unlock()
+
+	exitCode = code
+	exiting = true
+
+	ForceMessage(INFO, "%s", ExitSummary())
 	Message(INFO, "Log file closed")
 
-	if len(beforeFileBuf) > 0 {
+	if len(beforeFileBuf) > 0 || len(fallbackBuf) > 0 {
 		fd, err := os.OpenFile(dumpFileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 		if err == nil {
 			for _, s := range beforeFileBuf {
-				fd.Write([]byte(s))
+				if !dumpedLines[s] {
+					fd.Write([]byte(s))
+				}
+			}
+			for _, s := range fallbackBuf {
+				if !dumpedLines[s] {
+					fd.Write([]byte(s))
+				}
 			}
 			fd.Close()
 		}
 	}
 
-	active = false
+	ctx, cancel := context.WithTimeout(context.Background(), exitTimeout)
+	defer cancel()
 
-	writerFlush()
-
-	if file != nil {
-		file.Close()
-	}
+	Shutdown(ctx)
 }
 
-func writerFlusher() {
+func writerFlusher(stop chan struct{}, done chan struct{}) {
 	panicID := panic.ID()
 	defer panic.SaveStackToLogEx(panicID)
 
-	var period time.Duration
+	defer close(done)
+
 	lastFlushDate := ""
 
 	for {
-		if fileWriterFlushPeriod == 0 {
-			period = 1 * time.Second
-		} else {
-			period = fileWriterFlushPeriod
-		}
+		period := flusherPeriod()
 
-		if !misc.Sleep(period) {
+		if !misc.AppStarted() {
 			break
-		} else {
-			dt := now().Format(misc.DateFormatRev)
-			if lastFlushDate != "" && dt != lastFlushDate {
-				Message(-1*INFO, "Have a nice day")
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-time.After(flusherDelay(period)):
+			mutex.Lock()
+			dt := fileDate(now())
+			mutex.Unlock()
+
+			// The clock stepped back doesn't change the day
+			if dt > lastFlushDate {
+				if lastFlushDate != "" {
+					ForceMessage(INFO, "Have a nice day")
+				}
+				lastFlushDate = dt
 			}
-			lastFlushDate = dt
 			writerFlush()
+			mutex.Lock()
+			checkWriteError()
+			mutex.Unlock()
+			degradedTick()
+			periodicSync()
+			idleTick()
+			consoleDedupTick()
+			timingsFlush()
+			usageTick()
+			boostTick()
+			facilityCapTick()
 		}
 	}
 }
 
+// flusherPeriod -- the settings can be changed by SetFile at any time
+func flusherPeriod() time.Duration {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	period := fileWriterFlushPeriod
+	if period == 0 {
+		period = 1 * time.Second
+	}
+	if syncPeriod > 0 && syncPeriod < period {
+		period = syncPeriod
+	}
+	return period
+}
+
 //----------------------------------------------------------------------------------------------------------------------------//
 
 // Enable --
@@ -323,11 +405,26 @@ func GetLastLog() []string {
 
 // MaxLen --
 func MaxLen(ln int) int {
-	n := maxLen
-	maxLen = ln
+	var notify alertNotifications
+	defer notify.call()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	n := int(maxLen.Swap(int64(ln)))
+	notify.addConfig(ConfigMaxLen, strconv.Itoa(n), strconv.Itoa(ln))
 	return n
 }
 
+// SetLegacyFormatting -- format the message without params as before: "%%" collapses to "%", "%d" renders as "%!d(MISSING)".
+// Off by default, the message without params is written verbatim.
+func SetLegacyFormatting(legacy bool) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	legacyFormatting.Store(legacy)
+}
+
 //----------------------------------------------------------------------------------------------------------------------------//
 
 // Str2Level --
@@ -342,16 +439,20 @@ func Str2Level(levelName string) (level Level, ok bool) {
 			break
 		}
 	}
+
+	if !ok {
+		level, ok = verbosityLevelByName(levelName)
+	}
 	return level, ok
 }
 
 // GetLogLevels -- get log level list
 func GetLogLevels() []string {
-	list := make([]string, UNKNOWN)
+	list := make([]string, 0, len(levels)-1)
 
-	for _, def := range levels {
-		if def.code < UNKNOWN {
-			list[def.code] = def.name
+	for _, def := range orderedLevels() {
+		if def.code != UNKNOWN {
+			list = append(list, def.name)
 		}
 	}
 
@@ -385,74 +486,324 @@ func DelAlertFunc(id int64) {
 
 //----------------------------------------------------------------------------------------------------------------------------//
 
+// FileOptions -- options of SetFileEx
+type FileOptions struct {
+	Directory    string
+	Suffix       string
+	UseLocalTime bool
+	BufSize      int
+	FlushPeriod  time.Duration
+	Compression  Compression
+}
+
 // SetFile -- file for log
 func SetFile(directory string, suffix string, useLocalTime bool, bufSize int, flushPeriod time.Duration) {
+	SetFileEx(
+		FileOptions{
+			Directory:    directory,
+			Suffix:       suffix,
+			UseLocalTime: useLocalTime,
+			BufSize:      bufSize,
+			FlushPeriod:  flushPeriod,
+		},
+	)
+}
+
+// SetFileEx -- file for log with extended options
+func SetFileEx(opts FileOptions) {
+	_ = SetFileChecked(opts)
+}
+
+// SetFileChecked -- SetFileEx with the error of the exclusive ownership check, the file isn't changed on error
+func SetFileChecked(opts FileOptions) error {
+	ensureStarted()
+
+	var notify alertNotifications
+	defer notify.call()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	defer notify.addFileChanges(currentFileSettings())
+
+	directory := opts.Directory
 	if directory == "" {
 		directory = "./logs/"
 	}
 
+	exclusive := ""
+	if directory != "-" {
+		_, exclusive = filePatternOf(directory, opts.Suffix, opts.Compression)
+	}
+	exclusive, err := exclusivePattern(exclusive)
+	if err != nil {
+		lastError = err
+		logger(false, 0, StdFacilityName, ERR, nil, "Log file isn't set: %s", err)
+		return err
+	}
+
+	memoryToFile()
+	flushOpenPending()
+
+	if outputWriter != nil {
+		closeLogFile()
+		outputWriter = nil
+		lastWriteDate = ""
+	}
+
 	fileDirectory = directory
-	localTime = useLocalTime
-	fileWriterBufSize = bufSize
+	if localTime != opts.UseLocalTime {
+		localTime = opts.UseLocalTime
+		resetTimeCache()
+	}
+	fileWriterBufSize = opts.BufSize
+
+	if opts.FlushPeriod > 0 {
+		fileWriterFlushPeriod = opts.FlushPeriod
+	}
 
-	if flushPeriod > 0 {
-		fileWriterFlushPeriod = flushPeriod
+	if compression != opts.Compression {
+		compression = opts.Compression
+		closeLogFile()
+		lastWriteDate = ""
 	}
 
 	if fileDirectory == "-" {
+		if fileNamePattern != "-" {
+			closeLogFile()
+			lastWriteDate = ""
+		}
 		fileNamePattern = "-"
 	} else {
-		if suffix != "" {
-			suffix = "-" + suffix
+		if fileNamePattern == "-" {
+			closeLogFile()
+			lastWriteDate = ""
+		}
+		oldPattern := fileNamePattern
+		fileDirectory, fileNamePattern = filePattern(fileDirectory, opts.Suffix)
+		if exclusive != "" {
+			fileNamePattern = exclusive
+		}
+		if oldPattern != "" && oldPattern != fileNamePattern {
+			// The next message opens the file in the new location
+			closeLogFile()
+			lastWriteDate = ""
 		}
-		fileDirectory, _ = misc.AbsPath(fileDirectory)
-		fileNamePattern, _ = misc.AbsPath(fileDirectory + "/%s" + suffix + ".log")
 	}
+
+	return nil
 }
 
-//----------------------------------------------------------------------------------------------------------------------------//
+// filePattern -- absolute directory and the file name pattern with %s for the date
+func filePattern(directory string, suffix string) (string, string) {
+	return filePatternOf(directory, suffix, compression)
+}
 
-func writeToConsole(msg string) {
-	if consoleWriter != nil {
-		consoleWriter.Write([]byte(msg))
+// filePatternOf -- filePattern for the compression
+func filePatternOf(directory string, suffix string, c Compression) (string, string) {
+	if suffix != "" {
+		suffix = "-" + suffix
 	}
+	directory, _ = misc.AbsPath(directory)
+	pattern, _ := misc.AbsPath(directory + "/%s" + suffix + ".log" + c.extension())
+	return directory, pattern
 }
 
+//----------------------------------------------------------------------------------------------------------------------------//
+
 func write(s string) {
-	if file != nil {
+	if dst != nil {
+		if writeBroken {
+			destinationFailed(DestinationFile, strings.Count(s, misc.EOS), nil)
+			fallbackAppend(s)
+			return
+		}
+
+		text := s
+
+		if lineChecksums {
+			s = addChecksum(s)
+		}
+		if fileFormat == FormatCompact {
+			s = compactLines(s)
+		}
+
+		atomic.AddInt64(&writeCount, 1)
+
+		fileWriterMutex.Lock()
+		if fileWriter == nil && fileWriterBufSize > 0 {
+			fileWriter = bufio.NewWriterSize(dst, fileWriterBufSize)
+		}
+		var err error
 		if fileWriter != nil {
-			fileWriterMutex.Lock()
-			fileWriter.Write([]byte(s))
-			fileWriterMutex.Unlock()
+			_, err = fileWriter.Write([]byte(s))
 		} else {
-			file.Write([]byte(s))
+			_, err = dst.Write([]byte(s))
+		}
+		if err == nil {
+			err = fileWriteErr
+		}
+		fileWriteErr = nil
+		fileWriterMutex.Unlock()
+
+		if err != nil {
+			writeFailed(strings.Count(text, misc.EOS), err)
+			if writeBroken {
+				fallbackAppend(text)
+			}
+		} else {
+			writeFailing = false
+			destinationWritten(DestinationFile)
+			countWritten(text)
 		}
 	}
 }
 
-func openLogFile(dt string) {
-
-	if file != nil {
+func closeLogFile() {
+	if dst != nil {
+		fileWriterMutex.Lock()
 		if fileWriter != nil {
-			fileWriterMutex.Lock()
 			fileWriter.Flush()
 			fileWriter = nil
-			fileWriterMutex.Unlock()
 		}
-		file.Close()
+		dst.Close()
+		dst = nil
 		file = nil
+		fileWriteErr = nil
+		fileWriterMutex.Unlock()
+		writeBroken = false
+		resetCompact()
+
+		if stderrIntercepted {
+			updateCrashOutput()
+		}
+	}
+}
+
+// setDestination -- install the destination together with its buffer, so the flusher never sees the buffer of another
+// destination. Must be called under the mutex.
+func setDestination(d io.WriteCloser) {
+	fileWriterMutex.Lock()
+	defer fileWriterMutex.Unlock()
+
+	dst = d
+	fileWriter = nil
+	if fileWriterBufSize > 0 {
+		fileWriter = bufio.NewWriterSize(d, fileWriterBufSize)
+	}
+}
+
+// rotateLogFile -- open the destination for the date. Must be called under the mutex.
+func rotateLogFile(dt string) {
+	if outputWriter == nil && fileNamePattern == "-" {
+		if dst == nil {
+			setDestination(stdoutWriter{w: stdout})
+			fileName = "-"
+			startLogFile()
+		}
+		return
+	}
+
+	if outputWriter == nil {
+		openLogFile(dt)
+		return
+	}
+
+	if dst == nil {
+		setDestination(outputWriter)
+		startLogFile()
+		return
 	}
 
-	if _, err := os.Stat(fileDirectory); os.IsNotExist(err) {
-		os.MkdirAll(fileDirectory, 0755)
+	if r, ok := outputWriter.(Rotator); ok {
+		writerFlush()
+		r.Rotate()
+		resetCompact()
 	}
+}
+
+func openLogFile(dt string) {
+	if openThrottled(dt) {
+		return
+	}
+	lastOpenDate = dt
+	lastOpenAttempt = lastStamp
+
+	// The lock is released by Shutdown
+	pattern, err := exclusivePattern(fileNamePattern)
+	if err != nil {
+		lastError = err
+		return
+	}
+	fileNamePattern = pattern
+
+	name := fmt.Sprintf(fileNamePattern, dt)
+	writeTrailer(name)
+	closeLogFile()
+
+	o := openFileWithFallback(name)
+	fileName, file = o.name, o.file
+	if file != nil {
+		setDestination(injectFailures(compression.wrap(o.cipher.wrap(file))))
+		redirectStderr()
+	}
+
+	startLogFile()
+
+	if file != nil {
+		updateSymlinks(fileName)
+		applyRetention()
+	}
+}
 
-	fileName = fmt.Sprintf(fileNamePattern, dt)
-	file, _ = os.OpenFile(fileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+// openThrottled -- no file can be opened and the last attempt was recent. Must be called under the mutex.
+func openThrottled(dt string) bool {
+	return dst == nil && fileTier == TierMemory && dt == lastOpenDate && lastStamp.Sub(lastOpenAttempt) < openRetryPeriod
+}
 
-	os.Stderr.Close()
-	os.Stderr, _ = os.OpenFile(fileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+// startLogFile -- write the banner and the pending lines into the just opened destination
+func startLogFile() {
+	msg := bannerMessage()
+
+	if dst != nil {
+		resetFileCounts()
+		checkTruncatedTail()
+		write(msg)
 
+		if len(beforeFileBuf) > 0 {
+			for _, s := range beforeFileBuf {
+				write(s)
+			}
+			beforeFileBuf = []string{}
+		}
+
+		if len(fallbackBuf) > 0 {
+			// The lines return to the buffer if the write fails
+			lines := fallbackBuf
+			fallbackBuf = []string{}
+			for _, s := range lines {
+				write(s)
+			}
+		}
+
+		replayed := replayDump()
+
+		// The guaranteed lines leave the dump file only after they are synced
+		if !writeBroken && ((len(dumpedLines) == 0 && !replayed) || syncOutput() == nil) {
+			os.Remove(dumpFileName)
+			clear(dumpedLines)
+		}
+	}
+
+	if firstTime {
+		firstTime = false
+		if !consoleIsStdout() {
+			writeToConsole(msg)
+		}
+	}
+}
+
+func bannerMessage() string {
 	cmd := ""
 	for i := 0; i < len(os.Args); i++ {
 		cmd += " " + os.Args[i]
@@ -477,7 +828,7 @@ func openLogFile(dt string) {
 	msg := fmt.Sprintf("[%d] %s %s *** %s %s%s%s was launched at %sZ with command line \"%s\"",
 		pid,
 		levels[INFO].shortName,
-		now().Format(misc.DateTimeFormatRevWithMS),
+		lastStamp.Format(misc.DateTimeFormatRevWithMS),
 		misc.AppName(),
 		misc.AppVersion(),
 		tags,
@@ -485,37 +836,57 @@ func openLogFile(dt string) {
 		t.Format(misc.DateTimeFormatRev),
 		cmd)
 
-	if maxLen > 0 && maxLen < len(msg) {
-		msg = msg[:maxLen]
+	if n := int(maxLen.Load()); n > 0 && n < len(msg) {
+		msg = msg[:n]
 	}
-	msg += misc.EOS
+	return msg + misc.EOS
+}
 
-	if file != nil {
-		if fileWriterBufSize > 0 {
-			fileWriter = bufio.NewWriterSize(file, fileWriterBufSize)
-		}
+//----------------------------------------------------------------------------------------------------------------------------//
 
-		write(msg)
+// formatDirectLine -- line in the standard format for messages bypassing the logger. Must be called under the mutex.
+func formatDirectLine(level Level, msg string) string {
+	dt, tm := formatStamp(lastStamp)
+	return fmt.Sprintf("[%d] %s %s %s %s%s", pid, levels[level].shortName, dt, tm, msg, misc.EOS)
+}
 
-		if len(beforeFileBuf) > 0 {
-			for _, s := range beforeFileBuf {
-				write(s)
-			}
-			beforeFileBuf = []string{}
-		}
+// stamp -- current time for the message, never less than the previous one. Must be called under the mutex.
+func stamp() time.Time {
+	return nextStamp(now())
+}
 
-		os.Remove(dumpFileName)
-	}
+// levelStamp -- stamp taken from the coarse clock if it is allowed for the level. Must be called under the mutex.
+func levelStamp(level Level) time.Time {
+	return nextStamp(messageTime(level))
+}
 
-	if firstTime {
-		firstTime = false
-		writeToConsole(msg)
+func nextStamp(t time.Time) time.Time {
+	if !observeStamp(t) {
+		return lastStamp
 	}
+	return t
 }
 
-//----------------------------------------------------------------------------------------------------------------------------//
+// messageOptions -- the per message options of the extended message functions, nil means none
+type messageOptions struct {
+	replace  *misc.Replace
+	redact   RedactOpts
+	eventID  string
+	funcName FuncNameOverride
+	suffix   string // added after maxLen is applied, so it is never truncated
+}
+
+// lineSuffix -- the suffix of the line
+func (mo *messageOptions) lineSuffix() string {
+	if mo == nil {
+		return ""
+	}
+	return mo.suffix
+}
 
-func logger(withLock bool, stackShift int, facility string, level Level, replace *misc.Replace, message string, params ...any) {
+// logger -- withLock == false means the caller already holds the mutex.
+// The timestamp is taken and the line is written inside the same critical section so timestamps in the file never decrease.
+func logger(withLock bool, stackShift int, facility string, level Level, mo *messageOptions, message string, params ...any) {
 	if !enabled {
 		return
 	}
@@ -525,127 +896,201 @@ func logger(withLock bool, stackShift int, facility string, level Level, replace
 		defer mutex.Unlock()
 	}
 
+	msg, ok := redactMessage(facility, level, formatMessage(message, params), mo)
+	if !ok {
+		statDrop()
+		return
+	}
+
+	id := mo.event()
+	msg = eventToken(id) + msg
+
+	dt, prefix := linePrefix(stackShift+1, facility, level, mo.funcNameOverride())
+	notifySevere(facility, level, lastStamp, msg)
+	if level == TIME && writeTiming(facility, msg, "", "") {
+		return
+	}
+	outputEx(facility, level, dt, finishLineSuffix(prefix+msg, mo.lineSuffix()), "", mo)
+}
+
+// linePrefix -- count the message and build "[pid] LL date time <facility> func: ". Must be called under the mutex.
+func linePrefix(stackShift int, facility string, level Level, fn FuncNameOverride) (dt string, prefix string) {
+	return formatPrefix(stackShift+1, facility, level, levelStamp(level), fn)
+}
+
+// formatPrefix -- count the message and build the prefix with the given time
+func formatPrefix(stackShift int, facility string, level Level, t time.Time, fn FuncNameOverride) (dt string, prefix string) {
+	statMessage(level)
+	usageCount(facility, level, t)
+
+	firstLogged.Store(true)
+
+	return buildPrefix(stackShift+1, facility, level, t, fn)
+}
+
+// buildPrefix -- build the prefix with the given time without counting the message
+func buildPrefix(stackShift int, facility string, level Level, t time.Time, fn FuncNameOverride) (dt string, prefix string) {
 	levelName := ""
-	if (level >= EMERG) && (level < UNKNOWN) {
+	if (level >= EMERG) && (int(level) < len(levels)) && (level != UNKNOWN) {
 		levelName = levels[level].shortName
 	} else {
 		levelName = fmt.Sprintf("?%d?", level)
 	}
 
-	now := now()
-	dt := now.Format(misc.DateFormatRev)
-	tm := now.Format(misc.TimeFormatWithMS)
+	date, tm := formatStamp(t)
+	dt = fileDate(t)
 
-	var funcName string
-	if (level == EMERG) || (logFuncName == logFuncNameFull) {
-		funcName = " " + misc.GetFuncName(stackShift+1, false) + ":"
-	} else if logFuncName == logFuncNameShort {
-		funcName = " " + misc.GetFuncName(stackShift+1, true) + ":"
-	} else {
-		funcName = ""
+	funcName := ""
+	if with, full := fn.withFuncName(level); with {
+		funcName = " " + callerFuncName(stackShift+1, full) + moduleTag(stackShift+1) + ":"
 	}
 
+	facilityTag := ""
 	if facility != "" {
-		facility = " <" + facility + ">"
+		facilityTag = " <" + facility + ">"
 	}
 
-	format := fmt.Sprintf("[%d] %s %s %s%s%s %s", pid, levelName, dt, tm,
-		strings.ReplaceAll(facility, "%", "%%"),
-		strings.ReplaceAll(funcName, "%", "%%"),
-		message)
-	text := fmt.Sprintf(format, params...)
-	if maxLen > 0 && maxLen < len(text) {
-		text = text[:maxLen]
-	}
+	prefix = fmt.Sprintf("[%d] %s %s %s%s%s ", pid, levelName, date, tm, facilityTag, funcName)
+	return
+}
 
-	if replace != nil {
-		text = replace.Do(text)
-	}
+// finishLine -- apply maxLen, add EOS
+func finishLine(text string) string {
+	return finishLineSuffix(text, "")
+}
 
-	text += misc.EOS
+// finishLineSuffix -- apply maxLen, add the suffix and EOS
+func finishLineSuffix(text string, suffix string) string {
+	if n := int(maxLen.Load()); n > 0 && n < len(text) {
+		text = text[:n]
+		statTruncate()
+	}
 
-	if active {
-		if fileNamePattern == "" {
-			ln := len(beforeFileBuf)
-			if ln > beforeFileBufSize {
-			} else if ln < beforeFileBufSize {
-				beforeFileBuf = append(beforeFileBuf, text)
-			} else {
-				beforeFileBuf = append(beforeFileBuf, "...")
-			}
-		} else if fileNamePattern != "-" {
-			if (file == nil) || (lastWriteDate != dt) {
-				openLogFile(dt)
-			}
+	return text + suffix + misc.EOS
+}
 
-			if file != nil {
-				write(text)
-				lastWriteDate = dt
-			} else {
-				lastWriteDate = ""
-			}
-		}
+// formatMessage -- the message without params is taken verbatim unless the legacy formatting is on
+func formatMessage(message string, params []any) string {
+	if len(params) == 0 && !legacyFormatting.Load() {
+		return message
 	}
+	return fmt.Sprintf(message, params...)
+}
 
-	if len(lastBuf) >= lastBufSize {
-		lastBuf = lastBuf[1:]
-	}
-	lastBuf = append(lastBuf, text)
+// output -- send the formatted line to the destinations. Must be called under the mutex.
+func output(facility string, level Level, dt string, text string) {
+	outputEx(facility, level, dt, text, "", nil)
+}
 
-	writeToConsole(text)
+// outputEx -- output with the separate console text and the message options. Must be called under the mutex.
+func outputEx(facility string, level Level, dt string, text string, consoleText string, mo *messageOptions) {
+	ensureStarted()
+	text, consoleText = stripForFile(text, consoleText)
+	outputRecord(
+		&Record{
+			Time:     lastStamp,
+			Level:    level,
+			Facility: facility,
+			Date:     dt,
+			Line:     text,
+			EventID:  mo.event(),
+			console:  consoleText,
+			funcName: mo.funcNameOverride(),
+		},
+	)
 }
 
-//----------------------------------------------------------------------------------------------------------------------------//
+// outputRecord -- the file and the copies of the record within the facility quota. Must be called under the mutex.
+func outputRecord(r *Record) {
+	rotationCheck(r.Date)
 
-// FileNamePattern --
-func FileNamePattern() string {
-	return fileNamePattern
+	toFile, toCopies := quotaFilter(r.Facility, r.Date, r.Line)
+	if toFile {
+		fileDestination.output(r)
+	}
+	if toCopies {
+		outputCopies(r)
+	}
 }
 
-// FileName --
-func FileName() string {
-	return fileName
+// outputMain -- write the line to the memory, the file or the buffers while the file isn't set. Must be called under the mutex.
+func outputMain(level Level, dt string, text string) {
+	dt = forwardDate(dt)
+
+	if memoryMode {
+		memoryAppend(level, text)
+	} else if !active || (outputWriter == nil && fileNamePattern == "") {
+		// After Shutdown the lines are kept until Start
+		beforeFileAppend(text)
+	} else if !queueForOpen(level, dt, text) {
+		outputFile(level, dt, text)
+	}
 }
 
-//----------------------------------------------------------------------------------------------------------------------------//
+// beforeFileAppend -- keep the line until the file is opened. Must be called under the mutex.
+func beforeFileAppend(text string) {
+	ln := len(beforeFileBuf)
+	if ln < beforeFileBufSize || exiting {
+		beforeFileBuf = append(beforeFileBuf, text)
+	} else if ln == beforeFileBufSize {
+		beforeFileBuf = append(beforeFileBuf, "...")
+		statDrop()
+	} else {
+		statDrop()
+	}
+}
 
-// ServiceLogger --
-type ServiceLogger struct{}
+// outputFile -- write the line to the file rotating it if needed. Must be called under the mutex.
+func outputFile(level Level, dt string, text string) {
+	dt = forwardDate(dt)
 
-// Error --
-func (l *ServiceLogger) Error(v ...any) error {
-	Message(ERR, fmt.Sprint(v...))
-	return nil
-}
+	if (dst == nil) || (lastWriteDate != dt) || writeBroken {
+		rotateLogFile(dt)
+	}
 
-// Warning --
-func (l *ServiceLogger) Warning(v ...any) error {
-	Message(WARNING, fmt.Sprint(v...))
-	return nil
+	if dst != nil {
+		write(text)
+		flushIfSevere(level)
+		lastWriteDate = dt
+	} else {
+		lastWriteDate = ""
+		if outputWriter == nil {
+			fallbackAppend(text)
+		}
+	}
 }
 
-// Info --
-func (l *ServiceLogger) Info(v ...any) error {
-	Message(INFO, fmt.Sprint(v...))
-	return nil
-}
+// outputCopies -- last lines, subscribers, targets, the console, the added destinations and the flight recorder.
+// Must be called under the mutex.
+func outputCopies(r *Record) {
+	if !blockBody {
+		if len(lastBuf) >= lastBufSize {
+			lastBuf = lastBuf[1:]
+		}
+		lastBuf = append(lastBuf, r.Line)
+	}
+	lastLogAdd(r.Facility, r.Level, r.Time, r.Line)
 
-// Errorf --
-func (l *ServiceLogger) Errorf(message string, a ...any) error {
-	Message(ERR, message, a)
-	return nil
+	notifySubscribers(r.Facility, r.Line)
+	writeToTargets(r.Line)
+
+	for _, d := range destinations {
+		d.output(r)
+	}
+
+	flightRecord(r.Level, r.Line)
 }
 
-// Warningf --
-func (l *ServiceLogger) Warningf(message string, a ...any) error {
-	Message(WARNING, message, a)
-	return nil
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// FileNamePattern --
+func FileNamePattern() string {
+	return fileNamePattern
 }
 
-// Infof --
-func (l *ServiceLogger) Infof(message string, a ...any) error {
-	Message(INFO, message, a)
-	return nil
+// FileName --
+func FileName() string {
+	return fileName
 }
 
 //----------------------------------------------------------------------------------------------------------------------------//
@@ -668,6 +1113,7 @@ func CurrentLogLevelOfAll() (list map[string]Level) {
 		list[name] = f.level
 	}
 
+	flagUnregistered()
 	return
 }
 
@@ -685,8 +1131,35 @@ func CurrentLogLevelNamesOfAll() (list misc.StringMap) {
 	return
 }
 
+// FacilityState --
+type FacilityState struct {
+	Level        Level `json:"level"`
+	Enabled      bool  `json:"enabled"`
+	Unregistered bool  `json:"unregistered,omitempty"` // the facility isn't registered while some are
+}
+
+// CurrentStateOfAll -- get levels and enabled flags of all facilities
+func CurrentStateOfAll() (list map[string]FacilityState) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	list = make(map[string]FacilityState)
+	for name, f := range facilities {
+		list[name] = FacilityState{
+			Level:        f.level,
+			Enabled:      !f.disabled.Load(),
+			Unregistered: registeredFacilities != nil && !facilityKnown(name),
+		}
+	}
+
+	return
+}
+
 // SetLogLevels -- set log level
 func SetLogLevels(defaultLevelName string, levels misc.StringMap, logFunc FuncNameMode) (err error) {
+	var notify alertNotifications
+	defer notify.call()
+
 	mutex.Lock()
 	defer mutex.Unlock()
 
@@ -695,9 +1168,10 @@ func SetLogLevels(defaultLevelName string, levels misc.StringMap, logFunc FuncNa
 		if !exists {
 			level = defaultLevelName
 		}
-		_, _ = f.setLogLevel(level, logFunc)
+		_, _ = f.setLogLevel(level, logFunc, "", "", &notify)
 	}
 
+	levelsSet(&notify)
 	return
 }
 
@@ -708,10 +1182,26 @@ func NewFacility(name string) *Facility {
 	mutex.Lock()
 	defer mutex.Unlock()
 
+	if f, exists := facilities[name]; exists {
+		return f
+	}
+
+	name = facilityName(name)
+
+	if f := strictFacility(name); f != nil {
+		return f
+	}
+
+	if f := cappedFacility(name); f != nil {
+		return f
+	}
+
 	return newFacility(name)
 }
 
 func newFacility(name string) *Facility {
+	name = facilityName(name)
+
 	f, exists := facilities[name]
 	if exists {
 		return f
@@ -723,11 +1213,13 @@ func newFacility(name string) *Facility {
 	}
 
 	f = &Facility{
-		name:  name,
-		level: level,
+		name:    name,
+		created: now(),
 	}
+	f.setLevel(level)
 
 	facilities[name] = f
+	linkFacility(f)
 	return f
 }
 
@@ -741,6 +1233,16 @@ func GetFacility(name string) *Facility {
 		return f
 	}
 
+	name = facilityName(name)
+
+	if f := strictFacility(name); f != nil {
+		return f
+	}
+
+	if f := cappedFacility(name); f != nil {
+		return f
+	}
+
 	return newFacility(name)
 }
 
@@ -751,35 +1253,45 @@ func (f *Facility) Name() (name string) {
 
 // CurrentLogLevel -- get log level
 func (f *Facility) CurrentLogLevel() (level Level) {
-	return f.level
+	return f.token.Load()
+}
+
+// Enable -- resume logging of the facility
+func (f *Facility) Enable() {
+	f.disabled.Store(false)
+}
+
+// Disable -- mute the facility completely regardless of the level. Doesn't take the mutex, so safe to call from the alert subscriber.
+func (f *Facility) Disable() {
+	f.disabled.Store(true)
+}
+
+// Enabled -- is the facility not muted
+func (f *Facility) Enabled() bool {
+	return !f.disabled.Load()
 }
 
 // CurrentLogLevelEx -- get log level
 func (f *Facility) CurrentLogLevelEx() (level Level, short string, long string) {
-	level = f.level
+	level = f.token.Load()
 	short, long = GetLogLevelName(level)
 	return
 }
 
 // SetLogLevel -- set log level
 func (f *Facility) SetLogLevel(levelName string, funcNameMode FuncNameMode) (oldLevel Level, err error) {
+	var notify alertNotifications
+	defer notify.call()
+
 	mutex.Lock()
 	defer mutex.Unlock()
 
-	return f.setLogLevel(levelName, funcNameMode)
+	return f.setLogLevel(levelName, funcNameMode, "", "", &notify)
 }
 
-func (f *Facility) setLogLevel(levelName string, funcNameMode FuncNameMode) (oldLevel Level, err error) {
-	switch funcNameMode {
-	case FuncNameModeShort:
-		logFuncName = logFuncNameShort
-	case FuncNameModeFull:
-		logFuncName = logFuncNameFull
-	case FuncNameModeNone:
-		fallthrough
-	default:
-		logFuncName = logFuncNameNone
-	}
+// setLogLevel -- must be called under the mutex, notify must be called after the mutex is released
+func (f *Facility) setLogLevel(levelName string, funcNameMode FuncNameMode, actor string, reason string, notify *alertNotifications) (oldLevel Level, err error) {
+	setFuncNameMode(funcNameMode, notify)
 
 	oldLevel = f.level
 
@@ -787,50 +1299,105 @@ func (f *Facility) setLogLevel(levelName string, funcNameMode FuncNameMode) (old
 	if !ok {
 		msg := fmt.Sprintf(`Invalid log level "%s", left unchanged "%s" `, levelName, levels[oldLevel].name)
 		err = errors.New(msg)
-		logger(false, 0, f.name, WARNING, nil, msg)
+		logger(false, 0, f.name, WARNING, nil, "%s", msg)
 		return
 	}
 
+	cancelBoost(f)
+
 	if newLevel != oldLevel {
-		for _, alert := range alertSubscribers {
-			alert(f.name, oldLevel, newLevel)
+		change := LevelChange{
+			Time:     now(),
+			Facility: f.name,
+			Old:      oldLevel,
+			New:      newLevel,
+			Actor:    actor,
+			Reason:   reason,
 		}
 
-		f.level = newLevel
-		logger(false, 0, f.name, INFO, nil, `Log level is "%s"`, levels[newLevel].name)
+		f.setLevel(newLevel)
+		notify.add(f, change)
+		addLevelChange(change)
+		if actor != levelPersistenceActor {
+			persistLevels(notify)
+		}
+		logger(false, 0, f.name, INFO, nil, `Log level is "%s"%s`, levels[newLevel].name, change.by())
 	}
 
 	return
 }
 
-// MessageEx -- add message to the log with custom shift
+// MessageEx -- add message to the log with custom shift.
+// The negative level logs the message regardless of the facility level, it is deprecated, use ForceMessage.
 func (f *Facility) MessageEx(shift int, level Level, replace *misc.Replace, message string, params ...any) {
-	if level <= f.level {
-		if level < 0 {
-			level = -level
+	f.messageOpt(shift+1, level, MsgOpts{Replace: replace}, message, params...)
+}
+
+// ForceMessage -- add message to the log regardless of the facility level
+func (f *Facility) ForceMessage(level Level, message string, params ...any) {
+	f.messageEx(1, level, true, nil, message, params...)
+}
+
+func (f *Facility) messageEx(shift int, level Level, force bool, mo *messageOptions, message string, params ...any) {
+	if f == stdFacility && autoFacility.Load() {
+		f = callerFacility()
+	}
+
+	if f.disabled.Load() {
+		return
+	}
+
+	level = checkLevel(shift+1, f, level)
+
+	if force || (level.passes(f.token.Load()) && !f.sampledOut(level)) {
+		if stormDrop(f, level, mo.event(), message, params) {
+			return
+		}
+		if scopeFrames.Load() != 0 {
+			message = scopeMessage(message, params)
 		}
-		logger(true, shift+1, f.name, level, replace, message, params...)
+		if lb := f.lookBehind.Load(); lb != nil && level.passes(lb.trigger) {
+			f.triggerLookBehind(shift+1, level, mo, message, params...)
+			return
+		}
+		if r := groupCommitRing.Load(); r != nil && level != TIME {
+			f.commitMessage(r, shift+1, level, mo, message, params...)
+			return
+		}
+		logger(true, shift+1, f.name, level, mo, message, params...)
+	} else if f.lookBehind.Load() != nil {
+		f.keepSuppressed(shift+1, level, mo, message, params...)
 	}
 }
 
 // Message -- add message to the log
 func (f *Facility) Message(level Level, message string, params ...any) {
-	f.MessageEx(1, level, nil, message, params...)
+	f.messageOpt(1, level, MsgOpts{}, message, params...)
 }
 
 // MessageWithSource -- add message to the log with source
 func (f *Facility) MessageWithSource(level Level, source string, message string, params ...any) {
-	f.MessageEx(1, level, nil, "["+source+"] "+message, params...)
+	f.messageOpt(1, level, MsgOpts{Source: source}, message, params...)
 }
 
 // SecuredMessage -- add message to the log with securing
 func (f *Facility) SecuredMessage(level Level, replace *misc.Replace, message string, params ...any) {
-	f.MessageEx(1, level, replace, message, params...)
+	f.messageOpt(1, level, MsgOpts{Replace: replace}, message, params...)
 }
 
 // SecuredMessageWithSource -- add message to the log with source & securing
 func (f *Facility) SecuredMessageWithSource(level Level, replace *misc.Replace, source string, message string, params ...any) {
-	f.MessageEx(1, level, replace, "["+source+"] "+message, params...)
+	f.messageOpt(1, level, MsgOpts{Replace: replace, Source: source}, message, params...)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// sourceMessage -- the message with the source prefix, "%" in the source is escaped if the message is formatted
+func sourceMessage(source string, message string, params []any) string {
+	if len(params) > 0 || legacyFormatting.Load() {
+		source = strings.ReplaceAll(source, "%", "%%")
+	}
+	return "[" + sourceName(source) + "] " + message
 }
 
 //----------------------------------------------------------------------------------------------------------------------------//
@@ -857,27 +1424,32 @@ func SetLogLevel(levelName string, logFunc FuncNameMode) (oldLevel Level, err er
 
 // MessageEx -- add message to the log with custom shift
 func MessageEx(shift int, level Level, replace *misc.Replace, message string, params ...any) {
-	stdFacility.MessageEx(shift, level, replace, message, params...)
+	stdFacility.messageOpt(shift+1, level, MsgOpts{Replace: replace}, message, params...)
 }
 
 // Message -- add message to the log
 func Message(level Level, message string, params ...any) {
-	stdFacility.Message(level, message, params...)
+	stdFacility.messageOpt(1, level, MsgOpts{}, message, params...)
+}
+
+// ForceMessage -- add message to the log regardless of the level
+func ForceMessage(level Level, message string, params ...any) {
+	stdFacility.messageEx(1, level, true, nil, message, params...)
 }
 
 // SecuredMessage -- add message to the log with securing
 func SecuredMessage(level Level, replace *misc.Replace, message string, params ...any) {
-	stdFacility.SecuredMessage(level, replace, message, params...)
+	stdFacility.messageOpt(1, level, MsgOpts{Replace: replace}, message, params...)
 }
 
 // MessageWithSource -- add message to the log with source
 func MessageWithSource(level Level, source string, message string, params ...any) {
-	stdFacility.MessageWithSource(level, source, message, params...)
+	stdFacility.messageOpt(1, level, MsgOpts{Source: source}, message, params...)
 }
 
 // SecuredMessageWithSource -- add message to the log with source & securing
 func SecuredMessageWithSource(level Level, replace *misc.Replace, source string, message string, params ...any) {
-	stdFacility.SecuredMessageWithSource(level, replace, source, message, params...)
+	stdFacility.messageOpt(1, level, MsgOpts{Replace: replace, Source: source}, message, params...)
 }
 
 //----------------------------------------------------------------------------------------------------------------------------//
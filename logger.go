@@ -0,0 +1,141 @@
+package log
+
+import (
+	"sync"
+
+	"github.com/alrusov/misc"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Logger -- the core logging surface, implemented by *Facility. Depend on it to substitute NopLogger or RecorderLogger in tests.
+type Logger interface {
+	Name() string
+	CurrentLogLevel() Level
+	Message(level Level, message string, params ...any)
+	MessageEx(shift int, level Level, replace *misc.Replace, message string, params ...any)
+	SecuredMessage(level Level, replace *misc.Replace, message string, params ...any)
+	MessageWithSource(level Level, source string, message string, params ...any)
+}
+
+// NopLogger -- Logger that discards everything
+type NopLogger struct{}
+
+// RecordedEntry -- the message captured by RecorderLogger. Message is formatted, the replace rules are applied.
+type RecordedEntry struct {
+	Level   Level
+	Source  string
+	Message string
+}
+
+// RecorderLogger -- Logger that captures messages of all levels for assertions
+type RecorderLogger struct {
+	mutex   sync.Mutex
+	name    string
+	level   Level
+	entries []RecordedEntry
+}
+
+var (
+	_ Logger = (*Facility)(nil)
+	_ Logger = NopLogger{}
+	_ Logger = (*RecorderLogger)(nil)
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Name --
+func (NopLogger) Name() string {
+	return ""
+}
+
+// CurrentLogLevel --
+func (NopLogger) CurrentLogLevel() Level {
+	return EMERG
+}
+
+// Message --
+func (NopLogger) Message(level Level, message string, params ...any) {
+}
+
+// MessageEx --
+func (NopLogger) MessageEx(shift int, level Level, replace *misc.Replace, message string, params ...any) {
+}
+
+// SecuredMessage --
+func (NopLogger) SecuredMessage(level Level, replace *misc.Replace, message string, params ...any) {
+}
+
+// MessageWithSource --
+func (NopLogger) MessageWithSource(level Level, source string, message string, params ...any) {
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// NewRecorderLogger -- the recorder with the name and the level returned by CurrentLogLevel
+func NewRecorderLogger(name string, level Level) *RecorderLogger {
+	return &RecorderLogger{
+		name:  name,
+		level: level,
+	}
+}
+
+// Name --
+func (r *RecorderLogger) Name() string {
+	return r.name
+}
+
+// CurrentLogLevel --
+func (r *RecorderLogger) CurrentLogLevel() Level {
+	return r.level
+}
+
+// Message --
+func (r *RecorderLogger) Message(level Level, message string, params ...any) {
+	r.record(level, "", nil, message, params)
+}
+
+// MessageEx --
+func (r *RecorderLogger) MessageEx(shift int, level Level, replace *misc.Replace, message string, params ...any) {
+	r.record(level, "", replace, message, params)
+}
+
+// SecuredMessage --
+func (r *RecorderLogger) SecuredMessage(level Level, replace *misc.Replace, message string, params ...any) {
+	r.record(level, "", replace, message, params)
+}
+
+// MessageWithSource --
+func (r *RecorderLogger) MessageWithSource(level Level, source string, message string, params ...any) {
+	r.record(level, source, nil, message, params)
+}
+
+// Entries -- copy of the captured messages
+func (r *RecorderLogger) Entries() []RecordedEntry {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	list := make([]RecordedEntry, len(r.entries))
+	copy(list, r.entries)
+	return list
+}
+
+// Clear -- forget the captured messages
+func (r *RecorderLogger) Clear() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.entries = nil
+}
+
+func (r *RecorderLogger) record(level Level, source string, replace *misc.Replace, message string, params []any) {
+	msg := formatMessage(message, params)
+	msg = Redact(replace, msg)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.entries = append(r.entries, RecordedEntry{Level: level, Source: source, Message: msg})
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,64 @@
+package log
+
+import (
+	"testing"
+
+	"github.com/alrusov/misc"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+type testConsumer struct {
+	log Logger
+}
+
+func (c *testConsumer) login(user string, password string) {
+	replace := misc.NewReplace()
+	replace.Add(`(password=)\S+$`, "${1}***")
+
+	c.log.SecuredMessage(INFO, replace, "login user=%s password=%s", user, password)
+	if DEBUG.passes(c.log.CurrentLogLevel()) {
+		c.log.MessageWithSource(DEBUG, "auth", "%s checked", user)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestRecorderLogger(t *testing.T) {
+	rec := NewRecorderLogger("auth", DEBUG)
+	c := &testConsumer{log: rec}
+
+	c.login("bob", "secret")
+
+	expected := []RecordedEntry{
+		{Level: INFO, Message: "login user=bob password=***"},
+		{Level: DEBUG, Source: "auth", Message: "bob checked"},
+	}
+
+	entries := rec.Entries()
+	if len(entries) != len(expected) {
+		t.Fatalf("got %d entries, expected %d: %+v", len(entries), len(expected), entries)
+	}
+	for i, e := range expected {
+		if entries[i] != e {
+			t.Errorf("[%d] got %+v, expected %+v", i, entries[i], e)
+		}
+	}
+
+	rec.Clear()
+	if n := len(rec.Entries()); n != 0 {
+		t.Errorf("got %d entries after Clear", n)
+	}
+}
+
+func TestNopLogger(t *testing.T) {
+	c := &testConsumer{log: NopLogger{}}
+	c.login("bob", "secret")
+
+	c.log = GetFacility("consumer")
+	if c.log.Name() != "consumer" {
+		t.Errorf("got name %q", c.log.Name())
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,146 @@
+package log
+
+import (
+	"github.com/alrusov/misc"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// The look-behind keeps the newest lines suppressed by the facility level and writes them when the message of the
+// trigger level or more severe passes, so the details preceding the error are in the file while the facility logs
+// on INFO. The suppressed lines are formatted only when the look-behind is on, with their own time, and are truncated
+// by maxLen, so the memory is bounded by depth*maxLen. The kept lines are written between the "--- look-behind begin ---"
+// and "--- look-behind end ---" lines just before the triggering message and the ring is cleared.
+// Off by default.
+
+// lookBehindRing -- the ring of the suppressed lines, guarded by mutex
+type lookBehindRing struct {
+	trigger Level
+	lines   []lookBehindLine
+	head    int // index of the oldest line
+	count   int
+}
+
+type lookBehindLine struct {
+	level Level
+	dt    string
+	text  string
+	mo    messageOptions // the event ID and the function name mode of the line
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// EnableLookBehind -- keep the last depth suppressed lines and write them before the message of triggerLevel or more
+// severe, 0 switches the look-behind off. The kept lines are lost.
+func (f *Facility) EnableLookBehind(depth int, triggerLevel Level) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	f.setLookBehind(depth, triggerLevel)
+}
+
+// setLookBehind -- must be called under the mutex
+func (f *Facility) setLookBehind(depth int, triggerLevel Level) {
+	if depth <= 0 {
+		f.lookBehind.Store(nil)
+		return
+	}
+
+	f.lookBehind.Store(&lookBehindRing{trigger: triggerLevel, lines: make([]lookBehindLine, depth)})
+}
+
+// lookBehindConfig -- the depth and the trigger level, 0 depth if off. Must be called under the mutex.
+func (f *Facility) lookBehindConfig() (depth int, triggerLevel Level) {
+	lb := f.lookBehind.Load()
+	if lb == nil {
+		return 0, 0
+	}
+	return len(lb.lines), lb.trigger
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// keepSuppressed -- format the suppressed message and keep it in the ring
+func (f *Facility) keepSuppressed(shift int, level Level, mo *messageOptions, message string, params ...any) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	lb := f.lookBehind.Load()
+	if lb == nil {
+		return
+	}
+
+	msg, ok := redactMessage(f.name, level, formatMessage(message, params), mo)
+	if !ok {
+		return
+	}
+
+	dt, prefix := buildPrefix(shift+1, f.name, level, now(), mo.funcNameOverride())
+	text := prefix + eventToken(mo.event()) + msg
+	if n := int(maxLen.Load()); n > 0 && n < len(text) {
+		text = text[:n]
+	}
+
+	lb.add(
+		lookBehindLine{
+			level: level,
+			dt:    dt,
+			text:  text + misc.EOS,
+			mo:    messageOptions{eventID: mo.event(), funcName: mo.funcNameOverride()},
+		},
+	)
+}
+
+// triggerLookBehind -- write the kept lines and the triggering message back-to-back
+func (f *Facility) triggerLookBehind(shift int, level Level, mo *messageOptions, message string, params ...any) {
+	// The lines enqueued by the group commit go first
+	defer commitBarrier()()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	f.writeLookBehind(shift + 1)
+	logger(false, shift+1, f.name, level, mo, message, params...)
+}
+
+// writeLookBehind -- write the kept lines and clear the ring. Must be called under the mutex.
+func (f *Facility) writeLookBehind(shift int) {
+	if lb := f.lookBehind.Load(); lb != nil && lb.count > 0 {
+		logger(false, shift+1, f.name, NOTICE, nil, "--- look-behind begin ---")
+		lb.each(func(l *lookBehindLine) {
+			outputEx(f.name, l.level, l.dt, l.text, "", &l.mo)
+		})
+		logger(false, shift+1, f.name, NOTICE, nil, "--- look-behind end ---")
+		lb.reset()
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// add -- keep the line evicting the oldest one
+func (r *lookBehindRing) add(l lookBehindLine) {
+	size := len(r.lines)
+	if r.count == size {
+		r.lines[r.head] = l
+		r.head = (r.head + 1) % size
+		return
+	}
+
+	r.lines[(r.head+r.count)%size] = l
+	r.count++
+}
+
+// each -- call fn for the lines, the oldest first
+func (r *lookBehindRing) each(fn func(l *lookBehindLine)) {
+	for i := 0; i < r.count; i++ {
+		fn(&r.lines[(r.head+i)%len(r.lines)])
+	}
+}
+
+// reset -- forget the lines releasing their texts
+func (r *lookBehindRing) reset() {
+	clear(r.lines)
+	r.head, r.count = 0, 0
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
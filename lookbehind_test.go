@@ -0,0 +1,118 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestLookBehind(t *testing.T) {
+	console := resetLog(t)
+
+	f := NewFacility("lb")
+	f.SetLogLevel("INFO", FuncNameModeNone)
+	f.EnableLookBehind(3, ERR)
+	console.buf.Reset()
+
+	for i := 1; i <= 5; i++ {
+		f.Message(DEBUG, "d%d", i)
+	}
+	f.Message(INFO, "i1")
+	f.Message(TRACE1, "t1")
+	f.Message(ERR, "boom1")
+
+	f.Message(DEBUG, "d6")
+	f.Message(CRIT, "boom2")
+	f.Message(ERR, "boom3")
+
+	expected := []string{
+		"i1",
+		"--- look-behind begin ---",
+		"d4", "d5", "t1",
+		"--- look-behind end ---",
+		"boom1",
+		"--- look-behind begin ---",
+		"d6",
+		"--- look-behind end ---",
+		"boom2",
+		"boom3",
+	}
+
+	lines := console.Lines()
+	if len(lines) != len(expected) {
+		t.Fatalf("got %d lines, expected %d\n%s", len(lines), len(expected), console.String())
+	}
+	for i, e := range expected {
+		if !strings.HasSuffix(lines[i], "<lb> "+e) {
+			t.Errorf("[%d] got %q, expected %q", i, lines[i], e)
+		}
+	}
+	if !strings.Contains(lines[2], " DE ") || !strings.Contains(lines[4], " T1 ") {
+		t.Errorf("the kept lines lost their levels:\n%s", console.String())
+	}
+}
+
+func TestLookBehindTemplate(t *testing.T) {
+	console := resetLog(t)
+
+	f := NewFacility("lb")
+	f.SetLogLevel("INFO", FuncNameModeNone)
+	f.EnableLookBehind(3, ERR)
+	console.buf.Reset()
+
+	f.Template(DEBUG, "debug %d").Log(1)
+	f.Template(ERR, "error %d").Log(2)
+
+	expected := []string{
+		"--- look-behind begin ---",
+		"debug 1",
+		"--- look-behind end ---",
+		"error 2",
+	}
+
+	lines := console.Lines()
+	if len(lines) != len(expected) {
+		t.Fatalf("got %d lines, expected %d\n%s", len(lines), len(expected), console.String())
+	}
+	for i, e := range expected {
+		if !strings.HasSuffix(lines[i], "<lb> "+e) {
+			t.Errorf("[%d] got %q, expected %q", i, lines[i], e)
+		}
+	}
+}
+
+func TestLookBehindLimits(t *testing.T) {
+	console := resetLog(t)
+
+	f := NewFacility("lb")
+	f.SetLogLevel("INFO", FuncNameModeNone)
+	MaxLen(80)
+	f.EnableLookBehind(2, WARNING)
+	console.buf.Reset()
+
+	long := strings.Repeat("x", 200)
+	f.Message(DEBUG, "%s", long)
+	f.Message(WARNING, "trigger")
+
+	lines := console.Lines()
+	if len(lines) != 4 || len(lines[1]) != 80 {
+		t.Fatalf("unexpected output\n%s", console.String())
+	}
+
+	// Switched off the suppressed lines are not kept
+	f.EnableLookBehind(0, WARNING)
+	console.buf.Reset()
+
+	for i := 0; i < 3; i++ {
+		f.Message(DEBUG, fmt.Sprint(i))
+	}
+	f.Message(ERR, "boom")
+
+	if s := console.String(); strings.Contains(s, "look-behind") || len(console.Lines()) != 1 {
+		t.Errorf("unexpected output\n%s", s)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,96 @@
+package log
+
+import (
+	"strings"
+
+	"github.com/alrusov/misc"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+type memoryLine struct {
+	level Level
+	text  string
+}
+
+var (
+	memoryMode     = false
+	memoryMaxLines = 0
+	memoryBuf      = []memoryLine{}
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetMemoryMode -- disable the file output and keep up to maxLines last lines in the memory.
+// A subsequent SetFile call moves the kept lines into the new file.
+func SetMemoryMode(maxLines int) {
+	ensureStarted()
+
+	var notify alertNotifications
+	defer notify.call()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	defer notify.addFileChanges(currentFileSettings())
+
+	if maxLines <= 0 {
+		maxLines = lastBufSize
+	}
+
+	memoryMode = true
+	memoryMaxLines = maxLines
+	memoryBuf = []memoryLine{}
+
+	beforeFileBuf = []string{}
+	fileNamePattern = ""
+	lastWriteDate = ""
+	closeLogFile()
+	releaseExclusive()
+}
+
+// MemoryLog -- lines kept in the memory mode
+func MemoryLog() []string {
+	return MemoryLogFiltered(UNKNOWN)
+}
+
+// MemoryLogFiltered -- lines kept in the memory mode with the level minLevel or more severe
+func MemoryLogFiltered(minLevel Level) []string {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	list := make([]string, 0, len(memoryBuf))
+	for _, m := range memoryBuf {
+		if m.level.passes(minLevel) {
+			list = append(list, strings.TrimSuffix(m.text, misc.EOS))
+		}
+	}
+
+	return list
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Must be called under the mutex
+func memoryAppend(level Level, text string) {
+	if len(memoryBuf) >= memoryMaxLines {
+		memoryBuf = memoryBuf[len(memoryBuf)-memoryMaxLines+1:]
+	}
+	memoryBuf = append(memoryBuf, memoryLine{level: level, text: text})
+}
+
+// Must be called under the mutex
+func memoryToFile() {
+	if !memoryMode {
+		return
+	}
+
+	memoryMode = false
+
+	for _, m := range memoryBuf {
+		beforeFileBuf = append(beforeFileBuf, m.text)
+	}
+	memoryBuf = []memoryLine{}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
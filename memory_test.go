@@ -0,0 +1,79 @@
+package log
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestMemoryMode(t *testing.T) {
+	console := resetLog(t)
+
+	SetMemoryMode(3)
+
+	Message(ERR, "error 1")
+	Message(INFO, "info 1")
+	Message(WARNING, "warning 1")
+	Message(INFO, "info 2")
+
+	list := MemoryLog()
+	if len(list) != 3 {
+		t.Fatalf("got %d lines, expected 3", len(list))
+	}
+	for i, exp := range []string{"info 1", "warning 1", "info 2"} {
+		if !strings.HasSuffix(list[i], exp) {
+			t.Errorf("[%d] got %q, expected %q", i, list[i], exp)
+		}
+	}
+
+	list = MemoryLogFiltered(WARNING)
+	if len(list) != 1 || !strings.HasSuffix(list[0], "warning 1") {
+		t.Errorf("unexpected filtered lines %q", list)
+	}
+
+	if n := len(console.Lines()); n != 4 {
+		t.Errorf("got %d console lines, expected 4", n)
+	}
+
+	if len(beforeFileBuf) != 0 {
+		t.Errorf("beforeFileBuf has %d lines", len(beforeFileBuf))
+	}
+
+	exit(0, nil)
+	t.Cleanup(func() { resetLog(t) })
+
+	if _, err := os.Stat(dumpFileName); !os.IsNotExist(err) {
+		t.Errorf("dump file %s exists", dumpFileName)
+	}
+}
+
+func TestMemoryModeToFile(t *testing.T) {
+	resetLog(t)
+
+	SetMemoryMode(10)
+	Message(INFO, "kept in memory")
+
+	dir := t.TempDir()
+	SetFile(dir, "", false, 0, 0)
+	Message(INFO, "written to the file")
+
+	data, err := os.ReadFile(FileName())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := string(data)
+	i1 := strings.Index(s, "kept in memory")
+	i2 := strings.Index(s, "written to the file")
+	if i1 < 0 || i2 < i1 {
+		t.Errorf("unexpected file content:\n%s", s)
+	}
+
+	if len(MemoryLog()) != 0 {
+		t.Error("memory buffer is not empty")
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,93 @@
+package log
+
+import (
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// With the module tagging the function name is followed by the module of the caller: "{acme.io/billing@v1.4.2}",
+// "{main}" for the main module. The module is resolved once per program counter.
+
+var (
+	moduleTagging  atomic.Bool
+	moduleTags     sync.Map // pc -> tag
+	moduleResolver = resolveModule
+
+	buildInfo = sync.OnceValue(func() *debug.BuildInfo {
+		bi, ok := debug.ReadBuildInfo()
+		if !ok {
+			return nil
+		}
+		return bi
+	})
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetModuleTagging -- add the module of the caller to the function name. It works only when function names are logged.
+func SetModuleTagging(enabled bool) {
+	moduleTagging.Store(enabled)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// moduleTag -- " {module@version}" of the caller or empty string
+func moduleTag(shift int) string {
+	if !moduleTagging.Load() {
+		return ""
+	}
+
+	var pc [1]uintptr
+	if runtime.Callers(shift+2, pc[:]) == 0 {
+		return ""
+	}
+
+	if tag, exists := moduleTags.Load(pc[0]); exists {
+		return tag.(string)
+	}
+
+	tag := moduleResolver(pc[0])
+	if tag != "" {
+		tag = " {" + tag + "}"
+	}
+	moduleTags.Store(pc[0], tag)
+	return tag
+}
+
+// resolveModule -- "path@version" of the module containing the function, "main" for the main module
+func resolveModule(pc uintptr) string {
+	bi := buildInfo()
+	if bi == nil {
+		return ""
+	}
+
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	pkg := funcPackage(frame.Function)
+
+	if pkg == "main" || inModule(pkg, bi.Main.Path) {
+		return "main"
+	}
+
+	var found *debug.Module
+	for _, m := range bi.Deps {
+		if inModule(pkg, m.Path) && (found == nil || len(m.Path) > len(found.Path)) {
+			found = m
+		}
+	}
+
+	if found == nil {
+		return ""
+	}
+	return found.Path + "@" + found.Version
+}
+
+func inModule(pkg string, module string) bool {
+	return module != "" && (pkg == module || strings.HasPrefix(pkg, module+"/"))
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,106 @@
+package log
+
+import (
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/alrusov/misc"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// countModuleResolver -- the resolver counting calls, the cache is cleared
+func countModuleResolver(t *testing.T) *atomic.Int32 {
+	calls := &atomic.Int32{}
+
+	clearModuleTags := func() {
+		moduleTags.Range(func(k, _ any) bool {
+			moduleTags.Delete(k)
+			return true
+		})
+	}
+
+	clearModuleTags()
+	moduleResolver = func(pc uintptr) string {
+		calls.Add(1)
+		return resolveModule(pc)
+	}
+
+	t.Cleanup(func() {
+		moduleResolver = resolveModule
+		clearModuleTags()
+	})
+
+	return calls
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestModuleTagging(t *testing.T) {
+	console := resetLog(t)
+	calls := countModuleResolver(t)
+
+	SetLogLevel("INFO", FuncNameModeShort)
+
+	Message(INFO, "untagged")
+
+	SetModuleTagging(true)
+
+	for i := 0; i < 5; i++ {
+		Message(INFO, "tagged %d", i)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("resolver is called %d times, expected 1", n)
+	}
+
+	Message(INFO, "another caller")
+	if n := calls.Load(); n != 2 {
+		t.Errorf("resolver is called %d times, expected 2", n)
+	}
+
+	lines := console.Lines()
+	if len(lines) != 8 {
+		t.Fatalf("got %d lines, expected 8", len(lines))
+	}
+	if !strings.HasSuffix(lines[1], ": untagged") || strings.Contains(lines[1], "{") {
+		t.Errorf("unexpected untagged line %q", lines[1])
+	}
+	if !strings.HasSuffix(lines[2], " {main}: tagged 0") {
+		t.Errorf("unexpected tagged line %q", lines[2])
+	}
+	for _, line := range lines[2:] {
+		if !strings.Contains(line, " {main}: ") {
+			t.Errorf("unexpected tagged line %q", line)
+		}
+	}
+}
+
+func TestResolveModule(t *testing.T) {
+	version := ""
+	for _, m := range buildInfo().Deps {
+		if m.Path == "github.com/alrusov/misc" {
+			version = m.Version
+		}
+	}
+
+	tests := []struct {
+		f        any
+		expected string
+	}{
+		{TestResolveModule, "main"},
+		{misc.AppName, "github.com/alrusov/misc@" + version},
+		{strings.ToUpper, ""},
+	}
+
+	for _, test := range tests {
+		// The entry point isn't the return address, so it is moved into the function
+		pc := reflect.ValueOf(test.f).Pointer() + 1
+		if tag := resolveModule(pc); tag != test.expected {
+			t.Errorf("got %q, expected %q", tag, test.expected)
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
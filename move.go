@@ -0,0 +1,77 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// MoveTo -- move the log file to the new directory immediately, the next message is written to the new file.
+// The old file ends with "Log is continued in ..." and the new one starts with "Log is continued from ...".
+// On error the current file is kept.
+func MoveTo(directory string, suffix string) error {
+	ensureStarted()
+
+	var notify alertNotifications
+	defer notify.call()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	defer notify.addFileChanges(currentFileSettings())
+
+	if outputWriter != nil || fileNamePattern == "" || fileNamePattern == "-" {
+		return errors.New("log file is not set")
+	}
+	if directory == "" || directory == "-" {
+		return fmt.Errorf(`bad directory "%s"`, directory)
+	}
+
+	directory, pattern := filePattern(directory, suffix)
+	if pattern == fileNamePattern {
+		return nil
+	}
+
+	pattern, err := exclusivePattern(pattern)
+	if err != nil {
+		return err
+	}
+
+	flushOpenPending()
+
+	dt := fileDate(stamp())
+	name := fmt.Sprintf(pattern, dt)
+
+	fd, err := openFileInDir(directory, name)
+	if err != nil {
+		return err
+	}
+	fd.Close()
+
+	oldName := fileName
+	moved := dst != nil
+
+	if moved {
+		logger(false, 0, StdFacilityName, NOTICE, nil, "Log is continued in %s", name)
+	}
+
+	writeTrailer(name)
+	closeLogFile()
+	fileDirectory = directory
+	fileNamePattern = pattern
+	lastWriteDate = ""
+
+	rotateLogFile(dt)
+	if dst != nil {
+		lastWriteDate = dt
+	}
+
+	if moved {
+		logger(false, 0, StdFacilityName, NOTICE, nil, "Log is continued from %s", oldName)
+	}
+
+	return nil
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
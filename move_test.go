@@ -0,0 +1,104 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func readLogFile(t *testing.T, name string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestMoveTo(t *testing.T) {
+	resetLog(t)
+	setFakeClock(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+
+	oldDir := t.TempDir()
+	newDir := filepath.Join(t.TempDir(), "bigger")
+
+	SetFile(oldDir, "app", false, 4096, 0)
+	Message(INFO, "before")
+	oldName := FileName()
+
+	if err := MoveTo(newDir, "app"); err != nil {
+		t.Fatal(err)
+	}
+	newName := FileName()
+	Message(INFO, "after")
+	writerFlush()
+
+	if newName != filepath.Join(newDir, "2024-05-01-app.log") {
+		t.Errorf("unexpected new file %s", newName)
+	}
+
+	s := readLogFile(t, oldName)
+	if !strings.Contains(s, " before\n") || !strings.HasSuffix(s, " NO 2024-05-01 12:00:00.000 Log is continued in "+newName+"\n") || strings.Contains(s, "after") {
+		t.Errorf("unexpected old file:\n%s", s)
+	}
+
+	s = readLogFile(t, newName)
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[0], " was launched at ") || !strings.HasSuffix(lines[1], " Log is continued from "+oldName) || !strings.HasSuffix(lines[2], " after") {
+		t.Errorf("unexpected new file:\n%s", s)
+	}
+
+	if err := MoveTo("-", ""); err == nil {
+		t.Error("move to stdout is accepted")
+	}
+}
+
+func TestSetFileAgain(t *testing.T) {
+	resetLog(t)
+
+	dirs := []string{t.TempDir(), t.TempDir()}
+
+	const writers = 8
+	const count = 500
+
+	SetFile(dirs[0], "", false, 4096, 0)
+
+	var wg sync.WaitGroup
+	for g := 0; g < writers; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < count; n++ {
+				Message(INFO, "msg %d %d", g, n)
+				if g == 0 && n == count/2 {
+					SetFile(dirs[1], "", false, 4096, 0)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	writerFlush()
+
+	total := 0
+	for _, dir := range dirs {
+		names, _ := filepath.Glob(filepath.Join(dir, "*.log"))
+		if len(names) != 1 {
+			t.Fatalf("got files %v in %s", names, dir)
+		}
+		total += strings.Count(readLogFile(t, names[0]), " msg ")
+	}
+
+	if total != writers*count {
+		t.Errorf("got %d lines, expected %d", total, writers*count)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
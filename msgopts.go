@@ -0,0 +1,131 @@
+package log
+
+import (
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/alrusov/misc"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// MessageOpt takes the per call options: the additional stack shift for wrappers, the function name override and
+// the source. The function name override beats the global mode for the message only, EMERG always has the full
+// function name. The other message functions are its special cases.
+
+// FuncNameOverride -- the function name mode of the message
+type FuncNameOverride int
+
+const (
+	// FuncNameInherit -- the global mode
+	FuncNameInherit FuncNameOverride = iota
+	// FuncNameForceShort -- the function name of the caller
+	FuncNameForceShort
+	// FuncNameForceFull -- the call stack
+	FuncNameForceFull
+	// FuncNameSuppress -- no function name
+	FuncNameSuppress
+)
+
+// MsgOpts -- options of the message
+type MsgOpts struct {
+	Shift    int              // frames to skip above the caller, 1 records the caller's caller
+	FuncName FuncNameOverride // the function name mode
+	Source   string           // written as "[source] " before the message, see MessageWithSource
+	Replace  *misc.Replace    // see SecuredMessage
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// MessageOpt -- add message to the log with the options
+func (f *Facility) MessageOpt(level Level, opts MsgOpts, message string, params ...any) {
+	f.messageOpt(opts.Shift+1, level, opts, message, params...)
+}
+
+// MessageOpt -- add message to the log with the options
+func MessageOpt(level Level, opts MsgOpts, message string, params ...any) {
+	stdFacility.messageOpt(opts.Shift+1, level, opts, message, params...)
+}
+
+func (f *Facility) messageOpt(shift int, level Level, opts MsgOpts, message string, params ...any) {
+	force := level < 0
+	if force {
+		level = -level
+	}
+
+	if opts.Source != "" {
+		message = sourceMessage(opts.Source, message, params)
+	}
+
+	var mo *messageOptions
+	if opts.Replace != nil || opts.FuncName != FuncNameInherit {
+		mo = &messageOptions{replace: opts.Replace, funcName: opts.FuncName}
+	}
+
+	f.messageEx(shift+1, level, force, mo, message, params...)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// funcNameOverride -- the function name mode of the message
+func (mo *messageOptions) funcNameOverride() FuncNameOverride {
+	if mo == nil {
+		return FuncNameInherit
+	}
+	return mo.funcName
+}
+
+// withFuncName -- is the function name written and is it full
+func (fn FuncNameOverride) withFuncName(level Level) (with bool, full bool) {
+	if level == EMERG {
+		return true, true
+	}
+
+	switch fn {
+	case FuncNameForceShort:
+		return true, false
+	case FuncNameForceFull:
+		return true, true
+	case FuncNameSuppress:
+		return false, false
+	}
+
+	mode := logFuncName.Load()
+	return mode != logFuncNameNone, mode == logFuncNameFull
+}
+
+// callerFuncName -- the function name of the caller skipping shift frames, the call stack from the outermost
+// function if full. The frames are expanded, so the function inlined into the caller doesn't replace it.
+func callerFuncName(shift int, full bool) string {
+	pc := make([]uintptr, 1, 64)
+	if full {
+		pc = pc[:cap(pc)]
+	}
+
+	n := runtime.Callers(shift+2, pc)
+	if n == 0 {
+		return "?"
+	}
+
+	frames := runtime.CallersFrames(pc[:n])
+	var names []string
+	for {
+		frame, more := frames.Next()
+		names = append(names, filepath.Base(frame.Function))
+		if !full || !more {
+			break
+		}
+	}
+
+	if !full {
+		return names[0]
+	}
+
+	for i, j := 0, len(names)-1; i < j; i, j = i+1, j-1 {
+		names[i], names[j] = names[j], names[i]
+	}
+	return strings.Join(names, "->")
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,140 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestMessageOptFuncName(t *testing.T) {
+	console := resetLog(t)
+	f := NewFacility("opt")
+
+	const caller = "log.TestMessageOptFuncName"
+
+	modes := []FuncNameMode{FuncNameModeNone, FuncNameModeShort, FuncNameModeFull}
+	overrides := []FuncNameOverride{FuncNameInherit, FuncNameForceShort, FuncNameForceFull, FuncNameSuppress}
+
+	for _, mode := range modes {
+		for _, fn := range overrides {
+			SetLogLevel("DEBUG", mode)
+			console.buf.Reset()
+
+			f.MessageOpt(INFO, MsgOpts{FuncName: fn}, "text %d", 1)
+
+			with := fn == FuncNameForceShort || fn == FuncNameForceFull || (fn == FuncNameInherit && mode != FuncNameModeNone)
+			full := fn == FuncNameForceFull || (fn == FuncNameInherit && mode == FuncNameModeFull)
+
+			line := strings.TrimSuffix(console.String(), "\n")
+			name := fmt.Sprintf("%s/%d", mode, fn)
+
+			switch {
+			case !with:
+				if !strings.HasSuffix(line, "<opt> text 1") {
+					t.Errorf("[%s] unexpected function name in %q", name, line)
+				}
+			case full:
+				if !strings.HasSuffix(line, "->testing.tRunner->"+caller+": text 1") {
+					t.Errorf("[%s] no call stack in %q", name, line)
+				}
+			default:
+				if !strings.HasSuffix(line, "<opt> "+caller+": text 1") {
+					t.Errorf("[%s] no function name in %q", name, line)
+				}
+			}
+		}
+	}
+}
+
+func TestMessageOptEmerg(t *testing.T) {
+	console := resetLog(t)
+
+	for _, mode := range []FuncNameMode{FuncNameModeNone, FuncNameModeShort} {
+		SetLogLevel("DEBUG", mode)
+		console.buf.Reset()
+
+		MessageOpt(EMERG, MsgOpts{FuncName: FuncNameSuppress}, "fatal")
+
+		if s := console.String(); !strings.Contains(s, "->log.TestMessageOptEmerg: fatal\n") {
+			t.Errorf("[%s] EMERG without the function name %q", mode, s)
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// optHelper -- the wrapper recording its caller
+func optHelper(f *Facility, message string) {
+	f.MessageOpt(NOTICE, MsgOpts{Shift: 1, FuncName: FuncNameForceShort, Source: "helper"}, "%s", message)
+}
+
+func TestMessageOptShiftAndSource(t *testing.T) {
+	console := resetLog(t)
+
+	SetLogLevel("DEBUG", FuncNameModeNone)
+	f := NewFacility("opt")
+	console.buf.Reset()
+
+	optHelper(f, "via helper")
+	f.MessageWithSource(INFO, "src", "with source %d%%", 100)
+	MessageOpt(INFO, MsgOpts{Source: "pkg", FuncName: FuncNameForceShort}, "plain")
+	f.MessageOpt(-DEBUG, MsgOpts{}, "forced")
+
+	// The package wrappers record the caller too
+	SetFuncNameMode(FuncNameModeShort)
+
+	Message(INFO, "pkg message")
+	MessageEx(0, INFO, nil, "pkg message ex")
+	SecuredMessageWithSource(INFO, nil, "s", "pkg secured")
+
+	expected := []string{
+		"<opt> log.TestMessageOptShiftAndSource: [helper] via helper",
+		"<opt> [src] with source 100%",
+		" log.TestMessageOptShiftAndSource: [pkg] plain",
+		"<opt> forced",
+		" log.TestMessageOptShiftAndSource: pkg message",
+		" log.TestMessageOptShiftAndSource: pkg message ex",
+		" log.TestMessageOptShiftAndSource: [s] pkg secured",
+	}
+
+	lines := console.Lines()
+	if len(lines) != len(expected) {
+		t.Fatalf("got %d lines, expected %d\n%s", len(lines), len(expected), console.String())
+	}
+	for i, e := range expected {
+		if !strings.HasSuffix(lines[i], e) {
+			t.Errorf("[%d] got %q, expected %q", i, lines[i], e)
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestMessageOptJSON(t *testing.T) {
+	resetLog(t)
+	SetFuncNameMode(FuncNameModeShort)
+
+	format, err := NewJSONFormat(JSONOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The suppressed function name isn't taken from the text
+	r := Record{
+		Time:     time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC),
+		Level:    INFO,
+		Facility: "db",
+		Line:     "[123] IN 2024-05-03 12:00:00.000 <db> key: value\n",
+		funcName: FuncNameSuppress,
+	}
+
+	expected := `{"v":1,"time":"2024-05-03T12:00:00Z","level":"INFO","facility":"db","text":"key: value"}` + "\n"
+	if s := format.Render(r); s != expected {
+		t.Errorf("got\n%s\nexpected\n%s", s, expected)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,129 @@
+package log
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// The severe notifier is called on its own goroutine for messages with the level or more severe. The same message of
+// the facility is notified at most once per cooldown, records are dropped while the notifier is busy with the queue full.
+
+type severeNotifier struct {
+	minLevel Level
+	fn       func(rec Record)
+	queue    chan Record
+	stop     chan struct{}
+
+	mutex sync.Mutex
+	last  map[string]time.Time
+}
+
+const (
+	severeQueueSize = 64
+	severeMaxKeys   = 1000
+)
+
+var (
+	severe         atomic.Pointer[severeNotifier]
+	severeCooldown atomic.Int64
+)
+
+func init() {
+	severeCooldown.Store(int64(time.Minute))
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetSevereNotifier -- call fn for messages with minLevel or more severe, nil disables it
+func SetSevereNotifier(minLevel Level, fn func(rec Record)) {
+	var n *severeNotifier
+	if fn != nil {
+		n = &severeNotifier{
+			minLevel: minLevel,
+			fn:       fn,
+			queue:    make(chan Record, severeQueueSize),
+			stop:     make(chan struct{}),
+			last:     map[string]time.Time{},
+		}
+		go n.run()
+	}
+
+	if old := severe.Swap(n); old != nil {
+		close(old.stop)
+	}
+}
+
+// currentSevereNotifier -- the severe notifier in effect, nil fn if there is none
+func currentSevereNotifier() (minLevel Level, fn func(rec Record)) {
+	if n := severe.Load(); n != nil {
+		return n.minLevel, n.fn
+	}
+	return 0, nil
+}
+
+// SetSevereNotifierCooldown -- minimal interval between notifications of the same message, one minute by default
+func SetSevereNotifierCooldown(cooldown time.Duration) {
+	severeCooldown.Store(int64(cooldown))
+}
+
+// TerminalBellNotifier -- the notifier ringing the bell of the console
+func TerminalBellNotifier(rec Record) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	writeToConsole("\a")
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func (n *severeNotifier) run() {
+	for {
+		select {
+		case <-n.stop:
+			return
+		case rec := <-n.queue:
+			n.fn(rec)
+		}
+	}
+}
+
+// notifySevere -- queue the record for the severe notifier
+func notifySevere(facility string, level Level, t time.Time, msg string) {
+	n := severe.Load()
+	if n == nil || !level.passes(n.minLevel) {
+		return
+	}
+
+	cooldown := time.Duration(severeCooldown.Load())
+	key := facility + "\x00" + strconv.Itoa(int(level)) + "\x00" + msg
+
+	n.mutex.Lock()
+	if last, exists := n.last[key]; exists && t.Sub(last) < cooldown {
+		n.mutex.Unlock()
+		return
+	}
+	if len(n.last) >= severeMaxKeys {
+		for k, last := range n.last {
+			if t.Sub(last) >= cooldown {
+				delete(n.last, k)
+			}
+		}
+		if len(n.last) >= severeMaxKeys {
+			n.mutex.Unlock()
+			return
+		}
+	}
+	n.last[key] = t
+	n.mutex.Unlock()
+
+	select {
+	case n.queue <- Record{Time: t, Level: level, Facility: facility, Message: msg}:
+	default:
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,77 @@
+package log
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func waitCount(t *testing.T, n *atomic.Int32, expected int32) {
+	t.Helper()
+
+	for i := 0; i < 100 && n.Load() < expected; i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+	// Extra calls would arrive meanwhile
+	time.Sleep(20 * time.Millisecond)
+
+	if v := n.Load(); v != expected {
+		t.Fatalf("got %d notifications, expected %d", v, expected)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestSevereNotifier(t *testing.T) {
+	resetLog(t)
+	clock := setFakeClock(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+
+	var count atomic.Int32
+	var last atomic.Pointer[Record]
+
+	SetSevereNotifierCooldown(time.Minute)
+	SetSevereNotifier(ALERT, func(rec Record) {
+		last.Store(&rec)
+		count.Add(1)
+	})
+	defer SetSevereNotifier(0, nil)
+
+	f := GetFacility("disk")
+	for i := 0; i < 10; i++ {
+		f.Message(ALERT, "disk %s is full", "sda")
+	}
+	f.Message(CRIT, "not severe enough")
+	waitCount(t, &count, 1)
+
+	if rec := last.Load(); rec.Level != ALERT || rec.Facility != "disk" || rec.Message != "disk sda is full" {
+		t.Errorf("unexpected record %+v", rec)
+	}
+
+	Message(EMERG, "disk sda is full")
+	waitCount(t, &count, 2)
+
+	clock.Add(time.Minute)
+	f.Message(ALERT, "disk %s is full", "sda")
+	waitCount(t, &count, 3)
+}
+
+func TestTerminalBellNotifier(t *testing.T) {
+	console := resetLog(t)
+
+	SetSevereNotifier(EMERG, TerminalBellNotifier)
+	defer SetSevereNotifier(0, nil)
+
+	Message(EMERG, "ring")
+
+	for i := 0; i < 100 && !strings.Contains(console.String(), "\a"); i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !strings.Contains(console.String(), "\a") {
+		t.Errorf("no bell on the console:\n%q", console)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
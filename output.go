@@ -0,0 +1,65 @@
+package log
+
+import (
+	"io"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// OutputOptions -- options of SetOutput
+type OutputOptions struct {
+	UseLocalTime bool
+	BufSize      int
+	FlushPeriod  time.Duration
+}
+
+// Rotator -- the output writer implementing it is rotated on the date change
+type Rotator interface {
+	Rotate() error
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetOutput -- use the caller provided writer instead of the file.
+// The writer is owned by the package since then and is closed at exit or when the output is changed.
+// Daily rotation and file names are not used, the writer implementing Rotator is rotated on the date change instead.
+// nil returns to the unconfigured state.
+func SetOutput(w io.WriteCloser, opts OutputOptions) {
+	ensureStarted()
+
+	var notify alertNotifications
+	defer notify.call()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	defer notify.addFileChanges(currentFileSettings())
+
+	memoryToFile()
+	flushOpenPending()
+
+	closeLogFile()
+	lastWriteDate = ""
+
+	outputWriter = w
+	fileDirectory = ""
+	fileNamePattern = ""
+	fileName = ""
+	releaseExclusive()
+
+	if w == nil {
+		return
+	}
+
+	if localTime != opts.UseLocalTime {
+		localTime = opts.UseLocalTime
+		resetTimeCache()
+	}
+	fileWriterBufSize = opts.BufSize
+	if opts.FlushPeriod > 0 {
+		fileWriterFlushPeriod = opts.FlushPeriod
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,108 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+type testCloser struct {
+	mutex   sync.Mutex
+	buf     bytes.Buffer
+	events  []string
+	rotated int
+}
+
+func (w *testCloser) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.events = append(w.events, "write")
+	return w.buf.Write(p)
+}
+
+func (w *testCloser) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.events = append(w.events, "close")
+	return nil
+}
+
+func (w *testCloser) Rotate() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.rotated++
+	return nil
+}
+
+func (w *testCloser) String() string {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.buf.String()
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestSetOutput(t *testing.T) {
+	resetLog(t)
+
+	clock := setFakeClock(time.Date(2024, 5, 3, 23, 59, 59, 0, time.UTC))
+
+	Message(INFO, "before output")
+
+	w := &testCloser{}
+	SetOutput(w, OutputOptions{BufSize: 4096})
+
+	Message(INFO, "first")
+	if w.String() != "" {
+		t.Fatalf("unexpected unbuffered data:\n%s", w)
+	}
+
+	writerFlush()
+	s := w.String()
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[0], " *** ") || !strings.HasSuffix(lines[1], "before output") || !strings.HasSuffix(lines[2], "first") {
+		t.Fatalf("unexpected output:\n%s", s)
+	}
+	if FileName() != "" {
+		t.Errorf("unexpected file name %q", FileName())
+	}
+
+	clock.Add(time.Second)
+	Message(INFO, "next day")
+	if w.rotated != 1 {
+		t.Errorf("rotated %d times, expected 1", w.rotated)
+	}
+
+	exit(0, nil)
+	t.Cleanup(func() { resetLog(t) })
+
+	if !strings.HasSuffix(strings.TrimSpace(w.String()), "Log file closed") {
+		t.Errorf("the last lines are lost:\n%s", w)
+	}
+	if n := len(w.events); n == 0 || w.events[n-1] != "close" || w.events[n-2] != "write" {
+		t.Errorf("unexpected events order %q", w.events)
+	}
+}
+
+func TestSetOutputNil(t *testing.T) {
+	resetLog(t)
+
+	w := &testCloser{}
+	SetOutput(w, OutputOptions{})
+	Message(INFO, "to writer")
+	SetOutput(nil, OutputOptions{})
+	Message(INFO, "to buffer")
+
+	if !strings.Contains(w.String(), "to writer") || strings.Contains(w.String(), "to buffer") {
+		t.Errorf("unexpected output:\n%s", w)
+	}
+	if len(beforeFileBuf) != 1 {
+		t.Errorf("got %d buffered lines, expected 1", len(beforeFileBuf))
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,99 @@
+package log
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alrusov/misc"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// LineInfo -- parsed log line
+type LineInfo struct {
+	PID      int       `json:"pid"`
+	Level    Level     `json:"level"`
+	Time     time.Time `json:"time"`
+	Facility string    `json:"facility,omitempty"`
+	Text     string    `json:"text"`
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// ParseLine -- parse the line in the standard format "[pid] LV date time [<facility>] text".
+// The checksum trailer is stripped. Time is parsed in the current time zone of the log.
+func ParseLine(line string) (info LineInfo, ok bool) {
+	line = strings.TrimRight(line, "\r\n")
+	if s, found, _ := splitChecksum(line); found {
+		line = s
+	}
+
+	if !strings.HasPrefix(line, "[") {
+		return
+	}
+	i := strings.IndexByte(line, ']')
+	if i < 0 {
+		return
+	}
+	pid, err := strconv.Atoi(line[1:i])
+	if err != nil {
+		return
+	}
+	info.PID = pid
+	line = line[i+1:]
+
+	fields := strings.SplitN(line, " ", 5)
+	// "", level, date, time, rest
+	if len(fields) < 4 || fields[0] != "" {
+		return
+	}
+
+	level, exists := Str2Level(fields[1])
+	if !exists {
+		return
+	}
+	info.Level = level
+
+	loc := time.UTC
+	if localTime {
+		loc = time.Local
+	}
+	info.Time, err = time.ParseInLocation(misc.DateTimeFormatRevWithMS, fields[2]+" "+fields[3], loc)
+	if err != nil {
+		return
+	}
+
+	if len(fields) == 5 {
+		text := fields[4]
+		if strings.HasPrefix(text, "<") {
+			if j := strings.Index(text, "> "); j > 0 {
+				info.Facility = text[1:j]
+				text = text[j+2:]
+			} else if strings.HasSuffix(text, ">") {
+				info.Facility = text[1 : len(text)-1]
+				text = ""
+			}
+		}
+		info.Text = text
+	}
+
+	ok = true
+	return
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// ParseLineEx -- ParseLine that understands the compact format. The header line is stored into h and it isn't a line.
+func ParseLineEx(line string, h *CompactHeader) (info LineInfo, ok bool) {
+	if h != nil {
+		var isLine bool
+		if line, isLine = h.expand(line); !isLine {
+			return
+		}
+	}
+
+	return ParseLine(line)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
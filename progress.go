@@ -0,0 +1,90 @@
+package log
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Progress reports the long operation: the start, the "still running" ticks with the elapsed time and the result. The
+// ticks are logged by the goroutine which stops when done is called or the context is cancelled, the result is logged
+// by done only. The level is checked on every tick like for any message, so the ticks are suppressed while it's
+// filtered. Every call has its own goroutine and start time, nested operations don't affect each other.
+
+var (
+	// progressTicker -- source of the ticks, replaced in tests
+	progressTicker = func(d time.Duration) (<-chan time.Time, func()) {
+		t := time.NewTicker(d)
+		return t.C, t.Stop
+	}
+
+	progressActive atomic.Int32 // running tick goroutines
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Progress -- Facility.Progress for the standard facility
+func Progress(ctx context.Context, level Level, label string, tick time.Duration) (done func(err error)) {
+	return stdFacility.progress(ctx, level, label, tick)
+}
+
+// Progress -- log the start of the operation and the "still running" message every tick until done is called or
+// the context is cancelled. done logs the duration and the error if any, the repeated calls are ignored.
+func (f *Facility) Progress(ctx context.Context, level Level, label string, tick time.Duration) (done func(err error)) {
+	return f.progress(ctx, level, label, tick)
+}
+
+func (f *Facility) progress(ctx context.Context, level Level, label string, tick time.Duration) (done func(err error)) {
+	start := now()
+	f.messageEx(2, level, false, nil, "%s: started", label)
+
+	stop := make(chan struct{})
+	var once sync.Once
+
+	if tick > 0 {
+		ticks, stopTicker := progressTicker(tick)
+		progressActive.Add(1)
+
+		go func() {
+			defer progressActive.Add(-1)
+			defer stopTicker()
+
+			for {
+				select {
+				case <-stop:
+					return
+				case <-ctx.Done():
+					return
+				case <-ticks:
+					f.messageEx(0, level, false, nil, "%s: still running (%s elapsed)", label, progressElapsed(start))
+				}
+			}
+		}()
+	}
+
+	return func(err error) {
+		once.Do(func() {
+			close(stop)
+
+			if err != nil {
+				f.messageEx(1, level, false, nil, "%s: failed after %s: %s", label, progressElapsed(start), err)
+				return
+			}
+			f.messageEx(1, level, false, nil, "%s: finished in %s", label, progressElapsed(start))
+		})
+	}
+}
+
+// progressElapsed -- the time since the start rounded to the seconds, to the milliseconds if shorter
+func progressElapsed(start time.Time) time.Duration {
+	d := now().Sub(start)
+	if d < time.Second {
+		return d.Round(time.Millisecond)
+	}
+	return d.Round(time.Second)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,156 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+type manualTicker struct {
+	ch      []chan time.Time // per Progress call
+	stopped chan struct{}
+}
+
+// setManualTicker -- replace the progress ticks by the ticks sent by the test
+func setManualTicker(t *testing.T) *manualTicker {
+	m := &manualTicker{stopped: make(chan struct{}, 10)}
+
+	old := progressTicker
+	progressTicker = func(time.Duration) (<-chan time.Time, func()) {
+		ch := make(chan time.Time)
+		m.ch = append(m.ch, ch)
+		return ch, func() { m.stopped <- struct{}{} }
+	}
+	t.Cleanup(func() { progressTicker = old })
+
+	return m
+}
+
+func waitProgress(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("condition is not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestProgress(t *testing.T) {
+	console := resetLog(t)
+	clock := setFakeClock(time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC))
+	ticker := setManualTicker(t)
+
+	SetLogLevel("TRACE4", FuncNameModeNone)
+	console.buf.Reset()
+
+	f := NewFacility("progress")
+	done := f.Progress(context.Background(), INFO, "import", 30*time.Second)
+
+	for i := 1; i <= 3; i++ {
+		clock.Add(30 * time.Second)
+		ticker.ch[0] <- now()
+		n := i + 1
+		waitProgress(t, func() bool { return len(console.Lines()) >= n })
+	}
+
+	clock.Add(43 * time.Second)
+	done(nil)
+	done(errors.New("ignored"))
+
+	<-ticker.stopped
+	waitProgress(t, func() bool { return progressActive.Load() == 0 })
+
+	expected := []string{
+		"import: started",
+		"import: still running (30s elapsed)",
+		"import: still running (1m0s elapsed)",
+		"import: still running (1m30s elapsed)",
+		"import: finished in 2m13s",
+	}
+
+	lines := console.Lines()
+	if len(lines) != len(expected) {
+		t.Fatalf("got %d lines, expected %d\n%s", len(lines), len(expected), console.String())
+	}
+	for i, e := range expected {
+		if !strings.HasSuffix(lines[i], e) || !strings.Contains(lines[i], "<progress>") {
+			t.Errorf("[%d] got %q, expected %q", i, lines[i], e)
+		}
+	}
+}
+
+func TestProgressFailedAndCancelled(t *testing.T) {
+	console := resetLog(t)
+	clock := setFakeClock(time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC))
+	ticker := setManualTicker(t)
+
+	SetLogLevel("TRACE4", FuncNameModeNone)
+	console.buf.Reset()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := Progress(ctx, NOTICE, "sync", time.Second)
+
+	cancel()
+	<-ticker.stopped
+	waitProgress(t, func() bool { return progressActive.Load() == 0 })
+
+	clock.Add(5 * time.Second)
+	done(errors.New("connection reset"))
+
+	s := console.String()
+	if !strings.Contains(s, "sync: started") || !strings.Contains(s, "sync: failed after 5s: connection reset") {
+		t.Errorf("unexpected output\n%s", s)
+	}
+	if strings.Contains(s, "still running") {
+		t.Errorf("tick after the cancellation\n%s", s)
+	}
+}
+
+func TestProgressFilteredAndNested(t *testing.T) {
+	console := resetLog(t)
+	clock := setFakeClock(time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC))
+	ticker := setManualTicker(t)
+
+	SetLogLevel("INFO", FuncNameModeNone)
+	console.buf.Reset()
+
+	outer := Progress(context.Background(), INFO, "outer", time.Minute)
+	inner := Progress(context.Background(), DEBUG, "inner", time.Minute)
+
+	clock.Add(time.Minute)
+	ticker.ch[0] <- now()
+	ticker.ch[1] <- now()
+
+	// The inner tick is filtered
+	waitProgress(t, func() bool { return strings.Contains(console.String(), "outer: still running (1m0s elapsed)") })
+
+	inner(nil)
+	<-ticker.stopped
+
+	clock.Add(time.Minute)
+	ticker.ch[0] <- now()
+	waitProgress(t, func() bool { return strings.Count(console.String(), "outer: still running") == 2 })
+
+	outer(nil)
+	<-ticker.stopped
+	waitProgress(t, func() bool { return progressActive.Load() == 0 })
+
+	s := console.String()
+	if strings.Contains(s, "inner") {
+		t.Errorf("the filtered progress is logged\n%s", s)
+	}
+	if !strings.Contains(s, "outer: finished in 2m0s") {
+		t.Errorf("unexpected output\n%s", s)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,164 @@
+package log
+
+import (
+	"fmt"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// The quota limits bytes of the facility lines per rotation day. The first line over the quota is preceded by
+// the WARNING of the facility, the quota is renewed when the date changes.
+
+// QuotaAction -- what to do with lines of the facility over its daily quota
+type QuotaAction struct {
+	kind string
+	n    int
+}
+
+// QuotaInfo -- quota consumption of the facility for the day
+type QuotaInfo struct {
+	Day     string `json:"day"`
+	Limit   int64  `json:"limit"`
+	Used    int64  `json:"used"`
+	Dropped int64  `json:"dropped"` // lines not written to the file
+}
+
+type quotaState struct {
+	QuotaInfo
+	action   QuotaAction
+	exceeded bool
+	seq      int
+}
+
+var (
+	// QuotaDrop -- drop lines over the quota
+	QuotaDrop = QuotaAction{kind: "drop"}
+	// QuotaDowngradeToConsole -- lines over the quota skip the file, the console and other copies get them
+	QuotaDowngradeToConsole = QuotaAction{kind: "downgradeToConsole"}
+
+	quotas         = map[string]*quotaState{}
+	quotaReporting = false
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// QuotaSample -- keep 1 of n lines over the quota
+func QuotaSample(n int) QuotaAction {
+	return QuotaAction{kind: "sample", n: max(n, 1)}
+}
+
+func (a QuotaAction) String() string {
+	if a.kind == "sample" {
+		return fmt.Sprintf("sample(%d)", a.n)
+	}
+	return a.kind
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetDailyQuota -- limit bytes of the facility lines per day. Zero or negative bytes removes the quota.
+// The consumption of the current day is kept.
+func (f *Facility) SetDailyQuota(bytes int64, action QuotaAction) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	setDailyQuota(f.name, bytes, action)
+}
+
+// setDailyQuota -- must be called under the mutex
+func setDailyQuota(name string, bytes int64, action QuotaAction) {
+	if bytes <= 0 {
+		delete(quotas, name)
+		return
+	}
+
+	q, exists := quotas[name]
+	if !exists {
+		q = &quotaState{}
+		quotas[name] = q
+	}
+
+	q.Limit = bytes
+	q.action = action
+	q.exceeded = q.Used > bytes
+}
+
+// QuotaUsage -- consumption of facilities with quotas, the std facility is StdFacilityAlias
+func QuotaUsage() map[string]QuotaInfo {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	return quotaUsage()
+}
+
+// Must be called under the mutex
+func quotaUsage() map[string]QuotaInfo {
+	if len(quotas) == 0 {
+		return nil
+	}
+
+	list := make(map[string]QuotaInfo, len(quotas))
+	for name, q := range quotas {
+		if name == StdFacilityName {
+			name = StdFacilityAlias
+		}
+		list[name] = q.QuotaInfo
+	}
+	return list
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// quotaFilter -- should the line go to the file and to the copies. Must be called under the mutex.
+func quotaFilter(facility string, dt string, text string) (toFile bool, toCopies bool) {
+	if len(quotas) == 0 || quotaReporting {
+		return true, true
+	}
+
+	q := quotas[facility]
+	if q == nil {
+		return true, true
+	}
+
+	if q.Day != dt {
+		q.Day = dt
+		q.Used = 0
+		q.Dropped = 0
+		q.exceeded = false
+		q.seq = 0
+	}
+
+	size := int64(len(text))
+
+	if !q.exceeded {
+		if q.Used+size <= q.Limit {
+			q.Used += size
+			return true, true
+		}
+
+		q.exceeded = true
+
+		quotaReporting = true
+		logger(false, 0, facility, WARNING, nil, "Daily log quota of %d bytes is exceeded, action %s", q.Limit, q.action)
+		quotaReporting = false
+	}
+
+	switch q.action.kind {
+	case "downgradeToConsole":
+		q.Dropped++
+		return false, true
+
+	case "sample":
+		q.seq++
+		if q.seq%q.action.n == 1 || q.action.n == 1 {
+			q.Used += size
+			return true, true
+		}
+	}
+
+	q.Dropped++
+	statDrop()
+	return false, false
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
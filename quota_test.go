@@ -0,0 +1,115 @@
+package log
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// quotaRun -- the quota fits 3 lines of the facility, 10 lines are logged
+func quotaRun(t *testing.T, action QuotaAction) (console *captureWriter, file string, info QuotaInfo) {
+	t.Helper()
+
+	console = resetLog(t)
+	setFakeClock(time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC))
+	SetFile(t.TempDir(), "", false, 4096, 0)
+
+	f := GetFacility("events")
+	f.SetDailyQuota(1<<20, action)
+	f.Message(INFO, "event 0")
+
+	size := QuotaUsage()["events"].Used
+	f.SetDailyQuota(3*size+size/2, action)
+
+	for i := 1; i < 10; i++ {
+		f.Message(INFO, "event %d", i)
+		Message(INFO, "other %d", i)
+	}
+
+	writerFlush()
+	data, err := os.ReadFile(FileName())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return console, string(data), QuotaUsage()["events"]
+}
+
+func TestQuotaDrop(t *testing.T) {
+	console, file, info := quotaRun(t, QuotaDrop)
+
+	if n := strings.Count(file, "<events> event "); n != 3 {
+		t.Errorf("got %d lines in the file, expected 3:\n%s", n, file)
+	}
+	if n := strings.Count(file, "Daily log quota of"); n != 1 {
+		t.Errorf("got %d warnings, expected 1:\n%s", n, file)
+	}
+	if n := strings.Count(console.String(), "<events> event "); n != 3 {
+		t.Errorf("got %d lines on the console, expected 3", n)
+	}
+	if n := strings.Count(file, "other "); n != 9 {
+		t.Errorf("other facility lines are affected:\n%s", file)
+	}
+	if info.Dropped != 7 || info.Day != "2024-05-03" {
+		t.Errorf("unexpected usage %+v", info)
+	}
+	if st := Status().Quotas; st["events"] != info {
+		t.Errorf("unexpected status %+v", st)
+	}
+}
+
+func TestQuotaDowngradeToConsole(t *testing.T) {
+	console, file, info := quotaRun(t, QuotaDowngradeToConsole)
+
+	if n := strings.Count(file, "<events> event "); n != 3 {
+		t.Errorf("got %d lines in the file, expected 3:\n%s", n, file)
+	}
+	if n := strings.Count(console.String(), "<events> event "); n != 10 {
+		t.Errorf("got %d lines on the console, expected 10", n)
+	}
+	if info.Dropped != 7 {
+		t.Errorf("unexpected usage %+v", info)
+	}
+}
+
+func TestQuotaSample(t *testing.T) {
+	_, file, info := quotaRun(t, QuotaSample(3))
+
+	// 3 within the quota, then 1 of 3 of the remaining 7
+	if n := strings.Count(file, "<events> event "); n != 6 {
+		t.Errorf("got %d lines in the file, expected 6:\n%s", n, file)
+	}
+	if info.Dropped != 4 {
+		t.Errorf("unexpected usage %+v", info)
+	}
+}
+
+func TestQuotaNewDay(t *testing.T) {
+	resetLog(t)
+	clock := setFakeClock(time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC))
+
+	f := GetFacility("events")
+	f.SetDailyQuota(1, QuotaDrop)
+
+	f.Message(INFO, "dropped 1")
+	f.Message(INFO, "dropped 2")
+	if info := QuotaUsage()["events"]; info.Dropped != 2 {
+		t.Errorf("unexpected usage %+v", info)
+	}
+
+	clock.Add(24 * time.Hour)
+	f.Message(INFO, "next day")
+	if info := QuotaUsage()["events"]; info.Day != "2024-05-04" || info.Dropped != 1 {
+		t.Errorf("the quota isn't renewed %+v", info)
+	}
+
+	f.SetDailyQuota(0, QuotaDrop)
+	if q := QuotaUsage(); q != nil {
+		t.Errorf("the quota isn't removed %+v", q)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,57 @@
+package log
+
+import (
+	"strings"
+
+	"github.com/alrusov/misc"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// WriteRaw -- append the preformatted line as is, without the prefix and formatting
+func WriteRaw(line string) {
+	stdFacility.WriteRaw(line)
+}
+
+// WriteRaw -- append the preformatted line as is, without the prefix and formatting.
+// The line is not filtered by the level but the redaction and drop rules (the level is UNKNOWN), maxLen,
+// the destinations and the file rotation are applied as usual. The JSON formats emit the line as is in the raw field.
+func (f *Facility) WriteRaw(line string) {
+	if !enabled || f.disabled.Load() {
+		return
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	statRawMessage()
+
+	dt := fileDate(stamp())
+
+	line, ok := applyRules(f.name, UNKNOWN, strings.TrimRight(line, "\r\n"))
+	if !ok {
+		statDrop()
+		return
+	}
+
+	if n := int(maxLen.Load()); n > 0 && n < len(line) {
+		line = line[:n]
+		statTruncate()
+	}
+
+	ensureStarted()
+	text, console := stripForFile(line+misc.EOS, "")
+	outputRecord(
+		&Record{
+			Time:     lastStamp,
+			Level:    UNKNOWN,
+			Facility: f.name,
+			Date:     dt,
+			Line:     text,
+			Raw:      true,
+			console:  console,
+		},
+	)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
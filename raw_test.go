@@ -0,0 +1,108 @@
+package log
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestWriteRaw(t *testing.T) {
+	console := resetLog(t)
+
+	clock := setFakeClock(time.Date(2024, 5, 3, 23, 59, 59, 0, time.UTC))
+
+	dir := t.TempDir()
+	SetFile(dir, "", false, 0, 0)
+
+	raw := "[123] IN 2024-05-03 23:59:59.000 migrated line"
+
+	WriteRaw(raw)
+	if s := strings.TrimSpace(console.String()); !strings.HasSuffix(s, "\n"+raw) {
+		t.Errorf("raw line is prefixed again:\n%s", s)
+	}
+
+	clock.Add(2 * time.Second)
+	GetFacility("import").WriteRaw(raw + " next day\n")
+
+	day1, err := os.ReadFile(filepath.Join(dir, "2024-05-03.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	day2, err := os.ReadFile(filepath.Join(dir, "2024-05-04.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasSuffix(string(day1), "\n"+raw+"\n") {
+		t.Errorf("unexpected first file:\n%s", day1)
+	}
+	if !strings.HasSuffix(string(day2), "\n"+raw+" next day\n") {
+		t.Errorf("unexpected second file:\n%s", day2)
+	}
+
+	if n := GetStats().Raw; n != 2 {
+		t.Errorf("got %d raw lines, expected 2", n)
+	}
+}
+
+func TestWriteRawRules(t *testing.T) {
+	console := resetLog(t)
+
+	path := filepath.Join(t.TempDir(), "rules.toml")
+	writeRules(t, path, "[[redact]]\nname = \"password\"\nregexp = '(password=)\\w+'\nreplacement = \"${1}***\"\n"+
+		"[[drop]]\nregexp = \"health check\"\n", time.Now())
+	if err := LoadRulesFile(path); err != nil {
+		t.Fatal(err)
+	}
+	console.buf.Reset()
+
+	WriteRaw("[123] IN 2024-05-03 23:59:59.000 login password=secret")
+	WriteRaw("[123] DE 2024-05-03 23:59:59.000 health check")
+
+	if lines := console.Lines(); len(lines) != 1 || lines[0] != "[123] IN 2024-05-03 23:59:59.000 login password=***" {
+		t.Errorf("unexpected lines %q", lines)
+	}
+}
+
+func TestWriteRawJSON(t *testing.T) {
+	resetLog(t)
+	setFakeClock(time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC))
+	SetFile(t.TempDir(), "", false, 0, 0)
+
+	raw := "[123] IN 2024-05-03 12:00:00.000 <db> migrated \"line\""
+
+	// The raw record passes every level
+	AddDestination(DestinationFile, FormatJSON, nil, TRACE4)
+	sink := &captureWriter{}
+	format, err := NewJSONFormat(JSONOptions{Fields: JSONFieldNames{Raw: "line"}, ExtraFields: map[string]any{"app": "x"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	AddDestination("errors", format, sink, ERR)
+
+	WriteRaw(raw)
+	writerFlush()
+
+	lines := waitFile(t, FileName(), 2)
+	if expected := `{"v":1,"raw":"[123] IN 2024-05-03 12:00:00.000 \u003cdb\u003e migrated \"line\""}`; lines[1] != expected {
+		t.Errorf("got\n%s\nexpected\n%s", lines[1], expected)
+	}
+
+	var v struct {
+		Raw string `json:"raw"`
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &v); err != nil || v.Raw != raw {
+		t.Errorf("the raw line isn't untouched: %q %v", v.Raw, err)
+	}
+
+	if s, expected := sink.String(), `{"v":1,"line":`+jsonString(raw)+`,"app":"x"}`+"\n"; s != expected {
+		t.Errorf("got\n%s\nexpected\n%s", s, expected)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,25 @@
+package log
+
+import (
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Record -- the logged message passed to notifiers and destinations
+type Record struct {
+	Time     time.Time
+	Level    Level
+	Facility string
+	Message  string // the text without the prefix, notifiers only
+	Date     string // date of the file, destinations only
+	Line     string // the classic line with the line end, destinations only
+	EventID  string // the event ID of MessageID, destinations only
+	Raw      bool   // the preformatted line of WriteRaw, it has no level and isn't filtered by it
+
+	console  string           // the console rendering of the event
+	funcName FuncNameOverride // the function name mode of the line
+	cache    renderCache
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,74 @@
+package log
+
+import (
+	"github.com/alrusov/misc"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// The message text is redacted twice: by the caller's replace of SecuredMessage and by the global rules of the rules
+// file. The caller's replace goes first, so it can whitelist a field by rewriting it out of reach of the global
+// patterns, then the global rules mask what is left. Both are applied to the formatted message before the prefix is
+// added and before the truncation. Drop rules always see the message before any redaction.
+// SecuredMessageEx can change the order, skip the global rules or apply only some of them.
+
+// RedactOpts -- redaction options of SecuredMessageEx
+type RedactOpts struct {
+	GlobalFirst bool     // the global rules are applied before the caller's replace
+	SkipGlobal  bool     // the payload is already sanitized, only the caller's replace is applied
+	Rules       []string // names of the global rules to apply, all of them if empty
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SecuredMessageEx -- add message to the log with securing and the explicit redaction order
+func (f *Facility) SecuredMessageEx(level Level, replace *misc.Replace, opts RedactOpts, message string, params ...any) {
+	f.messageEx(1, level, false, &messageOptions{replace: replace, redact: opts}, message, params...)
+}
+
+// SecuredMessageEx -- add message to the log with securing and the explicit redaction order
+func SecuredMessageEx(level Level, replace *misc.Replace, opts RedactOpts, message string, params ...any) {
+	stdFacility.messageEx(1, level, false, &messageOptions{replace: replace, redact: opts}, message, params...)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// callerRedaction -- the default order with the caller's replace, nil if there is no replace
+func callerRedaction(replace *misc.Replace) *messageOptions {
+	if replace == nil {
+		return nil
+	}
+	return &messageOptions{replace: replace}
+}
+
+// redactMessage -- the message text after the drop rules and both redactions, false if it must be dropped
+func redactMessage(facility string, level Level, msg string, mo *messageOptions) (string, bool) {
+	if mo == nil {
+		return applyRules(facility, level, msg)
+	}
+
+	rs := activeRules.Load()
+	if rs.dropped(facility, level, msg) {
+		return "", false
+	}
+
+	global := func() {
+		if !mo.redact.SkipGlobal {
+			msg = redactRules(rs, msg, mo.redact.Rules)
+		}
+	}
+
+	if mo.redact.GlobalFirst {
+		global()
+	}
+	if mo.replace != nil {
+		msg = Redact(mo.replace, msg)
+	}
+	if !mo.redact.GlobalFirst {
+		global()
+	}
+
+	return msg, true
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,73 @@
+package log
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alrusov/misc"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestRedactOrder(t *testing.T) {
+	for _, mode := range []GroupCommitMode{GroupCommitOff, GroupCommitSync} {
+		console := resetLog(t)
+		SetGroupCommit(mode)
+
+		path := filepath.Join(t.TempDir(), "rules.toml")
+		writeRules(t, path, `
+[[redact]]
+name = "token"
+regexp = '(token=)\w+'
+replacement = "${1}***"
+
+[[redact]]
+name = "user"
+regexp = '(user=)\w+'
+replacement = "${1}<user>"
+`, time.Now())
+		if err := LoadRulesFile(path); err != nil {
+			t.Fatal(err)
+		}
+
+		// Whitelists the token by moving it out of reach of the global rule
+		replace := misc.NewReplace()
+		if err := replace.Add(`(?-U)token=(\w+)`, "tok:${1}"); err != nil {
+			t.Fatal(err)
+		}
+
+		tests := []struct {
+			opts     *RedactOpts
+			expected string
+		}{
+			{nil, "user=<user> tok:abc"},
+			{&RedactOpts{}, "user=<user> tok:abc"},
+			{&RedactOpts{GlobalFirst: true}, "user=<user> token=***"},
+			{&RedactOpts{SkipGlobal: true}, "user=joe tok:abc"},
+			{&RedactOpts{SkipGlobal: true, GlobalFirst: true}, "user=joe tok:abc"},
+			{&RedactOpts{Rules: []string{"token"}}, "user=joe tok:abc"},
+			{&RedactOpts{Rules: []string{"token"}, GlobalFirst: true}, "user=joe token=***"},
+			{&RedactOpts{Rules: []string{"user"}, GlobalFirst: true}, "user=<user> tok:abc"},
+		}
+
+		for i, tc := range tests {
+			console.buf.Reset()
+
+			if tc.opts == nil {
+				SecuredMessage(INFO, replace, "user=%s token=%s", "joe", "abc")
+			} else {
+				SecuredMessageEx(INFO, replace, *tc.opts, "user=%s token=%s", "joe", "abc")
+			}
+
+			if s := console.String(); !strings.HasSuffix(s, " "+tc.expected+"\n") {
+				t.Errorf("[%d.%d] got %q, expected %q", mode, i, s, tc.expected)
+			}
+		}
+
+		SetGroupCommit(GroupCommitOff)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
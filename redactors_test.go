@@ -0,0 +1,165 @@
+package log
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alrusov/misc"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+type redactCase struct {
+	in       string
+	expected string
+}
+
+// Must stay unchanged by any redactor
+var redactUntouched = []string{
+	"",
+	"plain message",
+	"id=123e4567-e89b-12d3-a456-426614174000",
+	"id=12345678-1234-1234-1234-123456789012",
+	"id 41111111-1111-1111-1111-111111111111",
+	"sha256 4111111111111111aa0b2c9d7e6f5a4b3c2d1e0f9a8b7c6d5e4f3a2b1c0d9e8f",
+	"md5 d41d8cd98f00b204e9800998ecf8427e",
+	"at 2024-05-03T12:00:00.123456789Z",
+	"at 2024-05-03 12:00:00.123",
+	"compact 20240503120000123",
+	"unix 1714737600 ms 1714737600123 ns 1714737600123456789",
+	"ip 192.168.100.200:8080",
+	"version v1.2.3 module github.com/alrusov/misc@v1.1.15",
+	"order 411111111111111 of 15 digits",
+	"too long 41111111111111111111",
+	"word4111111111111111",
+	"4111111111111111x",
+	"phone +1 555 123 4567",
+	"password_policy=strong passwords=3 bypass=1",
+	"http://example.com:8080/path",
+	"ratio 1:2 at 12:30",
+	"user@localhost",
+	"git ref refs/heads/main@{1}",
+}
+
+func checkRedact(t *testing.T, name string, r *misc.Replace, cases []redactCase) {
+	t.Helper()
+
+	for _, c := range cases {
+		if s := Redact(r, c.in); s != c.expected {
+			t.Errorf("%s: %q: got %q, expected %q", name, c.in, s, c.expected)
+		}
+	}
+
+	for _, s := range redactUntouched {
+		if got := Redact(r, s); got != s {
+			t.Errorf("%s: %q is changed to %q", name, s, got)
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestRedactPasswords(t *testing.T) {
+	checkRedact(t, "passwords", RedactPasswords(),
+		[]redactCase{
+			{"password=hunter2", "password=***"},
+			{"login user=joe password=hunter2 retry=1", "login user=joe password=*** retry=1"},
+			{"PASSWORD = hunter2", "PASSWORD = ***"},
+			{"db_password: s3cr3t!", "db_password: ***"},
+			{"?user=joe&pwd=abc&x=1", "?user=joe&pwd=***&x=1"},
+			{`passwd="two words" next`, "passwd=*** next"},
+			{"client.secret='a b'", "client.secret=***"},
+			{`{"user":"joe","password":"hun\"ter2","n":1}`, `{"user":"joe","password":"***","n":1}`},
+			{`{"Passphrase" : "x y z"}`, `{"Passphrase" : "***"}`},
+			{"dsn postgres://joe:hunter2@db:5432/app", "dsn postgres://joe:***@db:5432/app"},
+			{"a password=1 b secret=2", "a password=*** b secret=***"},
+		},
+	)
+}
+
+func TestRedactCardNumbers(t *testing.T) {
+	checkRedact(t, "cards", RedactCardNumbers(),
+		[]redactCase{
+			{"card 4111111111111111", "card ****1111"},
+			{"visa13 4222222222222 visa19 4111111111111111003", "visa13 ****2222 visa19 ****1003"},
+			{"mc 5500005555555559, mc2 2223003122003222", "mc ****5559, mc2 ****3222"},
+			{"amex 378282246310005.", "amex ****0005."},
+			{"discover 6011111111111117 jcb 3530111333300000", "discover ****1117 jcb ****0000"},
+			{"diners 30569309025904", "diners ****5904"},
+			{"card=4111 1111 1111 1111;", "card=****1111;"},
+			{"card 5500-0055-5555-5559 ok", "card ****5559 ok"},
+			{"amex 3782 822463 10005", "amex ****0005"},
+			{"amex 3782-822463-10005", "amex ****0005"},
+			{`{"pan":"4111111111111111"}`, `{"pan":"****1111"}`},
+		},
+	)
+
+	// Not the card schemes or the invalid Luhn sum
+	for _, s := range []string{
+		"1234567812345678", "9111111111111111", "0000 0000 0000 0000", "5900000000000000", "2100000000000000",
+		"order 4111111111111112", "order 4000000000000", "id 4123-4567-8901-2345", "ref 5500 0055 5555 5558",
+		"amex 378282246310006", "diners 30569309025905", "visa19 4111111111111111004",
+	} {
+		if got := Redact(RedactCardNumbers(), s); got != s {
+			t.Errorf("%q is changed to %q", s, got)
+		}
+	}
+}
+
+func TestRedactEmails(t *testing.T) {
+	checkRedact(t, "emails", RedactEmails(),
+		[]redactCase{
+			{"from joe@example.com", "from ***@example.com"},
+			{"to <John.Smith+tag@mail.example.co.uk>", "to <***@mail.example.co.uk>"},
+			{"a@b.io,c_d@e-f.org", "***@b.io,***@e-f.org"},
+		},
+	)
+}
+
+func TestCombineReplacers(t *testing.T) {
+	tail := misc.NewReplace()
+	tail.Add(`\*\*\*@`, "<email>@")
+
+	r := CombineReplacers(RedactPasswords(), nil, RedactCardNumbers(), RedactEmails(), tail)
+
+	s := Redact(r, "user=joe@example.com password=hunter2 card=4111111111111111")
+	if expected := "user=<email>@example.com password=*** card=****1111"; s != expected {
+		t.Errorf("got %q, expected %q", s, expected)
+	}
+
+	if s := Redact(CombineReplacers(), "password=x"); s != "password=x" {
+		t.Errorf("empty replacer changes %q", s)
+	}
+
+	console := resetLog(t)
+	SecuredMessage(INFO, r, "pay 5500 0055 5555 5559 by secret=abc")
+	if s := console.String(); !strings.HasSuffix(s, " pay ****5559 by secret=***\n") {
+		t.Errorf("unexpected message %q", s)
+	}
+}
+
+func TestBuiltinRedactRules(t *testing.T) {
+	console := resetLog(t)
+
+	path := filepath.Join(t.TempDir(), "rules.toml")
+	writeRules(t, path, "[[redact]]\nname = \"cards\"\nbuiltin = \"cards\"\n\n[[redact]]\nname = \"mail\"\nbuiltin = \"emails\"\n", time.Now())
+
+	if err := LoadRulesFile(path); err != nil {
+		t.Fatal(err)
+	}
+	defer ResetRules()
+
+	Message(INFO, "joe@example.com paid by 4111-1111-1111-1111 order 4111111111111112")
+	if s := console.String(); !strings.HasSuffix(s, " ***@example.com paid by ****1111 order 4111111111111112\n") {
+		t.Errorf("unexpected message %q", s)
+	}
+
+	writeRules(t, path, "[[redact]]\nname = \"x\"\nbuiltin = \"unknown\"\n", time.Now())
+	if err := LoadRulesFile(path); err == nil || !strings.Contains(err.Error(), `unknown builtin redactor "unknown"`) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,127 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Facilities registered by MustRegisterFacilities are the known ones. In the strict mode GetFacility and NewFacility
+// don't create an unknown facility, the standard one is returned and the name is reported once with WARNING.
+// Without the strict mode unknown facilities are created as usual, CurrentLogLevelOfAll reports them once with WARNING
+// and they can be found by UnregisteredFacilities and CurrentStateOfAll.
+// Nothing is checked until some facility is registered.
+
+var (
+	// ErrUnregisteredFacility -- the facility isn't registered by MustRegisterFacilities
+	ErrUnregisteredFacility = errors.New("unregistered facility")
+
+	registeredFacilities map[string]bool // nil if nothing is registered
+	strictFacilities     = false
+	unregisteredWarned   = map[string]bool{}
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// MustRegisterFacilities -- register and create the facilities, intended for init(). Panics on the standard facility name.
+func MustRegisterFacilities(names ...string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if registeredFacilities == nil {
+		registeredFacilities = map[string]bool{}
+	}
+
+	for _, name := range names {
+		if name == StdFacilityName {
+			panic("log: the standard facility can't be registered")
+		}
+		name = facilityName(name)
+		registeredFacilities[name] = true
+		newFacility(name)
+	}
+}
+
+// SetStrictFacilities -- don't create unregistered facilities
+func SetStrictFacilities(strict bool) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	strictFacilities = strict
+}
+
+// GetFacilityStrict -- the registered facility, ErrUnregisteredFacility for the unknown name
+func GetFacilityStrict(name string) (*Facility, error) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if !facilityKnown(name) {
+		return nil, fmt.Errorf(`%w "%s"`, ErrUnregisteredFacility, name)
+	}
+
+	return newFacility(name), nil
+}
+
+// UnregisteredFacilities -- names of the used facilities which aren't registered, empty if nothing is registered
+func UnregisteredFacilities() []string {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	list := []string{}
+	if registeredFacilities == nil {
+		return list
+	}
+
+	for name := range facilities {
+		if !facilityKnown(name) {
+			list = append(list, name)
+		}
+	}
+	sort.Strings(list)
+	return list
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Must be called under the mutex
+func facilityKnown(name string) bool {
+	return name == StdFacilityName || registeredFacilities[name]
+}
+
+// strictFacility -- the standard facility if the unknown facility can't be created. Must be called under the mutex.
+func strictFacility(name string) *Facility {
+	if !strictFacilities || registeredFacilities == nil || facilityKnown(name) {
+		return nil
+	}
+
+	if !unregisteredWarned[name] {
+		unregisteredWarned[name] = true
+		logger(false, 0, StdFacilityName, WARNING, nil, `Unregistered facility "%s" is replaced by the standard one`, name)
+	}
+
+	return stdFacility
+}
+
+// flagUnregistered -- report the used unregistered facilities. Must be called under the mutex.
+func flagUnregistered() {
+	if strictFacilities || registeredFacilities == nil {
+		return
+	}
+
+	var names []string
+	for name := range facilities {
+		if !facilityKnown(name) && !unregisteredWarned[name] {
+			unregisteredWarned[name] = true
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	for _, name := range names {
+		logger(false, 0, StdFacilityName, WARNING, nil, `Facility "%s" is used but not registered`, name)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
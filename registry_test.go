@@ -0,0 +1,84 @@
+package log
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestStrictFacilities(t *testing.T) {
+	console := resetLog(t)
+
+	MustRegisterFacilities("scheduler", "billing")
+	SetStrictFacilities(true)
+
+	if f := GetFacility("scheduler"); f.Name() != "scheduler" {
+		t.Errorf("got %q", f.Name())
+	}
+
+	for i := 0; i < 2; i++ {
+		if f := GetFacility("shedeuler"); f != StdFacility() {
+			t.Fatalf("got %q", f.Name())
+		}
+		if f := NewFacility("shedeuler"); f != StdFacility() {
+			t.Fatalf("got %q", f.Name())
+		}
+	}
+
+	if _, exists := CurrentLogLevelOfAll()["shedeuler"]; exists {
+		t.Errorf("the unregistered facility is created")
+	}
+	if n := strings.Count(console.String(), `Unregistered facility "shedeuler" is replaced by the standard one`); n != 1 {
+		t.Errorf("the warning is reported %d times:\n%s", n, console)
+	}
+
+	if f, err := GetFacilityStrict("billing"); err != nil || f.Name() != "billing" {
+		t.Errorf("unexpected %v, %v", f, err)
+	}
+	if _, err := GetFacilityStrict("biling"); !errors.Is(err, ErrUnregisteredFacility) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestUnregisteredFacilities(t *testing.T) {
+	console := resetLog(t)
+
+	GetFacility("reg-typo")
+	if list := UnregisteredFacilities(); len(list) != 0 {
+		t.Errorf("unregistered facilities without the registration %q", list)
+	}
+
+	MustRegisterFacilities("reg-ok")
+	GetFacility("reg-ok")
+	GetFacility("reg-typo")
+
+	if f, err := GetFacilityStrict("reg-typo"); err == nil {
+		t.Errorf("got %q", f.Name())
+	}
+
+	list := UnregisteredFacilities()
+	if !slices.Contains(list, "reg-typo") || slices.Contains(list, "reg-ok") || slices.Contains(list, StdFacilityName) {
+		t.Errorf("unexpected list %q", list)
+	}
+
+	state := CurrentStateOfAll()
+	if !state["reg-typo"].Unregistered || state["reg-ok"].Unregistered || state[StdFacilityName].Unregistered {
+		t.Errorf("unexpected state %+v", state)
+	}
+
+	CurrentLogLevelOfAll()
+	CurrentLogLevelOfAll()
+
+	s := console.String()
+	if n := strings.Count(s, `Facility "reg-typo" is used but not registered`); n != 1 {
+		t.Errorf("the typo is reported %d times:\n%s", n, s)
+	}
+	if strings.Contains(s, `"reg-ok" is used`) {
+		t.Errorf("the registered facility is reported:\n%s", s)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,283 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alrusov/misc"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// The retention removes the daily files of the log directory older than MaxAge and the files over MaxFiles, the newest
+// ones are kept. Only the files matching the current name pattern are considered, with and without the compression
+// extension, the current file is never removed. The cleanup is done when the file is opened, in the dry run mode it
+// only logs the intended actions with NOTICE. Off by default.
+// PolicyPreview shows the same decisions without touching anything. The compression is applied to the active file only,
+// so no file is compressed by the policy, the quotas are counted for the lines of the current day.
+
+// RetentionOptions -- retention of the daily files
+type RetentionOptions struct {
+	MaxAge   time.Duration // 0 -- unlimited
+	MaxFiles int           // the current file is counted, 0 -- unlimited
+	DryRun   bool          // only log what would be removed
+}
+
+// PreviewFile -- the daily file found by PolicyPreview
+type PreviewFile struct {
+	Name       string        `json:"name"`
+	Date       string        `json:"date"`
+	Size       int64         `json:"size"`
+	Age        time.Duration `json:"age"`
+	Compressed bool          `json:"compressed,omitempty"`
+	Current    bool          `json:"current,omitempty"`
+}
+
+// PreviewReport -- what the configured policies would do with the log directory
+type PreviewReport struct {
+	Directory   string               `json:"directory"`
+	Retention   RetentionOptions     `json:"retention"`
+	Compression Compression          `json:"compression"`
+	Remove      []PreviewFile        `json:"remove"`
+	Keep        []PreviewFile        `json:"keep"`
+	Quotas      map[string]QuotaInfo `json:"quotas,omitempty"`
+}
+
+// retentionState -- the configuration copied under the mutex
+type retentionState struct {
+	opts     RetentionOptions
+	pattern  string
+	current  string
+	today    string
+	now      time.Time
+	boundary time.Duration
+}
+
+var (
+	// ErrNoLogDirectory -- the log isn't written to the daily files
+	ErrNoLogDirectory = errors.New("log is not written to the daily files")
+
+	retention RetentionOptions
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetRetention -- remove old daily files when the file is opened. The cleanup is done at once if the file is open.
+func SetRetention(opts RetentionOptions) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	retention = opts
+	if file != nil {
+		applyRetention()
+	}
+}
+
+// PolicyPreview -- files which would be removed and kept by the retention, nothing is changed
+func PolicyPreview() (PreviewReport, error) {
+	mutex.Lock()
+	mode := currentMode()
+	report := PreviewReport{
+		Directory:   fileDirectory,
+		Retention:   retention,
+		Compression: compression,
+		Quotas:      quotaUsage(),
+	}
+	st := currentRetention()
+	mutex.Unlock()
+
+	if mode != ModeFile {
+		return report, ErrNoLogDirectory
+	}
+
+	var err error
+	report.Remove, report.Keep, err = st.plan()
+	return report, err
+}
+
+func (r PreviewReport) String() string {
+	b := new(strings.Builder)
+
+	fmt.Fprintf(b, "Directory %s, retention %s, compression %s%s", r.Directory, r.Retention, compressionName(r.Compression), misc.EOS)
+
+	list := func(title string, files []PreviewFile) {
+		fmt.Fprintf(b, "%s: %d files%s", title, len(files), misc.EOS)
+		for _, f := range files {
+			note := ""
+			if f.Current {
+				note = ", current"
+			}
+			fmt.Fprintf(b, "  %s (%d bytes, age %s%s)%s", filepath.Base(f.Name), f.Size, f.Age.Round(time.Minute), note, misc.EOS)
+		}
+	}
+	list("Remove", r.Remove)
+	list("Keep", r.Keep)
+
+	names := make([]string, 0, len(r.Quotas))
+	for name := range r.Quotas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		q := r.Quotas[name]
+		fmt.Fprintf(b, "Quota %s: %d of %d bytes on %s, %d lines dropped%s", name, q.Used, q.Limit, q.Day, q.Dropped, misc.EOS)
+	}
+
+	return b.String()
+}
+
+func (o RetentionOptions) String() string {
+	if o.MaxAge <= 0 && o.MaxFiles <= 0 {
+		return "off"
+	}
+
+	var list []string
+	if o.MaxAge > 0 {
+		list = append(list, "maxAge "+o.MaxAge.String())
+	}
+	if o.MaxFiles > 0 {
+		list = append(list, fmt.Sprintf("maxFiles %d", o.MaxFiles))
+	}
+	if o.DryRun {
+		list = append(list, "dry run")
+	}
+	return strings.Join(list, ", ")
+}
+
+func compressionName(c Compression) string {
+	if c == CompressionNone {
+		return "none"
+	}
+	return string(c)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Must be called under the mutex
+func currentRetention() retentionState {
+	st := retentionState{
+		opts:     retention,
+		pattern:  fileNamePattern,
+		current:  fileName,
+		now:      now(),
+		boundary: time.Duration(rotationBoundary.Load()),
+	}
+	st.today = fileDate(st.now)
+	return st
+}
+
+// applyRetention -- remove the old files or log the intended removal. Must be called under the mutex.
+func applyRetention() {
+	applyRetentionOf(currentRetention())
+}
+
+// applyRetentionOf -- applyRetention for the files of the pattern. Must be called under the mutex.
+func applyRetentionOf(st retentionState) {
+	if retention.MaxAge <= 0 && retention.MaxFiles <= 0 {
+		return
+	}
+
+	remove, _, err := st.plan()
+	if err != nil {
+		logger(false, 0, StdFacilityName, WARNING, nil, "Retention: %s", err)
+		return
+	}
+
+	for _, f := range remove {
+		if retention.DryRun {
+			logger(false, 0, StdFacilityName, NOTICE, nil, "Retention: would remove %s (%d bytes, age %s)", f.Name, f.Size, f.Age.Round(time.Minute))
+			continue
+		}
+
+		if err := os.Remove(f.Name); err != nil {
+			logger(false, 0, StdFacilityName, WARNING, nil, "Retention: %s", err)
+			continue
+		}
+		logger(false, 0, StdFacilityName, NOTICE, nil, "Retention: removed %s (%d bytes, age %s)", f.Name, f.Size, f.Age.Round(time.Minute))
+	}
+}
+
+// plan -- the daily files to remove and to keep, the newest first
+func (st retentionState) plan() (remove []PreviewFile, keep []PreviewFile, err error) {
+	files, err := st.dailyFiles()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	remove = []PreviewFile{}
+	keep = []PreviewFile{}
+
+	for _, f := range files {
+		expired := st.opts.MaxAge > 0 && f.Age > st.opts.MaxAge
+		over := st.opts.MaxFiles > 0 && len(keep) >= st.opts.MaxFiles
+		if !f.Current && (expired || over) {
+			remove = append(remove, f)
+			continue
+		}
+		keep = append(keep, f)
+	}
+
+	return remove, keep, nil
+}
+
+// dailyFiles -- files of the directory matching the pattern, the newest first
+func (st retentionState) dailyFiles() ([]PreviewFile, error) {
+	dir, base := filepath.Split(st.pattern)
+	prefix, rest, found := strings.Cut(base, "%s")
+	if !found {
+		return nil, ErrNoLogDirectory
+	}
+	rest = strings.TrimSuffix(rest, CompressionGzip.extension())
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []PreviewFile{}
+	for _, e := range entries {
+		name := e.Name()
+		if !e.Type().IsRegular() || !strings.HasPrefix(name, prefix) || len(name) < len(prefix)+len(misc.DateFormatRev) {
+			continue
+		}
+
+		date := name[len(prefix) : len(prefix)+len(misc.DateFormatRev)]
+		tail := name[len(prefix)+len(date):]
+		compressed := tail == rest+CompressionGzip.extension()
+		if tail != rest && !compressed {
+			continue
+		}
+
+		day, err := time.ParseInLocation(misc.DateFormatRev, date, st.now.Location())
+		if err != nil {
+			continue
+		}
+
+		fi, err := e.Info()
+		if err != nil {
+			continue
+		}
+
+		path := filepath.Join(dir, name)
+		files = append(files,
+			PreviewFile{
+				Name:       path,
+				Date:       date,
+				Size:       fi.Size(),
+				Age:        st.now.Sub(day.Add(st.boundary)),
+				Compressed: compressed,
+				Current:    date == st.today || path == st.current,
+			},
+		)
+	}
+
+	sort.SliceStable(files, func(i, j int) bool { return files[i].Date > files[j].Date })
+	return files, nil
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
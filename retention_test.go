@@ -0,0 +1,133 @@
+package log
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func previewNames(files []PreviewFile) []string {
+	list := make([]string, len(files))
+	for i, f := range files {
+		list[i] = filepath.Base(f.Name)
+	}
+	return list
+}
+
+func TestPolicyPreview(t *testing.T) {
+	resetLog(t)
+	setFakeClock(time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC))
+
+	if _, err := PolicyPreview(); !errors.Is(err, ErrNoLogDirectory) {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	dir := t.TempDir()
+	for _, name := range []string{"2024-05-02.log", "2024-05-01.log.gz", "2024-04-20.log", "2024-04-01.log", "2024-04-01-api.log", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("12345"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	SetFile(dir, "", false, 0, 0)
+	Message(INFO, "today")
+	GetFacility("preview").SetDailyQuota(1000, QuotaDrop)
+	GetFacility("preview").Message(INFO, "counted")
+
+	all := []string{"2024-05-03.log", "2024-05-02.log", "2024-05-01.log.gz", "2024-04-20.log", "2024-04-01.log"}
+
+	cases := []struct {
+		opts   RetentionOptions
+		remove []string
+	}{
+		{RetentionOptions{}, nil},
+		{RetentionOptions{MaxAge: 7 * 24 * time.Hour}, all[3:]},
+		{RetentionOptions{MaxFiles: 2}, all[2:]},
+		{RetentionOptions{MaxAge: 20 * 24 * time.Hour, MaxFiles: 4}, all[4:]},
+		{RetentionOptions{MaxAge: time.Hour, MaxFiles: 10}, all[1:]},
+	}
+
+	for i, c := range cases {
+		mutex.Lock()
+		retention = c.opts
+		mutex.Unlock()
+
+		report, err := PolicyPreview()
+		if err != nil {
+			t.Fatalf("[%d] %s", i, err)
+		}
+
+		remove := previewNames(report.Remove)
+		keep := previewNames(report.Keep)
+		if !slices.Equal(remove, c.remove) && !(len(remove) == 0 && len(c.remove) == 0) {
+			t.Errorf("[%d] remove %q, expected %q", i, remove, c.remove)
+		}
+		if !slices.Equal(keep, all[:len(all)-len(c.remove)]) {
+			t.Errorf("[%d] keep %q", i, keep)
+		}
+		if !report.Keep[0].Current || report.Keep[0].Size == 0 {
+			t.Errorf("[%d] unexpected current file %+v", i, report.Keep[0])
+		}
+		if q := report.Quotas["preview"]; q.Used == 0 || q.Limit != 1000 {
+			t.Errorf("[%d] unexpected quota %+v", i, q)
+		}
+	}
+
+	report, _ := PolicyPreview()
+	s := report.String()
+	for _, expected := range []string{"retention maxAge 1h0m0s, maxFiles 10", "Remove: 4 files", "2024-05-01.log.gz (5 bytes, age 60h0m0s)", "2024-05-03.log (", ", current)", "Quota preview: "} {
+		if !strings.Contains(s, expected) {
+			t.Errorf("%q isn't found in\n%s", expected, s)
+		}
+	}
+
+	for _, name := range all {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("the preview touched %s", name)
+		}
+	}
+}
+
+func TestRetention(t *testing.T) {
+	console := resetLog(t)
+	setFakeClock(time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC))
+
+	dir := t.TempDir()
+	for _, name := range []string{"2024-05-02.log", "2024-05-01.log", "2024-04-30-api.log"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	SetFile(dir, "", false, 0, 0)
+	Message(INFO, "today")
+
+	SetRetention(RetentionOptions{MaxFiles: 2, DryRun: true})
+	if n := strings.Count(console.String(), "Retention: would remove"); n != 1 {
+		t.Errorf("got %d dry run lines:\n%s", n, console)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "2024-05-01.log")); err != nil {
+		t.Errorf("the dry run removed the file")
+	}
+
+	SetRetention(RetentionOptions{MaxFiles: 2})
+	if _, err := os.Stat(filepath.Join(dir, "2024-05-01.log")); !os.IsNotExist(err) {
+		t.Errorf("the file isn't removed: %v", err)
+	}
+	for _, name := range []string{"2024-05-03.log", "2024-05-02.log", "2024-04-30-api.log"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s is removed", name)
+		}
+	}
+	if !strings.Contains(console.String(), "Retention: removed "+filepath.Join(dir, "2024-05-01.log")) {
+		t.Errorf("the removal isn't logged:\n%s", console)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,108 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// The rotation controller keeps the files paired by the date in step with the log file. The rotation key is the date
+// of the daily log file (with the rotation boundary) of the record being written, when it changes all registered
+// sinks are rotated in the registration order under the same mutex acquisition as the record, so the set of files
+// of a day starts and ends at the same instant. The first record gives the sinks the initial key. The clock stepped
+// back doesn't rotate back. After the rotation the retention of the log file is applied to the files of the sinks too.
+// The timings file is the pre-registered sink.
+
+// RotatingSink -- the file sink rotated together with the log file. The methods are called under the package mutex,
+// so they must not block and must not log.
+type RotatingSink interface {
+	Pattern() string         // the file name pattern with "%s" for the key, used by the retention
+	Rotate(key string) error // switch to the file of the key
+}
+
+type rotatingSink struct {
+	name string
+	sink RotatingSink
+}
+
+var (
+	rotationKey   string
+	rotatingSinks = builtinRotatingSinks()
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// RegisterRotatingSink -- rotate the sink with the log file, the sink with the same name is replaced
+func RegisterRotatingSink(name string, sink RotatingSink) error {
+	if name == "" || sink == nil {
+		return errors.New("rotating sink without name or sink")
+	}
+	if p := sink.Pattern(); p != "" && !strings.Contains(p, "%s") {
+		return fmt.Errorf(`rotating sink "%s" has the pattern without %%s`, name)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	for i, s := range rotatingSinks {
+		if s.name == name {
+			rotatingSinks[i].sink = sink
+			return nil
+		}
+	}
+
+	rotatingSinks = append(rotatingSinks, rotatingSink{name: name, sink: sink})
+	return nil
+}
+
+// UnregisterRotatingSink -- stop rotating the sink
+func UnregisterRotatingSink(name string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	for i, s := range rotatingSinks {
+		if s.name == name {
+			rotatingSinks = append(rotatingSinks[:i:i], rotatingSinks[i+1:]...)
+			return
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func builtinRotatingSinks() []rotatingSink {
+	return []rotatingSink{{name: "timings", sink: timingsSink{}}}
+}
+
+// rotationCheck -- rotate the sinks if the key of the record differs. Must be called under the mutex.
+func rotationCheck(key string) {
+	if key == "" || key <= rotationKey {
+		return
+	}
+
+	initial := rotationKey == ""
+	rotationKey = key
+
+	for _, s := range rotatingSinks {
+		if err := s.sink.Rotate(key); err != nil {
+			lastError = err
+			logger(false, 0, StdFacilityName, WARNING, nil, `Rotation of "%s" to %s: %s`, s.name, key, err)
+		}
+	}
+
+	if initial {
+		return
+	}
+
+	for _, s := range rotatingSinks {
+		if p := s.sink.Pattern(); p != "" {
+			st := currentRetention()
+			st.pattern, st.current = p, ""
+			applyRetentionOf(st)
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,141 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+type fakeRotatingSink struct {
+	mutex   sync.Mutex
+	pattern string
+	keys    []string
+}
+
+func (s *fakeRotatingSink) Pattern() string {
+	return s.pattern
+}
+
+func (s *fakeRotatingSink) Rotate(key string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.keys = append(s.keys, key)
+	return nil
+}
+
+func (s *fakeRotatingSink) take() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	keys := s.keys
+	s.keys = nil
+	return keys
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestRotationController(t *testing.T) {
+	for _, mode := range []GroupCommitMode{GroupCommitOff, GroupCommitSync} {
+		resetLog(t)
+		SetGroupCommit(mode)
+		clock := setFakeClock(time.Date(2024, 5, 3, 23, 59, 58, 0, time.UTC))
+
+		sinks := []*fakeRotatingSink{{}, {}, {}}
+		for i, s := range sinks {
+			if err := RegisterRotatingSink(string(rune('a'+i)), s); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		Message(INFO, "before")
+		for i, s := range sinks {
+			if keys := s.take(); len(keys) != 1 || keys[0] != "2024-05-03" {
+				t.Fatalf("[%d/%d] unexpected initial keys %q", mode, i, keys)
+			}
+		}
+
+		Message(INFO, "same day")
+		clock.Add(3 * time.Second)
+		for i := 0; i < 5; i++ {
+			Message(INFO, "next day %d", i)
+		}
+
+		// The clock stepped back doesn't rotate back
+		clock.Add(-10 * time.Second)
+		Message(INFO, "back")
+
+		for i, s := range sinks {
+			if keys := s.take(); len(keys) != 1 || keys[0] != "2024-05-04" {
+				t.Errorf("[%d/%d] got keys %q", mode, i, keys)
+			}
+		}
+
+		UnregisterRotatingSink("b")
+		clock.Add(25 * time.Hour)
+		Message(INFO, "third day")
+		if a, b := sinks[0].take(), sinks[1].take(); len(a) != 1 || len(b) != 0 {
+			t.Errorf("[%d] got keys %q and %q", mode, a, b)
+		}
+
+		SetGroupCommit(GroupCommitOff)
+	}
+
+	if RegisterRotatingSink("bad", &fakeRotatingSink{pattern: "/tmp/x.log"}) == nil {
+		t.Errorf("the pattern without %%s is accepted")
+	}
+}
+
+func TestRotationBoundaryTogether(t *testing.T) {
+	resetLog(t)
+	clock := setFakeClock(time.Date(2024, 5, 3, 5, 59, 0, 0, time.UTC))
+
+	if err := SetRotationBoundary(6, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	SetFile(dir, "", false, 0, 0)
+	SetTimingsFile(dir, "")
+
+	sink := &fakeRotatingSink{pattern: filepath.Join(dir, "%s.audit")}
+	RegisterRotatingSink("audit", sink)
+
+	// The old files of the sink are removed by the retention of the log file
+	for _, name := range []string{"2024-04-01.audit", "2024-04-02.audit", "2024-04-02.log"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	SetRetention(RetentionOptions{MaxAge: 7 * 24 * time.Hour})
+
+	MessageTime("query", time.Millisecond, "")
+	sink.take()
+
+	clock.Add(2 * time.Minute)
+	Message(INFO, "after the boundary")
+	MessageTime("query", time.Millisecond, "")
+
+	if keys := sink.take(); len(keys) != 1 || keys[0] != "2024-05-03" {
+		t.Errorf("got keys %q", keys)
+	}
+	if !strings.HasSuffix(FileName(), "2024-05-03.log") || !strings.HasSuffix(TimingsFileName(), "2024-05-03.timings.csv") {
+		t.Errorf("unexpected files %s and %s", FileName(), TimingsFileName())
+	}
+	if _, err := os.Stat(filepath.Join(dir, "2024-05-02.timings.csv")); err != nil {
+		t.Errorf("the timings file before the boundary: %s", err)
+	}
+
+	for _, name := range []string{"2024-04-01.audit", "2024-04-02.audit", "2024-04-02.log"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("%s isn't removed", name)
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
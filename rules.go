@@ -0,0 +1,471 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Rules file is a TOML subset:
+//
+//	# comment
+//	[[redact]]
+//	name = "token"
+//	regexp = '(token=)\w+'
+//	replacement = "${1}***"
+//
+//	[[redact]]
+//	name = "cards"
+//	builtin = "cards"         # instead of regexp: "passwords", "cards" or "emails"
+//
+//	[[drop]]
+//	facility = "http*"        # optional, StdFacilityAlias for the std facility
+//	level_from = "DEBUG"      # optional, EMERG by default
+//	level_to = "TRACE4"       # optional, UNKNOWN by default
+//	regexp = "health check"   # optional
+//
+// Values are "basic" or 'literal' strings. Redaction rules are applied to the message text, drop filters skip the message.
+
+// RuleError -- error in the rules file
+type RuleError struct {
+	Line    int
+	Message string
+}
+
+// RulesError -- all errors found in the rules file
+type RulesError struct {
+	Path   string
+	Errors []RuleError
+}
+
+type redactRule struct {
+	name        string
+	re          *regexp.Regexp
+	replacement string
+}
+
+type dropRule struct {
+	facility  string
+	levelFrom Level
+	levelTo   Level
+	re        *regexp.Regexp
+}
+
+type ruleSet struct {
+	redact []redactRule
+	drop   []dropRule
+}
+
+var (
+	activeRules atomic.Pointer[ruleSet]
+
+	rulesWatcherID = int64(0)
+	rulesWatchers  = map[int64]func(){}
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func (e RuleError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+func (e *RulesError) Error() string {
+	list := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		list[i] = err.Error()
+	}
+	return fmt.Sprintf("%s: %s", e.Path, strings.Join(list, "; "))
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// LoadRulesFile -- load redaction rules and drop filters. All or nothing: on error the previous rules stay active.
+func LoadRulesFile(path string) error {
+	_, _, err := loadRulesFile(path)
+	return err
+}
+
+// WatchRulesFile -- load the rules file and reload it every period if the modification time is changed.
+// The changed file is reloaded after its modification time and size stay the same for a period. The file should be
+// replaced atomically (written to a temporary file and renamed), a partially written one may be loaded anyway.
+// The reload result is logged. Call stop to finish watching.
+func WatchRulesFile(path string, period time.Duration) (stop func(), err error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = LoadRulesFile(path); err != nil {
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		mtime := fi.ModTime()
+		var seen os.FileInfo // the changed file is reloaded when its time and size are the same on two ticks
+		ticker := time.NewTicker(period)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			fi, err := os.Stat(path)
+			if err != nil || fi.ModTime().Equal(mtime) {
+				seen = nil
+				continue
+			}
+			if seen == nil || !fi.ModTime().Equal(seen.ModTime()) || fi.Size() != seen.Size() {
+				// The file may be being written, wait for the next tick
+				seen = fi
+				continue
+			}
+			seen = nil
+			mtime = fi.ModTime()
+
+			added, removed, err := loadRulesFile(path)
+			if err != nil {
+				Message(ERR, "Rules file is not reloaded, previous rules are kept: %s", err)
+				continue
+			}
+
+			Message(NOTICE, "Rules file %s is reloaded, added %d %q, removed %d %q", path, len(added), added, len(removed), removed)
+		}
+	}()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	rulesWatcherID++
+	id := rulesWatcherID
+
+	stop = func() {
+		mutex.Lock()
+		delete(rulesWatchers, id)
+		mutex.Unlock()
+
+		select {
+		case <-done:
+		default:
+			close(done)
+		}
+		<-stopped
+	}
+
+	rulesWatchers[id] = stop
+	return stop, nil
+}
+
+// ResetRules -- remove all redaction rules and drop filters
+func ResetRules() {
+	activeRules.Store(nil)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func loadRulesFile(path string) (added []string, removed []string, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+
+	rs, err := parseRules(path, data)
+	if err != nil {
+		return
+	}
+
+	added, removed = rulesDiff(activeRules.Load(), rs)
+	activeRules.Store(rs)
+	return
+}
+
+func parseRules(path string, data []byte) (*ruleSet, error) {
+	rs := &ruleSet{}
+	rErr := &RulesError{Path: path}
+
+	section := ""
+	sectionLine := 0
+	values := map[string]string{}
+	valueLines := map[string]int{}
+
+	bad := func(line int, format string, params ...any) {
+		rErr.Errors = append(rErr.Errors, RuleError{Line: line, Message: fmt.Sprintf(format, params...)})
+	}
+
+	compile := func(name string) *regexp.Regexp {
+		s, exists := values[name]
+		if !exists || s == "" {
+			return nil
+		}
+		re, err := regexp.Compile(s)
+		if err != nil {
+			bad(valueLines[name], "bad %s: %s", name, err)
+		}
+		return re
+	}
+
+	level := func(name string, dflt Level) Level {
+		s, exists := values[name]
+		if !exists {
+			return dflt
+		}
+		l, ok := Str2Level(s)
+		if !ok {
+			bad(valueLines[name], `unknown level "%s"`, s)
+		}
+		return l
+	}
+
+	closeSection := func() {
+		switch section {
+		case "redact":
+			name := values["name"]
+			if name == "" {
+				bad(sectionLine, "redaction rule without name")
+			}
+
+			if builtin, exists := values["builtin"]; exists {
+				patterns, ok := builtinRedactors[builtin]
+				if !ok {
+					bad(valueLines["builtin"], `unknown builtin redactor "%s"`, builtin)
+				}
+				for _, p := range patterns {
+					rs.redact = append(rs.redact, redactRule{name: name, re: regexp.MustCompile(`(?i)` + p.re), replacement: p.replacement})
+				}
+				break
+			}
+
+			r := redactRule{name: name, re: compile("regexp"), replacement: values["replacement"]}
+			if _, exists := values["regexp"]; !exists {
+				bad(sectionLine, `redaction rule "%s" without regexp`, r.name)
+			}
+			if r.re != nil {
+				rs.redact = append(rs.redact, r)
+			}
+
+		case "drop":
+			rs.drop = append(rs.drop,
+				dropRule{
+					facility:  values["facility"],
+					levelFrom: level("level_from", EMERG),
+					levelTo:   level("level_to", UNKNOWN),
+					re:        compile("regexp"),
+				},
+			)
+		}
+
+		values = map[string]string{}
+		valueLines = map[string]int{}
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	n := 0
+	for scanner.Scan() {
+		n++
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[[") {
+			name, ok := strings.CutSuffix(line[2:], "]]")
+			name = strings.TrimSpace(name)
+			if !ok || (name != "redact" && name != "drop") {
+				bad(n, "unknown section %s", line)
+				name = ""
+			}
+			closeSection()
+			section = name
+			sectionLine = n
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			bad(n, "key = value expected")
+			continue
+		}
+		key = strings.TrimSpace(key)
+
+		value, err := rulesValue(strings.TrimSpace(value))
+		if err != nil {
+			bad(n, "%s: %s", key, err)
+			continue
+		}
+
+		if section == "" {
+			bad(n, "%s outside of the section", key)
+			continue
+		}
+
+		values[key] = value
+		valueLines[key] = n
+	}
+	closeSection()
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(rErr.Errors) > 0 {
+		slices.SortStableFunc(rErr.Errors, func(a, b RuleError) int { return a.Line - b.Line })
+		return nil, rErr
+	}
+
+	return rs, nil
+}
+
+// rulesValue -- "basic" or 'literal' string with the optional trailing comment
+func rulesValue(s string) (string, error) {
+	if s == "" {
+		return "", errors.New("empty value")
+	}
+
+	switch s[0] {
+	case '\'':
+		end := strings.IndexByte(s[1:], '\'')
+		if end < 0 || !rulesComment(s[end+2:]) {
+			return "", errors.New("bad literal string")
+		}
+		return s[1 : end+1], nil
+
+	case '"':
+		for i := 1; i < len(s); i++ {
+			switch s[i] {
+			case '\\':
+				i++
+			case '"':
+				if !rulesComment(s[i+1:]) {
+					return "", errors.New("bad string")
+				}
+				return strconv.Unquote(s[:i+1])
+			}
+		}
+		return "", errors.New("unterminated string")
+	}
+
+	return "", errors.New("string expected")
+}
+
+func rulesComment(s string) bool {
+	s = strings.TrimSpace(s)
+	return s == "" || s[0] == '#'
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func (r redactRule) key() string {
+	return "redact:" + r.name
+}
+
+func (r dropRule) key() string {
+	re := ""
+	if r.re != nil {
+		re = r.re.String()
+	}
+	return fmt.Sprintf("drop:%s/%s-%s/%s", r.facility, levels[r.levelFrom].name, levels[r.levelTo].name, re)
+}
+
+func (rs *ruleSet) keys() (list []string) {
+	if rs == nil {
+		return
+	}
+	for _, r := range rs.redact {
+		// The builtin redactor is a few rules of one name
+		if k := r.key(); !slices.Contains(list, k) {
+			list = append(list, k)
+		}
+	}
+	for _, r := range rs.drop {
+		list = append(list, r.key())
+	}
+	return
+}
+
+func rulesDiff(old *ruleSet, new *ruleSet) (added []string, removed []string) {
+	oldKeys := old.keys()
+	newKeys := new.keys()
+
+	for _, k := range newKeys {
+		if !slices.Contains(oldKeys, k) {
+			added = append(added, k)
+		}
+	}
+	for _, k := range oldKeys {
+		if !slices.Contains(newKeys, k) {
+			removed = append(removed, k)
+		}
+	}
+	return
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// applyRules -- the message text after the redaction, false if it must be dropped
+func applyRules(facility string, level Level, msg string) (string, bool) {
+	rs := activeRules.Load()
+	if rs.dropped(facility, level, msg) {
+		return "", false
+	}
+
+	return redactRules(rs, msg, nil), true
+}
+
+// dropped -- is the message skipped by the drop rules
+func (rs *ruleSet) dropped(facility string, level Level, msg string) bool {
+	if rs == nil {
+		return false
+	}
+
+	for _, r := range rs.drop {
+		if r.facility != "" && !matchFacility([]string{r.facility}, facility) {
+			continue
+		}
+		if level.rank() < r.levelFrom.rank() || level.rank() > r.levelTo.rank() {
+			continue
+		}
+		if r.re != nil && !r.re.MatchString(msg) {
+			continue
+		}
+		return true
+	}
+
+	return false
+}
+
+// redactRules -- the message with the redact rules applied, no drop rules. Only the named rules if names isn't empty.
+func redactRules(rs *ruleSet, msg string, names []string) string {
+	if rs == nil {
+		return msg
+	}
+
+	for _, r := range rs.redact {
+		if len(names) > 0 && !slices.Contains(names, r.name) {
+			continue
+		}
+		msg = r.re.ReplaceAllString(msg, r.replacement)
+	}
+
+	// The builtin card rules mark the candidates
+	return maskCards(msg)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,168 @@
+package log
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+const testRules = `
+# secrets
+[[redact]]
+name = "token"
+regexp = '(token=)\w+'
+replacement = "${1}***"
+
+[[drop]]
+facility = "http*"
+level_from = "DEBUG"
+regexp = "health" # probes
+`
+
+func writeRules(t *testing.T, path string, data string, mtime time.Time) {
+	t.Helper()
+
+	// Atomic replacement, the watcher must not see the file partially written
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(tmp, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func waitLog(t *testing.T, w *captureWriter, s string) {
+	t.Helper()
+
+	for i := 0; i < 200; i++ {
+		if strings.Contains(w.String(), s) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("%q is not logged:\n%s", s, w.String())
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestLoadRulesFile(t *testing.T) {
+	w := resetLog(t)
+
+	path := filepath.Join(t.TempDir(), "rules.toml")
+	writeRules(t, path, testRules, time.Now())
+
+	if err := LoadRulesFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	http := GetFacility("http/api")
+	http.Message(DEBUG, "health check")
+	http.Message(INFO, "health check failed")
+	http.Message(DEBUG, "GET /?token=abc123&x=1")
+	Message(DEBUG, "health of std")
+
+	s := w.String()
+	for _, expected := range []string{"<http/api> health check failed", "<http/api> GET /?token=***&x=1", " health of std"} {
+		if !strings.Contains(s, expected) {
+			t.Errorf("%q not found in\n%s", expected, s)
+		}
+	}
+	if strings.Contains(s, "health check\n") || strings.Contains(s, "abc123") {
+		t.Errorf("rules are not applied:\n%s", s)
+	}
+	if n := GetStats().Dropped; n != 1 {
+		t.Errorf("got %d dropped, expected 1", n)
+	}
+}
+
+func TestLoadRulesFileErrors(t *testing.T) {
+	w := resetLog(t)
+
+	path := filepath.Join(t.TempDir(), "rules.toml")
+	writeRules(t, path, testRules, time.Now())
+	if err := LoadRulesFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	writeRules(t, path, `
+[[redact]]
+name = "broken"
+regexp = "(unclosed"
+
+[[drop]]
+level_to = "LOUD"
+color = red
+`, time.Now())
+
+	err := LoadRulesFile(path)
+
+	var rErr *RulesError
+	if !errors.As(err, &rErr) {
+		t.Fatalf("got %v, expected RulesError", err)
+	}
+
+	lines := []int{}
+	for _, e := range rErr.Errors {
+		lines = append(lines, e.Line)
+	}
+	if len(lines) != 3 || lines[0] != 4 || lines[1] != 7 || lines[2] != 8 {
+		t.Errorf("unexpected errors %v", err)
+	}
+
+	// Previous rules are kept
+	Message(INFO, "token=secret")
+	if s := w.String(); !strings.Contains(s, "token=***") {
+		t.Errorf("previous rules are lost:\n%s", s)
+	}
+}
+
+func TestWatchRulesFile(t *testing.T) {
+	w := resetLog(t)
+
+	path := filepath.Join(t.TempDir(), "rules.toml")
+	mtime := time.Now().Add(-time.Hour)
+	writeRules(t, path, testRules, mtime)
+
+	stop, err := WatchRulesFile(path, 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+
+	Message(INFO, "password=qwerty")
+	if !strings.Contains(w.String(), "password=qwerty") {
+		t.Fatal("password is redacted before the rule is added")
+	}
+
+	mtime = mtime.Add(time.Minute)
+	writeRules(t, path, `
+[[redact]]
+name = "password"
+regexp = '(password=)\w+'
+replacement = "${1}***"
+`, mtime)
+
+	waitLog(t, w, `is reloaded, added 1 ["redact:password"], removed 2 ["redact:token" "drop:http*/DEBUG-UNKNOWN/health"]`)
+
+	Message(INFO, "password=qwerty token=abc")
+	waitLog(t, w, "password=*** token=abc")
+
+	// Broken reload keeps the previous rules
+	mtime = mtime.Add(time.Minute)
+	writeRules(t, path, "[[redact]]\nregexp = \"(\"\n", mtime)
+	waitLog(t, w, "Rules file is not reloaded, previous rules are kept")
+
+	Message(INFO, "password=12345")
+	waitLog(t, w, "password=***\n")
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,209 @@
+package log
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// With GODEBUG=gctrace=1 (scavtrace=1) the runtime writes its trace lines to fd 2. When the stderr is intercepted and
+// the parsing is on, these lines are logged to the "runtime" facility with the TIME level as key-value fields instead
+// of the CRIT messages, so they can be filtered and shipped like other records. The line of the known kind which
+// doesn't match the expected format (the format changes between Go versions) is logged as is with raw=true.
+// Off by default.
+
+// RuntimeFacilityName -- the facility of the runtime trace lines
+const RuntimeFacilityName = "runtime"
+
+// runtimeTrace -- the parsed trace line
+type runtimeTrace struct {
+	kind string // gc, scav, forced
+	raw  string // the unparsed line
+
+	cycle        int
+	atSec        float64 // since the start of the program
+	cpuPercent   int     // the share of CPU used by GC since the start
+	pauseMs      float64 // the stop-the-world phases
+	concurrentMs float64 // the concurrent mark phase
+	assistMs     float64 // the CPU of the mark phase by the kinds
+	backgroundMs float64
+	idleMs       float64
+	heapBeforeMB int
+	heapAfterMB  int
+	heapLiveMB   int
+	heapGoalMB   int
+	procs        int
+	forced       bool
+
+	workKiB  int
+	eagerKiB int
+	totalKiB int
+	utilPct  int
+}
+
+var (
+	runtimeTraceParsing = false
+
+	// gc # @#s #%: #+#+# ms clock, #+#/#/#+# ms cpu, #->#-># MB, # MB goal, [# MB stacks, # MB globals, ]# P[ (forced)]
+	gcTraceRE = regexp.MustCompile(`^gc (\d+) @([\d.]+)s (\d+)%: ([\d.]+)\+([\d.]+)\+([\d.]+) ms clock, ` +
+		`[\d.]+\+([\d.]+)/([\d.]+)/([\d.]+)\+[\d.]+ ms cpu, (\d+)->(\d+)->(\d+) MB, (\d+) MB goal, (?:.*, )?(\d+) P( \(forced\))?$`)
+
+	scavWorkRE  = regexp.MustCompile(`(\d+) KiB work(?:,| \(bg\))`)
+	scavEagerRE = regexp.MustCompile(`(\d+) KiB work \(eager\)`)
+	scavTotalRE = regexp.MustCompile(`(\d+) KiB (?:total|now)`)
+	scavUtilRE  = regexp.MustCompile(`(\d+)% util`)
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetRuntimeTraceParsing -- log the intercepted runtime trace lines to the "runtime" facility as TIME messages
+func SetRuntimeTraceParsing(enabled bool) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	runtimeTraceParsing = enabled
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// runtimeTraceEmit -- log the runtime trace line, false if the line isn't the trace
+func runtimeTraceEmit(text string) bool {
+	mutex.Lock()
+	on := runtimeTraceParsing
+	mutex.Unlock()
+
+	if !on {
+		return false
+	}
+
+	tr, ok := parseRuntimeTrace(text)
+	if !ok {
+		return false
+	}
+
+	GetFacility(RuntimeFacilityName).MessageEx(0, TIME, nil, "%s", RenderKV(tr.pairs()))
+	return true
+}
+
+// parseRuntimeTrace -- the line of the known kind, raw is set if its format isn't recognized
+func parseRuntimeTrace(s string) (tr runtimeTrace, ok bool) {
+	switch {
+	case s == "GC forced":
+		tr.kind = "forced"
+
+	case strings.HasPrefix(s, "gc "):
+		tr.kind = "gc"
+		if !tr.parseGC(s) {
+			tr.raw = s
+		}
+
+	case strings.HasPrefix(s, "scav ") || strings.HasPrefix(s, "scvg"):
+		tr.kind = "scav"
+		if !tr.parseScav(s) {
+			tr.raw = s
+		}
+
+	default:
+		return tr, false
+	}
+
+	return tr, true
+}
+
+func (tr *runtimeTrace) parseGC(s string) bool {
+	m := gcTraceRE.FindStringSubmatch(s)
+	if m == nil {
+		return false
+	}
+
+	num := func(i int) int {
+		n, _ := strconv.Atoi(m[i])
+		return n
+	}
+	float := func(i int) float64 {
+		f, _ := strconv.ParseFloat(m[i], 64)
+		return f
+	}
+
+	tr.cycle = num(1)
+	tr.atSec = float(2)
+	tr.cpuPercent = num(3)
+	tr.pauseMs = roundMs(float(4) + float(6))
+	tr.concurrentMs = float(5)
+	tr.assistMs = float(7)
+	tr.backgroundMs = float(8)
+	tr.idleMs = float(9)
+	tr.heapBeforeMB = num(10)
+	tr.heapAfterMB = num(11)
+	tr.heapLiveMB = num(12)
+	tr.heapGoalMB = num(13)
+	tr.procs = num(14)
+	tr.forced = m[15] != ""
+	return true
+}
+
+func (tr *runtimeTrace) parseScav(s string) bool {
+	find := func(re *regexp.Regexp, v *int) bool {
+		m := re.FindStringSubmatch(s)
+		if m == nil {
+			return false
+		}
+		*v, _ = strconv.Atoi(m[1])
+		return true
+	}
+
+	if !find(scavWorkRE, &tr.workKiB) || !find(scavUtilRE, &tr.utilPct) {
+		return false
+	}
+	find(scavEagerRE, &tr.eagerKiB)
+	find(scavTotalRE, &tr.totalKiB)
+	return true
+}
+
+// pairs -- the fields of the message
+func (tr *runtimeTrace) pairs() []KVPair {
+	p := []KVPair{{"kind", tr.kind}}
+
+	switch {
+	case tr.raw != "":
+		p = append(p, KVPair{"raw", true}, KVPair{"line", tr.raw})
+
+	case tr.kind == "gc":
+		p = append(p,
+			KVPair{"cycle", tr.cycle},
+			KVPair{"at_s", tr.atSec},
+			KVPair{"cpu_pct", tr.cpuPercent},
+			KVPair{"pause_ms", tr.pauseMs},
+			KVPair{"concurrent_ms", tr.concurrentMs},
+			KVPair{"assist_ms", tr.assistMs},
+			KVPair{"background_ms", tr.backgroundMs},
+			KVPair{"idle_ms", tr.idleMs},
+			KVPair{"heap_before_mb", tr.heapBeforeMB},
+			KVPair{"heap_after_mb", tr.heapAfterMB},
+			KVPair{"heap_live_mb", tr.heapLiveMB},
+			KVPair{"heap_goal_mb", tr.heapGoalMB},
+			KVPair{"procs", tr.procs},
+			KVPair{"forced", tr.forced},
+		)
+
+	case tr.kind == "scav":
+		p = append(p,
+			KVPair{"work_kib", tr.workKiB},
+			KVPair{"eager_kib", tr.eagerKiB},
+			KVPair{"total_kib", tr.totalKiB},
+			KVPair{"util_pct", tr.utilPct},
+		)
+	}
+
+	return p
+}
+
+// roundMs -- the sum of the milliseconds without the float noise
+func roundMs(ms float64) float64 {
+	f, _ := strconv.ParseFloat(strconv.FormatFloat(ms, 'f', 3, 64), 64)
+	return f
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
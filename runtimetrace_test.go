@@ -0,0 +1,123 @@
+package log
+
+import (
+	"strings"
+	"testing"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestParseRuntimeTrace(t *testing.T) {
+	type testCase struct {
+		line     string
+		expected runtimeTrace
+	}
+
+	cases := []testCase{
+		// Go 1.16
+		{
+			"gc 1 @0.004s 2%: 0.011+0.36+0.003 ms clock, 0.090+0.20/0.34/0.13+0.025 ms cpu, 4->4->0 MB, 5 MB goal, 8 P",
+			runtimeTrace{kind: "gc", cycle: 1, atSec: 0.004, cpuPercent: 2, pauseMs: 0.014, concurrentMs: 0.36,
+				assistMs: 0.20, backgroundMs: 0.34, idleMs: 0.13,
+				heapBeforeMB: 4, heapAfterMB: 4, heapLiveMB: 0, heapGoalMB: 5, procs: 8},
+		},
+		// Go 1.21 with the stacks and globals
+		{
+			"gc 12 @3.051s 1%: 0.020+1.5+0.018 ms clock, 0.16+0.42/2.1/0.90+0.14 ms cpu, 30->41->12 MB, 44 MB goal, " +
+				"0 MB stacks, 1 MB globals, 16 P (forced)",
+			runtimeTrace{kind: "gc", cycle: 12, atSec: 3.051, cpuPercent: 1, pauseMs: 0.038, concurrentMs: 1.5,
+				assistMs: 0.42, backgroundMs: 2.1, idleMs: 0.90,
+				heapBeforeMB: 30, heapAfterMB: 41, heapLiveMB: 12, heapGoalMB: 44, procs: 16, forced: true},
+		},
+		// Go 1.17
+		{
+			"scav 16 KiB work, 64 KiB total, 99% util",
+			runtimeTrace{kind: "scav", workKiB: 16, totalKiB: 64, utilPct: 99},
+		},
+		// Go 1.21
+		{
+			"scav 64 KiB work (bg), 8 KiB work (eager), 2280 KiB now, 100% util",
+			runtimeTrace{kind: "scav", workKiB: 64, eagerKiB: 8, totalKiB: 2280, utilPct: 100},
+		},
+		{
+			"GC forced",
+			runtimeTrace{kind: "forced"},
+		},
+		// Unknown formats are passed as is
+		{
+			"gc 4 @1.2s 3%: something new",
+			runtimeTrace{kind: "gc", raw: "gc 4 @1.2s 3%: something new"},
+		},
+		{
+			"scvg0: inuse: 3, idle: 0, sys: 3, released: 0, consumed: 3 (MB)",
+			runtimeTrace{kind: "scav", raw: "scvg0: inuse: 3, idle: 0, sys: 3, released: 0, consumed: 3 (MB)"},
+		},
+	}
+
+	for i, c := range cases {
+		tr, ok := parseRuntimeTrace(c.line)
+		if !ok {
+			t.Errorf("[%d] %q is not recognized", i, c.line)
+			continue
+		}
+		if tr != c.expected {
+			t.Errorf("[%d] got\n%+v\nexpected\n%+v", i, tr, c.expected)
+		}
+	}
+
+	for _, s := range []string{"plain", "gcc failed", "scavenger"} {
+		if _, ok := parseRuntimeTrace(s); ok {
+			t.Errorf("%q is recognized", s)
+		}
+	}
+}
+
+func TestRuntimeTraceEmit(t *testing.T) {
+	console := resetLog(t)
+
+	SetLogLevel("DEBUG", FuncNameModeNone)
+	console.buf.Reset()
+
+	const line = "gc 1 @0.004s 2%: 0.011+0.36+0.003 ms clock, 0.090+0.20/0.34/0.13+0.025 ms cpu, 4->4->0 MB, 5 MB goal, 8 P"
+
+	// Off by default
+	stderrEmit(line, false)
+	if s := console.String(); !strings.Contains(s, " CR ") || !strings.HasSuffix(s, "[stderr] "+line+"\n") {
+		t.Fatalf("unexpected output %q", s)
+	}
+
+	SetRuntimeTraceParsing(true)
+	console.buf.Reset()
+
+	stderrEmit(line, false)
+	stderrEmit("gc 2 changed format", false)
+	stderrEmit("some error", false)
+
+	lines := console.Lines()
+	expected := []string{
+		" TM .* <runtime> kind=gc cycle=1 at_s=0.004 cpu_pct=2 pause_ms=0.014 concurrent_ms=0.36 assist_ms=0.2 background_ms=0.34 " +
+			"idle_ms=0.13 heap_before_mb=4 heap_after_mb=4 heap_live_mb=0 heap_goal_mb=5 procs=8 forced=false",
+		` TM .* <runtime> kind=gc raw=true line="gc 2 changed format"`,
+		" CR .* [stderr] some error",
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("got %d lines, expected %d\n%s", len(lines), len(expected), console.String())
+	}
+	for i, e := range expected {
+		before, after, _ := strings.Cut(e, ".*")
+		if !strings.Contains(lines[i], before) || !strings.HasSuffix(lines[i], after) {
+			t.Errorf("[%d] unexpected line %q", i, lines[i])
+		}
+	}
+
+	// The runtime facility is filtered like any other
+	GetFacility(RuntimeFacilityName).SetLogLevel("INFO", FuncNameModeNone)
+	console.buf.Reset()
+
+	stderrEmit(line, false)
+	if s := console.String(); strings.Contains(s, "kind=gc") {
+		t.Errorf("the filtered trace is logged %q", s)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
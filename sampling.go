@@ -0,0 +1,143 @@
+package log
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// The sampling keeps 1 of n messages of the facility with the sampled level and less severe ones, the forced messages
+// aren't sampled. ShouldLog makes the decision in advance, so the caller does the expensive side work (a dump,
+// an upload) only for the messages which will be written: the token of the positive decision writes the message by
+// MessageWithToken without checking the level and the sampling again. The token is single-use and expires, so it can't
+// be kept to bypass the sampling; the used, expired or foreign token is reported with WARNING and with the error,
+// the message isn't written. The storm protection still applies. Off by default.
+
+// LogToken -- the positive decision of ShouldLog, the zero value is invalid
+type LogToken struct {
+	t *logToken
+}
+
+type logToken struct {
+	f       *Facility
+	level   Level
+	expires time.Time
+	used    atomic.Bool
+}
+
+// samplingState -- 1 of n messages of the level and less severe ones
+type samplingState struct {
+	level atomic.Int32
+	n     atomic.Int64 // 0 if off
+	seq   atomic.Int64
+}
+
+const (
+	// DefaultLogTokenTTL -- lifetime of the token
+	DefaultLogTokenTTL = 5 * time.Second
+)
+
+var (
+	// ErrLogTokenInvalid -- the zero token or the token of another facility
+	ErrLogTokenInvalid = errors.New("invalid log token")
+	// ErrLogTokenUsed -- the token is already used
+	ErrLogTokenUsed = errors.New("log token is already used")
+	// ErrLogTokenExpired -- the token isn't used in time
+	ErrLogTokenExpired = errors.New("log token is expired")
+
+	logTokenTTL atomic.Int64
+)
+
+func init() {
+	logTokenTTL.Store(int64(DefaultLogTokenTTL))
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetSampling -- keep 1 of n messages of the level and less severe ones, n < 2 switches the sampling off
+func (f *Facility) SetSampling(level Level, n int) {
+	if n < 2 {
+		f.sampling.n.Store(0)
+		return
+	}
+
+	f.sampling.level.Store(int32(level))
+	f.sampling.seq.Store(0)
+	f.sampling.n.Store(int64(n))
+}
+
+// SetLogTokenTTL -- lifetime of the tokens of ShouldLog, DefaultLogTokenTTL if not positive
+func SetLogTokenTTL(ttl time.Duration) {
+	if ttl <= 0 {
+		ttl = DefaultLogTokenTTL
+	}
+	logTokenTTL.Store(int64(ttl))
+}
+
+// ShouldLog -- ShouldLog of the std facility
+func ShouldLog(level Level) (ok bool, token LogToken) {
+	return stdFacility.shouldLog(1, level)
+}
+
+// ShouldLog -- will the message of the level be written, the token writes it by MessageWithToken.
+// The positive decision consumes the sampling slot of the message.
+func (f *Facility) ShouldLog(level Level) (ok bool, token LogToken) {
+	return f.shouldLog(1, level)
+}
+
+// MessageWithToken -- write the message with the decision of ShouldLog of this facility
+func (f *Facility) MessageWithToken(token LogToken, message string, params ...any) error {
+	t := token.t
+
+	var err error
+	switch {
+	case t == nil || t.f != f:
+		err = ErrLogTokenInvalid
+	case !t.used.CompareAndSwap(false, true):
+		err = ErrLogTokenUsed
+	case now().After(t.expires):
+		err = ErrLogTokenExpired
+	}
+
+	if err != nil {
+		f.messageEx(1, WARNING, true, nil, "%s, the message %q is dropped", err, message)
+		return err
+	}
+
+	f.messageEx(1, t.level, true, nil, message, params...)
+	return nil
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func (f *Facility) shouldLog(shift int, level Level) (ok bool, token LogToken) {
+	if f.disabled.Load() {
+		return
+	}
+
+	level = checkLevel(shift+1, f, level)
+	if !level.passes(f.token.Load()) || f.sampledOut(level) {
+		return
+	}
+
+	t := &logToken{
+		f:       f,
+		level:   level,
+		expires: now().Add(time.Duration(logTokenTTL.Load())),
+	}
+	return true, LogToken{t: t}
+}
+
+// sampledOut -- is the message dropped by the sampling
+func (f *Facility) sampledOut(level Level) bool {
+	n := f.sampling.n.Load()
+	if n == 0 || !Level(f.sampling.level.Load()).passes(level) {
+		return false
+	}
+
+	return (f.sampling.seq.Add(1)-1)%n != 0
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,152 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestShouldLogSampling(t *testing.T) {
+	console := resetLog(t)
+	SetLogLevel("TRACE4", FuncNameModeNone)
+
+	f := NewFacility("sampled")
+	f.SetSampling(TRACE1, 10)
+	console.buf.Reset()
+
+	var expected []string
+
+	for i := 0; i < 100; i++ {
+		ok, token := f.ShouldLog(TRACE2)
+		if !ok {
+			continue
+		}
+
+		// The expensive work is done for the written lines only
+		msg := fmt.Sprintf("dump %d", i)
+		expected = append(expected, msg)
+
+		if err := f.MessageWithToken(token, "%s", msg); err != nil {
+			t.Fatalf("[%d] %v", i, err)
+		}
+	}
+
+	if len(expected) != 10 {
+		t.Errorf("got %d positive decisions, expected 10", len(expected))
+	}
+
+	lines := console.Lines()
+	if len(lines) != len(expected) {
+		t.Fatalf("got %d lines, expected %d\n%s", len(lines), len(expected), console.String())
+	}
+	for i, e := range expected {
+		if !strings.Contains(lines[i], "] T2 ") || !strings.HasSuffix(lines[i], "<sampled> "+e) {
+			t.Errorf("[%d] got %q, expected %q", i, lines[i], e)
+		}
+	}
+
+	// The more severe messages aren't sampled, the tokens don't pass the level
+	f.SetLogLevel("DEBUG", FuncNameModeNone)
+	console.buf.Reset()
+
+	if ok, _ := f.ShouldLog(TRACE1); ok {
+		t.Error("TRACE1 passes the DEBUG level")
+	}
+	for i := 0; i < 3; i++ {
+		if ok, _ := f.ShouldLog(INFO); !ok {
+			t.Error("INFO is sampled")
+		}
+		f.Message(DEBUG, "debug %d", i)
+	}
+	if n := len(console.Lines()); n != 3 {
+		t.Errorf("got %d debug lines, expected 3\n%s", n, console.String())
+	}
+}
+
+func TestShouldLogTokenMisuse(t *testing.T) {
+	console := resetLog(t)
+	clock := setFakeClock(time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC))
+	SetLogLevel("DEBUG", FuncNameModeNone)
+
+	f := NewFacility("tok")
+	other := NewFacility("other")
+	console.buf.Reset()
+
+	ok, token := f.ShouldLog(INFO)
+	if !ok {
+		t.Fatal("INFO isn't logged")
+	}
+
+	if err := f.MessageWithToken(token, "first"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.MessageWithToken(token, "second"); !errors.Is(err, ErrLogTokenUsed) {
+		t.Errorf("the reuse isn't reported: %v", err)
+	}
+
+	if err := f.MessageWithToken(LogToken{}, "zero"); !errors.Is(err, ErrLogTokenInvalid) {
+		t.Errorf("the zero token isn't reported: %v", err)
+	}
+
+	_, token = other.ShouldLog(INFO)
+	if err := f.MessageWithToken(token, "foreign"); !errors.Is(err, ErrLogTokenInvalid) {
+		t.Errorf("the foreign token isn't reported: %v", err)
+	}
+
+	SetLogTokenTTL(time.Second)
+	_, token = f.ShouldLog(INFO)
+	clock.Add(2 * time.Second)
+	if err := f.MessageWithToken(token, "hoarded"); !errors.Is(err, ErrLogTokenExpired) {
+		t.Errorf("the expired token isn't reported: %v", err)
+	}
+
+	expected := []string{
+		"IN 2024-05-03 12:00:00.000 <tok> first",
+		`WA 2024-05-03 12:00:00.000 <tok> log token is already used, the message "second" is dropped`,
+		`WA 2024-05-03 12:00:00.000 <tok> invalid log token, the message "zero" is dropped`,
+		`WA 2024-05-03 12:00:00.000 <tok> invalid log token, the message "foreign" is dropped`,
+		`WA 2024-05-03 12:00:02.000 <tok> log token is expired, the message "hoarded" is dropped`,
+	}
+
+	lines := console.Lines()
+	if len(lines) != len(expected) {
+		t.Fatalf("got %d lines, expected %d\n%s", len(lines), len(expected), console.String())
+	}
+	for i, e := range expected {
+		if !strings.HasSuffix(lines[i], e) {
+			t.Errorf("[%d] got %q, expected %q", i, lines[i], e)
+		}
+	}
+}
+
+func TestSamplingTemplate(t *testing.T) {
+	console := resetLog(t)
+	SetLogLevel("TRACE4", FuncNameModeNone)
+
+	f := NewFacility("sampled")
+	f.SetSampling(TRACE1, 10)
+	console.buf.Reset()
+
+	tm := f.Template(TRACE2, "template %d")
+	for i := 0; i < 100; i++ {
+		tm.Log(i)
+	}
+	for i := 0; i < 100; i++ {
+		f.Message(TRACE2, "message %d", i)
+	}
+
+	// The template is sampled as the message
+	s := console.String()
+	if n := len(console.Lines()); n != 20 {
+		t.Errorf("got %d lines, expected 20\n%s", n, s)
+	}
+	if strings.Count(s, "template ") != 10 || strings.Count(s, "message ") != 10 {
+		t.Errorf("unexpected lines\n%s", s)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,158 @@
+package log
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Scope fields belong to the goroutine which pushed them and are appended to its messages as key=value pairs,
+// the inner scope wins for the repeated key. Goroutines started inside the scope don't get it, they can inherit
+// the scope of the parent by the token. The goroutine is identified only while some scope exists.
+
+// ScopeToken -- the scope fields of the goroutine to be inherited by another one
+type ScopeToken struct {
+	layers [][]any
+}
+
+type scopeFrame struct {
+	id     uint64
+	layers [][]any // the inner layer first
+}
+
+var (
+	scopeMutex  sync.Mutex
+	scopes      = map[uint64][]*scopeFrame{}
+	scopeFrames atomic.Int64
+	scopeSeq    atomic.Uint64
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// PushScope -- add the fields to the messages of the current goroutine until the returned pop function is called.
+// Popping not the innermost scope pops the inner ones too with the WARNING.
+func PushScope(kv ...any) (pop func()) {
+	return pushScope([][]any{kv})
+}
+
+// CurrentScope -- token with the scope fields of the current goroutine
+func CurrentScope() ScopeToken {
+	if scopeFrames.Load() == 0 {
+		return ScopeToken{}
+	}
+
+	return ScopeToken{layers: goroutineLayers(goroutineID())}
+}
+
+// InheritScope -- push the scope fields of the token in the current goroutine, the returned function pops them
+func InheritScope(parent ScopeToken) (pop func()) {
+	return pushScope(parent.layers)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func pushScope(layers [][]any) func() {
+	if len(layers) == 0 {
+		return func() {}
+	}
+
+	gid := goroutineID()
+	frame := &scopeFrame{
+		id:     scopeSeq.Add(1),
+		layers: layers,
+	}
+
+	scopeMutex.Lock()
+	scopes[gid] = append(scopes[gid], frame)
+	scopeFrames.Add(1)
+	scopeMutex.Unlock()
+
+	return func() {
+		popScope(gid, frame)
+	}
+}
+
+// popScope -- remove the frame and the inner ones, the second pop does nothing
+func popScope(gid uint64, frame *scopeFrame) {
+	scopeMutex.Lock()
+
+	stack := scopes[gid]
+	i := len(stack) - 1
+	for i >= 0 && stack[i] != frame {
+		i--
+	}
+	if i < 0 {
+		scopeMutex.Unlock()
+		return
+	}
+
+	inner := len(stack) - i - 1
+	clear(stack[i:])
+	if i == 0 {
+		delete(scopes, gid)
+	} else {
+		scopes[gid] = stack[:i]
+	}
+	scopeFrames.Add(-int64(inner + 1))
+
+	scopeMutex.Unlock()
+
+	if inner > 0 {
+		Message(WARNING, "Scope #%d is popped out of order, %d inner scopes are popped too", frame.id, inner)
+	}
+}
+
+// goroutineLayers -- layers of all scopes of the goroutine from the innermost one
+func goroutineLayers(gid uint64) [][]any {
+	scopeMutex.Lock()
+	defer scopeMutex.Unlock()
+
+	stack := scopes[gid]
+	if len(stack) == 0 {
+		return nil
+	}
+
+	layers := make([][]any, 0, len(stack))
+	for i := len(stack) - 1; i >= 0; i-- {
+		layers = append(layers, stack[i].layers...)
+	}
+	return layers
+}
+
+// scopeMessage -- the message with the scope fields of the current goroutine
+func scopeMessage(message string, params []any) string {
+	layers := goroutineLayers(goroutineID())
+	if len(layers) == 0 {
+		return message
+	}
+
+	fields := RenderKV(NormalizeKV(nil, layers...))
+	if fields == "" {
+		return message
+	}
+
+	if len(params) > 0 || legacyFormatting.Load() {
+		fields = strings.ReplaceAll(fields, "%", "%%")
+	}
+	return message + " " + fields
+}
+
+// goroutineID -- the number from the first line of the goroutine stack trace
+func goroutineID() uint64 {
+	var buf [64]byte
+	s := bytes.TrimPrefix(buf[:runtime.Stack(buf[:], false)], []byte("goroutine "))
+
+	if i := bytes.IndexByte(s, ' '); i > 0 {
+		s = s[:i]
+	}
+
+	id, _ := strconv.ParseUint(string(s), 10, 64)
+	return id
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,132 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestScopeNesting(t *testing.T) {
+	console := resetLog(t)
+
+	Message(INFO, "no scope")
+
+	pop1 := PushScope("tenant", "acme", "region", "eu")
+	Message(INFO, "outer %d%%", 1)
+
+	pop2 := PushScope("region", "us", "request", 42)
+	GetFacility("db").Message(INFO, "inner")
+	pop2()
+
+	Message(INFO, "outer again")
+	pop1()
+
+	Message(INFO, "done")
+
+	lines := console.Lines()
+	expected := []string{
+		" no scope",
+		" outer 1% tenant=acme region=eu",
+		"<db> inner region=us request=42 tenant=acme",
+		" outer again tenant=acme region=eu",
+		" done",
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("got %d lines, expected %d:\n%s", len(lines), len(expected), console)
+	}
+	for i, s := range expected {
+		if !strings.HasSuffix(lines[i], s) {
+			t.Errorf("%d: got %q, expected the suffix %q", i, lines[i], s)
+		}
+	}
+
+	if n := scopeFrames.Load(); n != 0 || len(scopes) != 0 {
+		t.Errorf("%d frames are left", n)
+	}
+}
+
+func TestScopeMisorderedPop(t *testing.T) {
+	console := resetLog(t)
+
+	pop1 := PushScope("a", 1)
+	pop2 := PushScope("b", 2)
+	PushScope("c", 3)
+
+	pop2()
+	Message(INFO, "after")
+
+	pop2()
+	pop1()
+	Message(INFO, "clean")
+
+	s := console.String()
+	if !strings.Contains(s, " WA ") || !strings.Contains(s, "is popped out of order, 1 inner scopes are popped too a=1\n") {
+		t.Errorf("no warning:\n%s", s)
+	}
+	if !strings.Contains(s, " after a=1\n") || !strings.HasSuffix(s, " clean\n") {
+		t.Errorf("unexpected scopes:\n%s", s)
+	}
+	if n := scopeFrames.Load(); n != 0 {
+		t.Errorf("%d frames are left", n)
+	}
+}
+
+func TestScopeGoroutines(t *testing.T) {
+	console := resetLog(t)
+
+	pop := PushScope("tenant", "parent")
+	token := CurrentScope()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		Message(INFO, "not inherited")
+
+		defer InheritScope(token)()
+		defer PushScope("job", 7)()
+		Message(INFO, "inherited")
+	}()
+	wg.Wait()
+	pop()
+
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			defer PushScope("tenant", fmt.Sprintf("t%d", w))()
+			for i := 0; i < 100; i++ {
+				Message(INFO, "worker %d", w)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	n := 0
+	for _, line := range console.Lines() {
+		switch {
+		case strings.HasSuffix(line, " not inherited"), strings.HasSuffix(line, " inherited job=7 tenant=parent"):
+		case strings.Contains(line, " worker "):
+			_, s, _ := strings.Cut(line, " worker ")
+			var w int
+			fmt.Sscanf(s, "%d", &w)
+			if !strings.HasSuffix(line, fmt.Sprintf(" worker %d tenant=t%d", w, w)) {
+				t.Fatalf("wrong scope %q", line)
+			}
+			n++
+		default:
+			t.Errorf("unexpected line %q", line)
+		}
+	}
+	if n != 800 {
+		t.Errorf("got %d worker lines", n)
+	}
+	if n := scopeFrames.Load(); n != 0 {
+		t.Errorf("%d frames are left", n)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,340 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alrusov/misc"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// The self-test checks the configuration without touching the current file and the buffered lines: the probe file is
+// created and deleted in the log directory, the next file name is checked, the free space is compared with the minimum,
+// targets implementing Prober are probed, levels and file name templates are validated.
+
+// Prober -- the target which can check its connection
+type Prober interface {
+	Probe(timeout time.Duration) error
+}
+
+// SelfTestCheck -- result of the check
+type SelfTestCheck struct {
+	Name     string        `json:"name"`
+	Status   string        `json:"status"`
+	Detail   string        `json:"detail,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// SelfTestReport -- results of all checks
+type SelfTestReport struct {
+	Checks   []SelfTestCheck `json:"checks"`
+	Duration time.Duration   `json:"duration"`
+}
+
+// SelfTestOption -- option of the self-test
+type SelfTestOption func(*selfTestConfig)
+
+type selfTestConfig struct {
+	logOutput    bool
+	probeTimeout time.Duration
+}
+
+// selfTestState -- the configuration copied under the mutex
+type selfTestState struct {
+	mode         string
+	directory    string
+	pattern      string
+	timings      string
+	minFree      uint64
+	targets      map[string]Target
+	levels       map[string]Level
+	probeTimeout time.Duration
+}
+
+const (
+	// SelfTestOK -- the check passed
+	SelfTestOK = "ok"
+	// SelfTestFailed -- the check failed
+	SelfTestFailed = "failed"
+	// SelfTestSkipped -- the check isn't applicable to the configuration
+	SelfTestSkipped = "skipped"
+)
+
+var (
+	minFreeSpace uint64
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetMinFreeSpace -- minimal free space in bytes on the log file system checked by SelfTest, 0 means no check
+func SetMinFreeSpace(bytes uint64) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	minFreeSpace = bytes
+}
+
+// WithLogOutput -- log the report with NOTICE
+func WithLogOutput() SelfTestOption {
+	return func(c *selfTestConfig) {
+		c.logOutput = true
+	}
+}
+
+// WithProbeTimeout -- timeout of the target probes, 2 seconds by default
+func WithProbeTimeout(timeout time.Duration) SelfTestOption {
+	return func(c *selfTestConfig) {
+		if timeout > 0 {
+			c.probeTimeout = timeout
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SelfTest -- check the configuration, the error lists the failed checks
+func SelfTest(opts ...SelfTestOption) (report SelfTestReport, err error) {
+	cfg := selfTestConfig{probeTimeout: 2 * time.Second}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	mutex.Lock()
+	st := selfTestState{
+		mode:         currentMode(),
+		directory:    fileDirectory,
+		pattern:      fileNamePattern,
+		timings:      timingsPattern,
+		minFree:      minFreeSpace,
+		targets:      make(map[string]Target, len(targets)),
+		levels:       make(map[string]Level, len(facilities)),
+		probeTimeout: cfg.probeTimeout,
+	}
+	for name, t := range targets {
+		st.targets[name] = t
+	}
+	for name, f := range facilities {
+		st.levels[name] = f.level
+	}
+	today := fileDate(now())
+	tomorrow := fileDate(now().Add(24 * time.Hour))
+	mutex.Unlock()
+
+	start := time.Now()
+
+	add := func(name string, check func() (status string, detail string)) {
+		t0 := time.Now()
+		status, detail := check()
+		report.Checks = append(report.Checks, SelfTestCheck{Name: name, Status: status, Detail: detail, Duration: time.Since(t0)})
+	}
+
+	add("directory", st.checkDirectory)
+	add("rotation", func() (string, string) { return st.checkRotation(today, tomorrow) })
+	add("free-space", st.checkFreeSpace)
+
+	names := make([]string, 0, len(st.targets))
+	for name := range st.targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		t := st.targets[name]
+		add("target:"+name, func() (string, string) { return st.checkTarget(t) })
+	}
+
+	add("levels", st.checkLevels)
+	add("templates", st.checkTemplates)
+
+	report.Duration = time.Since(start)
+
+	var failed []string
+	for _, c := range report.Checks {
+		if c.Status == SelfTestFailed {
+			failed = append(failed, c.Name)
+		}
+	}
+	if len(failed) > 0 {
+		err = fmt.Errorf("self-test failed: %s", strings.Join(failed, ", "))
+	}
+
+	if cfg.logOutput {
+		report.log()
+	}
+
+	return
+}
+
+// log -- the report as NOTICE lines
+func (r SelfTestReport) log() {
+	for _, c := range r.Checks {
+		if c.Detail == "" {
+			Message(NOTICE, "Self-test %s: %s (%s)", c.Name, c.Status, c.Duration.Round(time.Microsecond))
+		} else {
+			Message(NOTICE, "Self-test %s: %s (%s) %s", c.Name, c.Status, c.Duration.Round(time.Microsecond), c.Detail)
+		}
+	}
+	Message(NOTICE, "Self-test is done in %s", r.Duration.Round(time.Microsecond))
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func (st *selfTestState) fileMode() bool {
+	return st.mode == ModeFile
+}
+
+// checkDirectory -- create, write, sync and delete the probe file. The missing directory is checked by its parent.
+func (st *selfTestState) checkDirectory() (string, string) {
+	if !st.fileMode() {
+		return SelfTestSkipped, "mode " + st.mode
+	}
+
+	dir := st.directory
+	detail := ""
+	for {
+		fi, err := os.Stat(dir)
+		if err == nil {
+			if !fi.IsDir() {
+				return SelfTestFailed, dir + " isn't a directory"
+			}
+			break
+		}
+		if !os.IsNotExist(err) {
+			return SelfTestFailed, err.Error()
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return SelfTestFailed, err.Error()
+		}
+		detail = st.directory + " will be created"
+		dir = parent
+	}
+
+	fd, err := os.CreateTemp(dir, ".selftest-*.probe")
+	if err != nil {
+		return SelfTestFailed, err.Error()
+	}
+
+	_, err = fd.Write([]byte("probe" + misc.EOS))
+	if err == nil {
+		err = fd.Sync()
+	}
+	if e := fd.Close(); err == nil {
+		err = e
+	}
+	if e := os.Remove(fd.Name()); err == nil {
+		err = e
+	}
+
+	if err != nil {
+		return SelfTestFailed, err.Error()
+	}
+	return SelfTestOK, detail
+}
+
+// checkRotation -- the next day file differs and can be written if it exists
+func (st *selfTestState) checkRotation(today string, tomorrow string) (string, string) {
+	if !st.fileMode() {
+		return SelfTestSkipped, "mode " + st.mode
+	}
+
+	current := fmt.Sprintf(st.pattern, today)
+	next := fmt.Sprintf(st.pattern, tomorrow)
+	if current == next {
+		return SelfTestFailed, "the file name doesn't depend on the date"
+	}
+
+	if _, err := os.Stat(next); err == nil {
+		fd, err := os.OpenFile(next, os.O_APPEND|os.O_WRONLY, 0)
+		if err != nil {
+			return SelfTestFailed, err.Error()
+		}
+		fd.Close()
+	}
+
+	return SelfTestOK, ""
+}
+
+// checkFreeSpace -- free space of the file system of the directory is not less than the minimum
+func (st *selfTestState) checkFreeSpace() (string, string) {
+	if !st.fileMode() || st.minFree == 0 {
+		return SelfTestSkipped, ""
+	}
+
+	dir := st.directory
+	for {
+		if _, err := os.Stat(dir); err == nil || filepath.Dir(dir) == dir {
+			break
+		}
+		dir = filepath.Dir(dir)
+	}
+
+	free, err := freeSpace(dir)
+	if errors.Is(err, errors.ErrUnsupported) {
+		return SelfTestSkipped, err.Error()
+	}
+	if err != nil {
+		return SelfTestFailed, err.Error()
+	}
+
+	detail := fmt.Sprintf("%d bytes free, %d required", free, st.minFree)
+	if free < st.minFree {
+		return SelfTestFailed, detail
+	}
+	return SelfTestOK, detail
+}
+
+// checkTarget -- probe the target connection
+func (st *selfTestState) checkTarget(t Target) (string, string) {
+	p, ok := t.(Prober)
+	if !ok {
+		return SelfTestSkipped, "no probe"
+	}
+
+	if err := p.Probe(st.probeTimeout); err != nil {
+		return SelfTestFailed, err.Error()
+	}
+	return SelfTestOK, ""
+}
+
+// checkLevels -- levels of the facilities are known
+func (st *selfTestState) checkLevels() (string, string) {
+	var bad []string
+	for name, l := range st.levels {
+		if l < EMERG || int(l) >= len(levels) {
+			bad = append(bad, fmt.Sprintf("%s=%d", name, l))
+		}
+	}
+
+	if len(bad) > 0 {
+		sort.Strings(bad)
+		return SelfTestFailed, "unknown levels " + strings.Join(bad, " ")
+	}
+	return SelfTestOK, fmt.Sprintf("%d facilities", len(st.levels))
+}
+
+// checkTemplates -- the file name patterns have the only date verb
+func (st *selfTestState) checkTemplates() (string, string) {
+	for _, pattern := range []string{st.pattern, st.timings} {
+		if pattern == "" || pattern == "-" {
+			continue
+		}
+
+		if err := checkFormat(pattern); err != nil {
+			return SelfTestFailed, fmt.Sprintf("%q: %s", pattern, err)
+		}
+		if n := strings.Count(strings.ReplaceAll(pattern, "%%", ""), "%"); n != 1 {
+			return SelfTestFailed, fmt.Sprintf("%q has %d verbs, expected the date only", pattern, n)
+		}
+	}
+
+	return SelfTestOK, ""
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
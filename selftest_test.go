@@ -0,0 +1,119 @@
+package log
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+type probeTarget struct {
+	failingSink
+	probeErr error
+}
+
+func (p *probeTarget) Probe(timeout time.Duration) error {
+	return p.probeErr
+}
+
+func checkVerdicts(t *testing.T, report SelfTestReport, expected map[string]string) {
+	t.Helper()
+
+	for _, c := range report.Checks {
+		if status, exists := expected[c.Name]; exists && status != c.Status {
+			t.Errorf("%s: got %s (%s), expected %s", c.Name, c.Status, c.Detail, status)
+		}
+		delete(expected, c.Name)
+	}
+	for name := range expected {
+		t.Errorf("%s isn't checked", name)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestSelfTestGoodDirectory(t *testing.T) {
+	console := resetLog(t)
+	setFakeClock(time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC))
+
+	dir := t.TempDir()
+	SetFile(dir, "", false, 4096, 0)
+	SetMinFreeSpace(1)
+	AddTarget("collector", &probeTarget{})
+	AddTarget("plain", &failingSink{})
+
+	Message(INFO, "buffered")
+	name := FileName()
+
+	report, err := SelfTest(WithLogOutput())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkVerdicts(t, report, map[string]string{
+		"directory":        SelfTestOK,
+		"rotation":         SelfTestOK,
+		"free-space":       SelfTestOK,
+		"target:collector": SelfTestOK,
+		"target:plain":     SelfTestSkipped,
+		"levels":           SelfTestOK,
+		"templates":        SelfTestOK,
+	})
+
+	if FileName() != name {
+		t.Errorf("the file is changed to %s", FileName())
+	}
+	if list, _ := os.ReadDir(dir); len(list) != 1 {
+		t.Errorf("unexpected files %v", list)
+	}
+
+	writerFlush()
+	data, _ := os.ReadFile(name)
+	if !strings.Contains(string(data), " buffered\n") || !strings.Contains(string(data), " NO ") {
+		t.Errorf("unexpected file:\n%s", data)
+	}
+	if !strings.Contains(console.String(), "Self-test directory: ok (") || !strings.Contains(console.String(), "Self-test is done in ") {
+		t.Errorf("no report:\n%s", console)
+	}
+}
+
+func TestSelfTestReadOnlyDirectory(t *testing.T) {
+	resetLog(t)
+	setFakeClock(time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC))
+
+	dir := t.TempDir()
+	os.Chmod(dir, 0555)
+	t.Cleanup(func() { os.Chmod(dir, 0755) })
+
+	if os.Geteuid() == 0 {
+		// The permissions don't restrict root
+		dir, _ = brokenDir(t)
+	}
+
+	SetFile(dir, "", false, 0, 0)
+	SetMinFreeSpace(1 << 62)
+	AddTarget("collector", &probeTarget{probeErr: errors.New("connection refused")})
+
+	report, err := SelfTest(WithProbeTimeout(time.Millisecond))
+	if err == nil || !strings.Contains(err.Error(), "directory") || !strings.Contains(err.Error(), "target:collector") {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	checkVerdicts(t, report, map[string]string{
+		"directory":        SelfTestFailed,
+		"rotation":         SelfTestOK,
+		"free-space":       SelfTestFailed,
+		"target:collector": SelfTestFailed,
+		"levels":           SelfTestOK,
+		"templates":        SelfTestOK,
+	})
+
+	if FileName() != "" {
+		t.Errorf("the file %s is opened", FileName())
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,386 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/alrusov/misc"
+	"github.com/alrusov/panic"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// ShipperOptions -- options of the batch shipper
+type ShipperOptions struct {
+	MaxBytes    int           // the batch is shipped when it reaches the size, default 1 MiB
+	MaxDelay    time.Duration // or when it is that old, default 5s
+	Compression Compression   // CompressionGzip or CompressionNone
+	Retries     int           // attempts per shipping round, default 3
+	RetryDelay  time.Duration // the first backoff, it is doubled on every attempt, default 1s
+	SpoolDir    string        // unacknowledged batches are kept there and replayed on start, empty means in the memory only
+	SpoolLimit  int64         // the oldest batches are dropped above the size, default 100 MiB
+	Client      *http.Client
+}
+
+// BatchShipper -- target sending lines to the collector in batches with at-least-once semantics.
+// Every batch is POSTed with X-Log-App, X-Log-Pid and X-Log-Seq ("from-to") headers.
+type BatchShipper struct {
+	endpoint string
+	opts     ShipperOptions
+
+	mutex      sync.Mutex
+	buf        bytes.Buffer
+	seq        uint64
+	seqFrom    uint64
+	batchStart time.Time
+	sealed     []*shipBatch
+	closed     bool
+
+	queue   []*shipBatch // only the worker goroutine
+	dropped int64
+
+	wake chan struct{}
+	stop chan struct{}
+	done chan struct{}
+}
+
+type shipMeta struct {
+	App         string      `json:"app"`
+	PID         int         `json:"pid"`
+	From        uint64      `json:"from"`
+	To          uint64      `json:"to"`
+	Compression Compression `json:"compression"`
+}
+
+type shipBatch struct {
+	meta      shipMeta
+	payload   []byte
+	spoolName string
+}
+
+const spoolExt = ".batch"
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// NewBatchShipper -- create the shipper and start its worker. Batches left in the spool directory are replayed first.
+func NewBatchShipper(endpoint string, opts ShipperOptions) (*BatchShipper, error) {
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = 1 << 20
+	}
+	if opts.MaxDelay <= 0 {
+		opts.MaxDelay = 5 * time.Second
+	}
+	if opts.Retries <= 0 {
+		opts.Retries = 3
+	}
+	if opts.RetryDelay <= 0 {
+		opts.RetryDelay = time.Second
+	}
+	if opts.SpoolLimit <= 0 {
+		opts.SpoolLimit = 100 << 20
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	switch opts.Compression {
+	case CompressionNone, CompressionGzip:
+	default:
+		return nil, fmt.Errorf(`unsupported compression "%s"`, opts.Compression)
+	}
+
+	s := &BatchShipper{
+		endpoint: endpoint,
+		opts:     opts,
+		wake:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	if opts.SpoolDir != "" {
+		if err := os.MkdirAll(opts.SpoolDir, 0755); err != nil {
+			return nil, err
+		}
+		if err := s.loadSpool(); err != nil {
+			return nil, err
+		}
+	}
+
+	go s.worker()
+
+	return s, nil
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Write -- add the line to the current batch, never blocks on the network
+func (s *BatchShipper) Write(p []byte) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return 0, errors.New("shipper is closed")
+	}
+
+	if s.buf.Len() == 0 {
+		s.batchStart = time.Now()
+		s.seqFrom = s.seq + 1
+	}
+
+	s.buf.Write(p)
+	s.seq++
+
+	if s.buf.Len() >= s.opts.MaxBytes {
+		s.seal()
+		s.signal()
+	}
+
+	return len(p), nil
+}
+
+// Close -- ship the rest and stop the worker. Unacknowledged batches stay in the spool.
+func (s *BatchShipper) Close() error {
+	s.mutex.Lock()
+	if s.closed {
+		s.mutex.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.seal()
+	s.mutex.Unlock()
+
+	close(s.stop)
+	<-s.done
+
+	return nil
+}
+
+// Dropped -- number of batches dropped because of the spool limit
+func (s *BatchShipper) Dropped() int64 {
+	return atomic.LoadInt64(&s.dropped)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Must be called under s.mutex
+func (s *BatchShipper) seal() {
+	if s.buf.Len() == 0 {
+		return
+	}
+
+	s.sealed = append(s.sealed,
+		&shipBatch{
+			meta: shipMeta{
+				App:         misc.AppName(),
+				PID:         pid,
+				From:        s.seqFrom,
+				To:          s.seq,
+				Compression: s.opts.Compression,
+			},
+			payload: bytes.Clone(s.buf.Bytes()),
+		},
+	)
+	s.buf.Reset()
+}
+
+func (s *BatchShipper) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *BatchShipper) worker() {
+	panicID := panic.ID()
+	defer panic.SaveStackToLogEx(panicID)
+
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.opts.MaxDelay)
+	defer ticker.Stop()
+
+	for {
+		stopped := false
+
+		select {
+		case <-s.stop:
+			stopped = true
+		case <-s.wake:
+		case <-ticker.C:
+		}
+
+		s.mutex.Lock()
+		if s.buf.Len() > 0 && time.Since(s.batchStart) >= s.opts.MaxDelay {
+			s.seal()
+		}
+		sealed := s.sealed
+		s.sealed = nil
+		s.mutex.Unlock()
+
+		for _, b := range sealed {
+			s.enqueue(b)
+		}
+
+		s.ship(stopped)
+
+		if stopped {
+			return
+		}
+	}
+}
+
+func (s *BatchShipper) enqueue(b *shipBatch) {
+	if b.meta.Compression == CompressionGzip {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		gz.Write(b.payload)
+		gz.Close()
+		b.payload = buf.Bytes()
+	}
+
+	if s.opts.SpoolDir != "" {
+		b.spoolName = filepath.Join(s.opts.SpoolDir, fmt.Sprintf("%019d-%d-%d%s", time.Now().UnixNano(), b.meta.From, b.meta.To, spoolExt))
+		s.saveSpool(b)
+	}
+
+	s.queue = append(s.queue, b)
+
+	size := int64(0)
+	for _, b := range s.queue {
+		size += int64(len(b.payload))
+	}
+	for size > s.opts.SpoolLimit && len(s.queue) > 1 {
+		size -= int64(len(s.queue[0].payload))
+		s.remove(s.queue[0])
+		s.queue = s.queue[1:]
+		atomic.AddInt64(&s.dropped, 1)
+	}
+}
+
+// ship -- send queued batches in order, stop at the first batch failed after all retries
+func (s *BatchShipper) ship(stopped bool) {
+	for len(s.queue) > 0 {
+		b := s.queue[0]
+
+		delay := s.opts.RetryDelay
+		var err error
+		for attempt := 0; attempt < s.opts.Retries; attempt++ {
+			if attempt > 0 {
+				select {
+				case <-time.After(delay):
+				case <-s.stop:
+					if !stopped {
+						return
+					}
+				}
+				delay *= 2
+			}
+
+			if err = s.post(b); err == nil {
+				break
+			}
+		}
+
+		if err != nil {
+			return
+		}
+
+		s.remove(b)
+		s.queue = s.queue[1:]
+	}
+}
+
+func (s *BatchShipper) post(b *shipBatch) error {
+	rq, err := http.NewRequest(http.MethodPost, s.endpoint, bytes.NewReader(b.payload))
+	if err != nil {
+		return err
+	}
+
+	rq.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if b.meta.Compression == CompressionGzip {
+		rq.Header.Set("Content-Encoding", "gzip")
+	}
+	rq.Header.Set("X-Log-App", b.meta.App)
+	rq.Header.Set("X-Log-Pid", fmt.Sprint(b.meta.PID))
+	rq.Header.Set("X-Log-Seq", fmt.Sprintf("%d-%d", b.meta.From, b.meta.To))
+
+	resp, err := s.opts.Client.Do(rq)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("collector returned %s", resp.Status)
+	}
+
+	return nil
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Spool file: JSON meta line followed by the payload
+
+func (s *BatchShipper) saveSpool(b *shipBatch) {
+	meta, _ := json.Marshal(b.meta)
+
+	tmp := b.spoolName + ".tmp"
+	data := append(append(meta, '\n'), b.payload...)
+	if os.WriteFile(tmp, data, 0644) != nil || os.Rename(tmp, b.spoolName) != nil {
+		os.Remove(tmp)
+		b.spoolName = ""
+	}
+}
+
+func (s *BatchShipper) remove(b *shipBatch) {
+	if b.spoolName != "" {
+		os.Remove(b.spoolName)
+	}
+}
+
+func (s *BatchShipper) loadSpool() error {
+	names, err := filepath.Glob(filepath.Join(s.opts.SpoolDir, "*"+spoolExt))
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			continue
+		}
+
+		r := bufio.NewReader(bytes.NewReader(data))
+		line, err := r.ReadString('\n')
+		if err != nil {
+			os.Remove(name)
+			continue
+		}
+
+		b := &shipBatch{spoolName: name}
+		if json.Unmarshal([]byte(strings.TrimSpace(line)), &b.meta) != nil {
+			os.Remove(name)
+			continue
+		}
+		b.payload = data[len(line):]
+
+		s.queue = append(s.queue, b)
+	}
+
+	return nil
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,165 @@
+package log
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+type testCollector struct {
+	mutex    sync.Mutex
+	fail     int
+	attempts int
+	bodies   []string
+	seqs     []string
+}
+
+func (c *testCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.attempts++
+	if c.fail != 0 {
+		if c.fail > 0 {
+			c.fail--
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body = gz
+	}
+
+	data, _ := io.ReadAll(body)
+	c.bodies = append(c.bodies, string(data))
+	c.seqs = append(c.seqs, r.Header.Get("X-Log-Seq"))
+}
+
+func spoolFiles(t *testing.T, dir string) []string {
+	t.Helper()
+
+	list, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, e := range list {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestBatchShipperRedelivery(t *testing.T) {
+	resetLog(t)
+
+	c := &testCollector{fail: 1}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	spool := t.TempDir()
+	s, err := NewBatchShipper(srv.URL, ShipperOptions{MaxDelay: time.Hour, Compression: CompressionGzip, RetryDelay: 10 * time.Millisecond, SpoolDir: spool})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	AddTarget("collector", s)
+	Message(INFO, "one")
+	Message(INFO, "two")
+	Message(INFO, "three")
+
+	if err := DelTarget("collector"); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.attempts != 2 || len(c.bodies) != 1 {
+		t.Fatalf("got %d attempts and %d batches, expected 2 and 1", c.attempts, len(c.bodies))
+	}
+	if b := c.bodies[0]; !strings.Contains(b, " one\n") || !strings.HasSuffix(b, " three\n") || c.seqs[0] != "1-3" {
+		t.Errorf("unexpected batch %s:\n%s", c.seqs[0], b)
+	}
+	if names := spoolFiles(t, spool); len(names) != 0 {
+		t.Errorf("spool is not cleaned: %q", names)
+	}
+}
+
+func TestBatchShipperReplay(t *testing.T) {
+	resetLog(t)
+
+	down := &testCollector{fail: -1}
+	srvDown := httptest.NewServer(down)
+	defer srvDown.Close()
+
+	spool := t.TempDir()
+	opts := ShipperOptions{MaxDelay: time.Hour, Retries: 1, SpoolDir: spool}
+
+	s, err := NewBatchShipper(srvDown.URL, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Write([]byte("unacknowledged line\n"))
+	s.Close()
+
+	if names := spoolFiles(t, spool); len(names) != 1 {
+		t.Fatalf("unexpected spool %q", names)
+	}
+
+	// Restart
+	up := &testCollector{}
+	srvUp := httptest.NewServer(up)
+	defer srvUp.Close()
+
+	s, err = NewBatchShipper(srvUp.URL, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	if len(up.bodies) != 1 || up.bodies[0] != "unacknowledged line\n" {
+		t.Errorf("unexpected replay %q", up.bodies)
+	}
+	if names := spoolFiles(t, spool); len(names) != 0 {
+		t.Errorf("spool is not cleaned: %q", names)
+	}
+}
+
+func TestBatchShipperSpoolLimit(t *testing.T) {
+	resetLog(t)
+
+	down := &testCollector{fail: -1}
+	srv := httptest.NewServer(down)
+	defer srv.Close()
+
+	s, err := NewBatchShipper(srv.URL, ShipperOptions{MaxBytes: 10, MaxDelay: time.Hour, Retries: 1, SpoolLimit: 25, SpoolDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 5; i++ {
+		s.Write([]byte("0123456789\n"))
+	}
+	s.Close()
+
+	if n := s.Dropped(); n != 3 {
+		t.Errorf("got %d dropped batches, expected 3", n)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,272 @@
+package log
+
+import (
+	"context"
+	"io"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/alrusov/misc"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Importing the package has no side effects. The exit handler, the stdlib logger hijack and the background flusher
+// are started by the first configuration call or the first message.
+
+var (
+	startOnce sync.Once
+	started   atomic.Bool
+
+	hijackStdLog   atomic.Bool
+	stdLogHijacked = false
+	stdLogWriter   io.Writer
+	stdLogFlags    int
+
+	startMutex sync.Mutex
+	bgDisabled = false
+	bgStop     chan struct{}
+	bgDone     chan struct{}
+
+	shutdownMutex sync.Mutex
+	suspended     *suspendedConfig // the settings switched off by Shutdown, guarded by shutdownMutex
+)
+
+// suspendedConfig -- the settings with goroutines, Start restores them
+type suspendedConfig struct {
+	groupCommit GroupCommitMode
+	severeLevel Level
+	severeFn    func(rec Record)
+	coarseClock time.Duration
+	asyncOpen   bool
+}
+
+func init() {
+	hijackStdLog.Store(true)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// ensureStarted -- start everything postponed from init
+func ensureStarted() {
+	startOnce.Do(func() {
+		misc.AddExitFunc("log.exit", exit, nil)
+
+		applyHijackStdLog()
+
+		startMutex.Lock()
+		if !bgDisabled {
+			startBackground()
+		}
+		startMutex.Unlock()
+
+		started.Store(true)
+	})
+}
+
+// Start -- start the background work, it is done automatically by the first configuration call or the first message.
+// Start after Shutdown resumes logging.
+func Start() {
+	ensureStarted()
+
+	mutex.Lock()
+	active = true
+	writeSuspended()
+	mutex.Unlock()
+
+	StartBackground()
+	resume()
+}
+
+// writeSuspended -- open the file for the lines logged after Shutdown. Must be called under the mutex.
+func writeSuspended() {
+	if len(beforeFileBuf) == 0 || dst != nil || memoryMode || (outputWriter == nil && fileNamePattern == "") {
+		return
+	}
+
+	// startLogFile writes the kept lines
+	dt := fileDate(now())
+	rotateLogFile(dt)
+	if dst != nil {
+		lastWriteDate = dt
+	}
+}
+
+// Shutdown -- stop the background goroutines, flush everything and close the files and the targets.
+// Group commit, the asynchronous file opening, the severe notifier and the coarse clock are switched off until Start.
+// Lines logged after it are kept in the memory (up to the limit of the lines logged before the file is set) and
+// written by Start. The context limits the waiting, not the work itself, its error is returned if it is done.
+func Shutdown(ctx context.Context) error {
+	if err := ShutdownCtx(ctx); err != nil {
+		return ctx.Err()
+	}
+	return nil
+}
+
+// ShutdownCtx -- Shutdown that returns *DrainError with the number of the unwritten lines if the context is done
+func ShutdownCtx(ctx context.Context) error {
+	return waitDrain(ctx, shutdown)
+}
+
+func shutdown() {
+	shutdownMutex.Lock()
+	defer shutdownMutex.Unlock()
+
+	mutex.Lock()
+	async := asyncOpen
+	mutex.Unlock()
+
+	if suspended == nil {
+		// The repeated Shutdown keeps the settings of the first one
+		level, fn := currentSevereNotifier()
+		suspended = &suspendedConfig{
+			groupCommit: currentGroupCommit(),
+			severeLevel: level,
+			severeFn:    fn,
+			coarseClock: currentCoarseClock(),
+			asyncOpen:   async,
+		}
+	}
+
+	SetGroupCommit(GroupCommitOff)
+	SetSevereNotifier(0, nil)
+	SetCoarseClock(0)
+
+	mutex.Lock()
+	asyncOpen = false
+	flushOpenPending()
+	mutex.Unlock()
+
+	StopBackground()
+
+	mutex.Lock()
+	active = false
+	mutex.Unlock()
+
+	closeTargets()
+
+	defer waitConsole()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	closeTimingsFile()
+	dumpEarlyStderr()
+	writeTrailer("-")
+	closeLogFile()
+	releaseExclusive()
+	lastWriteDate = ""
+}
+
+// resume -- restore the settings switched off by Shutdown, the ones set again after it are kept
+func resume() {
+	shutdownMutex.Lock()
+	defer shutdownMutex.Unlock()
+
+	c := suspended
+	if c == nil {
+		return
+	}
+	suspended = nil
+
+	if currentGroupCommit() == GroupCommitOff {
+		SetGroupCommit(c.groupCommit)
+	}
+	if _, fn := currentSevereNotifier(); fn == nil && c.severeFn != nil {
+		SetSevereNotifier(c.severeLevel, c.severeFn)
+	}
+	if currentCoarseClock() == 0 {
+		SetCoarseClock(c.coarseClock)
+	}
+
+	mutex.Lock()
+	asyncOpen = asyncOpen || c.asyncOpen
+	mutex.Unlock()
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// StartBackground -- start the background flusher if it isn't running
+func StartBackground() {
+	startMutex.Lock()
+	defer startMutex.Unlock()
+
+	bgDisabled = false
+	startBackground()
+}
+
+// StopBackground -- stop the background flusher and don't start it automatically. Buffered lines are flushed.
+func StopBackground() {
+	startMutex.Lock()
+	defer startMutex.Unlock()
+
+	bgDisabled = true
+
+	if bgStop == nil {
+		return
+	}
+
+	close(bgStop)
+	<-bgDone
+	bgStop = nil
+	bgDone = nil
+
+	writerFlush()
+}
+
+// BackgroundRunning -- is the background flusher running
+func BackgroundRunning() bool {
+	startMutex.Lock()
+	defer startMutex.Unlock()
+
+	return bgStop != nil
+}
+
+// Must be called under startMutex
+func startBackground() {
+	if bgStop != nil {
+		return
+	}
+
+	bgStop = make(chan struct{})
+	bgDone = make(chan struct{})
+	go writerFlusher(bgStop, bgDone)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetHijackStdLog -- redirect the stdlib logger to this log (default) or leave it untouched.
+// Switching it off after the start restores the previous stdlib logger output and flags.
+func SetHijackStdLog(hijack bool) {
+	hijackStdLog.Store(hijack)
+
+	if started.Load() {
+		applyHijackStdLog()
+	}
+}
+
+func applyHijackStdLog() {
+	startMutex.Lock()
+	defer startMutex.Unlock()
+
+	hijack := hijackStdLog.Load()
+	if hijack == stdLogHijacked {
+		return
+	}
+
+	if hijack {
+		stdLogWriter = log.Writer()
+		stdLogFlags = log.Flags()
+		log.SetFlags(0)
+		log.SetOutput(writer)
+	} else {
+		log.SetFlags(stdLogFlags)
+		log.SetOutput(stdLogWriter)
+	}
+
+	stdLogHijacked = hijack
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,229 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	stdlog "log"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func flusherRunning() bool {
+	buf := make([]byte, 1<<20)
+	return bytes.Contains(buf[:runtime.Stack(buf, true)], []byte(".writerFlusher("))
+}
+
+// TestImportOnly -- runs itself in the separate process to check the state of the package which is merely imported
+func TestImportOnly(t *testing.T) {
+	if os.Getenv("LOG_TEST_IMPORT_ONLY") == "" {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestImportOnly$", "-test.v")
+		cmd.Env = append(os.Environ(), "LOG_TEST_IMPORT_ONLY=1")
+		out, err := cmd.CombinedOutput()
+		if err != nil || !strings.Contains(string(out), "--- PASS: TestImportOnly") {
+			t.Fatalf("%v\n%s", err, out)
+		}
+		return
+	}
+
+	if flusherRunning() || BackgroundRunning() {
+		t.Error("flusher is running")
+	}
+	if stdlog.Writer() != os.Stderr || stdlog.Flags() != stdlog.LstdFlags {
+		t.Error("stdlib logger is changed")
+	}
+
+	w := &captureWriter{}
+	SetConsoleWriter(w)
+	defer SetConsoleWriter(nil)
+
+	Message(INFO, "first")
+
+	if !BackgroundRunning() {
+		t.Error("flusher is not started by the first message")
+	}
+
+	stdlog.Print("from stdlib")
+	if s := w.String(); !strings.Contains(s, " from stdlib\n") {
+		t.Errorf("stdlib logger is not hijacked:\n%s", s)
+	}
+
+	SetHijackStdLog(false)
+	if stdlog.Writer() != os.Stderr || stdlog.Flags() != stdlog.LstdFlags {
+		t.Error("stdlib logger is not restored")
+	}
+
+	StopBackground()
+	if flusherRunning() || BackgroundRunning() {
+		t.Error("flusher is not stopped")
+	}
+
+	StartBackground()
+	if !BackgroundRunning() {
+		t.Error("flusher is not started")
+	}
+}
+
+func waitGoroutines(t *testing.T, expected int) {
+	t.Helper()
+
+	n := 0
+	for i := 0; i < 100; i++ {
+		if n = runtime.NumGoroutine(); n <= expected {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("got %d goroutines, expected %d", n, expected)
+}
+
+func TestShutdown(t *testing.T) {
+	resetLog(t)
+	defer Start()
+
+	dir := t.TempDir()
+	SetFile(dir, "", false, 4096, 0)
+
+	if err := Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	base := runtime.NumGoroutine()
+
+	for round := 0; round < 2; round++ {
+		Start()
+		SetGroupCommit(GroupCommitAsync)
+		SetSevereNotifier(EMERG, func(Record) {})
+
+		Message(INFO, "round %d", round)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		err := Shutdown(ctx)
+		cancel()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		waitGoroutines(t, base)
+		if BackgroundRunning() {
+			t.Error("flusher is running")
+		}
+
+		data, _ := os.ReadFile(FileName())
+		if !strings.HasSuffix(string(data), fmt.Sprintf(" round %d\n", round)) {
+			t.Errorf("round %d: unexpected file:\n%s", round, data)
+		}
+	}
+}
+
+func TestShutdownKeepsLines(t *testing.T) {
+	resetLog(t)
+	defer Start()
+
+	SetFile(t.TempDir(), "", false, 4096, 0)
+	Message(INFO, "before shutdown")
+
+	if err := Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	Message(INFO, "after shutdown")
+
+	Start()
+	writerFlush()
+
+	data, _ := os.ReadFile(FileName())
+	s := string(data)
+	if !strings.Contains(s, " before shutdown\n") || !strings.HasSuffix(s, " after shutdown\n") {
+		t.Errorf("unexpected file:\n%s", s)
+	}
+}
+
+func TestShutdownResume(t *testing.T) {
+	resetLog(t)
+	defer Start()
+
+	SetFile(t.TempDir(), "", false, 4096, 0)
+
+	fired := make(chan Record, 10)
+	SetSevereNotifier(ERR, func(r Record) {
+		select {
+		case fired <- r:
+		default:
+		}
+	})
+	SetGroupCommit(GroupCommitAsync)
+	SetCoarseClock(time.Millisecond)
+
+	for round := 0; round < 2; round++ {
+		if err := Shutdown(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if _, fn := currentSevereNotifier(); fn != nil || currentGroupCommit() != GroupCommitOff || currentCoarseClock() != 0 {
+			t.Fatalf("round %d: the settings aren't switched off", round)
+		}
+
+		Start()
+		if currentGroupCommit() != GroupCommitAsync || currentCoarseClock() != time.Millisecond {
+			t.Errorf("round %d: the settings aren't restored", round)
+		}
+
+		Message(ERR, "failure %d", round)
+
+		select {
+		case r := <-fired:
+			if !strings.HasSuffix(r.Message, fmt.Sprintf("failure %d", round)) {
+				t.Errorf("round %d: unexpected record %+v", round, r)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("round %d: the notifier isn't called after Start", round)
+		}
+	}
+
+	// The setting changed after Shutdown wins
+	Shutdown(context.Background())
+	SetGroupCommit(GroupCommitSync)
+	Start()
+	if currentGroupCommit() != GroupCommitSync {
+		t.Errorf("the new setting is overridden")
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	resetLog(t)
+	defer Start()
+
+	release := make(chan struct{})
+	AddTarget("slow", &slowTarget{release: release})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Errorf("got %v, expected the context error", err)
+	}
+	close(release)
+
+	if err := Shutdown(context.Background()); err != nil {
+		t.Error(err)
+	}
+}
+
+type slowTarget struct {
+	release chan struct{}
+}
+
+func (w *slowTarget) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (w *slowTarget) Close() error {
+	<-w.release
+	return nil
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
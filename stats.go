@@ -0,0 +1,97 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/alrusov/misc"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Stats -- message counters
+type Stats struct {
+	Levels    map[string]int64 `json:"levels"`
+	Raw       int64            `json:"raw"`
+	Dropped   int64            `json:"dropped"`
+	Truncated int64            `json:"truncated"`
+}
+
+var (
+	statLevels    [maxLevels]int64
+	statRaw       int64
+	statDropped   int64
+	statTruncated int64
+
+	exitCode = -1
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func statMessage(level Level) {
+	if level < EMERG || int(level) >= len(levels) {
+		level = UNKNOWN
+	}
+	atomic.AddInt64(&statLevels[level], 1)
+}
+
+func statRawMessage() {
+	atomic.AddInt64(&statRaw, 1)
+}
+
+func statDrop() {
+	atomic.AddInt64(&statDropped, 1)
+}
+
+func statTruncate() {
+	atomic.AddInt64(&statTruncated, 1)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// GetStats -- get message counters
+func GetStats() (stats Stats) {
+	stats.Levels = make(map[string]int64, len(levels))
+	for i := range levels {
+		stats.Levels[levels[i].name] = atomic.LoadInt64(&statLevels[i])
+	}
+	stats.Raw = atomic.LoadInt64(&statRaw)
+	stats.Dropped = atomic.LoadInt64(&statDropped)
+	stats.Truncated = atomic.LoadInt64(&statTruncated)
+	return
+}
+
+// ResetStats -- reset message counters
+func ResetStats() {
+	for i := range statLevels {
+		atomic.StoreInt64(&statLevels[i], 0)
+	}
+	atomic.StoreInt64(&statRaw, 0)
+	atomic.StoreInt64(&statDropped, 0)
+	atomic.StoreInt64(&statTruncated, 0)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// ExitSummary -- machine-parsable summary line, the same as written to the log at exit
+func ExitSummary() string {
+	code := exitCode
+	if code < 0 {
+		code = misc.ExitCode()
+	}
+
+	uptime := now().Sub(misc.AppStartTime()).Truncate(time.Millisecond)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*** exit code=%d uptime=%s", code, uptime)
+	for _, def := range orderedLevels() {
+		fmt.Fprintf(&b, " %s=%d", strings.ToLower(def.name), atomic.LoadInt64(&statLevels[def.code]))
+	}
+	fmt.Fprintf(&b, " raw=%d dropped=%d truncated=%d", atomic.LoadInt64(&statRaw), atomic.LoadInt64(&statDropped), atomic.LoadInt64(&statTruncated))
+
+	return b.String()
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
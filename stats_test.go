@@ -0,0 +1,44 @@
+package log
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestExitSummary(t *testing.T) {
+	console := resetLog(t)
+
+	MaxLen(70)
+	Message(ERR, "error 1")
+	Message(ERR, "error 2")
+	Message(WARNING, "warning that is long enough to be truncated by the length limit")
+	Message(TRACE1, "filtered out")
+	MaxLen(0)
+
+	exit(3, nil)
+	t.Cleanup(func() { resetLog(t) })
+
+	expected := " emerg=0 alert=0 crit=0 err=2 warning=1 notice=0 info=0 time=0 debug=0 trace1=0 trace2=0 trace3=0 trace4=0 unknown=0 raw=0 dropped=0 truncated=1"
+
+	if !strings.Contains(console.String(), " *** exit code=3 uptime=") || !strings.Contains(console.String(), expected+"\n") {
+		t.Errorf("summary not found on console:\n%s", console)
+	}
+
+	summary := ExitSummary()
+	if !strings.HasPrefix(summary, "*** exit code=3 uptime=") || !strings.Contains(summary, " info=2 ") {
+		t.Errorf("unexpected summary %q", summary)
+	}
+
+	dump, err := os.ReadFile(dumpFileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(dump), "*** exit code=3 ") || !strings.Contains(string(dump), expected) {
+		t.Errorf("summary not found in the dump file:\n%s", dump)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,83 @@
+package log
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// StatusInfo -- current state of the log
+type StatusInfo struct {
+	Mode               string               `json:"mode"`
+	FileName           string               `json:"fileName"`
+	FileNamePattern    string               `json:"fileNamePattern"`
+	Tier               string               `json:"tier"`
+	LastError          string               `json:"lastError,omitempty"`
+	LocalTime          bool                 `json:"localTime"`
+	Storms             []string             `json:"storms,omitempty"`
+	Quotas             map[string]QuotaInfo `json:"quotas,omitempty"`
+	DestinationErrors  map[string]error     `json:"-"`
+	ClockAnomalies     int64                `json:"clockAnomalies"`
+	Events             []EventInfo          `json:"events,omitempty"`
+	UnregisteredEvents int64                `json:"unregisteredEvents"`
+	Stats              Stats                `json:"stats"`
+}
+
+const (
+	// ModeUnconfigured -- nothing is configured, messages are buffered until the file is set
+	ModeUnconfigured = "unconfigured"
+	// ModeFile -- daily files
+	ModeFile = "file"
+	// ModeStdout -- the "-" directory, lines are written to stdout with the file buffering
+	ModeStdout = "stdout"
+	// ModeMemory -- memory mode
+	ModeMemory = "memory"
+	// ModeOutput -- caller provided writer
+	ModeOutput = "output"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Status -- get current state of the log
+func Status() StatusInfo {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	return currentStatus()
+}
+
+// Must be called under the mutex
+func currentStatus() (status StatusInfo) {
+	status.Mode = currentMode()
+	status.FileName = fileName
+	status.FileNamePattern = fileNamePattern
+	status.Tier = fileTier
+	checkWriteError()
+	if lastError != nil {
+		status.LastError = lastError.Error()
+	}
+	status.LocalTime = localTime
+	status.Storms = stormFacilities()
+	status.Quotas = quotaUsage()
+	status.DestinationErrors = destinationErrors()
+	status.ClockAnomalies = clockAnomalies
+	status.Events = EventIDs()
+	status.UnregisteredEvents = unregistered.Load()
+	status.Stats = GetStats()
+
+	return
+}
+
+// Must be called under the mutex
+func currentMode() string {
+	switch {
+	case memoryMode:
+		return ModeMemory
+	case outputWriter != nil:
+		return ModeOutput
+	case fileNamePattern == "":
+		return ModeUnconfigured
+	case fileNamePattern == "-":
+		return ModeStdout
+	default:
+		return ModeFile
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,397 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Without the interception fd 2 is the log file, so runtime tracebacks get there without prefixes.
+// With it fd 2 is the pipe, its reader logs the data with the CRIT level: a traceback starting with "panic:",
+// "fatal error:" or "goroutine " is one message, other lines are logged separately. Off by default.
+//
+// The early capture makes fd 2 the pipe from the start of the process until the log file is opened, so the data written
+// to stderr before that isn't lost on the console. The data is buffered up to the limit, when the file is opened it
+// is written there after the banner as NOTICE lines and fd 2 is pointed to the file (or to the interceptor). If no file
+// is opened the data goes to the unsaved dump on Shutdown.
+//
+// The pipe reader doesn't run after the runtime crashes, so while fd 2 is the pipe the fatal tracebacks are written
+// by the runtime to the crash output as well: the plain log file (it is re-pointed when the file is changed) or
+// the fd 2 saved before the interception. The crash output needs go1.23.
+
+const (
+	stderrSource      = "stderr"
+	earlyStderrSource = "early-stderr"
+	stderrBufSize     = 32 * 1024
+	stderrMaxLines    = 10000 // longer blocks are split
+)
+
+var (
+	stderrQuiet = 20 * time.Millisecond // the block is complete when nothing comes for this period
+
+	stderrIntercepted = false
+	stderrSaved       = -1 // fd 2 before the interception
+	stderrDone        chan struct{}
+
+	earlyStderr *earlyCapture
+)
+
+type earlyCapture struct {
+	saved   int // fd 2 before the capture
+	limit   int
+	done    chan struct{}
+	mutex   sync.Mutex
+	buf     []byte
+	dropped int
+}
+
+type stderrFramer struct {
+	partial   []byte
+	block     []string
+	traceback bool
+	severe    bool
+	emit      func(text string, severe bool)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetStderrInterception -- log the data written to fd 2 (runtime panics included) as CRIT messages.
+// errors.ErrUnsupported is returned on platforms where fd 2 can't be replaced.
+func SetStderrInterception(enabled bool) error {
+	mutex.Lock()
+
+	if enabled == stderrIntercepted {
+		mutex.Unlock()
+		return nil
+	}
+
+	if enabled {
+		endEarlyStderr()
+	}
+
+	if !enabled {
+		err := restoreStderr(stderrSaved)
+		stderrSaved = -1
+		stderrIntercepted = false
+		updateCrashOutput()
+		done := stderrDone
+		stderrDone = nil
+		mutex.Unlock()
+
+		// The pipe is closed, the reader logs the rest
+		<-done
+		return err
+	}
+
+	defer mutex.Unlock()
+
+	if file != nil && file.Fd() == 2 {
+		return errors.New("fd 2 is the log file")
+	}
+
+	r, saved, err := interceptStderr()
+	if err != nil {
+		return err
+	}
+
+	if os.Stderr == nil || os.Stderr.Fd() != 2 {
+		if os.Stderr != nil {
+			os.Stderr.Close()
+		}
+		os.Stderr = os.NewFile(2, "/dev/stderr")
+	}
+
+	stderrSaved = saved
+	stderrIntercepted = true
+	stderrDone = make(chan struct{})
+	go stderrReader(r, stderrDone)
+
+	updateCrashOutput()
+
+	return nil
+}
+
+// CaptureEarlyStderr -- buffer up to limitKB kilobytes written to fd 2 until the log file is opened.
+// It should be the first statement of the application. The repeated call does nothing.
+// errors.ErrUnsupported is returned on platforms where fd 2 can't be replaced.
+func CaptureEarlyStderr(limitKB int) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if earlyStderr != nil || stderrIntercepted {
+		return nil
+	}
+
+	if file != nil {
+		return errors.New("the log file is already open")
+	}
+
+	r, saved, err := interceptStderr()
+	if err != nil {
+		return err
+	}
+
+	earlyStderr = &earlyCapture{
+		saved: saved,
+		limit: max(limitKB, 1) * 1024,
+		done:  make(chan struct{}),
+	}
+	go earlyStderr.read(r)
+
+	updateCrashOutput()
+
+	return nil
+}
+
+// redirectStderr -- make the log file the stderr. The closed fd 2 is the lowest free descriptor, so the file is opened as fd 2
+// and runtime tracebacks get there too. The encrypted and the compressed files don't get the plain text, it would
+// corrupt them. Must be called under the mutex.
+func redirectStderr() {
+	endEarlyStderr()
+
+	if stderrIntercepted {
+		updateCrashOutput()
+		return
+	}
+
+	if encryptionKey != nil || compression != CompressionNone {
+		return
+	}
+
+	os.Stderr.Close()
+	os.Stderr, _ = os.OpenFile(fileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+}
+
+// updateCrashOutput -- point the crash output to the plain log file or to the saved fd 2 while fd 2 is the pipe,
+// stop it otherwise. Must be called under the mutex.
+func updateCrashOutput() {
+	saved := -1
+
+	switch {
+	case earlyStderr != nil:
+		saved = earlyStderr.saved
+	case !stderrIntercepted:
+		setCrashOutput(nil)
+		return
+	case file != nil && encryptionKey == nil && compression == CompressionNone:
+		setCrashOutput(file)
+		return
+	default:
+		saved = stderrSaved
+	}
+
+	f := stderrFile(saved)
+	setCrashOutput(f)
+	if f != nil {
+		f.Close()
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// endEarlyStderr -- stop the early capture, fd 2 is restored and the captured lines go to the buffer of the file.
+// Must be called under the mutex.
+func endEarlyStderr() {
+	c := earlyStderr
+	if c == nil {
+		return
+	}
+	earlyStderr = nil
+
+	// The write end is closed, the reader gets the rest and stops
+	restoreStderr(c.saved)
+	updateCrashOutput()
+	<-c.done
+
+	beforeFileBuf = append(beforeFileBuf, c.lines()...)
+}
+
+// dumpEarlyStderr -- the file isn't opened, the captured lines go to the unsaved dump. Must be called under the mutex.
+func dumpEarlyStderr() {
+	if earlyStderr == nil {
+		return
+	}
+
+	n := len(beforeFileBuf)
+	endEarlyStderr()
+	lines := beforeFileBuf[n:]
+	beforeFileBuf = beforeFileBuf[:n]
+
+	if len(lines) == 0 {
+		return
+	}
+
+	fd, err := os.OpenFile(dumpFileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return
+	}
+	defer fd.Close()
+
+	for _, s := range lines {
+		fd.Write([]byte(s))
+	}
+}
+
+func (c *earlyCapture) read(r *os.File) {
+	defer close(c.done)
+	defer r.Close()
+
+	buf := make([]byte, stderrBufSize)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			c.mutex.Lock()
+			keep := min(n, c.limit-len(c.buf))
+			c.buf = append(c.buf, buf[:keep]...)
+			c.dropped += n - keep
+			c.mutex.Unlock()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// lines -- the captured data as NOTICE lines. Must be called under the mutex after the reader is stopped.
+func (c *earlyCapture) lines() (list []string) {
+	text := strings.TrimRight(string(c.buf), "\r\n")
+	if c.dropped > 0 {
+		text += fmt.Sprintf("\n... %d bytes dropped", c.dropped)
+	}
+	if text == "" {
+		return
+	}
+
+	t := levelStamp(NOTICE)
+	for _, s := range strings.Split(text, "\n") {
+		s = strings.TrimSuffix(s, "\r")
+		if s == "" {
+			continue
+		}
+		_, prefix := formatPrefix(0, StdFacilityName, NOTICE, t, FuncNameInherit)
+		list = append(list, finishLine(prefix+"["+earlyStderrSource+"] "+s))
+	}
+	return
+}
+
+func stderrReader(r *os.File, done chan struct{}) {
+	defer close(done)
+	defer r.Close()
+
+	fr := &stderrFramer{emit: stderrEmit}
+	buf := make([]byte, stderrBufSize)
+
+	for {
+		if fr.pending() {
+			r.SetReadDeadline(time.Now().Add(stderrQuiet))
+		} else {
+			r.SetReadDeadline(time.Time{})
+		}
+
+		n, err := r.Read(buf)
+		if n > 0 {
+			fr.write(buf[:n])
+		}
+
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			fr.end()
+			continue
+		}
+		if err != nil {
+			fr.end()
+			return
+		}
+	}
+}
+
+// stderrEmit -- the severe block is flushed to the file at once, the process is probably dying
+func stderrEmit(text string, severe bool) {
+	if !severe && runtimeTraceEmit(text) {
+		return
+	}
+
+	stdFacility.MessageWithSource(CRIT, stderrSource, "%s", text)
+
+	if severe {
+		writerFlush()
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func (fr *stderrFramer) pending() bool {
+	return len(fr.partial) > 0 || len(fr.block) > 0
+}
+
+func (fr *stderrFramer) write(p []byte) {
+	fr.partial = append(fr.partial, p...)
+
+	for {
+		i := bytes.IndexByte(fr.partial, '\n')
+		if i < 0 {
+			break
+		}
+		line := strings.TrimSuffix(string(fr.partial[:i]), "\r")
+		fr.partial = fr.partial[i+1:]
+		fr.line(line)
+	}
+
+	if len(fr.partial) == 0 {
+		fr.partial = nil
+	}
+}
+
+func (fr *stderrFramer) line(s string) {
+	switch {
+	case strings.HasPrefix(s, "panic: ") || strings.HasPrefix(s, "fatal error: "):
+		fr.flush()
+		fr.traceback = true
+		fr.severe = true
+		writerFlush()
+
+	case strings.HasPrefix(s, "goroutine ") && !fr.traceback:
+		fr.flush()
+		fr.traceback = true
+
+	case !fr.traceback:
+		if s != "" {
+			fr.emit(s, false)
+		}
+		return
+	}
+
+	fr.block = append(fr.block, s)
+	if len(fr.block) >= stderrMaxLines {
+		fr.flush()
+	}
+}
+
+// end -- log the incomplete line and the current block
+func (fr *stderrFramer) end() {
+	if len(fr.partial) > 0 {
+		s := string(fr.partial)
+		fr.partial = nil
+		fr.line(s)
+	}
+
+	fr.flush()
+}
+
+// flush -- log the current block
+func (fr *stderrFramer) flush() {
+	if len(fr.block) > 0 {
+		fr.emit(strings.TrimRight(strings.Join(fr.block, "\n"), "\n"), fr.severe)
+	}
+
+	fr.block = nil
+	fr.traceback = false
+	fr.severe = false
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
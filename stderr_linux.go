@@ -0,0 +1,80 @@
+package log
+
+import (
+	"os"
+	"syscall"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// interceptStderr -- make fd 2 the write end of the pipe, the read end and the copy of the previous fd 2 are returned.
+// The closed fd 2 is replaced by /dev/null first, otherwise the pipe could take it.
+func interceptStderr() (r *os.File, saved int, err error) {
+	saved, err = syscall.Dup(2)
+	if err == syscall.EBADF {
+		saved = -1
+		if err = occupyStderr(); err != nil {
+			return nil, -1, err
+		}
+	} else if err != nil {
+		return nil, -1, err
+	} else {
+		syscall.CloseOnExec(saved)
+	}
+
+	r, w, err := os.Pipe()
+	if err == nil {
+		err = syscall.Dup3(int(w.Fd()), 2, 0)
+		w.Close()
+	}
+
+	if err != nil {
+		if r != nil {
+			r.Close()
+		}
+		restoreStderr(saved)
+		return nil, -1, err
+	}
+
+	return r, saved, nil
+}
+
+func occupyStderr() error {
+	fd, err := syscall.Open(os.DevNull, syscall.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	if fd == 2 {
+		return nil
+	}
+
+	defer syscall.Close(fd)
+	return syscall.Dup3(fd, 2, 0)
+}
+
+// restoreStderr -- make fd 2 the saved one again, -1 means fd 2 was closed
+func restoreStderr(saved int) error {
+	if saved < 0 {
+		return syscall.Close(2)
+	}
+
+	defer syscall.Close(saved)
+	return syscall.Dup3(saved, 2, 0)
+}
+
+// stderrFile -- the copy of the saved fd 2, nil if fd 2 was closed
+func stderrFile(saved int) *os.File {
+	if saved < 0 {
+		return nil
+	}
+
+	fd, err := syscall.Dup(saved)
+	if err != nil {
+		return nil
+	}
+	syscall.CloseOnExec(fd)
+
+	return os.NewFile(uintptr(fd), "/dev/stderr")
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,24 @@
+//go:build !linux
+
+package log
+
+import (
+	"errors"
+	"os"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func interceptStderr() (r *os.File, saved int, err error) {
+	return nil, -1, errors.ErrUnsupported
+}
+
+func restoreStderr(saved int) error {
+	return nil
+}
+
+func stderrFile(saved int) *os.File {
+	return nil
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
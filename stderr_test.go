@@ -0,0 +1,233 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+const fakeTraceback = `panic: fake failure
+
+goroutine 7 [running]:
+main.worker(0x1)
+	/src/main.go:12 +0x1d
+created by main.main in goroutine 1
+	/src/main.go:20 +0x25
+
+goroutine 1 [sleep]:
+time.Sleep(0x3b9aca00)
+	/usr/local/go/src/runtime/time.go:195 +0x125
+exit status 2
+`
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestStderrFramer(t *testing.T) {
+	type emitted struct {
+		text   string
+		severe bool
+	}
+	var list []emitted
+
+	fr := &stderrFramer{
+		emit: func(text string, severe bool) {
+			list = append(list, emitted{text, severe})
+		},
+	}
+
+	fr.write([]byte("plain 1\nplain"))
+	fr.write([]byte(" 2\n\n"))
+
+	// The traceback comes by parts
+	for i := 0; i < len(fakeTraceback); i += 10 {
+		fr.write([]byte(fakeTraceback[i:min(i+10, len(fakeTraceback))]))
+	}
+	fr.end()
+
+	fr.write([]byte("goroutine 3 [chan receive]:\nmain.f()\nincomplete"))
+	fr.end()
+
+	expected := []emitted{
+		{"plain 1", false},
+		{"plain 2", false},
+		{strings.TrimSuffix(fakeTraceback, "\n"), true},
+		{"goroutine 3 [chan receive]:\nmain.f()\nincomplete", false},
+	}
+
+	if len(list) != len(expected) {
+		t.Fatalf("got %d messages %+v, expected %d", len(list), list, len(expected))
+	}
+	for i, e := range expected {
+		if list[i] != e {
+			t.Errorf("%d: got %+v, expected %+v", i, list[i], e)
+		}
+	}
+}
+
+func TestStderrInterception(t *testing.T) {
+	resetLog(t)
+
+	err := SetStderrInterception(true)
+	if errors.Is(err, errors.ErrUnsupported) {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	os.Stderr.WriteString(fakeTraceback)
+
+	var list []LogEntry
+	for i := 0; i < 200 && len(list) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+		list = LastLogQuery(LastLogFilter{MinLevel: CRIT, Contains: "panic: fake failure"})
+	}
+
+	if err := SetStderrInterception(false); err != nil {
+		t.Error(err)
+	}
+
+	if len(list) != 1 {
+		t.Fatalf("got %d records", len(list))
+	}
+	if e := list[0]; e.Level != CRIT || !strings.HasSuffix(e.Text, "[stderr] "+strings.TrimSuffix(fakeTraceback, "\n")) {
+		t.Errorf("unexpected record %+v", e)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestCaptureEarlyStderr(t *testing.T) {
+	resetLog(t)
+
+	err := CaptureEarlyStderr(1)
+	if errors.Is(err, errors.ErrUnsupported) {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CaptureEarlyStderr(1); err != nil {
+		t.Fatal(err)
+	}
+
+	os.Stderr.WriteString("early warning\nsecond line\n")
+
+	SetFile(t.TempDir(), "", false, 0, 0)
+	Message(INFO, "first message")
+	os.Stderr.WriteString("late write\n")
+	writerFlush()
+
+	lines := waitFile(t, FileName(), 5)
+	expected := []string{" was launched at ", "] NO .* [early-stderr] early warning", "] NO .* [early-stderr] second line", " first message", "late write"}
+	if len(lines) != len(expected) {
+		t.Fatalf("unexpected file %q", lines)
+	}
+	for i, e := range expected {
+		if before, after, found := strings.Cut(e, ".*"); found {
+			if !strings.Contains(lines[i], before) || !strings.HasSuffix(lines[i], after) {
+				t.Errorf("unexpected line %q", lines[i])
+			}
+		} else if !strings.Contains(lines[i], e) {
+			t.Errorf("unexpected line %q", lines[i])
+		}
+	}
+	if lines[4] != "late write" {
+		t.Errorf("stderr isn't the file: %q", lines[4])
+	}
+}
+
+func TestCaptureEarlyStderrDump(t *testing.T) {
+	resetLog(t)
+	defer Start()
+
+	err := CaptureEarlyStderr(1)
+	if errors.Is(err, errors.ErrUnsupported) {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	os.Stderr.WriteString("never logged\n" + strings.Repeat("x", 2000) + "\n")
+
+	if err := Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(dumpFileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := string(data)
+	if !strings.Contains(s, "] NO ") || !strings.Contains(s, " [early-stderr] never logged\n") || !strings.Contains(s, " [early-stderr] ... 990 bytes dropped\n") ||
+		strings.Count(s, "x") != 1024-len("never logged\n") {
+		t.Errorf("unexpected dump:\n%s", s)
+	}
+}
+
+// TestStderrCrashOutput -- runs itself in the separate process that crashes with fd 2 intercepted
+func TestStderrCrashOutput(t *testing.T) {
+	if mode := os.Getenv("LOG_TEST_CRASH_OUTPUT"); mode != "" {
+		var err error
+		if mode == "early" {
+			err = CaptureEarlyStderr(1)
+		} else {
+			SetFile(mode, "", false, 0, 0)
+			Message(INFO, "before the crash")
+			err = SetStderrInterception(true)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		go func() {
+			panic("crash output test")
+		}()
+		time.Sleep(time.Second)
+		return
+	}
+
+	if !crashOutputSupported || runtime.GOOS != "linux" {
+		t.Skip("no crash output")
+	}
+
+	run := func(mode string) string {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestStderrCrashOutput$")
+		cmd.Env = append(os.Environ(), "LOG_TEST_CRASH_OUTPUT="+mode)
+		out, err := cmd.CombinedOutput()
+		if err == nil {
+			t.Fatalf("[%s] no crash\n%s", mode, out)
+		}
+		return string(out)
+	}
+
+	// The traceback goes to the log file
+	dir := t.TempDir()
+	out := run(dir)
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.log"))
+	if len(files) != 1 {
+		t.Fatalf("got files %q\n%s", files, out)
+	}
+	data, _ := os.ReadFile(files[0])
+	if s := string(data); !strings.Contains(s, " before the crash\n") || !strings.Contains(s, "\npanic: crash output test\n\ngoroutine ") {
+		t.Errorf("no traceback in the file:\n%s", s)
+	}
+
+	// The early capture: the traceback goes to the original stderr
+	out = run("early")
+	if !strings.Contains(out, "panic: crash output test\n\ngoroutine ") {
+		t.Errorf("no traceback in stderr:\n%s", out)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,40 @@
+package log
+
+import (
+	"io"
+	"os"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// With the "-" directory lines are written to stdout through the same buffer and flusher as the file.
+// The default console mirror is skipped in this mode, otherwise every line would be written twice.
+
+// stdout -- destination of the stdout mode, replaced in tests
+var stdout io.Writer = os.Stdout
+
+type stdoutWriter struct {
+	w io.Writer
+}
+
+func (s stdoutWriter) Write(p []byte) (int, error) {
+	return s.w.Write(p)
+}
+
+func (stdoutWriter) Close() error {
+	return nil
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// consoleIsStdout -- stdout mode with the default console writer. Must be called under the mutex.
+func consoleIsStdout() bool {
+	if fileNamePattern != "-" || outputWriter != nil || memoryMode {
+		return false
+	}
+
+	_, isDefault := consoleWriter.(*ConsoleWriter)
+	return isDefault
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,70 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestStdoutMode(t *testing.T) {
+	resetLog(t)
+
+	// The flusher would write the buffer before the check
+	if BackgroundRunning() {
+		StopBackground()
+		t.Cleanup(StartBackground)
+	}
+
+	fd, err := os.Create(filepath.Join(t.TempDir(), "stdout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+
+	stdout = fd
+	defer func() { stdout = os.Stdout }()
+
+	SetConsoleWriter(nil)
+	SetFile("-", "", false, 4096, 0)
+
+	for i := 0; i < 3; i++ {
+		Message(INFO, "line %d", i)
+	}
+
+	read := func() string {
+		data, err := os.ReadFile(fd.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	if s := read(); s != "" {
+		t.Fatalf("lines are not buffered:\n%s", s)
+	}
+
+	writerFlush()
+
+	s := read()
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	if len(lines) != 4 || !strings.Contains(lines[0], " was launched at ") || strings.Count(s, " was launched at ") != 1 {
+		t.Fatalf("unexpected stdout:\n%s", s)
+	}
+	for i, line := range lines[1:] {
+		if !strings.HasSuffix(line, " line "+string(rune('0'+i))) {
+			t.Errorf("[%d] unexpected line %q", i, line)
+		}
+	}
+
+	if name := FileName(); name != "-" {
+		t.Errorf("got file name %q, expected \"-\"", name)
+	}
+	if mode := Status().Mode; mode != ModeStdout {
+		t.Errorf("got mode %q, expected %q", mode, ModeStdout)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,192 @@
+package log
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// StormAction -- what to do with the facility producing too many messages
+type StormAction string
+
+const (
+	// StormThrottle -- only WARNING and more severe messages pass during the storm
+	StormThrottle = StormAction("throttle")
+	// StormDropDuplicates -- messages identical to the previous one are dropped during the storm
+	StormDropDuplicates = StormAction("dropDuplicates")
+	// StormAlertOnly -- only report the storm
+	StormAlertOnly = StormAction("alertOnly")
+)
+
+// stormState -- the messages are counted in the windows aligned to the window length, the window is identified by
+// its epoch: the time divided by the window length
+type stormState struct {
+	count  int64        // the count and the epoch are the only things touched per message while the facility is quiet
+	epoch  atomic.Int64 // epoch of the counted window
+	active atomic.Bool
+
+	mutex   sync.Mutex
+	last    string
+	dropped int64
+}
+
+var (
+	stormThreshold = int64(0)
+	stormWindow    = time.Duration(0)
+	stormWindowNs  = int64(0) // stormWindow for the lock-free path
+	stormAction    = StormAlertOnly
+	stormActionLF  atomic.Pointer[StormAction] // stormAction for the lock-free path, nil is StormAlertOnly
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetStormProtection -- detect facilities logging more than threshold messages per window. Zero threshold disables it.
+// Entering the storm mode is logged as CRIT, leaving as NOTICE.
+func SetStormProtection(threshold int, window time.Duration, action StormAction) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	setStormProtection(threshold, window, action)
+}
+
+// Must be called under the mutex
+func setStormProtection(threshold int, window time.Duration, action StormAction) {
+	if threshold <= 0 || window <= 0 {
+		threshold = 0
+	}
+
+	atomic.StoreInt64(&stormThreshold, int64(threshold))
+	atomic.StoreInt64(&stormWindowNs, int64(window))
+	stormWindow = window
+	stormAction = action
+	stormActionLF.Store(&action)
+
+	for _, f := range facilities {
+		f.storm.mutex.Lock()
+		atomic.StoreInt64(&f.storm.count, 0)
+		f.storm.epoch.Store(0)
+		f.storm.active.Store(false)
+		f.storm.mutex.Unlock()
+	}
+}
+
+// StormFacilities -- facilities in the storm mode
+func StormFacilities() []string {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	return stormFacilities()
+}
+
+// Must be called under the mutex
+func stormFacilities() (list []string) {
+	for name, f := range facilities {
+		if f.storm.active.Load() {
+			list = append(list, name)
+		}
+	}
+	return
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// stormDrop -- count the message and decide if it must be dropped. Duplicates are found by the event ID if it is set.
+func stormDrop(f *Facility, level Level, eventID string, message string, params []any) bool {
+	threshold := atomic.LoadInt64(&stormThreshold)
+	if threshold == 0 {
+		return false
+	}
+
+	window := atomic.LoadInt64(&stormWindowNs)
+	if window <= 0 {
+		return false
+	}
+	epoch := now().UnixNano() / window
+
+	s := &f.storm
+	counted := s.epoch.Load() == epoch
+	if counted && atomic.AddInt64(&s.count, 1) <= threshold && !s.active.Load() {
+		return false
+	}
+
+	// The transitions are logged after the facility mutex is released: logger takes the global mutex, and
+	// setStormProtection takes the facility mutexes under it
+	action := StormAlertOnly
+	if a := stormActionLF.Load(); a != nil {
+		action = *a
+	}
+
+	drop, over, dropped, detected := stormCheck(s, epoch, counted, threshold, action, level, eventID, message, params)
+
+	if over {
+		logger(true, 0, f.name, NOTICE, nil, "Log storm is over, %d messages dropped", dropped)
+	}
+	if detected {
+		logger(true, 0, f.name, CRIT, nil, "Log storm detected: more than %d messages in %s, action %s", threshold, time.Duration(window), action)
+	}
+
+	return drop
+}
+
+// stormCheck -- the part of stormDrop done under the facility mutex
+func stormCheck(s *stormState, epoch int64, counted bool, threshold int64, action StormAction, level Level, eventID string, message string, params []any) (drop bool, over bool, dropped int64, detected bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if prev := s.epoch.Load(); epoch > prev {
+		// The window is over, start the new one. The count is reset before the epoch is changed, so the messages
+		// counted by the lock-free path with the new epoch aren't lost.
+		count := atomic.LoadInt64(&s.count)
+		atomic.StoreInt64(&s.count, 0)
+		s.epoch.Store(epoch)
+		counted = false
+
+		if s.active.Load() && (count <= threshold || epoch > prev+1) {
+			s.active.Store(false)
+			over = true
+			dropped = s.dropped
+			s.dropped = 0
+		}
+	}
+
+	count := atomic.LoadInt64(&s.count)
+	if !counted {
+		// The message of the late or the new window
+		count = atomic.AddInt64(&s.count, 1)
+	}
+
+	if !s.active.Load() {
+		if count <= threshold {
+			return
+		}
+		s.active.Store(true)
+		s.last = ""
+		detected = true
+	}
+
+	switch action {
+	case StormThrottle:
+		drop = !level.passes(WARNING)
+	case StormDropDuplicates:
+		var text string
+		if eventID != "" {
+			text = fmt.Sprintf("%d %s", level, eventToken(eventID))
+		} else {
+			text = fmt.Sprintf("%d %s", level, formatMessage(message, params))
+		}
+		drop = text == s.last
+		s.last = text
+	}
+
+	if drop {
+		s.dropped++
+		statDrop()
+	}
+
+	return
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,164 @@
+package log
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestStormThrottle(t *testing.T) {
+	console := resetLog(t)
+
+	clock := setFakeClock(time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC))
+
+	SetStormProtection(10, time.Minute, StormThrottle)
+
+	f := GetFacility("db")
+	for i := 0; i < 100; i++ {
+		f.Message(INFO, "info %d", i)
+		f.Message(ERR, "error %d", i)
+		clock.Add(100 * time.Millisecond)
+	}
+
+	s := console.String()
+	if !strings.Contains(s, " CR ") || !strings.Contains(s, "<db> Log storm detected") {
+		t.Fatalf("storm is not detected:\n%s", s)
+	}
+	if strings.Contains(s, "info 10") || !strings.Contains(s, "error 99") {
+		t.Errorf("storm is not throttled:\n%s", s)
+	}
+	if st := Status().Storms; len(st) != 1 || st[0] != "db" {
+		t.Errorf("unexpected status %q", st)
+	}
+
+	// Quiet window
+	clock.Add(time.Minute)
+	f.Message(INFO, "quiet 1")
+	clock.Add(time.Minute)
+	f.Message(INFO, "quiet 2")
+
+	s = console.String()
+	if !strings.Contains(s, " NO ") || !strings.Contains(s, "<db> Log storm is over") || !strings.Contains(s, "quiet 2") {
+		t.Errorf("storm is not over:\n%s", s)
+	}
+	if st := Status().Storms; len(st) != 0 {
+		t.Errorf("unexpected status %q", st)
+	}
+}
+
+func TestStormSlowRate(t *testing.T) {
+	console := resetLog(t)
+
+	clock := setFakeClock(time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC))
+
+	SetStormProtection(10, time.Second, StormThrottle)
+
+	// More than the threshold in total, but far below the rate
+	f := GetFacility("db")
+	for i := 0; i < 100; i++ {
+		f.Message(INFO, "slow %d", i)
+		clock.Add(time.Minute)
+	}
+
+	s := console.String()
+	if strings.Contains(s, "Log storm detected") || !strings.Contains(s, "slow 99") {
+		t.Errorf("false storm:\n%s", s)
+	}
+	if st := Status().Storms; len(st) != 0 {
+		t.Errorf("unexpected status %q", st)
+	}
+
+	// The burst within one window is still detected
+	for i := 0; i < 20; i++ {
+		f.Message(INFO, "burst %d", i)
+	}
+	if s := console.String(); !strings.Contains(s, "Log storm detected") || strings.Contains(s, "burst 10") {
+		t.Errorf("storm is not detected:\n%s", s)
+	}
+}
+
+func TestStormDropDuplicates(t *testing.T) {
+	console := resetLog(t)
+
+	setFakeClock(time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC))
+
+	SetStormProtection(5, time.Minute, StormDropDuplicates)
+
+	for i := 0; i < 20; i++ {
+		Message(WARNING, "retry failed")
+		if i%5 == 0 {
+			Message(WARNING, "other %d", i)
+		}
+	}
+
+	n := strings.Count(console.String(), "retry failed")
+	// 5 before the storm, then only the ones following "other"
+	if n != 8 {
+		t.Errorf("got %d duplicates, expected 8:\n%s", n, console)
+	}
+}
+
+func TestStormAlertOnly(t *testing.T) {
+	console := resetLog(t)
+
+	setFakeClock(time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC))
+
+	SetStormProtection(5, time.Minute, StormAlertOnly)
+
+	for i := 0; i < 20; i++ {
+		Message(INFO, "message")
+	}
+
+	s := console.String()
+	if strings.Count(s, " message\n") != 20 || !strings.Contains(s, "Log storm detected") {
+		t.Errorf("unexpected output:\n%s", s)
+	}
+}
+
+func TestStormReconfigure(t *testing.T) {
+	resetLog(t)
+
+	// The transitions are logged while the settings are changed
+	stop := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				SetStormProtection(1, time.Minute, StormAlertOnly)
+			}
+		}
+	}()
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f := GetFacility("storm")
+			for j := 0; j < 2000; j++ {
+				f.Message(INFO, "message %d", j)
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(stop)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(30 * time.Second):
+		t.Fatal("deadlock")
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
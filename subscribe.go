@@ -0,0 +1,112 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/alrusov/misc"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+type subscriber struct {
+	all      bool
+	facility string
+	ch       chan string
+	skipped  int
+}
+
+var (
+	subscriberID = int64(0)
+	subscribers  = map[int64]*subscriber{}
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Subscribe -- receive formatted lines of the facility.
+// If the subscriber falls behind the oldest lines are dropped and "[N messages skipped]" is delivered instead.
+// cancel closes the channel, it is safe to call it more than once.
+func (f *Facility) Subscribe(buffer int) (ch <-chan string, cancel func()) {
+	return subscribe(false, f.name, buffer)
+}
+
+// SubscribeAll -- receive formatted lines of all facilities
+func SubscribeAll(buffer int) (ch <-chan string, cancel func()) {
+	return subscribe(true, "", buffer)
+}
+
+func subscribe(all bool, facility string, buffer int) (<-chan string, func()) {
+	if buffer < 2 {
+		buffer = 2
+	}
+
+	s := &subscriber{
+		all:      all,
+		facility: facility,
+		ch:       make(chan string, buffer),
+	}
+
+	mutex.Lock()
+	subscriberID++
+	id := subscriberID
+	subscribers[id] = s
+	mutex.Unlock()
+
+	once := new(sync.Once)
+	cancel := func() {
+		once.Do(func() {
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			// The subscription can be removed by RestoreConfig
+			if _, exists := subscribers[id]; exists {
+				delete(subscribers, id)
+				close(s.ch)
+			}
+		})
+	}
+
+	return s.ch, cancel
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Must be called under the mutex
+func notifySubscribers(facility string, text string) {
+	if len(subscribers) == 0 {
+		return
+	}
+
+	text = strings.TrimSuffix(text, misc.EOS)
+
+	for _, s := range subscribers {
+		if !s.all && s.facility != facility {
+			continue
+		}
+		s.send(text)
+	}
+}
+
+func (s *subscriber) send(text string) {
+	if s.skipped > 0 && cap(s.ch)-len(s.ch) >= 2 {
+		s.ch <- fmt.Sprintf("[%d messages skipped]", s.skipped)
+		s.skipped = 0
+	}
+
+	for {
+		select {
+		case s.ch <- text:
+			return
+		default:
+		}
+
+		select {
+		case <-s.ch:
+			s.skipped++
+		default:
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
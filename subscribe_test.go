@@ -0,0 +1,88 @@
+package log
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestSubscribe(t *testing.T) {
+	resetLog(t)
+
+	goroutines := runtime.NumGoroutine()
+
+	f := GetFacility("replication")
+	ch, cancel := f.Subscribe(10)
+	chAll, cancelAll := SubscribeAll(10)
+
+	f.Message(INFO, "one")
+	Message(INFO, "two")
+	f.Message(INFO, "three")
+
+	for _, exp := range []string{"one", "three"} {
+		s := <-ch
+		if !strings.HasSuffix(s, "<replication> "+exp) {
+			t.Errorf("got %q, expected %q", s, exp)
+		}
+	}
+	if len(ch) != 0 {
+		t.Errorf("unexpected %d lines left", len(ch))
+	}
+
+	if len(chAll) != 3 {
+		t.Errorf("got %d lines, expected 3", len(chAll))
+	}
+
+	cancel()
+	cancel()
+	cancelAll()
+
+	f.Message(INFO, "after cancel")
+
+	if _, ok := <-ch; ok {
+		t.Error("channel is not closed")
+	}
+
+	mutex.Lock()
+	n := len(subscribers)
+	mutex.Unlock()
+	if n != 0 {
+		t.Errorf("%d subscribers left", n)
+	}
+
+	if delta := runtime.NumGoroutine() - goroutines; delta > 0 {
+		t.Errorf("%d goroutines leaked", delta)
+	}
+}
+
+func TestSubscribeOverflow(t *testing.T) {
+	resetLog(t)
+
+	ch, cancel := SubscribeAll(3)
+	defer cancel()
+
+	for i := 0; i < 10; i++ {
+		Message(INFO, "message %d", i)
+	}
+
+	// The buffer keeps the newest lines, the rest are counted
+	got := []string{<-ch, <-ch, <-ch}
+	for i, exp := range []string{"message 7", "message 8", "message 9"} {
+		if !strings.HasSuffix(got[i], exp) {
+			t.Errorf("[%d] got %q, expected %q", i, got[i], exp)
+		}
+	}
+
+	Message(INFO, "next")
+
+	if s := <-ch; s != "[7 messages skipped]" {
+		t.Errorf("got %q, expected skip marker", s)
+	}
+	if s := <-ch; !strings.HasSuffix(s, "next") {
+		t.Errorf("got %q, expected next", s)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,150 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alrusov/misc"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// The "<suffix or app>.current.log" link in the log directory points to the opened file, the ".previous.log" one points
+// to the file opened before it. Links are replaced atomically by the renaming. If the symlink can't be created
+// the link is the text file with the target path. Errors never break the rotation, the first one is reported
+// with WARNING. Off by default.
+
+const (
+	linkCurrent  = "current"
+	linkPrevious = "previous"
+)
+
+var (
+	maintainSymlinks = false
+	symlinksWarned   = false
+
+	// symlink -- link creation, replaced in tests
+	symlink = os.Symlink
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetMaintainSymlinks -- keep the current and the previous links in the log directory
+func SetMaintainSymlinks(enabled bool) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	maintainSymlinks = enabled
+	if enabled && file != nil {
+		updateSymlinks(fileName)
+	}
+}
+
+// LinkName -- the path of the current or the previous link
+func LinkName(previous bool) string {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if previous {
+		return linkPath(linkPrevious)
+	}
+	return linkPath(linkCurrent)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// linkPath -- "<directory>/<suffix or app>.<kind>.log". Must be called under the mutex.
+func linkPath(kind string) string {
+	base := filepath.Base(fileNamePattern)
+	base = strings.TrimPrefix(base[:strings.Index(base+".log", ".log")], "%s")
+	base = strings.TrimPrefix(base, "-")
+	if base == "" {
+		base = misc.AppName()
+	}
+
+	return filepath.Join(fileDirectory, base+"."+kind+".log")
+}
+
+// updateSymlinks -- the opened file becomes current, the current one becomes previous. Must be called under the mutex.
+func updateSymlinks(name string) {
+	if !maintainSymlinks || fileNamePattern == "" || fileNamePattern == "-" {
+		return
+	}
+
+	current := linkPath(linkCurrent)
+
+	old := readLink(current)
+	if old == name {
+		return
+	}
+
+	if old != "" {
+		if err := replaceLink(linkPath(linkPrevious), old); err != nil {
+			symlinkFailed(err)
+		}
+	}
+
+	if err := replaceLink(current, name); err != nil {
+		symlinkFailed(err)
+	}
+}
+
+// readLink -- the absolute target of the symlink or of the text file link, empty if there is no link
+func readLink(link string) string {
+	target, err := os.Readlink(link)
+	if err != nil {
+		data, err := os.ReadFile(link)
+		if err != nil {
+			return ""
+		}
+		target = strings.TrimSpace(string(data))
+	}
+
+	if target != "" && !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(link), target)
+	}
+	return target
+}
+
+// replaceLink -- create the temporary symlink (or text file) and rename it to the link
+func replaceLink(link string, target string) error {
+	if filepath.Dir(target) == filepath.Dir(link) {
+		target = filepath.Base(target)
+	}
+
+	tmp := fmt.Sprintf("%s.%d.tmp", link, pid)
+	os.Remove(tmp)
+
+	err := symlink(target, tmp)
+	if err != nil {
+		symlinkFailed(fmt.Errorf("%s, the text file is used", err))
+		if err = os.WriteFile(tmp, []byte(target+misc.EOS), 0644); err != nil {
+			return err
+		}
+	}
+
+	if err = os.Rename(tmp, link); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return nil
+}
+
+// symlinkFailed -- report the first error. Must be called under the mutex.
+func symlinkFailed(err error) {
+	if symlinksWarned {
+		return
+	}
+	symlinksWarned = true
+
+	line := formatDirectLine(WARNING, fmt.Sprintf("Log file links: %s", err))
+	if dst != nil {
+		write(line)
+	}
+	writeToConsole(line)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,90 @@
+package log
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestSymlinksRotation(t *testing.T) {
+	for _, async := range []bool{false, true} {
+		resetLog(t)
+		clock := setFakeClock(time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC))
+
+		dir := t.TempDir()
+		SetFile(dir, "api", false, 0, 0)
+		SetAsyncFileOpen(async)
+		SetMaintainSymlinks(true)
+
+		current := filepath.Join(dir, "api.current.log")
+		previous := filepath.Join(dir, "api.previous.log")
+
+		if LinkName(false) != current || LinkName(true) != previous {
+			t.Fatalf("unexpected links %s, %s", LinkName(false), LinkName(true))
+		}
+
+		day := func(d int) string {
+			return filepath.Join(dir, time.Date(2024, 5, d, 0, 0, 0, 0, time.UTC).Format("2006-01-02")+"-api.log")
+		}
+
+		for i, d := range []int{3, 4, 5} {
+			if i > 0 {
+				clock.Add(24 * time.Hour)
+			}
+			Message(INFO, "day %d", d)
+			waitFile(t, day(d), 2)
+
+			if target, _ := os.Readlink(current); target != filepath.Base(day(d)) {
+				t.Errorf("%v: current link is %q on the day %d", async, target, d)
+			}
+			if data, _ := os.ReadFile(current); !strings.Contains(string(data), " day ") {
+				t.Errorf("%v: current link doesn't lead to the file:\n%s", async, data)
+			}
+
+			target, err := os.Readlink(previous)
+			switch {
+			case i == 0 && err == nil:
+				t.Errorf("%v: previous link %q on the first day", async, target)
+			case i > 0 && target != filepath.Base(day(d-1)):
+				t.Errorf("%v: previous link is %q on the day %d", async, target, d)
+			}
+		}
+	}
+}
+
+func TestSymlinksTextFallback(t *testing.T) {
+	console := resetLog(t)
+	clock := setFakeClock(time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC))
+
+	symlink = func(string, string) error {
+		return errors.New("not supported")
+	}
+
+	dir := t.TempDir()
+	SetFile(dir, "", false, 0, 0)
+	SetMaintainSymlinks(true)
+
+	Message(INFO, "first")
+	clock.Add(24 * time.Hour)
+	Message(INFO, "second")
+
+	if readLink(LinkName(false)) != FileName() || readLink(LinkName(true)) != filepath.Join(dir, "2024-05-03.log") {
+		t.Errorf("unexpected links %q, %q", readLink(LinkName(false)), readLink(LinkName(true)))
+	}
+
+	if n := strings.Count(console.String(), "Log file links: not supported"); n != 1 {
+		t.Errorf("the warning is reported %d times:\n%s", n, console)
+	}
+
+	data, _ := os.ReadFile(FileName())
+	if !strings.Contains(string(data), " second\n") {
+		t.Errorf("the rotation is broken:\n%s", data)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
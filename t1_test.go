@@ -1,7 +1,18 @@
 package log
 
 import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/alrusov/misc"
 )
 
 //----------------------------------------------------------------------------------------------------------------------------//
@@ -10,4 +21,505 @@ func Test1(t *testing.T) {
 	// TODO
 }
 
+func TestPercentMessages(t *testing.T) {
+	w := resetLog(t)
+
+	cases := []struct {
+		legacy  bool
+		message string
+		params  []any
+		expect  string
+	}{
+		{false, "%d", nil, "%d"},
+		{false, "%%", nil, "%%"},
+		{false, "100%", nil, "100%"},
+		{false, "%d", []any{5}, "5"},
+		{false, "100%% of %d", []any{5}, "100% of 5"},
+		{false, "%d%%", []any{100}, "100%"},
+		{true, "%d", nil, "%!d(MISSING)"},
+		{true, "%%", nil, "%"},
+		{true, "100%", nil, "100%!(NOVERB)"},
+		{true, "%d", []any{5}, "5"},
+	}
+
+	for i, c := range cases {
+		SetLegacyFormatting(c.legacy)
+		w.buf.Reset()
+		Message(INFO, c.message, c.params...)
+		if s := strings.TrimSpace(w.String()); !strings.HasSuffix(s, " "+c.expect) {
+			t.Errorf("[%d] got %q, expected suffix %q", i, s, c.expect)
+		}
+	}
+}
+
+func TestSourceInjection(t *testing.T) {
+	w := resetLog(t)
+
+	f := GetFacility("tenant")
+	f.MessageWithSource(INFO, "%s%s%s", "token=%s", "s3cr3t")
+	f.MessageWithSource(INFO, "100%", "no params")
+	SecuredMessageWithSource(INFO, nil, "%v", "%d items", 5)
+
+	expected := []string{
+		"<tenant> [%s%s%s] token=s3cr3t",
+		"<tenant> [100%] no params",
+		" [%v] 5 items",
+	}
+
+	lines := w.Lines()
+	if len(lines) != len(expected) {
+		t.Fatalf("got %d lines, expected %d:\n%s", len(lines), len(expected), w)
+	}
+	for i, e := range expected {
+		if !strings.HasSuffix(lines[i], e) {
+			t.Errorf("[%d] got %q, expected suffix %q", i, lines[i], e)
+		}
+	}
+}
+
+func TestFacilityDisable(t *testing.T) {
+	w := resetLog(t)
+
+	SetFile(t.TempDir(), "", false, 0, 0)
+
+	f := GetFacility("harness")
+	id := f.AddAlertFunc(func(facility string, old Level, new Level) {
+		// Must not deadlock
+		f.Disable()
+	})
+	defer f.DelAlertFunc(id)
+
+	f.SetLogLevel("TRACE4", FuncNameModeNone)
+	if f.Enabled() || CurrentStateOfAll()["harness"].Enabled {
+		t.Fatal("facility is not disabled")
+	}
+
+	f.Message(EMERG, "muted emerg")
+	f.Message(-1*INFO, "muted forced")
+	f.WriteRaw("muted raw")
+	Message(INFO, "std message")
+
+	check := func(what string, text string) {
+		if strings.Contains(text, "muted") {
+			t.Errorf("%s contains muted messages:\n%s", what, text)
+		}
+		if !strings.Contains(text, "std message") {
+			t.Errorf("%s doesn't contain the std message:\n%s", what, text)
+		}
+	}
+
+	data, err := os.ReadFile(FileName())
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("file", string(data))
+	check("last buffer", strings.Join(GetLastLog(), "\n"))
+	check("console", w.String())
+
+	f.Enable()
+	f.Message(INFO, "resumed")
+	if !CurrentStateOfAll()["harness"].Enabled {
+		t.Error("facility is not enabled")
+	}
+	if s := strings.Join(GetLastLog(), "\n"); !strings.Contains(s, "<harness> resumed") {
+		t.Errorf("logging is not resumed:\n%s", s)
+	}
+}
+
+func TestMonotonicTimestamps(t *testing.T) {
+	for _, mode := range []GroupCommitMode{GroupCommitOff, GroupCommitSync, GroupCommitAsync} {
+		t.Run(fmt.Sprintf("GroupCommit%d", mode), func(t *testing.T) {
+			checkMonotonicTimestamps(t, mode)
+		})
+	}
+}
+
+func checkMonotonicTimestamps(t *testing.T, mode GroupCommitMode) {
+	resetLog(t)
+
+	SetFile(t.TempDir(), "", false, 4096, 0)
+	SetGroupCommit(mode)
+	defer SetGroupCommit(GroupCommitOff)
+
+	const (
+		goroutines = 32
+		count      = 500
+	)
+
+	wg := new(sync.WaitGroup)
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			f := GetFacility(fmt.Sprintf("f%d", i%4))
+			for j := 0; j < count; j++ {
+				if j%100 == 0 {
+					f.SetLogLevel("DEBUG", FuncNameModeNone)
+				}
+				f.Message(INFO, "message %d.%d", i, j)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	SetGroupCommit(GroupCommitOff)
+	writerFlush()
+
+	data, err := os.ReadFile(FileName())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := 0
+	prev := ""
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), misc.EOS) {
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			t.Fatalf("bad line %q", line)
+		}
+		ts := fields[2] + " " + fields[3]
+		if ts < prev {
+			t.Fatalf("timestamp %s is less than the previous %s", ts, prev)
+		}
+		prev = ts
+		if strings.Contains(line, " message ") {
+			n++
+		}
+	}
+
+	if n != goroutines*count {
+		t.Errorf("got %d messages, expected %d", n, goroutines*count)
+	}
+}
+
+func TestRotationWithConcurrentFlush(t *testing.T) {
+	resetLog(t)
+	clock := setFakeClock(time.Date(2024, 5, 3, 23, 59, 59, 0, time.UTC))
+
+	dir := t.TempDir()
+	SetFile(dir, "", false, 1<<16, time.Minute)
+	Message(INFO, "before")
+
+	const (
+		goroutines = 8
+		count      = 500
+	)
+
+	stop := make(chan struct{})
+	flushed := make(chan struct{})
+	go func() {
+		defer close(flushed)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				writerFlush()
+			}
+		}
+	}()
+
+	wg := new(sync.WaitGroup)
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < count; j++ {
+				if i == 0 && j == count/2 {
+					clock.Set(time.Date(2024, 5, 4, 0, 0, 1, 0, time.UTC))
+					ForceMessage(INFO, "Have a nice day")
+				}
+				Message(INFO, "rotation %d.%d", i, j)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	close(stop)
+	<-flushed
+
+	mutex.Lock()
+	closeLogFile()
+	mutex.Unlock()
+
+	names, _ := filepath.Glob(filepath.Join(dir, "*.log"))
+	if len(names) != 2 {
+		t.Fatalf("got files %q", names)
+	}
+
+	seen := map[string]int{}
+	for _, name := range names {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, line := range strings.Split(string(data), misc.EOS) {
+			if _, msg, found := strings.Cut(line, " rotation "); found {
+				seen[msg]++
+			}
+		}
+	}
+
+	for i := 0; i < goroutines; i++ {
+		for j := 0; j < count; j++ {
+			if n := seen[fmt.Sprintf("%d.%d", i, j)]; n != 1 {
+				t.Fatalf("line %d.%d is found %d times", i, j, n)
+			}
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+type fakeClock struct {
+	mutex sync.Mutex
+	t     time.Time
+}
+
+// setFakeClock -- replace the time source by the manually driven clock
+func setFakeClock(t time.Time) *fakeClock {
+	c := &fakeClock{t: t}
+	mutex.Lock()
+	timeNow = c.Now
+	mutex.Unlock()
+	return c
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) Set(t time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.t = t
+}
+
+func (c *fakeClock) Add(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.t = c.t.Add(d)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+type captureWriter struct {
+	mutex sync.Mutex
+	buf   bytes.Buffer
+}
+
+func (w *captureWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *captureWriter) String() string {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.buf.String()
+}
+
+func (w *captureWriter) Lines() []string {
+	s := strings.TrimSpace(w.String())
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+// resetLog -- bring the package to the initial unconfigured state, the console is captured
+func resetLog(t *testing.T) *captureWriter {
+	t.Helper()
+
+	SetGroupCommit(GroupCommitOff)
+	SetEnqueueTimeout(0)
+	SetSevereNotifier(0, nil)
+	SetCoarseClock(0)
+	SetStderrInterception(false)
+
+	shutdownMutex.Lock()
+	suspended = nil
+	shutdownMutex.Unlock()
+
+	mutex.Lock()
+
+	if fileWriter != nil {
+		fileWriter.Flush()
+		fileWriter = nil
+	}
+	if dst != nil {
+		dst.Close()
+		dst = nil
+		file = nil
+	}
+	outputWriter = nil
+
+	enabled = true
+	active = true
+	exiting = false
+	firstTime = true
+	exitCode = -1
+
+	beforeFileBuf = []string{}
+	memoryMode = false
+	memoryBuf = []memoryLine{}
+	lastBuf = []string{}
+	lastLog = make([]LogEntry, defaultLastLogSize)
+	lastLogStart = 0
+	lastLogLen = 0
+	logFuncName.Store(logFuncNameNone)
+	localTime = false
+	lastStamp = time.Time{}
+	lastWriteDate = ""
+	fileDirectory = ""
+	fileNamePattern = ""
+	fileName = ""
+	fileWriterBufSize = 0
+	fileWriterFlushPeriod = 0
+	maxLen.Store(0)
+	legacyFormatting.Store(false)
+	fileFormat = FormatClassic
+	severeCooldown.Store(int64(time.Minute))
+	asyncOpen = false
+	openGen++
+	openPending = []pendingLine{}
+	openFile = openFileInDir
+	usage.Store(nil)
+	moduleTagging.Store(false)
+	quotas = map[string]*quotaState{}
+	resetDestinations()
+
+	scopeMutex.Lock()
+	scopes = map[uint64][]*scopeFrame{}
+	scopeFrames.Store(0)
+	scopeMutex.Unlock()
+	usageDump.Store(false)
+	resetCompact()
+	autoFacility.Store(false)
+	autoFacilityCache.Range(func(k, _ any) bool {
+		autoFacilityCache.Delete(k)
+		return true
+	})
+	flushOnSevere = -1
+	consoleFilter = nil
+	lineChecksums = false
+	compression = CompressionNone
+	encryptionKey = nil
+	levelHistory = []LevelChange{}
+	stormThreshold = 0
+	stormWindowNs = 0
+	unknownFacilitiesWarned = map[string]bool{}
+	invalidLevelWarned.Range(func(k, _ any) bool {
+		invalidLevelWarned.Delete(k)
+		return true
+	})
+	levels = levels[:UNKNOWN+1]
+	fallbackDirectory = t.TempDir()
+	fallbackBuf = []string{}
+	fileTier = TierPrimary
+	lastError = nil
+	lastOpenDate = ""
+	failureInjector.Store(nil)
+	stripANSI.Store(false)
+	fileTrailer = false
+	truncationCheck = true
+	truncationChecked = false
+	boostRules = map[string]BoostRule{}
+	boosts = map[*Facility]*boostState{}
+	eventIDs = sync.Map{}
+	unregistered.Store(0)
+	strictConsole.Store(false)
+	sanitizedWarned = map[string]bool{}
+	flushAlign = false
+	stdlogFacility = nil
+	stdlogLevel = NOTICE
+	flight = nil
+	flightDumpInterval = DefaultFlightDumpInterval
+	flightLastDump = time.Time{}
+	rotationKey = ""
+	rotatingSinks = builtinRotatingSinks()
+	rotationBoundary.Store(0)
+	clockTolerance = defaultClockTolerance
+	clockBehind = false
+	clockAnomalies = 0
+	maintainSymlinks = false
+	destErrors = nil
+	minFreeSpace = 0
+	retention = RetentionOptions{}
+	registeredFacilities = nil
+	strictFacilities = false
+	unregisteredWarned = map[string]bool{}
+	maxFacilities = 0
+	releaseExclusive()
+	exclusiveFile = false
+	exclusivePolicy = ExclusiveFail
+	runtimeTraceParsing = false
+	SetLogTokenTTL(0)
+	SetHumanUnits(true)
+	SetDurationPrecision(-1)
+	levelPersistPath = ""
+	levelPersistMaxAge = DefaultLevelPersistenceMaxAge
+	levelPersistPending = false
+	levelsConfigured = false
+	levelPersistDirty.Store(false)
+	rejectedCount = 0
+	rejectedNames = nil
+	rejectedReported = false
+	degradedReport = time.Time{}
+	degradedInterval = 30 * time.Second
+	symlinksWarned = false
+	symlink = os.Symlink
+	writeBroken = false
+	writeFailing = false
+	fileWriteErr = nil
+	firstLogged.Store(false)
+	syncPeriod = 0
+	consoleDedupWindow = 0
+	activeRules.Store(nil)
+	closeTimingsFile()
+	timingsPattern = ""
+	timingsFileName = ""
+	timingsOnly = false
+	renderers = map[reflect.Type]EventRenderer{}
+	dupKey = ""
+	dupCount = 0
+	idleShrinkPeriods = 60
+	idlePeriods = 0
+	atomic.StoreInt64(&writeCount, 0)
+	dumpFileName = t.TempDir() + "/unsaved.log"
+	dumpedLines = map[string]bool{}
+	configAlertSubscribers = map[int64]ConfigAlertFunc{}
+
+	hierarchicalLevels.Store(false)
+	for name, f := range facilities {
+		if name != StdFacilityName {
+			delete(facilities, name)
+		}
+		f.setLevel(DEBUG)
+		f.alertSubscribers = nil
+		f.storm = stormState{}
+		f.disabled.Store(false)
+		f.lookBehind.Store(nil)
+		f.SetSampling(DEBUG, 0)
+	}
+
+	mutex.Unlock()
+
+	ResetStats()
+
+	w := &captureWriter{}
+	SetConsoleWriter(w)
+	t.Cleanup(func() {
+		SetConsoleWriter(nil)
+		mutex.Lock()
+		timeNow = time.Now
+		mutex.Unlock()
+	})
+
+	return w
+}
+
 //----------------------------------------------------------------------------------------------------------------------------//
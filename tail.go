@@ -0,0 +1,223 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+const tailChunkSize = 4096
+
+var (
+	// ErrTailEncoded -- the log file is compressed or encrypted and can't be tailed
+	ErrTailEncoded = errors.New("compressed or encrypted log file can't be tailed")
+
+	tailPollPeriod = 200 * time.Millisecond
+)
+
+type tailer struct {
+	name    string
+	file    *os.File
+	offset  int64
+	partial []byte
+	fn      func(line string)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// TailFile -- call fn for about fromEnd last lines of the current log file and then for every new complete line
+// until the context is done. The file is polled, the new file is followed after the rotation.
+// The buffered lines of this process are flushed before every poll. ErrTailEncoded is returned if the file is
+// compressed or encrypted.
+func TailFile(ctx context.Context, fromEnd int, fn func(line string)) error {
+	t := &tailer{fn: fn}
+	defer t.close()
+
+	first := true
+
+	for {
+		writerFlush()
+
+		mutex.Lock()
+		name := fileName
+		encoded := compression != CompressionNone || encryptionKey != nil
+		mutex.Unlock()
+
+		if encoded {
+			return ErrTailEncoded
+		}
+
+		if name != t.name {
+			if t.file != nil {
+				// The rest of the previous file
+				if err := t.read(); err != nil {
+					return err
+				}
+				t.flushPartial()
+			}
+
+			if err := t.open(name, fromEnd, first); err != nil {
+				return err
+			}
+		}
+
+		if t.file != nil {
+			first = false
+			if err := t.read(); err != nil {
+				return err
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(tailPollPeriod):
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// open -- open the file, the first file is read from about fromEnd last lines, the next ones from the beginning
+func (t *tailer) open(name string, fromEnd int, first bool) error {
+	t.close()
+
+	if name == "" {
+		return nil
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			// Not created yet
+			return nil
+		}
+		return err
+	}
+
+	offset := int64(0)
+	if first {
+		offset, err = tailOffset(f, fromEnd)
+		if err != nil {
+			f.Close()
+			return err
+		}
+	}
+
+	t.name = name
+	t.file = f
+	t.offset = offset
+	return nil
+}
+
+func (t *tailer) close() {
+	if t.file != nil {
+		t.file.Close()
+	}
+	t.name = ""
+	t.file = nil
+	t.offset = 0
+	t.partial = nil
+}
+
+// read -- call fn for complete lines up to the end of the file, the incomplete last line is kept
+func (t *tailer) read() error {
+	st, err := t.file.Stat()
+	if err != nil {
+		return err
+	}
+	if st.Size() < t.offset {
+		// Truncated
+		t.offset = 0
+		t.partial = nil
+	}
+
+	buf := make([]byte, tailChunkSize)
+
+	for {
+		n, err := t.file.ReadAt(buf, t.offset)
+		t.offset += int64(n)
+
+		data := buf[:n]
+		for len(data) > 0 {
+			i := bytes.IndexByte(data, '\n')
+			if i < 0 {
+				t.partial = append(t.partial, data...)
+				break
+			}
+
+			line := data[:i]
+			if len(t.partial) > 0 {
+				line = append(t.partial, line...)
+				t.partial = nil
+			}
+			t.fn(string(bytes.TrimSuffix(line, []byte{'\r'})))
+			data = data[i+1:]
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// flushPartial -- the incomplete last line of the file is never completed after the rotation
+func (t *tailer) flushPartial() {
+	if len(t.partial) > 0 {
+		t.fn(string(t.partial))
+		t.partial = nil
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// tailOffset -- offset of the beginning of n last lines
+func tailOffset(f *os.File, n int) (int64, error) {
+	st, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	size := st.Size()
+	if n <= 0 {
+		return size, nil
+	}
+
+	buf := make([]byte, tailChunkSize)
+	end := size
+	count := 0
+
+	for end > 0 {
+		start := max(end-tailChunkSize, 0)
+		chunk := buf[:end-start]
+		if _, err := f.ReadAt(chunk, start); err != nil && err != io.EOF {
+			return 0, err
+		}
+
+		for i := len(chunk) - 1; i >= 0; i-- {
+			if chunk[i] != '\n' || start+int64(i) == size-1 {
+				// The newline at the end of the file doesn't start a line
+				continue
+			}
+			count++
+			if count == n {
+				return start + int64(i) + 1, nil
+			}
+		}
+
+		end = start
+	}
+
+	return 0, nil
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
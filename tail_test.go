@@ -0,0 +1,183 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+type tailCollector struct {
+	mutex sync.Mutex
+	lines []string
+}
+
+func (c *tailCollector) add(line string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.lines = append(c.lines, line)
+}
+
+// wait -- lines after the last one with the suffix appears
+func (c *tailCollector) wait(t *testing.T, suffix string) []string {
+	t.Helper()
+
+	for i := 0; i < 500; i++ {
+		c.mutex.Lock()
+		list := append([]string{}, c.lines...)
+		c.mutex.Unlock()
+
+		if len(list) > 0 && strings.HasSuffix(list[len(list)-1], suffix) {
+			return list
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("no line with %q", suffix)
+	return nil
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestTailFile(t *testing.T) {
+	resetLog(t)
+	clock := setFakeClock(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC))
+
+	period := tailPollPeriod
+	tailPollPeriod = 10 * time.Millisecond
+	defer func() { tailPollPeriod = period }()
+
+	SetFile(t.TempDir(), "", false, 4096, 0)
+
+	for i := 1; i <= 3; i++ {
+		Message(INFO, "old %d", i)
+	}
+
+	c := &tailCollector{}
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error)
+	go func() {
+		result <- TailFile(ctx, 2, c.add)
+	}()
+
+	lines := c.wait(t, " old 3")
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], " old 2") {
+		t.Errorf("unexpected first lines %q", lines)
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 25; i++ {
+				Message(INFO, "live %d-%d", w, i)
+			}
+		}(w)
+	}
+	wg.Wait()
+	Message(INFO, "live done")
+
+	lines = c.wait(t, " live done")
+	seen := map[string]bool{}
+	for _, line := range lines {
+		if _, s, ok := strings.Cut(line, " live "); ok {
+			seen[s] = true
+		}
+	}
+	for w := 0; w < 4; w++ {
+		for i := 0; i < 25; i++ {
+			if s := fmt.Sprintf("%d-%d", w, i); !seen[s] {
+				t.Errorf("no line %s", s)
+			}
+		}
+	}
+
+	// The line of another process is written by parts
+	fd, err := os.OpenFile(FileName(), os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd.WriteString("external par")
+	time.Sleep(5 * tailPollPeriod)
+	fd.WriteString("tial\n")
+	fd.Close()
+
+	lines = c.wait(t, "external partial")
+	if last := lines[len(lines)-1]; last != "external partial" {
+		t.Errorf("got %q, expected the complete line", last)
+	}
+
+	clock.Add(24 * time.Hour)
+	Message(INFO, "new day")
+
+	lines = c.wait(t, " new day")
+	if !strings.HasSuffix(FileName(), "2024-05-02.log") || !strings.Contains(lines[len(lines)-2], " was launched at ") {
+		t.Errorf("the new file %s isn't followed from the beginning: %q", FileName(), lines[len(lines)-2:])
+	}
+
+	cancel()
+	if err := <-result; err != context.Canceled {
+		t.Errorf("got %v, expected canceled", err)
+	}
+}
+
+func TestTailFileEncoded(t *testing.T) {
+	resetLog(t)
+
+	SetFileEx(FileOptions{Directory: t.TempDir(), BufSize: 4096, Compression: CompressionGzip})
+	Message(INFO, "compressed")
+
+	if err := TailFile(context.Background(), 10, func(string) {}); err != ErrTailEncoded {
+		t.Errorf("got %v for the compressed file, expected %v", err, ErrTailEncoded)
+	}
+
+	SetFileEx(FileOptions{Directory: t.TempDir(), BufSize: 4096})
+	SetFileEncryption(testKey, EncryptionAESGCM)
+	Message(INFO, "encrypted")
+
+	if err := TailFile(context.Background(), 10, func(string) {}); err != ErrTailEncoded {
+		t.Errorf("got %v for the encrypted file, expected %v", err, ErrTailEncoded)
+	}
+}
+
+func TestTailOffset(t *testing.T) {
+	name := t.TempDir() + "/tail.log"
+
+	var b strings.Builder
+	for i := 0; i < 2000; i++ {
+		fmt.Fprintf(&b, "line %d\n", i)
+	}
+	os.WriteFile(name, []byte(b.String()), 0644)
+
+	fd, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+
+	for _, n := range []int{0, 1, 5, 1500, 2000, 3000} {
+		offset, err := tailOffset(fd, n)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := b.String()
+		lines := strings.SplitAfter(expected, "\n")
+		lines = lines[:len(lines)-1]
+		if n < len(lines) {
+			expected = strings.Join(lines[len(lines)-n:], "")
+		}
+
+		if s := b.String()[offset:]; s != expected {
+			t.Errorf("%d: got %d bytes, expected %d", n, len(s), len(expected))
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
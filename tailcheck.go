@@ -0,0 +1,80 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/alrusov/misc"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// After the power loss the last line of the file can be cut in the middle. On the first opening of the file by
+// the process the last bytes of the existing file are checked, if the file doesn't end with EOS the line is finished
+// and the marker line is written before the banner, so parsers concatenating files resynchronize. Compressed and
+// encrypted files are not checked. On by default.
+
+const truncationMarker = "*** previous line truncated by abnormal shutdown"
+
+var (
+	truncationCheck   = true
+	truncationChecked = false
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetTruncationCheck -- check the end of the existing file on the first opening
+func SetTruncationCheck(enabled bool) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	truncationCheck = enabled
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// checkTruncatedTail -- finish the cut line of the file opened first time. Must be called under the mutex before
+// anything is written to the file.
+func checkTruncatedTail() {
+	if truncationChecked {
+		return
+	}
+	truncationChecked = true
+
+	if !truncationCheck || compression != CompressionNone || encryptionKey != nil || !truncatedTail(fileName) {
+		return
+	}
+
+	write(fmt.Sprintf("%s[%d] %s %s %s%s",
+		misc.EOS,
+		pid,
+		levels[WARNING].shortName,
+		lastStamp.Format(misc.DateTimeFormatRevWithMS),
+		truncationMarker,
+		misc.EOS,
+	))
+}
+
+// truncatedTail -- the file isn't empty and doesn't end with EOS. Only the last bytes are read.
+func truncatedTail(name string) bool {
+	f, err := os.Open(name)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil || fi.Size() == 0 {
+		return false
+	}
+
+	buf := make([]byte, min(fi.Size(), int64(len(misc.EOS))))
+	if _, err := f.ReadAt(buf, fi.Size()-int64(len(buf))); err != nil {
+		return false
+	}
+
+	return !bytes.HasSuffix(buf, []byte(misc.EOS))
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,71 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestTruncatedTail(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		disabled bool
+		marker   bool
+	}{
+		{"cut", "[1] IN 2024-05-03 10:00:00.000 complete\n[1] IN 2024-05-03 10:00:01.000 cut in the mid", false, true},
+		{"cut-disabled", "[1] IN 2024-05-03 10:00:00.000 cut in the mid", true, false},
+		{"complete", "[1] IN 2024-05-03 10:00:00.000 complete\n", false, false},
+		{"eos-only", "\n", false, false},
+		{"empty", "", false, false},
+	}
+
+	for _, tc := range tests {
+		resetLog(t)
+		clock := setFakeClock(time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC))
+		SetTruncationCheck(!tc.disabled)
+
+		dir := t.TempDir()
+		name := filepath.Join(dir, "2024-05-03.log")
+		if err := os.WriteFile(name, []byte(tc.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		SetFile(dir, "", false, 4096, 0)
+		Message(INFO, "next")
+
+		// Only the first opening by the process is checked
+		if err := os.WriteFile(filepath.Join(dir, "2024-05-04.log"), []byte("cut"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		clock.Set(time.Date(2024, 5, 4, 12, 0, 0, 0, time.UTC))
+		Message(INFO, "tomorrow")
+		writerFlush()
+
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.SplitAfter(strings.TrimPrefix(string(data), tc.content), "\n")
+
+		if tc.marker {
+			if len(lines) < 3 || lines[0] != "\n" || !strings.HasSuffix(lines[1], " WA 2024-05-03 12:00:00.000 "+truncationMarker+"\n") ||
+				!strings.Contains(lines[2], " was launched at ") {
+				t.Errorf("[%s] unexpected file:\n%s", tc.name, data)
+			}
+		} else if strings.Contains(string(data), truncationMarker) || !strings.Contains(lines[0], " was launched at ") {
+			t.Errorf("[%s] unexpected file:\n%s", tc.name, data)
+		}
+
+		data, _ = os.ReadFile(filepath.Join(dir, "2024-05-04.log"))
+		if strings.Contains(string(data), truncationMarker) {
+			t.Errorf("[%s] the next file is checked:\n%s", tc.name, data)
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
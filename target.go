@@ -0,0 +1,84 @@
+package log
+
+import (
+	"io"
+	"sort"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Target -- additional destination receiving every formatted line.
+// Write is called under the package mutex so it must not block and must not log itself.
+type Target interface {
+	io.WriteCloser
+}
+
+var (
+	targets = map[string]Target{}
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// AddTarget -- add the target, the previous target with the same name is closed
+func AddTarget(name string, t Target) {
+	mutex.Lock()
+	old, exists := targets[name]
+	targets[name] = t
+	mutex.Unlock()
+
+	if exists {
+		old.Close()
+	}
+}
+
+// DelTarget -- remove and close the target
+func DelTarget(name string) error {
+	mutex.Lock()
+	t, exists := targets[name]
+	delete(targets, name)
+	mutex.Unlock()
+
+	if !exists {
+		return nil
+	}
+	return t.Close()
+}
+
+// TargetNames -- names of the added targets
+func TargetNames() []string {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	list := make([]string, 0, len(targets))
+	for name := range targets {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+	return list
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Must be called under the mutex
+func writeToTargets(text string) {
+	for name, t := range targets {
+		if _, err := t.Write([]byte(text)); err != nil {
+			destinationFailed(name, 1, err)
+		} else {
+			destinationWritten(name)
+		}
+	}
+}
+
+func closeTargets() {
+	mutex.Lock()
+	list := targets
+	targets = map[string]Target{}
+	mutex.Unlock()
+
+	for _, t := range list {
+		t.Close()
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,176 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/alrusov/misc"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// MsgTemplate -- the prepared message of the facility. The line is the same as the one produced by Message with the same
+// level, format and params. The message is formatted outside of the mutex into the pooled buffer.
+// Function names, rules, the group commit, the look-behind and the TIME level take the usual way, the sampling applies.
+type MsgTemplate struct {
+	f      *Facility
+	level  Level
+	format string
+	head   string // "[pid] LL "
+	tag    string // " <facility>"
+}
+
+var (
+	templateBufs = sync.Pool{
+		New: func() any {
+			return new(bytes.Buffer)
+		},
+	}
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Template -- prepare the message, the invalid format is reported once with WARNING
+func (f *Facility) Template(level Level, format string) *MsgTemplate {
+	level = checkLevel(1, f, level)
+
+	t := &MsgTemplate{
+		f:      f,
+		level:  level,
+		format: format,
+		head:   fmt.Sprintf("[%d] %s ", pid, levels[level].shortName),
+	}
+
+	if f.name != "" {
+		t.tag = " <" + f.name + ">"
+	}
+
+	if err := checkFormat(format); err != nil {
+		f.Message(WARNING, "template %q: %s", format, err)
+	}
+
+	return t
+}
+
+// Log -- add the message with params to the log
+func (t *MsgTemplate) Log(params ...any) {
+	f := t.f
+
+	if f.lookBehind.Load() != nil {
+		// The suppressed message is kept, the severe one writes the kept lines
+		f.messageEx(1, t.level, false, nil, t.format, params...)
+		return
+	}
+
+	if f.disabled.Load() || !t.level.passes(f.token.Load()) {
+		return
+	}
+
+	if t.level == TIME || groupCommitRing.Load() != nil || activeRules.Load() != nil || scopeFrames.Load() != 0 ||
+		(f == stdFacility && autoFacility.Load()) {
+		f.messageEx(1, t.level, false, nil, t.format, params...)
+		return
+	}
+
+	if f.sampledOut(t.level) || stormDrop(f, t.level, "", t.format, params) {
+		return
+	}
+
+	msgBuf := templateBufs.Get().(*bytes.Buffer)
+	msgBuf.Reset()
+	defer templateBufs.Put(msgBuf)
+
+	if len(params) == 0 && !legacyFormatting.Load() {
+		msgBuf.WriteString(t.format)
+	} else {
+		fmt.Fprintf(msgBuf, t.format, params...)
+	}
+
+	t.output(msgBuf.Bytes(), params)
+}
+
+func (t *MsgTemplate) output(msg []byte, params []any) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if !enabled {
+		return
+	}
+
+	if t.level == EMERG || logFuncName.Load() != logFuncNameNone {
+		logger(false, 2, t.f.name, t.level, nil, t.format, params...)
+		return
+	}
+
+	tm := levelStamp(t.level)
+
+	statMessage(t.level)
+	usageCount(t.f.name, t.level, tm)
+	firstLogged.Store(true)
+
+	dt, ts := formatStamp(tm)
+
+	lineBuf := templateBufs.Get().(*bytes.Buffer)
+	lineBuf.Reset()
+	defer templateBufs.Put(lineBuf)
+
+	lineBuf.WriteString(t.head)
+	lineBuf.WriteString(dt)
+	lineBuf.WriteByte(' ')
+	lineBuf.WriteString(ts)
+	lineBuf.WriteString(t.tag)
+	lineBuf.WriteByte(' ')
+	start := lineBuf.Len()
+	lineBuf.Write(msg)
+
+	n := int(maxLen.Load())
+	truncated := n > 0 && n < lineBuf.Len()
+	if truncated {
+		lineBuf.Truncate(n)
+		statTruncate()
+	}
+	end := lineBuf.Len()
+	lineBuf.WriteString(misc.EOS)
+
+	line := lineBuf.String()
+
+	if truncated {
+		notifySevere(t.f.name, t.level, tm, string(msg))
+	} else {
+		notifySevere(t.f.name, t.level, tm, line[start:end])
+	}
+
+	output(t.f.name, t.level, fileDate(tm), line)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// checkFormat -- the format has no unknown verbs and no trailing "%"
+func checkFormat(format string) error {
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+
+		i++
+		for i < len(format) && strings.IndexByte("+-# 0123456789.*[]", format[i]) >= 0 {
+			i++
+		}
+
+		if i >= len(format) {
+			return errors.New("no verb at the end")
+		}
+
+		if c, _ := utf8.DecodeRuneInString(format[i:]); c != '%' && !strings.ContainsRune("vTtbcdoOqxXUeEfFgGsp", c) {
+			return fmt.Errorf("unknown verb %%%c", c)
+		}
+	}
+
+	return nil
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
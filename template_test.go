@@ -0,0 +1,166 @@
+package log
+
+import (
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+type countedStringer struct {
+	calls *atomic.Int32
+}
+
+func (s countedStringer) String() string {
+	s.calls.Add(1)
+	return "counted"
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestTemplate(t *testing.T) {
+	console := resetLog(t)
+	setFakeClock(time.Date(2024, 5, 1, 12, 30, 45, 123456789, time.UTC))
+
+	http := GetFacility("http")
+	http.SetLogLevel("DEBUG", FuncNameModeNone)
+
+	tests := []struct {
+		name   string
+		f      *Facility
+		level  Level
+		format string
+		params []any
+		setup  func()
+	}{
+		{"params", http, INFO, "request done method=%s path=%s status=%d dur=%v", []any{"GET", "/x", 200, 15 * time.Millisecond}, nil},
+		{"std facility", stdFacility, ERR, "code %d", []any{500}, nil},
+		{"no params", http, WARNING, "100%% done", nil, nil},
+		{"legacy", http, WARNING, "100%% done", nil, func() { legacyFormatting.Store(true) }},
+		{"missing param", http, INFO, "%s and %d", []any{"one"}, nil},
+		{"max length", http, DEBUG, "long message %s", []any{strings.Repeat("x", 100)}, func() { maxLen.Store(60) }},
+		{"func name", http, INFO, "with func %d", []any{1}, func() { logFuncName.Store(logFuncNameFull) }},
+		{"emerg", http, EMERG, "emergency %d", []any{1}, nil},
+	}
+
+	for _, test := range tests {
+		mutex.Lock()
+		legacyFormatting.Store(false)
+		maxLen.Store(0)
+		logFuncName.Store(logFuncNameNone)
+		if test.setup != nil {
+			test.setup()
+		}
+		mutex.Unlock()
+
+		tm := test.f.Template(test.level, test.format)
+
+		test.f.Message(test.level, test.format, test.params...)
+		tm.Log(test.params...)
+
+		lines := console.Lines()
+		if len(lines) < 2 {
+			t.Fatalf("%s: got %d lines", test.name, len(lines))
+		}
+		expected, got := lines[len(lines)-2], lines[len(lines)-1]
+		if got != expected {
+			t.Errorf("%s: got\n%q\nexpected\n%q", test.name, got, expected)
+		}
+		if test.level == EMERG && !strings.Contains(got, ".TestTemplate:") {
+			t.Errorf("%s: no caller in %q", test.name, got)
+		}
+	}
+}
+
+func TestTemplateLevel(t *testing.T) {
+	console := resetLog(t)
+
+	f := GetFacility("http")
+	f.SetLogLevel("INFO", FuncNameModeNone)
+
+	tm := f.Template(DEBUG, "value %s")
+
+	calls := &atomic.Int32{}
+	n := len(console.Lines())
+	tm.Log(countedStringer{calls: calls})
+
+	if calls.Load() != 0 || len(console.Lines()) != n {
+		t.Errorf("filtered template is formatted %d times, %d lines", calls.Load(), len(console.Lines())-n)
+	}
+
+	f.SetLogLevel("DEBUG", FuncNameModeNone)
+	n = len(console.Lines())
+	tm.Log(countedStringer{calls: calls})
+
+	if lines := console.Lines()[n:]; calls.Load() != 1 || len(lines) != 1 || !strings.HasSuffix(lines[0], "<http> value counted") {
+		t.Errorf("unexpected lines %q", lines)
+	}
+}
+
+func TestTemplateFormat(t *testing.T) {
+	tests := []struct {
+		format string
+		valid  bool
+	}{
+		{"plain", true},
+		{"%s %d %v %+v %#v %-10s %08.3f %% %[1]d %*d", true},
+		{"trailing %", false},
+		{"trailing %-5", false},
+		{"bad %y verb", false},
+		{"bad %ж verb", false},
+	}
+
+	for _, test := range tests {
+		if err := checkFormat(test.format); (err == nil) != test.valid {
+			t.Errorf("%q: got %v", test.format, err)
+		}
+	}
+
+	console := resetLog(t)
+	GetFacility("http").Template(INFO, "bad %y")
+
+	if lines := console.Lines(); len(lines) != 1 || !strings.HasSuffix(lines[0], `template "bad %y": unknown verb %y`) {
+		t.Errorf("unexpected lines %q", lines)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func BenchmarkTemplate(b *testing.B) {
+	const format = "request done method=%s path=%s status=%d dur=%v"
+
+	run := func(b *testing.B, log func(i int)) {
+		defer SetConsoleWriter(nil)
+
+		SetConsoleWriter(io.Discard)
+		SetFile(b.TempDir(), "", false, 64*1024, 0)
+
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			log(i)
+		}
+	}
+
+	f := GetFacility("bench")
+	f.SetLogLevel("INFO", FuncNameModeNone)
+	tm := f.Template(INFO, format)
+
+	b.Run("Message", func(b *testing.B) {
+		run(b, func(i int) { f.Message(INFO, format, "GET", "/api/v1/items", 200, time.Duration(i)) })
+	})
+
+	b.Run("Template", func(b *testing.B) {
+		run(b, func(i int) { tm.Log("GET", "/api/v1/items", 200, time.Duration(i)) })
+	})
+
+	b.Run("Filtered", func(b *testing.B) {
+		filtered := f.Template(DEBUG, format)
+		run(b, func(i int) { filtered.Log("GET", "/api/v1/items", 200, time.Duration(i)) })
+	})
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
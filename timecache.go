@@ -0,0 +1,58 @@
+package log
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/alrusov/misc"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Formatted date and seconds part of the time are regenerated only when the second or the time zone changes
+
+type timeCache struct {
+	sec        int64
+	loc        *time.Location
+	date       string
+	timePrefix string
+}
+
+const timePrefixFormat = "15:04:05."
+
+var (
+	lastTimeCache atomic.Pointer[timeCache]
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// formatStamp -- the same as t.Format(misc.DateFormatRev) and t.Format(misc.TimeFormatWithMS)
+func formatStamp(t time.Time) (date string, tm string) {
+	if c := coarseClock.Load(); c != nil && c.t.Equal(t) {
+		return c.date, c.tm
+	}
+
+	sec := t.Unix()
+	loc := t.Location()
+
+	c := lastTimeCache.Load()
+	if c == nil || c.sec != sec || c.loc != loc {
+		c = &timeCache{
+			sec:        sec,
+			loc:        loc,
+			date:       t.Format(misc.DateFormatRev),
+			timePrefix: t.Format(timePrefixFormat),
+		}
+		lastTimeCache.Store(c)
+	}
+
+	ms := t.Nanosecond() / int(time.Millisecond)
+	return c.date, c.timePrefix + string([]byte{byte('0' + ms/100), byte('0' + ms/10%10), byte('0' + ms%10)})
+}
+
+// resetTimeCache -- must be called when the time settings are changed
+func resetTimeCache() {
+	lastTimeCache.Store(nil)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
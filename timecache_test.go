@@ -0,0 +1,55 @@
+package log
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alrusov/misc"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestFormatStamp(t *testing.T) {
+	resetLog(t)
+
+	clock := setFakeClock(time.Date(2024, 5, 3, 23, 59, 58, 990*int(time.Millisecond), time.UTC))
+
+	for i := 0; i < 3000; i++ {
+		tm := now()
+		date, tms := formatStamp(tm)
+		if date != tm.Format(misc.DateFormatRev) || tms != tm.Format(misc.TimeFormatWithMS) {
+			t.Fatalf("got %s %s, expected %s", date, tms, tm.Format(misc.DateTimeFormatRevWithMS))
+		}
+		clock.Add(time.Millisecond + 7*time.Microsecond)
+	}
+
+	if date, _ := formatStamp(now()); date != "2024-05-04" {
+		t.Errorf("got date %s after midnight", date)
+	}
+
+	// The same second in another time zone
+	loc := time.FixedZone("X", 3*3600)
+	tm := now().In(loc)
+	if date, tms := formatStamp(tm); date+" "+tms != tm.Format(misc.DateTimeFormatRevWithMS) {
+		t.Errorf("got %s %s, expected %s", date, tms, tm.Format(misc.DateTimeFormatRevWithMS))
+	}
+}
+
+func BenchmarkFormatStamp(b *testing.B) {
+	tm := time.Now().UTC()
+
+	b.Run("format", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = tm.Format(misc.DateFormatRev)
+			_ = tm.Format(misc.TimeFormatWithMS)
+		}
+	})
+
+	b.Run("cached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			formatStamp(tm)
+		}
+	})
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,190 @@
+package log
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alrusov/misc"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// TIME level messages are additionally written to the daily timings file "ts;facility;label;duration_ms;extra".
+// MessageTime fills all columns, other TIME messages put the whole text into the label column. The file is named by
+// the key of the rotation controller and rotated together with the log file.
+
+const (
+	timingsHeader  = "ts;facility;label;duration_ms;extra\n"
+	timingsBufSize = 64 * 1024
+)
+
+var (
+	timingsPattern  string
+	timingsFileName string
+	timingsFile     *os.File
+	timingsWriter   *bufio.Writer
+	timingsDate     string
+	timingsOnly     = false
+)
+
+// timingsSink -- the timings file as the sink of the rotation controller
+type timingsSink struct{}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetTimingsFile -- write TIME level messages to the daily timings file in the directory. Empty directory disables it.
+func SetTimingsFile(directory string, suffix string) {
+	ensureStarted()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	closeTimingsFile()
+
+	if directory == "" {
+		timingsPattern = ""
+		return
+	}
+
+	if suffix != "" {
+		suffix = "-" + suffix
+	}
+	directory, _ = misc.AbsPath(directory)
+	timingsPattern = filepath.Join(directory, "%s"+suffix+".timings.csv")
+}
+
+// SetTimingsOnlyToTimingsFile -- don't write TIME level messages to the main log while the timings file is set
+func SetTimingsOnlyToTimingsFile(only bool) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	timingsOnly = only
+}
+
+// TimingsFileName -- current timings file name
+func TimingsFileName() string {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	return timingsFileName
+}
+
+// MessageTime -- log the execution time of the std facility, see Facility.MessageTime
+func MessageTime(label string, duration time.Duration, extra string) {
+	stdFacility.messageTime(1, label, duration, extra)
+}
+
+// MessageTime -- log the execution time with the TIME level as separate columns of the timings file
+func (f *Facility) MessageTime(label string, duration time.Duration, extra string) {
+	f.messageTime(1, label, duration, extra)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func (f *Facility) messageTime(shift int, label string, duration time.Duration, extra string) {
+	if !enabled || f.disabled.Load() || !TIME.passes(f.token.Load()) {
+		return
+	}
+
+	ms := strconv.FormatFloat(float64(duration)/float64(time.Millisecond), 'f', 3, 64)
+
+	msg := label + " " + ms + "ms"
+	if extra != "" {
+		msg += " " + extra
+	}
+
+	msg, ok := applyRules(f.name, TIME, msg)
+	if !ok {
+		statDrop()
+		return
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	dt, prefix := linePrefix(shift+1, f.name, TIME, FuncNameInherit)
+	if writeTiming(f.name, label, ms, extra) {
+		return
+	}
+	output(f.name, TIME, dt, finishLine(prefix+msg))
+}
+
+// writeTiming -- write the record to the timings file, true if it must not go to the main log. Must be called under the mutex.
+func writeTiming(facility string, label string, duration string, extra string) bool {
+	if timingsPattern == "" {
+		return false
+	}
+
+	dt, tm := formatStamp(lastStamp)
+	rotationCheck(fileDate(lastStamp))
+
+	if timingsWriter == nil {
+		name := fmt.Sprintf(timingsPattern, rotationKey)
+		fd, err := openFileInDir(filepath.Dir(name), name)
+		if err != nil {
+			lastError = err
+			return false
+		}
+
+		timingsFile = fd
+		timingsFileName = name
+		timingsDate = rotationKey
+		timingsWriter = bufio.NewWriterSize(fd, timingsBufSize)
+
+		if fi, err := fd.Stat(); err == nil && fi.Size() == 0 {
+			timingsWriter.WriteString(timingsHeader)
+		}
+	}
+
+	timingsWriter.WriteString(dt + " " + tm + ";" + timingsField(facility) + ";" + timingsField(label) + ";" + duration + ";" + timingsField(extra) + "\n")
+
+	return timingsOnly
+}
+
+// timingsField -- quote the field with ';', '"' or line breaks, the quotes are doubled
+func timingsField(s string) string {
+	if !strings.ContainsAny(s, ";\"\r\n") {
+		return s
+	}
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+func timingsFlush() {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if timingsWriter != nil {
+		timingsWriter.Flush()
+	}
+}
+
+func (timingsSink) Pattern() string {
+	return timingsPattern
+}
+
+func (timingsSink) Rotate(key string) error {
+	if timingsDate != key {
+		closeTimingsFile()
+	}
+	return nil
+}
+
+// Must be called under the mutex
+func closeTimingsFile() {
+	if timingsWriter != nil {
+		timingsWriter.Flush()
+		timingsWriter = nil
+	}
+	if timingsFile != nil {
+		timingsFile.Close()
+		timingsFile = nil
+	}
+	timingsDate = ""
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
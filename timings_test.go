@@ -0,0 +1,92 @@
+package log
+
+import (
+	"encoding/csv"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func readTimings(t *testing.T) [][]string {
+	t.Helper()
+
+	timingsFlush()
+
+	fd, err := os.Open(TimingsFileName())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+
+	r := csv.NewReader(fd)
+	r.Comma = ';'
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return records
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestTimingsFile(t *testing.T) {
+	w := resetLog(t)
+	setFakeClock(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+
+	SetTimingsFile(t.TempDir(), "perf")
+
+	GetFacility("db").MessageTime("select; users", 12345678*time.Nanosecond, `rows=5 note="x"`)
+	MessageTime("startup", 2*time.Second, "")
+	Message(TIME, "plain %d", 1)
+	Message(INFO, "not a timing")
+
+	if name := TimingsFileName(); !strings.HasSuffix(name, "/2024-05-01-perf.timings.csv") {
+		t.Errorf("unexpected file name %s", name)
+	}
+
+	expected := [][]string{
+		{"ts", "facility", "label", "duration_ms", "extra"},
+		{"2024-05-01 12:00:00.000", "db", "select; users", "12.346", `rows=5 note="x"`},
+		{"2024-05-01 12:00:00.000", "", "startup", "2000.000", ""},
+		{"2024-05-01 12:00:00.000", "", "plain 1", "", ""},
+	}
+
+	records := readTimings(t)
+	if len(records) != len(expected) {
+		t.Fatalf("got %d records, expected %d: %q", len(records), len(expected), records)
+	}
+	for i, r := range records {
+		if strings.Join(r, "|") != strings.Join(expected[i], "|") {
+			t.Errorf("[%d] got %q, expected %q", i, r, expected[i])
+		}
+	}
+
+	s := w.String()
+	for _, m := range []string{"<db> select; users 12.346ms rows=5", " startup 2000.000ms\n", " plain 1\n", " not a timing\n"} {
+		if !strings.Contains(s, m) {
+			t.Errorf("%q not found in the main log:\n%s", m, s)
+		}
+	}
+}
+
+func TestTimingsOnlyToTimingsFile(t *testing.T) {
+	w := resetLog(t)
+
+	SetTimingsFile(t.TempDir(), "")
+	SetTimingsOnlyToTimingsFile(true)
+
+	MessageTime("query", time.Millisecond, "")
+	Message(INFO, "narrative")
+
+	if records := readTimings(t); len(records) != 2 || records[1][2] != "query" {
+		t.Errorf("unexpected timings %q", records)
+	}
+	if s := w.String(); strings.Contains(s, "query") || !strings.Contains(s, "narrative") {
+		t.Errorf("unexpected main log:\n%s", s)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
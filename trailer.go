@@ -0,0 +1,101 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/alrusov/misc"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// The trailer is the last line of the file closed by the rotation, MoveTo or Shutdown: the lines, the bytes and
+// the lines of every level written to the file, and the name of the next file, "-" on Shutdown. The counters include
+// the banner and the buffered lines, the level is taken from the line prefix. The trailer goes through the file buffer
+// and is flushed by the close. Off by default.
+
+type fileCounters struct {
+	lines  int64
+	bytes  int64
+	levels map[string]int64 // by the short name
+}
+
+var (
+	fileTrailer = false
+	fileCounts  = fileCounters{levels: map[string]int64{}}
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetFileTrailer -- end the closed file with the statistics line
+func SetFileTrailer(enabled bool) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	fileTrailer = enabled
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// resetFileCounts -- the new file is started. Must be called under the mutex.
+func resetFileCounts() {
+	fileCounts.lines = 0
+	fileCounts.bytes = 0
+	clear(fileCounts.levels)
+}
+
+// countWritten -- count the lines written to the file. Must be called under the mutex.
+func countWritten(text string) {
+	if !fileTrailer {
+		return
+	}
+
+	fileCounts.bytes += int64(len(text))
+
+	for text != "" {
+		var line string
+		line, text, _ = strings.Cut(text, misc.EOS)
+		fileCounts.lines++
+
+		// "[pid] LL ..."
+		_, rest, found := strings.Cut(line, "] ")
+		if !found {
+			continue
+		}
+		if short, _, found := strings.Cut(rest, " "); found {
+			fileCounts.levels[short]++
+		}
+	}
+}
+
+// writeTrailer -- write the statistics line before the file is closed. Must be called under the mutex.
+func writeTrailer(next string) {
+	if !fileTrailer || dst == nil || writeBroken {
+		return
+	}
+
+	write(trailerMessage(next) + misc.EOS)
+}
+
+func trailerMessage(next string) string {
+	var list []string
+	for _, def := range orderedLevels() {
+		if n := fileCounts.levels[def.shortName]; n > 0 {
+			list = append(list, fmt.Sprintf("%s=%d", strings.ToLower(def.name), n))
+		}
+	}
+
+	ts := lastStamp.Format(misc.DateTimeFormatRevWithMS)
+
+	return fmt.Sprintf("[%d] %s %s *** file closed at %s, lines=%d, bytes=%d, levels: %s, continued in %s",
+		pid,
+		levels[INFO].shortName,
+		ts,
+		ts,
+		fileCounts.lines,
+		fileCounts.bytes,
+		strings.Join(list, " "),
+		next)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,93 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alrusov/misc"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// checkTrailer -- the last line is the trailer matching the other lines of the file
+func checkTrailer(t *testing.T, name string, next string) []string {
+	t.Helper()
+
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.SplitAfter(string(data), misc.EOS)
+	lines = lines[:len(lines)-1]
+	trailer := lines[len(lines)-1]
+	body := lines[:len(lines)-1]
+
+	bytes := 0
+	levels := map[string]int{}
+	for _, s := range body {
+		bytes += len(s)
+		levels[strings.Fields(s)[1]]++
+	}
+
+	var list []string
+	for _, def := range orderedLevels() {
+		if n := levels[def.shortName]; n > 0 {
+			list = append(list, fmt.Sprintf("%s=%d", strings.ToLower(def.name), n))
+		}
+	}
+
+	expected := fmt.Sprintf(", lines=%d, bytes=%d, levels: %s, continued in %s\n", len(body), bytes, strings.Join(list, " "), next)
+	if !strings.Contains(trailer, " *** file closed at ") || !strings.HasSuffix(trailer, expected) {
+		t.Errorf("got trailer %q, expected suffix %q", trailer, expected)
+	}
+
+	return lines
+}
+
+func TestFileTrailer(t *testing.T) {
+	resetLog(t)
+	defer Start()
+
+	clock := setFakeClock(time.Date(2024, 5, 3, 23, 59, 0, 0, time.UTC))
+
+	SetFile(t.TempDir(), "", false, 4096, time.Minute)
+	SetFileTrailer(true)
+
+	Message(INFO, "first")
+	Message(ERR, "failed")
+	Message(WARNING, "warned")
+	Message(WARNING, "warned again")
+	first := FileName()
+
+	clock.Set(time.Date(2024, 5, 4, 0, 0, 1, 0, time.UTC))
+	Message(INFO, "second day")
+	second := FileName()
+
+	if first == second {
+		t.Fatalf("the file isn't rotated: %s", first)
+	}
+
+	lines := checkTrailer(t, first, second)
+	if len(lines) != 6 {
+		t.Errorf("got %d lines in the first file", len(lines))
+	}
+
+	if err := Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	lines = checkTrailer(t, second, "-")
+	if !strings.Contains(lines[0], " *** ") || !strings.Contains(lines[0], " was launched at ") {
+		t.Errorf("the second file doesn't start with the banner: %q", lines[0])
+	}
+	if !strings.HasSuffix(lines[1], " second day\n") {
+		t.Errorf("unexpected line %q", lines[1])
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
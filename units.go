@@ -0,0 +1,205 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// The key-value values of the types time.Duration, Bytes and Count are rendered with human-friendly units: "183.457ms",
+// "10.0 MiB", "1,234,567". The durations are rounded to the precision digits after the point, the sizes use
+// the binary units with one digit. The human units are switched off by SetHumanUnits, then the values are printed
+// as is. The JSON rendering isn't affected: the durations and the sizes are objects with the raw value and the unit,
+// the counts are raw numbers.
+
+// Bytes -- the size in bytes
+type Bytes int64
+
+// Count -- the number printed with the thousands separators
+type Count int64
+
+const (
+	// DefaultDurationPrecision -- digits after the point of the durations
+	DefaultDurationPrecision = 3
+)
+
+var (
+	humanUnits        atomic.Bool
+	durationPrecision atomic.Int32
+
+	byteUnits = []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+)
+
+func init() {
+	humanUnits.Store(true)
+	durationPrecision.Store(DefaultDurationPrecision)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetHumanUnits -- render the durations, sizes and counts with the human-friendly units (default) or as is
+func SetHumanUnits(enabled bool) {
+	humanUnits.Store(enabled)
+}
+
+// SetDurationPrecision -- digits after the point of the durations, DefaultDurationPrecision if negative
+func SetDurationPrecision(digits int) {
+	if digits < 0 {
+		digits = DefaultDurationPrecision
+	}
+	durationPrecision.Store(int32(digits))
+}
+
+// HumanDuration -- "183.457ms", "-2.500s", "1h2m3.457s"
+func HumanDuration(d time.Duration) string {
+	if d == 0 {
+		return "0s"
+	}
+
+	sign := ""
+	v := float64(d)
+	if d < 0 {
+		sign = "-"
+		v = -v
+	}
+
+	prec := int(durationPrecision.Load())
+
+	var unit string
+	switch {
+	case v < float64(time.Microsecond):
+		return sign + strconv.FormatFloat(v, 'f', 0, 64) + "ns"
+	case v < float64(time.Millisecond):
+		v, unit = v/float64(time.Microsecond), "µs"
+	case v < float64(time.Second):
+		v, unit = v/float64(time.Millisecond), "ms"
+	case v < float64(time.Minute):
+		v, unit = v/float64(time.Second), "s"
+	default:
+		// The rounding of the negative value is symmetric, so the sign is kept
+		r := time.Second
+		for i := 0; i < prec && r > 1; i++ {
+			r /= 10
+		}
+		rd := d.Round(r)
+		if rd == math.MinInt64 || rd == math.MaxInt64 {
+			// Round saturates instead of rounding away from the limit
+			rd = d.Truncate(r)
+		}
+		return rd.String()
+	}
+
+	return sign + strconv.FormatFloat(v, 'f', prec, 64) + unit
+}
+
+// String -- "10.0 MiB", the number of bytes if the human units are off
+func (b Bytes) String() string {
+	if !humanUnits.Load() {
+		return strconv.FormatInt(int64(b), 10)
+	}
+
+	sign := ""
+	v := float64(b)
+	if b < 0 {
+		sign = "-"
+		v = -v
+	}
+
+	if v < 1024 {
+		return sign + strconv.FormatFloat(v, 'f', 0, 64) + " B"
+	}
+
+	i := 0
+	for v >= 1024 && i < len(byteUnits)-1 {
+		v /= 1024
+		i++
+	}
+
+	return sign + strconv.FormatFloat(v, 'f', 1, 64) + " " + byteUnits[i]
+}
+
+// MarshalJSON -- {"value":10485760,"unit":"B"}
+func (b Bytes) MarshalJSON() ([]byte, error) {
+	return []byte(`{"value":` + strconv.FormatInt(int64(b), 10) + `,"unit":"B"}`), nil
+}
+
+// String -- "1,234,567", the number without the separators if the human units are off
+func (c Count) String() string {
+	s := strconv.FormatInt(int64(c), 10)
+	if !humanUnits.Load() {
+		return s
+	}
+
+	sign := ""
+	if c < 0 {
+		sign, s = "-", s[1:]
+	}
+
+	var b strings.Builder
+	b.WriteString(sign)
+	for i, r := range s {
+		if i > 0 && (len(s)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// MarshalJSON -- the raw number
+func (c Count) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.FormatInt(int64(c), 10)), nil
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// RenderKVJSON -- pairs as the JSON object in the pairs order, the durations are {"value":183456723,"unit":"ns"}
+func RenderKVJSON(pairs []KVPair) string {
+	var b strings.Builder
+	b.WriteByte('{')
+
+	for i, p := range pairs {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(jsonString(p.Key))
+		b.WriteByte(':')
+
+		if d, ok := p.Value.(time.Duration); ok {
+			b.WriteString(`{"value":` + strconv.FormatInt(int64(d), 10) + `,"unit":"ns"}`)
+			continue
+		}
+
+		data, err := json.Marshal(p.Value)
+		if err != nil {
+			data = []byte(jsonString(fmt.Sprint(p.Value)))
+		}
+		b.Write(data)
+	}
+
+	b.WriteByte('}')
+	return b.String()
+}
+
+// kvValue -- the text of the value
+func kvValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case time.Duration:
+		if humanUnits.Load() {
+			return HumanDuration(v)
+		}
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
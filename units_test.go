@@ -0,0 +1,101 @@
+package log
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestHumanUnitsText(t *testing.T) {
+	resetLog(t)
+
+	list := []struct {
+		value    any
+		expected string
+	}{
+		{time.Duration(0), "0s"},
+		{183456723 * time.Nanosecond, "183.457ms"},
+		{-183456723 * time.Nanosecond, "-183.457ms"},
+		{512 * time.Nanosecond, "512ns"},
+		{-512 * time.Nanosecond, "-512ns"},
+		{12345 * time.Nanosecond, "12.345µs"},
+		{2500 * time.Millisecond, "2.500s"},
+		{time.Hour + 2*time.Minute + 3456789*time.Microsecond, "1h2m3.457s"},
+		{-(time.Hour + 3456789*time.Microsecond), "-1h0m3.457s"},
+		{time.Duration(math.MinInt64), "-2562047h47m16.854s"},
+		{Bytes(0), "0 B"},
+		{Bytes(1023), "1023 B"},
+		{Bytes(1024), "1.0 KiB"},
+		{Bytes(-1536), "-1.5 KiB"},
+		{Bytes(10 << 20), "10.0 MiB"},
+		{Bytes(3 << 50), "3.0 PiB"},
+		{Bytes(math.MaxInt64), "8.0 EiB"},
+		{Count(0), "0"},
+		{Count(999), "999"},
+		{Count(1000), "1,000"},
+		{Count(-1234567), "-1,234,567"},
+		{Count(math.MinInt64), "-9,223,372,036,854,775,808"},
+	}
+
+	for i, c := range list {
+		if s := kvValue(c.value); s != c.expected {
+			t.Errorf("[%d] got %q, expected %q", i, s, c.expected)
+		}
+	}
+
+	s := RenderKV(NormalizeKV([]any{"took", 183456723 * time.Nanosecond, "size", Bytes(10 << 20), "rows", Count(1234567)}))
+	if expected := `took=183.457ms size="10.0 MiB" rows=1,234,567`; s != expected {
+		t.Errorf("got %q, expected %q", s, expected)
+	}
+
+	SetDurationPrecision(1)
+	if s := kvValue(183456723 * time.Nanosecond); s != "183.5ms" {
+		t.Errorf("precision 1: got %q", s)
+	}
+	if s := kvValue(time.Minute + 1260*time.Millisecond); s != "1m1.3s" {
+		t.Errorf("precision 1: got %q", s)
+	}
+
+	SetDurationPrecision(0)
+	if s := kvValue(2500 * time.Microsecond); s != "2ms" {
+		t.Errorf("precision 0: got %q", s)
+	}
+}
+
+func TestHumanUnitsOff(t *testing.T) {
+	resetLog(t)
+	SetHumanUnits(false)
+
+	s := RenderKV(NormalizeKV([]any{"took", 183456723 * time.Nanosecond, "size", Bytes(10 << 20), "rows", Count(-1234567)}))
+	if expected := `took=183.456723ms size=10485760 rows=-1234567`; s != expected {
+		t.Errorf("got %q, expected %q", s, expected)
+	}
+}
+
+func TestHumanUnitsJSON(t *testing.T) {
+	resetLog(t)
+
+	pairs := NormalizeKV([]any{
+		"zero", time.Duration(0),
+		"took", -183456723 * time.Nanosecond,
+		"size", Bytes(3 << 50),
+		"empty", Bytes(0),
+		"rows", Count(-1234567),
+		"name", "a \"b\"",
+	})
+
+	expected := `{"zero":{"value":0,"unit":"ns"},"took":{"value":-183456723,"unit":"ns"},` +
+		`"size":{"value":3377699720527872,"unit":"B"},"empty":{"value":0,"unit":"B"},"rows":-1234567,"name":"a \"b\""}`
+
+	// The JSON rendering is the same with the human units on and off
+	for _, on := range []bool{true, false} {
+		SetHumanUnits(on)
+		if s := RenderKVJSON(pairs); s != expected {
+			t.Errorf("[%v] got\n%s\nexpected\n%s", on, s, expected)
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,221 @@
+package log
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Messages are counted by (bucket, facility, level) in the ring of retention buckets of the resolution length.
+// The current bucket is updated with atomics, the mutex of the ring is taken only when the bucket is changed.
+
+// UsageStats -- message counters by the facility (StdFacilityAlias for the std one) and the level name
+type UsageStats map[string]map[string]int64
+
+type usageCounters [maxLevels]atomic.Int64
+
+type usageBucket struct {
+	n        int64 // start time / resolution
+	counters sync.Map
+}
+
+type usageRing struct {
+	resolution time.Duration
+	mutex      sync.Mutex
+	slots      []atomic.Pointer[usageBucket]
+	dumped     int64
+}
+
+var (
+	usage     atomic.Pointer[usageRing]
+	usageDump atomic.Bool
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetUsageBuckets -- count messages in the buckets of the resolution length, the last retentionBuckets buckets are kept.
+// Zero resolution disables counting, the collected data is discarded on every call.
+func SetUsageBuckets(resolution time.Duration, retentionBuckets int) {
+	if resolution <= 0 || retentionBuckets <= 0 {
+		usage.Store(nil)
+		return
+	}
+
+	usage.Store(
+		&usageRing{
+			resolution: resolution,
+			slots:      make([]atomic.Pointer[usageBucket], retentionBuckets),
+		},
+	)
+}
+
+// SetUsageDump -- log totals of the previous bucket with the DEBUG level by the flusher
+func SetUsageDump(dump bool) {
+	usageDump.Store(dump)
+}
+
+// Usage -- sum of counters of buckets started in [from, to)
+func Usage(from time.Time, to time.Time) UsageStats {
+	stats := UsageStats{}
+
+	u := usage.Load()
+	if u == nil {
+		return stats
+	}
+
+	first := u.bucket(from)
+	if from.UnixNano()%int64(u.resolution) != 0 {
+		first++
+	}
+	last := u.bucket(to)
+	if to.UnixNano()%int64(u.resolution) == 0 {
+		last--
+	}
+
+	for i := range u.slots {
+		b := u.slots[i].Load()
+		if b == nil || b.n < first || b.n > last {
+			continue
+		}
+		b.addTo(stats)
+	}
+
+	return stats
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func usageCount(facility string, level Level, t time.Time) {
+	u := usage.Load()
+	if u == nil {
+		return
+	}
+
+	if level < EMERG || int(level) >= len(levels) {
+		level = UNKNOWN
+	}
+
+	n := u.bucket(t)
+	i := n % int64(len(u.slots))
+	if i < 0 {
+		i += int64(len(u.slots))
+	}
+	slot := &u.slots[i]
+
+	b := slot.Load()
+	if b == nil || b.n != n {
+		if b = u.rotate(slot, n); b == nil {
+			return
+		}
+	}
+
+	c, exists := b.counters.Load(facility)
+	if !exists {
+		c, _ = b.counters.LoadOrStore(facility, &usageCounters{})
+	}
+	c.(*usageCounters)[level].Add(1)
+}
+
+func (u *usageRing) bucket(t time.Time) int64 {
+	ns := t.UnixNano()
+	n := ns / int64(u.resolution)
+	if ns < 0 && ns%int64(u.resolution) != 0 {
+		n--
+	}
+	return n
+}
+
+// rotate -- the bucket for n, nil if n is older than the bucket in the slot
+func (u *usageRing) rotate(slot *atomic.Pointer[usageBucket], n int64) *usageBucket {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	b := slot.Load()
+	if b != nil && b.n >= n {
+		if b.n == n {
+			return b
+		}
+		return nil
+	}
+
+	b = &usageBucket{n: n}
+	slot.Store(b)
+	return b
+}
+
+func (b *usageBucket) addTo(stats UsageStats) {
+	b.counters.Range(func(k, v any) bool {
+		facility := k.(string)
+		if facility == StdFacilityName {
+			facility = StdFacilityAlias
+		}
+
+		c := v.(*usageCounters)
+		for l := range c {
+			count := c[l].Load()
+			if count == 0 {
+				continue
+			}
+
+			m, exists := stats[facility]
+			if !exists {
+				m = map[string]int64{}
+				stats[facility] = m
+			}
+			m[levels[l].name] += count
+		}
+		return true
+	})
+}
+
+// usageTick -- log totals of the previous bucket once
+func usageTick() {
+	u := usage.Load()
+	if u == nil || !usageDump.Load() {
+		return
+	}
+
+	mutex.Lock()
+	t := now()
+	mutex.Unlock()
+
+	prev := u.bucket(t) - 1
+
+	u.mutex.Lock()
+	if u.dumped >= prev {
+		u.mutex.Unlock()
+		return
+	}
+	u.dumped = prev
+	u.mutex.Unlock()
+
+	from := time.Unix(0, prev*int64(u.resolution))
+	stats := Usage(from, from.Add(u.resolution))
+	if len(stats) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(stats))
+	for name := range stats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	list := make([]string, len(names))
+	for i, name := range names {
+		total := int64(0)
+		for _, n := range stats[name] {
+			total += n
+		}
+		list[i] = fmt.Sprintf("%s=%d", name, total)
+	}
+
+	Message(DEBUG, "usage: %s", strings.Join(list, " "))
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,77 @@
+package log
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestUsageBuckets(t *testing.T) {
+	console := resetLog(t)
+
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	clock := setFakeClock(start)
+
+	SetUsageBuckets(time.Hour, 3)
+
+	http := GetFacility("http")
+	db := GetFacility("db")
+
+	// 10:xx, 11:xx, 12:xx, 13:xx
+	for h := 0; h < 4; h++ {
+		clock.Set(start.Add(time.Duration(h)*time.Hour + 30*time.Minute))
+		for i := 0; i <= h; i++ {
+			http.Message(INFO, "request")
+		}
+		db.Message(ERR, "failed")
+		Message(DEBUG, "std")
+	}
+
+	// 10:xx is evicted
+	if stats := Usage(start, start.Add(time.Hour)); len(stats) != 0 {
+		t.Errorf("evicted bucket is found: %v", stats)
+	}
+
+	expected := UsageStats{
+		"http":           {"INFO": 2 + 3},
+		"db":             {"ERR": 2},
+		StdFacilityAlias: {"DEBUG": 2},
+	}
+	if stats := Usage(start.Add(time.Hour), start.Add(3*time.Hour)); !reflect.DeepEqual(stats, expected) {
+		t.Errorf("got %v, expected %v", stats, expected)
+	}
+
+	if stats := Usage(start, start.Add(24*time.Hour)); stats["http"]["INFO"] != 2+3+4 {
+		t.Errorf("unexpected totals %v", stats)
+	}
+
+	SetUsageDump(true)
+	clock.Set(start.Add(4*time.Hour + time.Minute))
+	console.buf.Reset()
+
+	usageTick()
+	usageTick()
+
+	lines := console.Lines()
+	if len(lines) != 1 || !strings.HasSuffix(lines[0], " DE 2024-05-01 14:01:00.000 usage: <std>=1 db=1 http=4") {
+		t.Errorf("unexpected dump %q", lines)
+	}
+}
+
+func BenchmarkUsageCount(b *testing.B) {
+	SetUsageBuckets(time.Minute, 60)
+	defer SetUsageBuckets(0, 0)
+
+	t := time.Now()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			usageCount("http", INFO, t)
+		}
+	})
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
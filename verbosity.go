@@ -0,0 +1,95 @@
+package log
+
+import (
+	"strconv"
+	"strings"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// The verbosity is another view of the levels from DEBUG to TRACE4: 0 is DEBUG, 1..4 are TRACE1..TRACE4.
+// "V0".."V4" are accepted everywhere the level name is expected. The verbosity of the facility is kept atomically
+// together with its level, so V(n) is a single comparison and can guard the expensive preparation of the message.
+// V(n) doesn't check whether the facility is disabled, Vf does.
+
+const (
+	// MaxVerbosity -- verbosity of TRACE4
+	MaxVerbosity = int(TRACE4 - DEBUG)
+
+	noVerbosity = -1
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// SetVerbosity -- set the facility level by the verbosity, 0 is DEBUG, 1..4 are TRACE1..TRACE4
+func (f *Facility) SetVerbosity(v int) {
+	var notify alertNotifications
+	defer notify.call()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	_, _ = f.setLogLevel(levels[verbosityLevel(v)].name, FuncNameModeKeep, "", "", &notify)
+}
+
+// Verbosity -- the current verbosity of the facility, -1 if DEBUG isn't logged
+func (f *Facility) Verbosity() int {
+	return int(f.verbosity.Load())
+}
+
+// V -- is the verbosity n logged
+func (f *Facility) V(n int) bool {
+	if hierarchicalLevels.Load() {
+		return n < 0 || (n <= MaxVerbosity && verbosityLevel(n).passes(f.token.Load()))
+	}
+	return int32(n) <= f.verbosity.Load()
+}
+
+// Vf -- add message to the log with the level of the verbosity n
+func (f *Facility) Vf(n int, message string, params ...any) {
+	if !f.V(n) {
+		return
+	}
+
+	f.messageEx(1, verbosityLevel(n), false, nil, message, params...)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// setLevel -- set the level, the level token and the verbosity. Must be called under the mutex.
+func (f *Facility) setLevel(level Level) {
+	f.level = level
+	f.token.level.Store(int32(level))
+	levelGeneration.Add(1)
+
+	v := noVerbosity
+	for n := MaxVerbosity; n >= 0; n-- {
+		if verbosityLevel(n).passes(level) {
+			v = n
+			break
+		}
+	}
+	f.verbosity.Store(int32(v))
+}
+
+// verbosityLevel -- the level of the verbosity, clamped to DEBUG..TRACE4
+func verbosityLevel(v int) Level {
+	return DEBUG + Level(min(max(v, 0), MaxVerbosity))
+}
+
+// verbosityLevelByName -- the level for "V0".."V4"
+func verbosityLevelByName(name string) (Level, bool) {
+	s, found := strings.CutPrefix(name, "V")
+	if !found {
+		return UNKNOWN, false
+	}
+
+	v, err := strconv.Atoi(s)
+	if err != nil || v < 0 || v > MaxVerbosity || strconv.Itoa(v) != s {
+		return UNKNOWN, false
+	}
+
+	return verbosityLevel(v), true
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
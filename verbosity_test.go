@@ -0,0 +1,70 @@
+package log
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alrusov/misc"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestVerbosity(t *testing.T) {
+	console := resetLog(t)
+
+	f := GetFacility("verbose")
+	f.SetVerbosity(2)
+
+	if level := f.CurrentLogLevel(); level != TRACE2 {
+		t.Fatalf("got level %d", level)
+	}
+
+	for n := 1; n <= 4; n++ {
+		f.Vf(n, "verbosity %d", n)
+	}
+
+	s := console.String()
+	for n := 1; n <= 4; n++ {
+		logged := strings.Contains(s, "verbosity "+string(rune('0'+n)))
+		if logged != (n <= 2) {
+			t.Errorf("verbosity %d: logged=%v\n%s", n, logged, s)
+		}
+	}
+
+	for n, expected := range []bool{true, true, true, false, false} {
+		if f.V(n) != expected {
+			t.Errorf("V(%d) is %v", n, !expected)
+		}
+	}
+
+	f.SetLogLevel("INFO", FuncNameModeNone)
+	if f.V(0) || f.Verbosity() != -1 {
+		t.Errorf("V(0) on INFO, verbosity %d", f.Verbosity())
+	}
+}
+
+func TestVerbosityNames(t *testing.T) {
+	resetLog(t)
+
+	GetFacility("vnames")
+	if err := SetLogLevels("INFO", misc.StringMap{"vnames": "V3"}, FuncNameModeNone); err != nil {
+		t.Fatal(err)
+	}
+	if level := GetFacility("vnames").CurrentLogLevel(); level != TRACE3 {
+		t.Errorf("got level %d", level)
+	}
+	if !GetFacility("vnames").V(3) || GetFacility("vnames").V(4) {
+		t.Errorf("unexpected verbosity %d", GetFacility("vnames").Verbosity())
+	}
+
+	for _, name := range []string{"V", "V5", "V-1", "V03", "v1"} {
+		if _, ok := Str2Level(name); ok {
+			t.Errorf("%q is accepted", name)
+		}
+	}
+	if level, ok := Str2Level("V0"); !ok || level != DEBUG {
+		t.Errorf("V0 is %d", level)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//